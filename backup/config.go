@@ -0,0 +1,19 @@
+package backup
+
+import (
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// Config is a set of configurations for backups of states.
+type Config struct {
+	// Storage is an interface of factory method for Storage.
+	// The storage must implement storage.NamedConfig, and its path or key is
+	// used as a prefix of backup files.
+	Storage storage.Config
+	// Retention is a duration to keep snapshots.
+	// Snapshots older than it are pruned on save.
+	// If zero, snapshots are kept forever.
+	Retention time.Duration
+}
@@ -0,0 +1,300 @@
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// fileVersion is a version of the backup file format.
+const fileVersion = 2
+
+// maxSaveRetries is the maximum number of retries when the index file has
+// been changed concurrently by someone else.
+const maxSaveRetries = 3
+
+// indexName is a name of the index file under the prefix.
+const indexName = "index.json"
+
+// snapshotDir is a name of the directory for snapshot files under the prefix.
+const snapshotDir = "snapshots"
+
+// File is a format of the snapshot file.
+// A snapshot file is stored for each migration.
+type File struct {
+	// Version is a version of the backup file format.
+	Version int `json:"version"`
+	// Snapshot is a snapshot of the migration.
+	Snapshot
+}
+
+// Snapshot is a set of states taken before applying a migration.
+type Snapshot struct {
+	// CreatedAt is a timestamp when the snapshot was taken.
+	CreatedAt time.Time `json:"created_at"`
+	// States is a list of states affected by the migration.
+	States []State `json:"states"`
+}
+
+// State is a state of a directory and workspace before applying a migration.
+type State struct {
+	// Dir is a working directory of the state.
+	Dir string `json:"dir"`
+	// Workspace is a workspace of the state.
+	Workspace string `json:"workspace"`
+	// State is a raw content of tfstate.
+	// It is null if the state didn't exist before applying the migration.
+	State json.RawMessage `json:"state"`
+}
+
+// Index is a format of the index file.
+// Since the storage interface doesn't have a way to list objects, we keep
+// track of saved snapshots in the index file to prune old ones.
+// It doesn't contain any states.
+type Index struct {
+	// Version is a version of the backup file format.
+	Version int `json:"version"`
+	// Snapshots is a map of migration file name to its index entry.
+	Snapshots map[string]IndexEntry `json:"snapshots"`
+}
+
+// IndexEntry is metadata of a saved snapshot.
+type IndexEntry struct {
+	// CreatedAt is a timestamp when the snapshot was taken.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Controller manages snapshots of states in a storage.
+// Each snapshot is stored in a separate object named by the migration file
+// under a prefix of the storage, and only the latest snapshot is kept for
+// each migration. Snapshots older than the retention are pruned on save.
+type Controller struct {
+	// config customizes behavior of backup management.
+	config Config
+}
+
+// NewController returns a new Controller instance.
+func NewController(config *Config) *Controller {
+	return &Controller{
+		config: *config,
+	}
+}
+
+// Save saves snapshots of states for a given migration file.
+// If the migration already has a snapshot, it is overwritten.
+// After saving, it prunes snapshots older than the retention if set. Note
+// that a failure of pruning is not treated as an error, because it doesn't
+// affect the snapshot just saved.
+func (c *Controller) Save(ctx context.Context, migration string, states []State) error {
+	key := snapshotKey(migration)
+	s, err := c.newStorage(snapshotName(key))
+	if err != nil {
+		return err
+	}
+
+	f := &File{
+		Version: fileVersion,
+		Snapshot: Snapshot{
+			CreatedAt: time.Now().UTC().Round(time.Second),
+			States:    states,
+		},
+	}
+	b, err := json.MarshalIndent(f, "", "    ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup file: %s", err)
+	}
+
+	log.Printf("[DEBUG] [backup] write backup file: %s\n", key)
+	if err := s.Write(ctx, b); err != nil {
+		return err
+	}
+
+	err = c.updateIndex(ctx, func(idx *Index) {
+		idx.Snapshots[key] = IndexEntry{CreatedAt: f.CreatedAt}
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := c.Prune(ctx); err != nil {
+		log.Printf("[WARN] [backup] failed to prune old backups: %s\n", err)
+	}
+	return nil
+}
+
+// Load returns a snapshot of states for a given migration file.
+func (c *Controller) Load(ctx context.Context, migration string) (*Snapshot, error) {
+	s, err := c.newStorage(snapshotName(snapshotKey(migration)))
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := s.Read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("no backup found for migration: %s", migration)
+	}
+
+	var f File
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse backup file: %s", err)
+	}
+	if f.Version != fileVersion {
+		return nil, fmt.Errorf("unknown backup file version: %d", f.Version)
+	}
+
+	return &f.Snapshot, nil
+}
+
+// Prune deletes snapshots older than the retention.
+// If the retention is zero, it does nothing, which means snapshots are kept
+// forever.
+func (c *Controller) Prune(ctx context.Context) error {
+	if c.config.Retention == 0 {
+		return nil
+	}
+
+	s, err := c.newStorage(indexName)
+	if err != nil {
+		return err
+	}
+	idx, _, err := readIndex(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	expiry := time.Now().UTC().Add(-c.config.Retention)
+	expired := []string{}
+	for key, e := range idx.Snapshots {
+		if e.CreatedAt.Before(expiry) {
+			expired = append(expired, key)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+	sort.Strings(expired)
+
+	// Delete snapshot files before updating the index. If we failed to update
+	// the index, the remaining entries will be deleted again next time.
+	for _, key := range expired {
+		log.Printf("[INFO] [backup] prune an old backup: %s\n", key)
+		s, err := c.newStorage(snapshotName(key))
+		if err != nil {
+			return err
+		}
+		d, ok := s.(storage.Deleter)
+		if !ok {
+			return fmt.Errorf("backup storage doesn't support deletion: %T", s)
+		}
+		if err := d.Delete(ctx); err != nil {
+			return err
+		}
+	}
+
+	return c.updateIndex(ctx, func(idx *Index) {
+		for _, key := range expired {
+			// Keep the entry if it has been saved again concurrently.
+			if e, ok := idx.Snapshots[key]; ok && e.CreatedAt.Before(expiry) {
+				delete(idx.Snapshots, key)
+			}
+		}
+	})
+}
+
+// newStorage returns a new instance of storage for a given object name under
+// the prefix.
+func (c *Controller) newStorage(name string) (storage.Storage, error) {
+	nc, ok := c.config.Storage.(storage.NamedConfig)
+	if !ok {
+		return nil, fmt.Errorf("backup storage doesn't support multiple objects: %T", c.config.Storage)
+	}
+	return nc.NewNamedStorage(name)
+}
+
+// updateIndex reads the index file, applies a given function to it and
+// writes it back. If the index file has been changed concurrently, it retries
+// from reading.
+func (c *Controller) updateIndex(ctx context.Context, f func(*Index)) error {
+	s, err := c.newStorage(indexName)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		idx, version, err := readIndex(ctx, s)
+		if err != nil {
+			return err
+		}
+		f(idx)
+
+		b, err := json.MarshalIndent(idx, "", "    ")
+		if err != nil {
+			return fmt.Errorf("failed to encode backup index: %s", err)
+		}
+
+		_, err = s.WriteIfMatch(ctx, b, version)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, storage.ErrVersionConflict) {
+			return err
+		}
+		if i >= maxSaveRetries {
+			return fmt.Errorf("failed to update backup index after %d retries: %s", maxSaveRetries, err)
+		}
+		log.Printf("[WARN] [backup] the backup index has been changed since loaded, retry: %s\n", err)
+	}
+}
+
+// snapshotKey returns a key of snapshot for a given migration file.
+// We use the base name of the migration file so that it doesn't depend on
+// the path where the migration file was given.
+func snapshotKey(migration string) string {
+	return filepath.Base(migration)
+}
+
+// snapshotName returns a name of the snapshot file for a given key.
+func snapshotName(key string) string {
+	return path.Join(snapshotDir, key+".json")
+}
+
+// readIndex reads the index file from a given storage.
+// If it doesn't exist, it returns a new empty one.
+func readIndex(ctx context.Context, s storage.Storage) (*Index, storage.Version, error) {
+	b, version, err := s.ReadWithVersion(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	idx := &Index{
+		Version:   fileVersion,
+		Snapshots: make(map[string]IndexEntry),
+	}
+	if len(b) == 0 {
+		return idx, version, nil
+	}
+
+	if err := json.Unmarshal(b, idx); err != nil {
+		return nil, "", fmt.Errorf("failed to parse backup index: %s", err)
+	}
+	if idx.Version != fileVersion {
+		return nil, "", fmt.Errorf("unknown backup index version: %d", idx.Version)
+	}
+	if idx.Snapshots == nil {
+		idx.Snapshots = make(map[string]IndexEntry)
+	}
+
+	return idx, version, nil
+}
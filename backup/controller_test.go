@@ -0,0 +1,355 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+// writeNamedStorage is a test helper function which writes data to a named
+// mock storage.
+func writeNamedStorage(t *testing.T, config *mock.Config, name string, data string) {
+	t.Helper()
+	s, err := config.NewNamedStorage(name)
+	if err != nil {
+		t.Fatalf("failed to NewNamedStorage: %s", err)
+	}
+	if err := s.Write(context.Background(), []byte(data)); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+}
+
+// readIndexKeys is a test helper function which returns a sorted list of keys
+// in the index file of a mock storage.
+func readIndexKeys(t *testing.T, config *mock.Config) []string {
+	t.Helper()
+	s, err := config.NewNamedStorage(indexName)
+	if err != nil {
+		t.Fatalf("failed to NewNamedStorage: %s", err)
+	}
+	idx, _, err := readIndex(context.Background(), s)
+	if err != nil {
+		t.Fatalf("failed to read index: %s", err)
+	}
+	keys := []string{}
+	for k := range idx.Snapshots {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// compactJSON is a test helper function which returns a compact form of a
+// given JSON.
+func compactJSON(t *testing.T, b json.RawMessage) json.RawMessage {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, b); err != nil {
+		t.Fatalf("failed to compact json: %s", err)
+	}
+	return json.RawMessage(buf.Bytes())
+}
+
+func TestControllerSaveAndLoad(t *testing.T) {
+	cases := []struct {
+		desc      string
+		files     map[string]string
+		migration string
+		states    []State
+		want      []State
+		wantIndex []string
+		ok        bool
+	}{
+		{
+			desc:      "new file",
+			files:     map[string]string{},
+			migration: "tfmigrate/20201109000001_test.hcl",
+			states: []State{
+				{Dir: "foo", Workspace: "default", State: json.RawMessage(`{"serial":1}`)},
+				{Dir: "bar", Workspace: "default", State: nil},
+			},
+			want: []State{
+				{Dir: "foo", Workspace: "default", State: json.RawMessage(`{"serial":1}`)},
+				{Dir: "bar", Workspace: "default", State: json.RawMessage(`null`)},
+			},
+			wantIndex: []string{"20201109000001_test.hcl"},
+			ok:        true,
+		},
+		{
+			desc: "overwrite",
+			files: map[string]string{
+				"index.json": `{
+    "version": 2,
+    "snapshots": {
+        "20201109000001_test.hcl": {"created_at": "2020-11-09T00:00:00Z"},
+        "20201109000002_test.hcl": {"created_at": "2020-11-09T00:00:00Z"}
+    }
+}`,
+				"snapshots/20201109000001_test.hcl.json": `{
+    "version": 2,
+    "created_at": "2020-11-09T00:00:00Z",
+    "states": [
+        {"dir": "foo", "workspace": "default", "state": {"serial":1}}
+    ]
+}`,
+			},
+			migration: "20201109000001_test.hcl",
+			states: []State{
+				{Dir: "foo", Workspace: "default", State: json.RawMessage(`{"serial":2}`)},
+			},
+			want: []State{
+				{Dir: "foo", Workspace: "default", State: json.RawMessage(`{"serial":2}`)},
+			},
+			wantIndex: []string{"20201109000001_test.hcl", "20201109000002_test.hcl"},
+			ok:        true,
+		},
+		{
+			desc: "unknown index version",
+			files: map[string]string{
+				"index.json": `{"version": 0, "snapshots": {}}`,
+			},
+			migration: "20201109000001_test.hcl",
+			states:    []State{},
+			want:      nil,
+			wantIndex: nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &mock.Config{}
+			for name, data := range tc.files {
+				writeNamedStorage(t, config, name, data)
+			}
+
+			c := NewController(&Config{Storage: config})
+			err := c.Save(context.Background(), tc.migration, tc.states)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				return
+			}
+
+			got, err := c.Load(context.Background(), tc.migration)
+			if err != nil {
+				t.Fatalf("failed to load: %s", err)
+			}
+			if got.CreatedAt.IsZero() {
+				t.Errorf("expected created_at to be set, but zero")
+			}
+			// the raw state is re-indented in the backup file, so compare them
+			// in the compact form.
+			for i := range got.States {
+				got.States[i].State = compactJSON(t, got.States[i].State)
+			}
+			if !reflect.DeepEqual(got.States, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got.States, tc.want)
+			}
+
+			gotIndex := readIndexKeys(t, config)
+			if !reflect.DeepEqual(gotIndex, tc.wantIndex) {
+				t.Errorf("got index: %#v, want: %#v", gotIndex, tc.wantIndex)
+			}
+		})
+	}
+}
+
+func TestControllerLoad(t *testing.T) {
+	cases := []struct {
+		desc      string
+		config    *mock.Config
+		files     map[string]string
+		migration string
+		want      []State
+		ok        bool
+	}{
+		{
+			desc:   "found",
+			config: &mock.Config{},
+			files: map[string]string{
+				"snapshots/20201109000001_test.hcl.json": `{
+    "version": 2,
+    "created_at": "2020-11-09T00:00:00Z",
+    "states": [
+        {"dir": "foo", "workspace": "default", "state": {"serial":1}}
+    ]
+}`,
+			},
+			migration: "tfmigrate/20201109000001_test.hcl",
+			want: []State{
+				{Dir: "foo", Workspace: "default", State: json.RawMessage(`{"serial":1}`)},
+			},
+			ok: true,
+		},
+		{
+			desc:      "not found",
+			config:    &mock.Config{},
+			files:     map[string]string{},
+			migration: "20201109000001_test.hcl",
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:   "unknown version",
+			config: &mock.Config{},
+			files: map[string]string{
+				"snapshots/20201109000001_test.hcl.json": `{"version": 1, "states": []}`,
+			},
+			migration: "20201109000001_test.hcl",
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:      "read error",
+			config:    &mock.Config{ReadError: true},
+			files:     map[string]string{},
+			migration: "20201109000001_test.hcl",
+			want:      nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			for name, data := range tc.files {
+				writeNamedStorage(t, tc.config, name, data)
+			}
+
+			c := NewController(&Config{Storage: tc.config})
+			got, err := c.Load(context.Background(), tc.migration)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %#v", got)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got.States, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got.States, tc.want)
+			}
+		})
+	}
+}
+
+func TestControllerSaveConflict(t *testing.T) {
+	c := NewController(&Config{Storage: &mock.Config{WriteConflict: true}})
+	err := c.Save(context.Background(), "20201109000001_test.hcl", []State{})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+// singleConfig is a storage config which doesn't implement
+// storage.NamedConfig for testing.
+type singleConfig struct {
+	storage.Config
+}
+
+func TestControllerSaveNotNamedStorage(t *testing.T) {
+	var config storage.Config = &singleConfig{&mock.Config{}}
+	if _, ok := config.(storage.NamedConfig); ok {
+		t.Fatal("expected the config not to implement storage.NamedConfig")
+	}
+
+	c := NewController(&Config{Storage: config})
+	err := c.Save(context.Background(), "20201109000001_test.hcl", []State{})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestControllerPrune(t *testing.T) {
+	now := time.Now().UTC()
+	oldTime := now.Add(-48 * time.Hour).Format(time.RFC3339)
+	newTime := now.Add(-1 * time.Hour).Format(time.RFC3339)
+
+	cases := []struct {
+		desc         string
+		retention    time.Duration
+		wantIndex    []string
+		wantSnapshot []string
+	}{
+		{
+			desc:         "prune old snapshots",
+			retention:    24 * time.Hour,
+			wantIndex:    []string{"2.hcl"},
+			wantSnapshot: []string{"2.hcl"},
+		},
+		{
+			desc:         "no retention",
+			retention:    0,
+			wantIndex:    []string{"1.hcl", "2.hcl"},
+			wantSnapshot: []string{"1.hcl", "2.hcl"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &mock.Config{}
+			writeNamedStorage(t, config, indexName, `{
+    "version": 2,
+    "snapshots": {
+        "1.hcl": {"created_at": "`+oldTime+`"},
+        "2.hcl": {"created_at": "`+newTime+`"}
+    }
+}`)
+			writeNamedStorage(t, config, snapshotName("1.hcl"), `{"version": 2, "created_at": "`+oldTime+`", "states": []}`)
+			writeNamedStorage(t, config, snapshotName("2.hcl"), `{"version": 2, "created_at": "`+newTime+`", "states": []}`)
+
+			c := NewController(&Config{Storage: config, Retention: tc.retention})
+			if err := c.Prune(context.Background()); err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+
+			gotIndex := readIndexKeys(t, config)
+			if !reflect.DeepEqual(gotIndex, tc.wantIndex) {
+				t.Errorf("got index: %#v, want: %#v", gotIndex, tc.wantIndex)
+			}
+
+			gotSnapshot := []string{}
+			for _, key := range []string{"1.hcl", "2.hcl"} {
+				if _, err := c.Load(context.Background(), key); err == nil {
+					gotSnapshot = append(gotSnapshot, key)
+				}
+			}
+			if !reflect.DeepEqual(gotSnapshot, tc.wantSnapshot) {
+				t.Errorf("got snapshots: %#v, want: %#v", gotSnapshot, tc.wantSnapshot)
+			}
+		})
+	}
+}
+
+func TestControllerSavePrune(t *testing.T) {
+	config := &mock.Config{}
+	oldTime := time.Now().UTC().Add(-48 * time.Hour).Format(time.RFC3339)
+	writeNamedStorage(t, config, indexName, `{"version": 2, "snapshots": {"1.hcl": {"created_at": "`+oldTime+`"}}}`)
+	writeNamedStorage(t, config, snapshotName("1.hcl"), `{"version": 2, "created_at": "`+oldTime+`", "states": []}`)
+
+	c := NewController(&Config{Storage: config, Retention: 24 * time.Hour})
+	if err := c.Save(context.Background(), "2.hcl", []State{}); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got := readIndexKeys(t, config)
+	want := []string{"2.hcl"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got index: %#v, want: %#v", got, want)
+	}
+	if d := config.NamedStorage(snapshotName("1.hcl")).Data(); d != "" {
+		t.Errorf("expected the old snapshot to be deleted, but got: %s", d)
+	}
+}
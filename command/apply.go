@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -13,6 +14,8 @@ import (
 type ApplyCommand struct {
 	Meta
 	backendConfig []string
+	skipVerify    bool
+	jsonReport    string
 }
 
 // Run runs the procedure of this command.
@@ -20,6 +23,8 @@ func (c *ApplyCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("apply", flag.ContinueOnError)
 	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
 	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.BoolVar(&c.skipVerify, "skip-verify", false, "Don't fail when applied migrations have been modified or deleted")
+	cmdFlags.StringVar(&c.jsonReport, "json-report", "", "Write a machine-readable report in JSON format to the given path")
 
 	if err := cmdFlags.Parse(args); err != nil {
 		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
@@ -48,7 +53,10 @@ func (c *ApplyCommand) Run(args []string) int {
 		}
 
 		migrationFile := cmdFlags.Arg(0)
-		if err = c.applyWithoutHistory(migrationFile); err != nil {
+		err = runWithReport(c.jsonReport, "apply", func(report *Report) error {
+			return c.applyWithoutHistory(migrationFile, report)
+		})
+		if err != nil {
 			c.UI.Error(err.Error())
 			return 1
 		}
@@ -70,7 +78,10 @@ func (c *ApplyCommand) Run(args []string) int {
 	}
 
 	// Apply all unapplied pending migrations and save them to history.
-	if err = c.applyWithHistory(migrationFile); err != nil {
+	err = runWithReport(c.jsonReport, "apply", func(report *Report) error {
+		return c.applyWithHistory(migrationFile, report)
+	})
+	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
@@ -79,22 +90,28 @@ func (c *ApplyCommand) Run(args []string) int {
 }
 
 // applyWithoutHistory is a helper function which applies a given migration file without history.
-func (c *ApplyCommand) applyWithoutHistory(filename string) error {
+func (c *ApplyCommand) applyWithoutHistory(filename string, report *Report) error {
 	fr, err := NewFileRunner(filename, c.config, c.Option)
 	if err != nil {
+		report.add(filename, nil, nil, time.Now(), err)
 		return err
 	}
+	fr.report = report
 
 	return fr.Apply(context.Background())
 }
 
 // applyWithHistory is a helper function which applies all unapplied pending migrations and saves them to history.
-func (c *ApplyCommand) applyWithHistory(filename string) error {
+func (c *ApplyCommand) applyWithHistory(filename string, report *Report) error {
 	ctx := context.Background()
 	hr, err := NewHistoryRunner(ctx, filename, c.config, c.Option)
 	if err != nil {
 		return err
 	}
+	hr.skipVerify = c.skipVerify
+	hr.report = report
+	// record the version of tfmigrate in history for auditing.
+	hr.tfmigrateVersion = c.Version
 
 	return hr.Apply(ctx)
 }
@@ -106,6 +123,8 @@ Usage: tfmigrate apply [PATH]
 
 Apply computes a new state and pushes it to remote state.
 It will fail if terraform plan detects any diffs with the new state.
+It will also fail if the remote state has been changed by someone else
+since it was pulled, so that it never overwrites a concurrent update.
 
 Arguments
   PATH                     A path of migration file
@@ -116,6 +135,16 @@ Options:
   --backend-config=path    A backend configuration, a path to backend configuration file or
                            key=value format backend configuraion.
                            This option is passed to terraform init when switching backend to remote.
+
+  --skip-verify            Don't fail when applied migrations have been modified or deleted
+                           since they were applied. They are only reported as warnings.
+                           This option is ignored in non-history mode.
+
+  --json-report=path       Write a machine-readable report in JSON format to the given path.
+                           The report contains a result of each migration file such as
+                           expanded actions, resources added/removed per state, plan results,
+                           results of imports from an import manifest and timings.
+                           It is written even if the migration fails.
 `
 	return strings.TrimSpace(helpText)
 }
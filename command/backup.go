@@ -0,0 +1,70 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/minamijoyo/tfmigrate/backup"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// migrationBackup implements the tfmigrate.Backup interface.
+// It saves snapshots of states keyed by a migration file.
+type migrationBackup struct {
+	// bc is a controller for backup files.
+	bc *backup.Controller
+	// migration is a migration file name.
+	migration string
+}
+
+var _ tfmigrate.Backup = (*migrationBackup)(nil)
+
+// newMigrationBackup returns a new migrationBackup instance.
+func newMigrationBackup(config *backup.Config, migration string) *migrationBackup {
+	return &migrationBackup{
+		bc:        backup.NewController(config),
+		migration: migration,
+	}
+}
+
+// Save saves snapshots of states affected by the migration.
+func (b *migrationBackup) Save(ctx context.Context, snapshots []tfmigrate.StateSnapshot) error {
+	states := make([]backup.State, 0, len(snapshots))
+	for _, s := range snapshots {
+		var raw json.RawMessage
+		if s.State != nil && len(s.State.Bytes()) > 0 {
+			raw = json.RawMessage(s.State.Bytes())
+		}
+		states = append(states, backup.State{
+			Dir:       s.Dir,
+			Workspace: s.Workspace,
+			State:     raw,
+		})
+	}
+
+	return b.bc.Save(ctx, b.migration, states)
+}
+
+// loadSnapshots loads snapshots of states saved for a given migration file.
+func loadSnapshots(ctx context.Context, config *backup.Config, migration string) ([]tfmigrate.StateSnapshot, error) {
+	snapshot, err := backup.NewController(config).Load(ctx, migration)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]tfmigrate.StateSnapshot, 0, len(snapshot.States))
+	for _, s := range snapshot.States {
+		var state *tfexec.State
+		if len(s.State) > 0 && string(s.State) != "null" {
+			state = tfexec.NewState([]byte(s.State))
+		}
+		snapshots = append(snapshots, tfmigrate.StateSnapshot{
+			Dir:       s.Dir,
+			Workspace: s.Workspace,
+			State:     state,
+		})
+	}
+
+	return snapshots, nil
+}
@@ -0,0 +1,181 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfconfig"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// ExportCommand is a command which converts a state migration into
+// configuration blocks such as moved, removed and import blocks.
+type ExportCommand struct {
+	Meta
+	out    string
+	expand bool
+}
+
+// Run runs the procedure of this command.
+func (c *ExportCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("export", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.out, "out", "", "A path to write configuration blocks")
+	cmdFlags.BoolVar(&c.expand, "expand", false, "Expand xmv, rmv and xrm actions against the current remote state")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	filename := cmdFlags.Arg(0)
+	path, err := exportMigration(context.Background(), c.config, filename, c.out, c.expand, c.Option)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("exported: %s", path))
+	return 0
+}
+
+// exportMigration converts a given state migration into configuration blocks
+// and writes them to a file. If the out is empty, it defaults to
+// tfmigrate_<name>.tf in the dir of the migration. It returns the path of
+// the written file. It never overwrites an existing file.
+func exportMigration(ctx context.Context, config *config.TfmigrateConfig, filename string, out string, expand bool, option *tfmigrate.MigratorOption) (string, error) {
+	mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, filename))
+	if err != nil {
+		return "", err
+	}
+
+	sc, ok := mc.Migrator.(*tfmigrate.StateMigratorConfig)
+	if !ok {
+		return "", fmt.Errorf("only a state migration can be exported, but got: %s", mc.Type)
+	}
+
+	dir := "."
+	if len(sc.Dir) > 0 {
+		dir = sc.Dir
+	}
+
+	var stateList []string
+	if expand {
+		workspace := "default"
+		if len(sc.Workspace) > 0 {
+			workspace = sc.Workspace
+		}
+		stateList, err = tfmigrate.ListRemoteState(ctx, dir, workspace, option)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	blocks, err := sc.ExportBlocks(stateList)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := tfconfig.FormatBlocks(blocks)
+	if err != nil {
+		return "", err
+	}
+
+	path := out
+	if len(path) == 0 {
+		path = filepath.Join(dir, "tfmigrate_"+mc.Name+".tf")
+	}
+
+	log.Printf("[INFO] [command] write configuration blocks: %s\n", path)
+	if err := writeNewFile(path, b); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// writeNewFile writes given data to a new file. It never overwrites an
+// existing file. If it fails to write or close the file, it removes the
+// partially written file.
+func writeNewFile(path string, b []byte) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create a file: %s", err)
+	}
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close a file: %s", cerr)
+		}
+		if err != nil {
+			if rerr := os.Remove(path); rerr != nil {
+				log.Printf("[ERROR] [command] failed to remove a partially written file: %s\n", rerr)
+			}
+		}
+	}()
+
+	if _, err := f.Write(b); err != nil {
+		return fmt.Errorf("failed to write a file: %s", err)
+	}
+
+	return nil
+}
+
+// Help returns long-form help text.
+func (c *ExportCommand) Help() string {
+	helpText := `
+Usage: tfmigrate export [options] <PATH>
+
+Export a state migration as configuration blocks for config-driven
+refactoring. The mv, rm and import actions are converted into moved,
+removed and import blocks respectively. The removed blocks have
+lifecycle { destroy = false } so that they never destroy real resources.
+Note that the removed blocks require Terraform v1.7 or later.
+
+The xmv actions with wildcards, the rmv and xrm actions are refused unless
+the --expand flag is set.
+The replace-provider actions are always refused because they have no
+configuration equivalent.
+
+Arguments:
+  PATH               A path of migration file
+
+Options:
+  --config           A path to tfmigrate config file
+  --out              A path to write configuration blocks.
+                     Default to tfmigrate_<name>.tf in the dir of the migration.
+                     It never overwrites an existing file.
+  --expand           Expand xmv, rmv and xrm actions against the current
+                     remote state.
+                     It requires terraform init in the dir of the migration.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ExportCommand) Synopsis() string {
+	return "Export a migration as moved/removed/import blocks"
+}
@@ -0,0 +1,152 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+)
+
+func TestExportMigration(t *testing.T) {
+	cases := []struct {
+		desc      string
+		migration string
+		out       string
+		exists    bool
+		want      string
+		ok        bool
+	}{
+		{
+			desc: "default out",
+			migration: `
+migration "state" "test" {
+  dir = "%s"
+  actions = [
+    "mv aws_security_group.foo aws_security_group.foo2",
+    "rm aws_security_group.bar",
+  ]
+}
+`,
+			out: "",
+			want: `moved {
+  from = aws_security_group.foo
+  to   = aws_security_group.foo2
+}
+
+removed {
+  from = aws_security_group.bar
+  lifecycle {
+    destroy = false
+  }
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "custom out",
+			migration: `
+migration "state" "test" {
+  dir = "%s"
+  actions = [
+    "import aws_security_group.foo sg-1234",
+  ]
+}
+`,
+			out: "import.tf",
+			want: `import {
+  to = aws_security_group.foo
+  id = "sg-1234"
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "file exists",
+			migration: `
+migration "state" "test" {
+  dir = "%s"
+  actions = [
+    "mv aws_security_group.foo aws_security_group.foo2",
+  ]
+}
+`,
+			out:    "",
+			exists: true,
+			ok:     false,
+		},
+		{
+			desc: "multi_state",
+			migration: `
+migration "multi_state" "test" {
+  from_dir = "%s"
+  to_dir   = "dir2"
+  actions = [
+    "mv aws_security_group.foo aws_security_group.foo2",
+  ]
+}
+`,
+			out: "",
+			ok:  false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			migrationDir := setupMigrationDir(t, map[string]string{
+				"20201109000001_test.hcl": strings.ReplaceAll(tc.migration, "%s", dir),
+			})
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+			}
+
+			out := tc.out
+			if len(out) > 0 {
+				out = filepath.Join(dir, out)
+			}
+			defaultOut := filepath.Join(dir, "tfmigrate_test.tf")
+			if tc.exists {
+				if err := os.WriteFile(defaultOut, []byte("# keep\n"), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			path, err := exportMigration(context.Background(), config, "20201109000001_test.hcl", out, false, nil)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				if tc.exists {
+					b, err := os.ReadFile(defaultOut)
+					if err != nil {
+						t.Fatalf("failed to read file: %s", err)
+					}
+					if string(b) != "# keep\n" {
+						t.Errorf("expected an existing file not to be overwritten, but got: %s", string(b))
+					}
+				}
+				return
+			}
+
+			if len(out) == 0 {
+				out = defaultOut
+			}
+			if path != out {
+				t.Errorf("got path: %s, want: %s", path, out)
+			}
+			b, err := os.ReadFile(out)
+			if err != nil {
+				t.Fatalf("failed to read file: %s", err)
+			}
+			if string(b) != tc.want {
+				t.Errorf("got: %s, want: %s", string(b), tc.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
@@ -20,6 +21,8 @@ type FileRunner struct {
 	mc *tfmigrate.MigrationConfig
 	// A migrator instance to be run.
 	m tfmigrate.Migrator
+	// A report to which a result of the migration is added. This is optional.
+	report *Report
 }
 
 // NewFileRunner returns a new FileRunner instance.
@@ -39,6 +42,14 @@ func NewFileRunner(filename string, config *config.TfmigrateConfig, option *tfmi
 		}
 	}
 
+	if config.Backup != nil {
+		// The backup is bound to each migration file, so we copy the option
+		// not to affect other migrations sharing the same option.
+		o := *option
+		o.Backup = newMigrationBackup(config.Backup, filename)
+		option = &o
+	}
+
 	m, err := mc.Migrator.NewMigrator(option)
 
 	if err != nil {
@@ -72,12 +83,29 @@ func loadMigrationFile(filename string) (*tfmigrate.MigrationConfig, error) {
 
 // Plan plans a single migration.
 func (r *FileRunner) Plan(ctx context.Context) error {
-	return r.m.Plan(ctx)
+	start := time.Now()
+	err := r.m.Plan(ctx)
+	r.report.add(r.filename, r.mc, r.m, start, err)
+	return err
 }
 
 // Apply applies a single migration.
 func (r *FileRunner) Apply(ctx context.Context) error {
-	return r.m.Apply(ctx)
+	start := time.Now()
+	err := r.m.Apply(ctx)
+	r.report.add(r.filename, r.mc, r.m, start, err)
+	return err
+}
+
+// ApplyMetadata returns metadata about the applied migration.
+// If the migrator doesn't provide metadata or the migration has not been
+// applied successfully, it returns nil.
+func (r *FileRunner) ApplyMetadata() *tfmigrate.ApplyMetadata {
+	p, ok := r.m.(tfmigrate.ApplyMetadataProvider)
+	if !ok {
+		return nil
+	}
+	return p.ApplyMetadata()
 }
 
 // MigrationConfig returns an instance of migration.
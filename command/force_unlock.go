@@ -0,0 +1,91 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	flag "github.com/spf13/pflag"
+)
+
+// ForceUnlockCommand is a command which releases a stuck lock.
+type ForceUnlockCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *ForceUnlockCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("force-unlock", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	lockID := cmdFlags.Arg(0)
+	ctx := context.Background()
+	if err := forceUnlock(ctx, c.config, lockID); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("lock has been released: %s", lockID))
+	return 0
+}
+
+// forceUnlock releases a lock with a given ID.
+func forceUnlock(ctx context.Context, config *config.TfmigrateConfig, lockID string) error {
+	if config.History == nil || config.History.Lock == nil {
+		return fmt.Errorf("no history lock setting")
+	}
+
+	locker, err := config.History.Lock.NewLocker()
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] [command] force unlock: %s\n", lockID)
+	return locker.Unlock(ctx, lockID)
+}
+
+// Help returns long-form help text.
+func (c *ForceUnlockCommand) Help() string {
+	helpText := `
+Usage: tfmigrate force-unlock [options] <LOCK_ID>
+
+Release a stuck lock of history-mode runs.
+The lock ID is shown in an error message when failing to acquire the lock.
+
+Be very careful with this command. If you release a lock held by someone
+else, multiple processes may apply migrations to the same states at the
+same time.
+
+Arguments:
+  LOCK_ID            A lock ID to be released
+
+Options:
+  --config           A path to tfmigrate config file
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ForceUnlockCommand) Synopsis() string {
+	return "Release a stuck lock of history-mode runs"
+}
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/lock/local"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+func TestForceUnlock(t *testing.T) {
+	lockFile := `{
+    "id": "foo",
+    "owner": "someone-else",
+    "created_at": "2020-11-10T00:00:01Z"
+}`
+	cases := []struct {
+		desc     string
+		lockFile string
+		noLock   bool
+		lockID   string
+		ok       bool
+	}{
+		{
+			desc:     "simple",
+			lockFile: lockFile,
+			noLock:   false,
+			lockID:   "foo",
+			ok:       true,
+		},
+		{
+			desc:     "ID mismatch",
+			lockFile: lockFile,
+			noLock:   false,
+			lockID:   "bar",
+			ok:       false,
+		},
+		{
+			desc:     "not locked",
+			lockFile: "",
+			noLock:   false,
+			lockID:   "foo",
+			ok:       false,
+		},
+		{
+			desc:     "no lock setting",
+			lockFile: lockFile,
+			noLock:   true,
+			lockID:   "foo",
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			lockPath := filepath.Join(t.TempDir(), "history.lock")
+			if len(tc.lockFile) != 0 {
+				if err := os.WriteFile(lockPath, []byte(tc.lockFile), 0600); err != nil {
+					t.Fatalf("failed to write lock file: %s", err)
+				}
+			}
+			historyConfig := &history.Config{
+				Storage: &mock.Config{},
+				Lock: &local.Config{
+					Path: lockPath,
+				},
+			}
+			if tc.noLock {
+				historyConfig.Lock = nil
+			}
+			config := &config.TfmigrateConfig{
+				History: historyConfig,
+			}
+
+			err := forceUnlock(context.Background(), config, tc.lockID)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok {
+				if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+					t.Errorf("expected the lock file to be removed, but got: %v", err)
+				}
+			}
+		})
+	}
+}
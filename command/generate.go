@@ -0,0 +1,33 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/mitchellh/cli"
+)
+
+// GenerateCommand is a parent command of subcommands for generating
+// migration files. It only shows a help of subcommands.
+type GenerateCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *GenerateCommand) Run(_ []string) int {
+	return cli.RunResultHelp
+}
+
+// Help returns long-form help text.
+func (c *GenerateCommand) Help() string {
+	helpText := `
+Usage: tfmigrate generate <subcommand> [options] [args]
+
+This command has subcommands for generating migration files.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *GenerateCommand) Synopsis() string {
+	return "Generate migration files"
+}
@@ -0,0 +1,185 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfconfig"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+	flag "github.com/spf13/pflag"
+)
+
+// GenerateFromMovedCommand is a command which generates a state migration
+// file from moved blocks in Terraform configuration.
+type GenerateFromMovedCommand struct {
+	Meta
+	name  string
+	out   string
+	strip bool
+}
+
+// Run runs the procedure of this command.
+func (c *GenerateFromMovedCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("generate from-moved", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.name, "name", "from_moved", "A name of migration")
+	cmdFlags.StringVar(&c.out, "out", "", "A path to write the migration file")
+	cmdFlags.BoolVar(&c.strip, "strip", false, "Remove moved blocks from configuration files after generating")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	dir := cmdFlags.Arg(0)
+	migration, err := generateFromMoved(dir, c.name)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.out) == 0 {
+		c.UI.Output(strings.TrimSuffix(migration, "\n"))
+	} else {
+		log.Printf("[INFO] [command] write a migration file: %s\n", c.out)
+		if err := os.WriteFile(c.out, []byte(migration), 0644); err != nil {
+			c.UI.Error(fmt.Sprintf("failed to write a migration file: %s", err))
+			return 1
+		}
+	}
+
+	if c.strip {
+		modified, err := tfconfig.StripMovedBlocks(dir)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("failed to strip moved blocks: %s", err))
+			return 1
+		}
+		for _, path := range modified {
+			c.UI.Info(fmt.Sprintf("stripped moved blocks: %s", path))
+		}
+	}
+
+	return 0
+}
+
+// moduleWildcard is a wildcard of an instance key of module in addresses of
+// moved blocks.
+const moduleWildcard = "[*]"
+
+// generateFromMoved returns a content of a state migration file converted
+// from moved blocks in a given directory.
+func generateFromMoved(dir string, name string) (string, error) {
+	moved, err := tfconfig.LoadMovedBlocks(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to load moved blocks: %s", err)
+	}
+	if len(moved) == 0 {
+		return "", fmt.Errorf("no moved blocks found in %s", dir)
+	}
+
+	actions := []string{}
+	for _, m := range moved {
+		log.Printf("[DEBUG] [command] convert a moved block at %s: %s -> %s\n", m.Range, m.From, m.To)
+		if !m.HasWildcard() {
+			actions = append(actions, tfmigrate.FormatAction("mv", m.From, m.To))
+			continue
+		}
+
+		// The instance key of a module with count or for_each cannot be
+		// determined statically, so we convert it to rmv, which matches
+		// each instance in the state with a regex anchored to a whole address.
+		pattern, template, err := movedToRmv(m)
+		if err != nil {
+			return "", err
+		}
+		actions = append(actions, tfmigrate.FormatAction("rmv", pattern, template))
+	}
+
+	return renderStateMigration(name, dir, plainActions(actions)), nil
+}
+
+// movedToRmv returns a pattern and a template of rmv action for a given moved
+// block with wildcards. Each wildcard is converted to a named capture group
+// of an instance key, which is referenced in the destination.
+// If the source is a resource without an instance key, the pattern also
+// matches each instance of the resource with count or for_each.
+// If the source is a module, the pattern matches all resources in it.
+// (e.g.)
+// module.foo[*].aws_instance.foo => module.foo[*].aws_instance.bar
+// rmv 'module\.foo\[(?P<key1>[^\]]+)\]\.aws_instance\.foo(?P<index>\[[^\]]+\])?' module.foo[${key1}].aws_instance.bar${index}
+func movedToRmv(m *tfconfig.MovedBlock) (string, string, error) {
+	// Replace wildcards with a valid key to parse the address.
+	from, err := tfstate.ParseAddress(strings.ReplaceAll(m.From, moduleWildcard, "[0]"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to parse an address of moved block at %s: %s", m.Range, err)
+	}
+
+	var pattern strings.Builder
+	for i, part := range strings.Split(m.From, moduleWildcard) {
+		if i > 0 {
+			fmt.Fprintf(&pattern, `\[(?P<key%d>[^\]]+)\]`, i)
+		}
+		pattern.WriteString(regexp.QuoteMeta(part))
+	}
+
+	var template strings.Builder
+	for i, part := range strings.Split(m.To, moduleWildcard) {
+		if i > 0 {
+			fmt.Fprintf(&template, "[${key%d}]", i)
+		}
+		template.WriteString(strings.ReplaceAll(part, "$", "$$"))
+	}
+
+	switch {
+	case from.IsModule():
+		pattern.WriteString(`(?P<rest>(?:\[[^\]]+\])?\..+)`)
+		template.WriteString("${rest}")
+	case !from.IsInstance():
+		pattern.WriteString(`(?P<index>\[[^\]]+\])?`)
+		template.WriteString("${index}")
+	}
+
+	return pattern.String(), template.String(), nil
+}
+
+// Help returns long-form help text.
+func (c *GenerateFromMovedCommand) Help() string {
+	helpText := `
+Usage: tfmigrate generate from-moved [options] <DIR>
+
+Generate a state migration file from moved blocks in Terraform configuration
+files in a given directory. Moved blocks in local modules called from the
+directory are also converted with addresses prefixed by the module path.
+If a module has count or for_each, or a moved block moves such a module
+call, the instance key cannot be determined statically, so the action is
+converted to rmv, which matches each instance in the state.
+
+Note that it doesn't check the current state. The generated file should be
+planned before applying.
+
+Arguments:
+  DIR                A working directory of Terraform configuration
+
+Options:
+  --name             A name of migration (default: from_moved)
+  --out              A path to write the migration file.
+                     If not set, print it to stdout.
+  --strip            Remove moved blocks from configuration files after
+                     generating
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *GenerateFromMovedCommand) Synopsis() string {
+	return "Generate a migration file from moved blocks"
+}
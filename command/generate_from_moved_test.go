@@ -0,0 +1,303 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfconfig"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestGenerateFromMoved(t *testing.T) {
+	cases := []struct {
+		desc  string
+		files map[string]string
+		want  string
+		ok    bool
+	}{
+		{
+			desc: "simple",
+			files: map[string]string{
+				"main.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+
+moved {
+  from = aws_instance.baz[0]
+  to   = aws_instance.baz["a"]
+}
+
+module "foo" {
+  source   = "./modules/foo"
+  for_each = toset(["a", "b"])
+}
+`,
+				"modules/foo/main.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+`,
+			},
+			want: `migration "state" "test" {
+  dir = "%s"
+  actions = [
+    "mv aws_instance.foo aws_instance.bar",
+    "mv aws_instance.baz[0] 'aws_instance.baz[\"a\"]'",
+    "rmv 'module\\.foo\\[(?P<key1>[^\\]]+)\\]\\.aws_instance\\.foo(?P<index>\\[[^\\]]+\\])?' module.foo[$${key1}].aws_instance.bar$${index}",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc: "no moved blocks",
+			files: map[string]string{
+				"main.tf": `resource "aws_instance" "foo" {}`,
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			got, err := generateFromMoved(dir, "test")
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %s", got)
+				}
+				return
+			}
+
+			want := fmt.Sprintf(tc.want, dir)
+			if got != want {
+				t.Errorf("got: %s, want: %s", got, want)
+			}
+
+			// The generated file should be a valid migration.
+			mc, err := config.ParseMigrationFile("test.hcl", []byte(got))
+			if err != nil {
+				t.Fatalf("failed to parse a generated migration: %s", err)
+			}
+			sc := mc.Migrator.(*tfmigrate.StateMigratorConfig)
+			if errs := sc.Validate(); len(errs) != 0 {
+				t.Errorf("unexpected validation errors: %v", errs)
+			}
+			wantLast := `rmv 'module\.foo\[(?P<key1>[^\]]+)\]\.aws_instance\.foo(?P<index>\[[^\]]+\])?' module.foo[${key1}].aws_instance.bar${index}`
+			if last := sc.Actions[len(sc.Actions)-1]; last != wantLast {
+				t.Errorf("got action: %s, want: %s", last, wantLast)
+			}
+		})
+	}
+}
+
+func TestGenerateFromMovedExpand(t *testing.T) {
+	cases := []struct {
+		desc      string
+		files     map[string]string
+		stateList []string
+		want      []string
+	}{
+		{
+			desc: "count resource in for_each module",
+			files: map[string]string{
+				"main.tf": `
+module "foo" {
+  source   = "./modules/foo"
+  for_each = toset(["a", "b"])
+}
+`,
+				"modules/foo/main.tf": `
+resource "aws_instance" "foo" {
+  count = 2
+}
+
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+`,
+			},
+			stateList: []string{
+				`module.foo["a"].aws_instance.foo[0]`,
+				`module.foo["a"].aws_instance.foo[1]`,
+				`module.foo["a"].aws_instance.foo_other`,
+				`module.foo["b"].aws_instance.foo[0]`,
+				`module.foo["b"].module.baz.aws_instance.foo`,
+				`aws_instance.foo`,
+			},
+			want: []string{
+				`module.foo["a"].aws_instance.foo[0] -> module.foo["a"].aws_instance.bar[0]`,
+				`module.foo["a"].aws_instance.foo[1] -> module.foo["a"].aws_instance.bar[1]`,
+				`module.foo["b"].aws_instance.foo[0] -> module.foo["b"].aws_instance.bar[0]`,
+			},
+		},
+		{
+			desc: "module in count module",
+			files: map[string]string{
+				"main.tf": `
+module "foo" {
+  source = "./modules/foo"
+  count  = 2
+}
+`,
+				"modules/foo/main.tf": `
+module "new" {
+  source = "./modules/bar"
+}
+
+moved {
+  from = module.old
+  to   = module.new
+}
+`,
+				"modules/foo/modules/bar/main.tf": `
+resource "aws_instance" "foo" {}
+`,
+			},
+			stateList: []string{
+				`module.foo[0].module.old.aws_instance.foo`,
+				`module.foo[0].module.old.module.baz[0].aws_instance.foo`,
+				`module.foo[1].module.old.aws_instance.foo`,
+				`module.foo[1].module.older.aws_instance.foo`,
+			},
+			want: []string{
+				`module.foo[0].module.old.aws_instance.foo -> module.foo[0].module.new.aws_instance.foo`,
+				`module.foo[0].module.old.module.baz[0].aws_instance.foo -> module.foo[0].module.new.module.baz[0].aws_instance.foo`,
+				`module.foo[1].module.old.aws_instance.foo -> module.foo[1].module.new.aws_instance.foo`,
+			},
+		},
+		{
+			desc: "string key with quotes",
+			files: map[string]string{
+				"main.tf": `
+moved {
+  from = aws_instance.foo["it's"]
+  to   = aws_instance.bar["a \"b\""]
+}
+`,
+			},
+			stateList: []string{},
+			want: []string{
+				`aws_instance.foo["it's"] -> aws_instance.bar["a \"b\""]`,
+			},
+		},
+		{
+			desc: "count module call",
+			files: map[string]string{
+				"main.tf": `
+module "new" {
+  source = "./modules/bar"
+  count  = 2
+}
+
+moved {
+  from = module.old
+  to   = module.new
+}
+`,
+				"modules/bar/main.tf": `
+resource "aws_instance" "foo" {}
+`,
+			},
+			stateList: []string{
+				`module.old[0].aws_instance.foo`,
+				`module.old[1].aws_instance.foo`,
+				`module.old[1].module.baz.aws_instance.foo`,
+				`module.older[0].aws_instance.foo`,
+			},
+			want: []string{
+				`module.old[0].aws_instance.foo -> module.new[0].aws_instance.foo`,
+				`module.old[1].aws_instance.foo -> module.new[1].aws_instance.foo`,
+				`module.old[1].module.baz.aws_instance.foo -> module.new[1].module.baz.aws_instance.foo`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tc.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatalf("failed to create dir: %s", err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write file: %s", err)
+				}
+			}
+
+			migration, err := generateFromMoved(dir, "test")
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			mc, err := config.ParseMigrationFile("test.hcl", []byte(migration))
+			if err != nil {
+				t.Fatalf("failed to parse a generated migration: %s", err)
+			}
+			sc := mc.Migrator.(*tfmigrate.StateMigratorConfig)
+
+			// Expand the generated actions against the state.
+			blocks, err := sc.ExportBlocks(tc.stateList)
+			if err != nil {
+				t.Fatalf("failed to expand actions: %s", err)
+			}
+			got := []string{}
+			for _, b := range blocks {
+				m := b.(*tfconfig.MovedBlock)
+				got = append(got, m.From+" -> "+m.To)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHCLString(t *testing.T) {
+	cases := []struct {
+		desc string
+		s    string
+		want string
+	}{
+		{
+			desc: "simple",
+			s:    "mv aws_instance.foo aws_instance.bar",
+			want: `"mv aws_instance.foo aws_instance.bar"`,
+		},
+		{
+			desc: "escape",
+			s:    `mv 'foo["a"]' foo[${1}] %{x} \`,
+			want: `"mv 'foo[\"a\"]' foo[$${1}] %%{x} \\"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := hclString(tc.s)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
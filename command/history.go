@@ -0,0 +1,112 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/kylelemons/godebug/diff"
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/mitchellh/cli"
+)
+
+// HistoryCommand is a parent command of subcommands for managing history.
+// It only shows a help of subcommands.
+type HistoryCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryCommand) Run(_ []string) int {
+	return cli.RunResultHelp
+}
+
+// Help returns long-form help text.
+func (c *HistoryCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history <subcommand> [options] [args]
+
+This command has subcommands for managing migration history.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryCommand) Synopsis() string {
+	return "Manage migration history"
+}
+
+// newHistoryController is a helper function which loads a history controller.
+func newHistoryController(ctx context.Context, config *config.TfmigrateConfig) (*history.Controller, error) {
+	if config.History == nil {
+		return nil, fmt.Errorf("no history setting")
+	}
+
+	return history.NewController(ctx, config.MigrationDir, config.History)
+}
+
+// updateHistory is a helper function which updates history with a given
+// function and saves it. If a lock is configured, it acquires the lock and
+// reloads the latest history before updating.
+// If dryRun is true, it doesn't save the history and returns a diff of the
+// history file instead.
+func updateHistory(ctx context.Context, config *config.TfmigrateConfig, dryRun bool, update func(hc *history.Controller) error) (out string, err error) {
+	hc, err := newHistoryController(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	if !dryRun && config.History.Lock != nil {
+		locker, info, lerr := lockHistory(ctx, config.History.Lock, hc)
+		if lerr != nil {
+			return "", lerr
+		}
+		defer func() {
+			// be sure not to overwrite an original error generated by outside of defer
+			log.Printf("[INFO] [command] release lock: %s\n", info.ID)
+			uerr := locker.Unlock(ctx, info.ID)
+			if uerr == nil {
+				log.Print("[INFO] [command] lock released\n")
+				return
+			}
+
+			// return a named error from defer
+			log.Printf("[ERROR] [command] failed to release lock. You can release it manually with `tfmigrate force-unlock %s`\n", info.ID)
+			if err == nil {
+				err = fmt.Errorf("history updated, but failed to release lock: %v", uerr)
+				return
+			}
+			err = fmt.Errorf("failed to release lock: %v, %v", uerr, err)
+		}()
+	}
+
+	before, err := hc.Serialize()
+	if err != nil {
+		return "", err
+	}
+
+	if err := update(hc); err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		after, err := hc.Serialize()
+		if err != nil {
+			return "", err
+		}
+		if string(before) == string(after) {
+			return "No changes.", nil
+		}
+		return diff.Diff(string(before), string(after)), nil
+	}
+
+	log.Print("[INFO] [command] save history\n")
+	if err := hc.Save(ctx); err != nil {
+		return "", err
+	}
+	log.Print("[INFO] [command] history saved\n")
+
+	return "", nil
+}
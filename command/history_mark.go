@@ -0,0 +1,121 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryMarkCommand is a command which records a migration as applied
+// without running it.
+type HistoryMarkCommand struct {
+	Meta
+	dryRun bool
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryMarkCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history mark", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.BoolVar(&c.dryRun, "dry-run", false, "Show a diff of the history file without saving it")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	filename := cmdFlags.Arg(0)
+	out, err := markHistory(context.Background(), c.config, filename, c.Version, c.dryRun)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(out)
+	return 0
+}
+
+// markHistory records a given migration as applied without running it.
+// The tfmigrateVersion is recorded in metadata for auditing.
+func markHistory(ctx context.Context, config *config.TfmigrateConfig, filename string, tfmigrateVersion string, dryRun bool) (string, error) {
+	out, err := updateHistory(ctx, config, dryRun, func(hc *history.Controller) error {
+		if hc.AlreadyApplied(filename) {
+			return fmt.Errorf("a migration has already been applied: %s", filename)
+		}
+
+		found := false
+		for _, m := range hc.Migrations() {
+			if m == filename {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("a migration file not found in migration dir: %s", filename)
+		}
+
+		mc, err := loadMigrationFile(resolveMigrationFile(config.MigrationDir, filename))
+		if err != nil {
+			return err
+		}
+
+		// There are no actions and states because the migration is not run.
+		md := &history.Metadata{
+			TfmigrateVersion: tfmigrateVersion,
+			User:             currentUser(),
+			Hostname:         currentHostname(),
+			Actions:          []string{},
+			States:           []history.StateMetadata{},
+		}
+		log.Printf("[INFO] [command] add a record to history: %s\n", filename)
+		hc.AddRecord(filename, mc.Type, mc.Name, nil, md)
+		return nil
+	})
+	if err != nil || dryRun {
+		return out, err
+	}
+
+	return fmt.Sprintf("marked as applied: %s", filename), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryMarkCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history mark [options] <FILE>
+
+Record a migration as applied without running it.
+This is useful when you have already migrated states manually, for example,
+with terraform state mv.
+
+Arguments:
+  FILE               A file name of migration in the migration directory
+
+Options:
+  --config           A path to tfmigrate config file
+  --dry-run          Show a diff of the history file without saving it
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryMarkCommand) Synopsis() string {
+	return "Record a migration as applied without running it"
+}
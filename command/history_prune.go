@@ -0,0 +1,92 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryPruneCommand is a command which deletes records of migrations whose
+// files no longer exist.
+type HistoryPruneCommand struct {
+	Meta
+	dryRun bool
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryPruneCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history prune", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.BoolVar(&c.dryRun, "dry-run", false, "Show a diff of the history file without saving it")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 0 {
+		c.UI.Error(fmt.Sprintf("The command expects no arguments, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	out, err := pruneHistory(context.Background(), c.config, c.dryRun)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(out)
+	return 0
+}
+
+// pruneHistory deletes records of migrations whose files no longer exist.
+func pruneHistory(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+	var pruned []string
+	out, err := updateHistory(ctx, config, dryRun, func(hc *history.Controller) error {
+		pruned = hc.PruneRecords()
+		log.Printf("[INFO] [command] delete records from history: %v\n", pruned)
+		return nil
+	})
+	if err != nil || dryRun {
+		return out, err
+	}
+
+	if len(pruned) == 0 {
+		return "no records to prune", nil
+	}
+
+	return fmt.Sprintf("pruned:\n%s", strings.Join(pruned, "\n")), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryPruneCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history prune [options]
+
+Delete records of migrations whose files no longer exist in the migration
+directory.
+
+Options:
+  --config           A path to tfmigrate config file
+  --dry-run          Show a diff of the history file without saving it
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryPruneCommand) Synopsis() string {
+	return "Delete records of migrations whose files no longer exist"
+}
@@ -0,0 +1,96 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryResolveCommand is a command which deletes an incomplete mark of a
+// migration from history.
+type HistoryResolveCommand struct {
+	Meta
+	dryRun bool
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryResolveCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history resolve", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.BoolVar(&c.dryRun, "dry-run", false, "Show a diff of the history file without saving it")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	filename := cmdFlags.Arg(0)
+	out, err := resolveHistory(context.Background(), c.config, filename, c.dryRun)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(out)
+	return 0
+}
+
+// resolveHistory deletes an incomplete mark of a given migration from history.
+func resolveHistory(ctx context.Context, config *config.TfmigrateConfig, filename string, dryRun bool) (string, error) {
+	out, err := updateHistory(ctx, config, dryRun, func(hc *history.Controller) error {
+		log.Printf("[INFO] [command] delete an incomplete mark from history: %s\n", filename)
+		return hc.ResolveIncomplete(filename)
+	})
+	if err != nil || dryRun {
+		return out, err
+	}
+
+	return fmt.Sprintf("resolved: %s", filename), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryResolveCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history resolve [options] <FILE>
+
+Delete an incomplete mark of a migration from history.
+A migration is marked as incomplete when its apply failed in the middle of
+pushing states and left them inconsistent. While any migrations are marked,
+tfmigrate plan and apply in history mode refuse to run.
+Run this after recovering the states manually.
+
+Note that this doesn't record the migration as applied. If you have
+completed the migration manually, run tfmigrate history mark <FILE>.
+
+Arguments:
+  FILE               A file name of migration marked as incomplete
+
+Options:
+  --config           A path to tfmigrate config file
+  --dry-run          Show a diff of the history file without saving it
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryResolveCommand) Synopsis() string {
+	return "Delete an incomplete mark of a migration from history"
+}
@@ -2,11 +2,16 @@ package command
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/user"
+	"time"
 
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/lock"
 	"github.com/minamijoyo/tfmigrate/tfmigrate"
 )
 
@@ -23,6 +28,13 @@ type HistoryRunner struct {
 	option *tfmigrate.MigratorOption
 	// A controller which manages history.
 	hc *history.Controller
+	// A version of tfmigrate recorded in history. This is optional.
+	tfmigrateVersion string
+	// If true, don't fail when applied migrations have been modified or
+	// deleted since they were applied. They are only reported as warnings.
+	skipVerify bool
+	// A report to which results of migrations are added. This is optional.
+	report *Report
 }
 
 // NewHistoryRunner returns a new HistoryRunner instance.
@@ -46,6 +58,14 @@ func NewHistoryRunner(ctx context.Context, filename string, config *config.Tfmig
 // If a filename is set, run a single migration.
 // If not set, run all unapplied migrations.
 func (r *HistoryRunner) Plan(ctx context.Context) error {
+	if err := r.checkIncomplete(); err != nil {
+		return err
+	}
+
+	if err := r.verify(); err != nil {
+		return err
+	}
+
 	if len(r.filename) != 0 {
 		// file mode
 		return r.planFile(ctx, r.filename)
@@ -64,8 +84,10 @@ func (r *HistoryRunner) planFile(ctx context.Context, filename string) error {
 	fr, err := NewFileRunner(filename, r.config, r.option)
 	if err != nil {
 		log.Printf("[ERROR] [runner] failed to plan: %s\n", filename)
+		r.report.add(filename, nil, nil, time.Now(), err)
 		return err
 	}
+	fr.report = r.report
 
 	return fr.Plan(ctx)
 }
@@ -94,14 +116,57 @@ func (r *HistoryRunner) planDir(ctx context.Context) error {
 // If a filename is set, run a single migration.
 // If not set, run all unapplied migrations.
 func (r *HistoryRunner) Apply(ctx context.Context) (err error) {
+	// Acquire a lock before applying the first unapplied migration.
+	// Note that a deferred function to release the lock must be registered
+	// before the one to save history so that the lock is released after
+	// saving history.
+	if r.config.History.Lock != nil && r.hasUnapplied() {
+		locker, info, lerr := lockHistory(ctx, r.config.History.Lock, r.hc)
+		if lerr != nil {
+			return lerr
+		}
+		hb := startLockHeartbeat(ctx, locker, info.ID, lockHeartbeatInterval(info))
+		defer func() {
+			// stop renewing the lock before releasing it.
+			hb.stop()
+
+			// be sure not to overwrite an original error generated by outside of defer
+			log.Printf("[INFO] [runner] release lock: %s\n", info.ID)
+			uerr := locker.Unlock(ctx, info.ID)
+			if uerr == nil {
+				log.Print("[INFO] [runner] lock released\n")
+				return
+			}
+
+			// return a named error from defer
+			log.Printf("[ERROR] [runner] failed to release lock. You can release it manually with `tfmigrate force-unlock %s`\n", info.ID)
+			if err == nil {
+				err = fmt.Errorf("apply succeed, but failed to release lock: %v", uerr)
+				return
+			}
+			err = fmt.Errorf("failed to release lock: %v, %v", uerr, err)
+		}()
+	}
+
+	// Verify the history after acquiring the lock because it may have been
+	// reloaded.
+	if err := r.checkIncomplete(); err != nil {
+		return err
+	}
+	if err := r.verify(); err != nil {
+		return err
+	}
+
 	// save history on exit
 	beforeLen := r.hc.HistoryLength()
+	beforeIncompleteLen := len(r.hc.IncompleteMigrations())
 	defer func() {
 		// if the number of records in history doesn't change,
 		// we don't want to update a timestamp of history file.
 		afterLen := r.hc.HistoryLength()
+		afterIncompleteLen := len(r.hc.IncompleteMigrations())
 		log.Printf("[DEBUG] [runner] length of history records: beforeLen = %d, afterLen = %d\n", beforeLen, afterLen)
-		if beforeLen == afterLen {
+		if beforeLen == afterLen && beforeIncompleteLen == afterIncompleteLen {
 			return
 		}
 
@@ -133,6 +198,142 @@ func (r *HistoryRunner) Apply(ctx context.Context) (err error) {
 	return err
 }
 
+// verify checks whether applied migrations have been modified or deleted
+// since they were applied. If skipVerify is true, discrepancies are only
+// logged as warnings.
+func (r *HistoryRunner) verify() error {
+	discrepancies, err := r.hc.Verify()
+	if err != nil {
+		return fmt.Errorf("failed to verify history: %s", err)
+	}
+
+	if len(discrepancies) == 0 {
+		return nil
+	}
+
+	for _, d := range discrepancies {
+		log.Printf("[WARN] [runner] applied migration does not match history: %s\n", d)
+	}
+
+	if r.skipVerify {
+		log.Printf("[WARN] [runner] ignore %d applied migrations which do not match history\n", len(discrepancies))
+		return nil
+	}
+
+	return fmt.Errorf("%d applied migrations have been modified or deleted since they were applied. Run `tfmigrate history verify` for details, or use --skip-verify to ignore them", len(discrepancies))
+}
+
+// checkIncomplete returns an error if there are migrations whose apply failed
+// in the middle, because applying other migrations on inconsistent states
+// makes it harder to recover them.
+func (r *HistoryRunner) checkIncomplete() error {
+	incompletes := r.hc.IncompleteMigrations()
+	if len(incompletes) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("incomplete migrations found: %v. Recover the states manually by following the reason recorded in `tfmigrate history show`, and then run `tfmigrate history resolve <FILE>`", incompletes)
+}
+
+// hasUnapplied returns true if there are migrations to be applied.
+func (r *HistoryRunner) hasUnapplied() bool {
+	if len(r.filename) != 0 {
+		// file mode
+		return !r.hc.AlreadyApplied(r.filename)
+	}
+
+	// directory mode
+	return len(r.hc.UnappliedMigrations()) != 0
+}
+
+// lockHistory acquires a lock for history-mode runs and reloads the latest
+// history, because it may have been updated by someone else before the lock
+// is acquired. It returns a locker and information about the acquired lock.
+func lockHistory(ctx context.Context, lockConfig lock.Config, hc *history.Controller) (lock.Locker, *lock.Info, error) {
+	locker, err := lockConfig.NewLocker()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Print("[INFO] [runner] acquire lock\n")
+	info, err := locker.Lock(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire lock: %s", err)
+	}
+	log.Printf("[INFO] [runner] lock acquired: %s\n", info)
+
+	if err := hc.Reload(ctx); err != nil {
+		if uerr := locker.Unlock(ctx, info.ID); uerr != nil {
+			return nil, nil, fmt.Errorf("failed to release lock: %v, failed to reload history: %v", uerr, err)
+		}
+		return nil, nil, err
+	}
+
+	return locker, info, nil
+}
+
+// lockHeartbeat renews a lock periodically in background while it is held,
+// so that the lock is not taken over by others even if applying migrations
+// takes longer than the TTL of the lock.
+type lockHeartbeat struct {
+	// cancel stops the background goroutine.
+	cancel context.CancelFunc
+	// done is closed when the background goroutine exits.
+	done chan struct{}
+}
+
+// lockHeartbeatInterval returns an interval of renewing a given lock.
+// We renew it three times per TTL so that a single failure of renewal doesn't
+// let it expire. If the lock never expires, it returns zero.
+func lockHeartbeatInterval(info *lock.Info) time.Duration {
+	return info.TTL() / 3
+}
+
+// startLockHeartbeat starts renewing a lock with a given ID at a given
+// interval. If the interval is zero, it does nothing.
+// The returned heartbeat must be stopped before releasing the lock.
+func startLockHeartbeat(ctx context.Context, locker lock.Locker, id string, interval time.Duration) *lockHeartbeat {
+	ctx, cancel := context.WithCancel(ctx)
+	hb := &lockHeartbeat{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	if interval <= 0 {
+		close(hb.done)
+		return hb
+	}
+
+	go func() {
+		defer close(hb.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				log.Printf("[DEBUG] [runner] renew lock: %s\n", id)
+				if err := locker.Renew(ctx, id); err != nil {
+					// If the context has been canceled, the heartbeat is being stopped.
+					if ctx.Err() != nil {
+						return
+					}
+					log.Printf("[ERROR] [runner] failed to renew lock. The lock may be taken over by someone else: %s\n", err)
+				}
+			}
+		}
+	}()
+
+	return hb
+}
+
+// stop stops renewing the lock and waits for the background goroutine to exit.
+func (hb *lockHeartbeat) stop() {
+	hb.cancel()
+	<-hb.done
+}
+
 // applyFile applies a single migration.
 func (r *HistoryRunner) applyFile(ctx context.Context, filename string) error {
 	if r.hc.AlreadyApplied(filename) {
@@ -141,22 +342,79 @@ func (r *HistoryRunner) applyFile(ctx context.Context, filename string) error {
 
 	fr, err := NewFileRunner(filename, r.config, r.option)
 	if err != nil {
+		r.report.add(filename, nil, nil, time.Now(), err)
 		return err
 	}
+	fr.report = r.report
 
+	start := time.Now()
 	err = fr.Apply(ctx)
 	if err != nil {
 		log.Printf("[ERROR] [runner] failed to apply: %s\n", filename)
+		var ierr *tfmigrate.IncompleteApplyError
+		if errors.As(err, &ierr) {
+			mc := fr.MigrationConfig()
+			log.Printf("[ERROR] [runner] mark the migration as incomplete: %s\n", filename)
+			r.hc.MarkIncomplete(filename, mc.Type, mc.Name, ierr.Error())
+		}
 		return err
 	}
+	duration := time.Since(start)
 
 	mc := fr.MigrationConfig()
+	md := r.newMetadata(fr.ApplyMetadata(), duration)
 	log.Printf("[INFO] [runner] add a record to history: %s\n", filename)
-	r.hc.AddRecord(filename, mc.Type, mc.Name, nil)
+	r.hc.AddRecord(filename, mc.Type, mc.Name, nil, md)
 
 	return nil
 }
 
+// newMetadata builds metadata recorded in history for auditing.
+// The applyMetadata is optional and can be nil.
+func (r *HistoryRunner) newMetadata(applyMetadata *tfmigrate.ApplyMetadata, duration time.Duration) *history.Metadata {
+	md := &history.Metadata{
+		TfmigrateVersion: r.tfmigrateVersion,
+		User:             currentUser(),
+		Hostname:         currentHostname(),
+		Duration:         duration,
+		Actions:          []string{},
+		States:           []history.StateMetadata{},
+	}
+
+	if applyMetadata == nil {
+		return md
+	}
+
+	md.ExecType = applyMetadata.ExecType
+	md.ExecVersion = applyMetadata.ExecVersion
+	md.Actions = append(md.Actions, applyMetadata.Actions...)
+	for _, s := range applyMetadata.States {
+		md.States = append(md.States, history.StateMetadata(s))
+	}
+
+	return md
+}
+
+// currentUser returns a name of the current user.
+// If it fails to get it, fallback to unknown.
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+	return u.Username
+}
+
+// currentHostname returns a hostname of the current machine.
+// If it fails to get it, fallback to unknown.
+func currentHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return hostname
+}
+
 // applyDir applies all unapplied migrations.
 func (r *HistoryRunner) applyDir(ctx context.Context) (err error) {
 	unapplied := r.hc.UnappliedMigrations()
@@ -2,12 +2,17 @@ package command
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/minamijoyo/tfmigrate/config"
 	"github.com/minamijoyo/tfmigrate/history"
+	lockMock "github.com/minamijoyo/tfmigrate/lock/mock"
 	"github.com/minamijoyo/tfmigrate/storage/mock"
 )
 
@@ -746,9 +751,543 @@ migration "mock" "test4" {
 				t.Fatalf("failed to parse history file (got): %s", err)
 			}
 			recordObj := history.Record{}
-			if diff := cmp.Diff(*got, *want, cmp.AllowUnexported(*got), cmpopts.IgnoreFields(recordObj, "AppliedAt")); diff != "" {
+			if diff := cmp.Diff(*got, *want, cmp.AllowUnexported(*got), cmpopts.IgnoreFields(recordObj, "AppliedAt", "Checksum", "Metadata")); diff != "" {
 				t.Errorf("got = %#v, want = %#v, diff = %s", got, want, diff)
 			}
 		})
 	}
 }
+
+func TestHistoryRunnerApplyWithLock(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000003_test3.hcl": `
+migration "mock" "test3" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	historyFile := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        }
+    }
+}`
+	cases := []struct {
+		desc        string
+		latest      string
+		locked      bool
+		unlockError bool
+		want        string
+		lockHistory []string
+		ok          bool
+	}{
+		{
+			desc:        "lock and unlock",
+			latest:      historyFile,
+			locked:      false,
+			unlockError: false,
+			want: `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "test2",
+            "applied_at": "2020-11-10T00:00:02Z"
+        },
+        "20201109000003_test3.hcl": {
+            "type": "mock",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z"
+        }
+    }
+}`,
+			lockHistory: []string{"lock", "unlock"},
+			ok:          true,
+		},
+		{
+			desc:        "locked by someone else",
+			latest:      historyFile,
+			locked:      true,
+			unlockError: false,
+			want:        historyFile,
+			lockHistory: nil,
+			ok:          false,
+		},
+		{
+			desc:        "unlock error",
+			latest:      historyFile,
+			locked:      false,
+			unlockError: true,
+			want: `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "test2",
+            "applied_at": "2020-11-10T00:00:02Z"
+        },
+        "20201109000003_test3.hcl": {
+            "type": "mock",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z"
+        }
+    }
+}`,
+			lockHistory: []string{"lock"},
+			ok:          false,
+		},
+		{
+			desc: "history updated by someone else before lock",
+			latest: `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "applied-by-someone-else",
+            "applied_at": "2020-11-10T00:00:02Z"
+        }
+    }
+}`,
+			locked:      false,
+			unlockError: false,
+			want: `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "applied-by-someone-else",
+            "applied_at": "2020-11-10T00:00:02Z"
+        },
+        "20201109000003_test3.hcl": {
+            "type": "mock",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z"
+        }
+    }
+}`,
+			lockHistory: []string{"lock", "unlock"},
+			ok:          true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, migrations)
+			mockConfig := &mock.Config{
+				Data:       historyFile,
+				WriteError: false,
+				ReadError:  false,
+			}
+			lockConfig := &lockMock.Config{
+				Locked:      tc.locked,
+				UnlockError: tc.unlockError,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: mockConfig,
+					Lock:    lockConfig,
+				},
+			}
+			r, err := NewHistoryRunner(context.Background(), "", config, nil)
+			if err != nil {
+				t.Fatalf("failed to new history runner: %s", err)
+			}
+
+			// simulate an update by someone else before acquiring the lock
+			mockConfig.Data = tc.latest
+
+			err = r.Apply(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if diff := cmp.Diff(lockConfig.Locker().History(), tc.lockHistory); diff != "" {
+				t.Errorf("got lock history = %#v, want = %#v, diff = %s", lockConfig.Locker().History(), tc.lockHistory, diff)
+			}
+
+			want, err := history.ParseHistoryFile([]byte(tc.want))
+			if err != nil {
+				t.Fatalf("failed to parse history file (want): %s", err)
+			}
+			data := mockConfig.Storage().Data()
+			got, err := history.ParseHistoryFile([]byte(data))
+			if err != nil {
+				t.Fatalf("failed to parse history file (got): %s", err)
+			}
+			recordObj := history.Record{}
+			if diff := cmp.Diff(*got, *want, cmp.AllowUnexported(*got), cmpopts.IgnoreFields(recordObj, "AppliedAt", "Checksum", "Metadata")); diff != "" {
+				t.Errorf("got = %#v, want = %#v, diff = %s", got, want, diff)
+			}
+		})
+	}
+}
+
+func TestHistoryRunnerApplyMetadata(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	historyFile := `{
+    "version": 1,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        }
+    }
+}`
+
+	migrationDir := setupMigrationDir(t, migrations)
+	mockConfig := &mock.Config{
+		Data:       historyFile,
+		WriteError: false,
+		ReadError:  false,
+	}
+	config := &config.TfmigrateConfig{
+		MigrationDir: migrationDir,
+		History: &history.Config{
+			Storage: mockConfig,
+		},
+	}
+	r, err := NewHistoryRunner(context.Background(), "", config, nil)
+	if err != nil {
+		t.Fatalf("failed to new history runner: %s", err)
+	}
+	r.tfmigrateVersion = "0.0.1"
+
+	err = r.Apply(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// The history file should be upgraded to v2.
+	var f history.FileV2
+	if err := json.Unmarshal([]byte(mockConfig.Storage().Data()), &f); err != nil {
+		t.Fatalf("failed to parse history file: %s", err)
+	}
+	if f.Version != 2 {
+		t.Errorf("got version = %d, want = 2", f.Version)
+	}
+
+	// A record upgraded from v1 has no metadata.
+	if md := f.Records["20201109000001_test1.hcl"].Metadata; md != nil {
+		t.Errorf("expected no metadata for a record upgraded from v1, but got: %#v", md)
+	}
+
+	// A new record has metadata.
+	md := f.Records["20201109000002_test2.hcl"].Metadata
+	if md == nil {
+		t.Fatal("expected to have metadata for a new record, but got nil")
+	}
+	if md.TfmigrateVersion != "0.0.1" {
+		t.Errorf("got tfmigrate_version = %s, want = 0.0.1", md.TfmigrateVersion)
+	}
+	if md.ExecType != "mock" || md.ExecVersion != "0.0.0" {
+		t.Errorf("got exec_type = %s, exec_version = %s, want = mock, 0.0.0", md.ExecType, md.ExecVersion)
+	}
+	if len(md.User) == 0 || len(md.Hostname) == 0 || len(md.Duration) == 0 {
+		t.Errorf("expected user, hostname and duration to be set, but got: %#v", md)
+	}
+}
+
+func TestHistoryRunnerVerify(t *testing.T) {
+	test1 := `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`
+	test2 := `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`
+	historyFile := fmt.Sprintf(`{
+    "version": 2,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z",
+            "checksum": "%s"
+        }
+    }
+}`, history.Checksum([]byte(test1)))
+
+	cases := []struct {
+		desc       string
+		migrations map[string]string
+		skipVerify bool
+		ok         bool
+	}{
+		{
+			desc: "not modified",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": test1,
+				"20201109000002_test2.hcl": test2,
+			},
+			skipVerify: false,
+			ok:         true,
+		},
+		{
+			desc: "modified",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": test1 + "# modified\n",
+				"20201109000002_test2.hcl": test2,
+			},
+			skipVerify: false,
+			ok:         false,
+		},
+		{
+			desc: "deleted",
+			migrations: map[string]string{
+				"20201109000002_test2.hcl": test2,
+			},
+			skipVerify: false,
+			ok:         false,
+		},
+		{
+			desc: "modified but skip verify",
+			migrations: map[string]string{
+				"20201109000001_test1.hcl": test1 + "# modified\n",
+				"20201109000002_test2.hcl": test2,
+			},
+			skipVerify: true,
+			ok:         true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, tc.migrations)
+			mockConfig := &mock.Config{
+				Data:       historyFile,
+				WriteError: false,
+				ReadError:  false,
+			}
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+				History: &history.Config{
+					Storage: mockConfig,
+				},
+			}
+
+			for _, phase := range []string{"plan", "apply"} {
+				r, err := NewHistoryRunner(context.Background(), "", config, nil)
+				if err != nil {
+					t.Fatalf("failed to new history runner: %s", err)
+				}
+				r.skipVerify = tc.skipVerify
+
+				if phase == "plan" {
+					err = r.Plan(context.Background())
+				} else {
+					err = r.Apply(context.Background())
+				}
+				if tc.ok && err != nil {
+					t.Fatalf("unexpected err in %s: %s", phase, err)
+				}
+				if !tc.ok && err == nil {
+					t.Fatalf("expected to return an error in %s, but no error", phase)
+				}
+			}
+
+			if !tc.ok {
+				// The history should not be changed.
+				if got := mockConfig.Storage().Data(); got != historyFile {
+					t.Errorf("expected the history not to be changed, but got: %s", got)
+				}
+				return
+			}
+
+			// A checksum of the applied migration should be recorded.
+			var f history.FileV2
+			if err := json.Unmarshal([]byte(mockConfig.Storage().Data()), &f); err != nil {
+				t.Fatalf("failed to parse history file: %s", err)
+			}
+			got := f.Records["20201109000002_test2.hcl"].Checksum
+			want := history.Checksum([]byte(test2))
+			if got != want {
+				t.Errorf("got checksum = %s, want = %s", got, want)
+			}
+		})
+	}
+}
+
+func TestHistoryRunnerIncomplete(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error       = false
+	apply_error      = false
+	apply_incomplete = true
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+
+	migrationDir := setupMigrationDir(t, migrations)
+	mockConfig := &mock.Config{
+		Data:       "",
+		WriteError: false,
+		ReadError:  false,
+	}
+	config := &config.TfmigrateConfig{
+		MigrationDir: migrationDir,
+		History: &history.Config{
+			Storage: mockConfig,
+		},
+	}
+
+	r, err := NewHistoryRunner(context.Background(), "", config, nil)
+	if err != nil {
+		t.Fatalf("failed to new history runner: %s", err)
+	}
+	err = r.Apply(context.Background())
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+
+	// The failed migration should be marked as incomplete and the subsequent
+	// one should not be applied.
+	saved := &history.Config{
+		Storage: &mock.Config{
+			Data: mockConfig.Storage().Data(),
+		},
+	}
+	hc, err := history.NewController(context.Background(), migrationDir, saved)
+	if err != nil {
+		t.Fatalf("failed to new history controller: %s", err)
+	}
+	if got, want := hc.IncompleteMigrations(), []string{"20201109000001_test1.hcl"}; !cmp.Equal(got, want) {
+		t.Errorf("got incompletes = %v, want = %v", got, want)
+	}
+	if got := hc.HistoryLength(); got != 0 {
+		t.Errorf("got length = %d, want = 0", got)
+	}
+
+	// Any subsequent runs should be refused until resolved.
+	config.History.Storage = saved.Storage
+	for _, phase := range []string{"plan", "apply"} {
+		r, err := NewHistoryRunner(context.Background(), "20201109000002_test2.hcl", config, nil)
+		if err != nil {
+			t.Fatalf("failed to new history runner: %s", err)
+		}
+
+		if phase == "plan" {
+			err = r.Plan(context.Background())
+		} else {
+			err = r.Apply(context.Background())
+		}
+		if err == nil {
+			t.Fatalf("expected to return an error in %s, but no error", phase)
+		}
+		if !strings.Contains(err.Error(), "tfmigrate history resolve") {
+			t.Errorf("expected an error to contain a hint to resolve, but got: %s", err)
+		}
+	}
+}
+
+func TestStartLockHeartbeat(t *testing.T) {
+	locker, err := lockMock.NewLocker(&lockMock.Config{})
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	info, err := locker.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to lock: %s", err)
+	}
+
+	hb := startLockHeartbeat(context.Background(), locker, info.ID, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	hb.stop()
+
+	got := locker.History()
+	if len(got) < 2 || got[0] != "lock" || got[1] != "renew" {
+		t.Fatalf("expected the lock to be renewed, but got: %#v", got)
+	}
+	n := len(got)
+
+	// no more renewal after stop.
+	time.Sleep(5 * time.Millisecond)
+	if len(locker.History()) != n {
+		t.Errorf("expected no renewal after stop, but got: %#v", locker.History())
+	}
+}
+
+func TestStartLockHeartbeatNoTTL(t *testing.T) {
+	locker, err := lockMock.NewLocker(&lockMock.Config{})
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	info, err := locker.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("failed to lock: %s", err)
+	}
+
+	hb := startLockHeartbeat(context.Background(), locker, info.ID, lockHeartbeatInterval(info))
+	hb.stop()
+
+	want := []string{"lock"}
+	if diff := cmp.Diff(locker.History(), want); diff != "" {
+		t.Errorf("got: %#v, want: %#v, diff: %s", locker.History(), want, diff)
+	}
+}
@@ -0,0 +1,84 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryShowCommand is a command which shows the migration history.
+type HistoryShowCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryShowCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history show", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 0 {
+		c.UI.Error(fmt.Sprintf("The command expects no arguments, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	out, err := showHistory(context.Background(), c.config)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(out)
+	return 0
+}
+
+// showHistory returns the migration history in the latest file format.
+func showHistory(ctx context.Context, config *config.TfmigrateConfig) (string, error) {
+	hc, err := newHistoryController(ctx, config)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := hc.Serialize()
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryShowCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history show [options]
+
+Show the migration history.
+The history is shown in the latest file format even if it is stored in an
+older format.
+
+Options:
+  --config           A path to tfmigrate config file
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryShowCommand) Synopsis() string {
+	return "Show the migration history"
+}
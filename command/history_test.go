@@ -0,0 +1,194 @@
+package command
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+)
+
+func TestUpdateHistory(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test1.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000002_test2.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+	}
+	historyFile := `{
+    "version": 2,
+    "records": {
+        "20201109000001_test1.hcl": {
+            "type": "mock",
+            "name": "test1",
+            "applied_at": "2020-11-10T00:00:01Z"
+        },
+        "20201109000003_test3.hcl": {
+            "type": "mock",
+            "name": "test3",
+            "applied_at": "2020-11-10T00:00:03Z"
+        }
+    },
+    "incompletes": {
+        "20201109000002_test2.hcl": {
+            "type": "mock",
+            "name": "test2",
+            "failed_at": "2020-11-10T00:00:02Z",
+            "reason": "failed to push"
+        }
+    }
+}`
+
+	cases := []struct {
+		desc        string
+		run         func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error)
+		applied     []string
+		incompletes []string
+		out         string
+		ok          bool
+	}{
+		{
+			desc: "mark",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return markHistory(ctx, config, "20201109000002_test2.hcl", "0.0.1", dryRun)
+			},
+			applied:     []string{"20201109000001_test1.hcl", "20201109000002_test2.hcl", "20201109000003_test3.hcl"},
+			incompletes: []string{"20201109000002_test2.hcl"},
+			out:         `+        "20201109000002_test2.hcl": {`,
+			ok:          true,
+		},
+		{
+			desc: "mark already applied",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return markHistory(ctx, config, "20201109000001_test1.hcl", "0.0.1", dryRun)
+			},
+			ok: false,
+		},
+		{
+			desc: "mark file not found",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return markHistory(ctx, config, "20201109000004_test4.hcl", "0.0.1", dryRun)
+			},
+			ok: false,
+		},
+		{
+			desc: "unmark",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return unmarkHistory(ctx, config, "20201109000001_test1.hcl", dryRun)
+			},
+			applied:     []string{"20201109000003_test3.hcl"},
+			incompletes: []string{"20201109000002_test2.hcl"},
+			out:         `-        "20201109000001_test1.hcl": {`,
+			ok:          true,
+		},
+		{
+			desc: "unmark not applied",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return unmarkHistory(ctx, config, "20201109000002_test2.hcl", dryRun)
+			},
+			ok: false,
+		},
+		{
+			desc: "prune",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return pruneHistory(ctx, config, dryRun)
+			},
+			applied:     []string{"20201109000001_test1.hcl"},
+			incompletes: []string{"20201109000002_test2.hcl"},
+			out:         `-        "20201109000003_test3.hcl": {`,
+			ok:          true,
+		},
+		{
+			desc: "resolve",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return resolveHistory(ctx, config, "20201109000002_test2.hcl", dryRun)
+			},
+			applied:     []string{"20201109000001_test1.hcl", "20201109000003_test3.hcl"},
+			incompletes: []string{},
+			out:         `-    "incompletes": {`,
+			ok:          true,
+		},
+		{
+			desc: "resolve not incomplete",
+			run: func(ctx context.Context, config *config.TfmigrateConfig, dryRun bool) (string, error) {
+				return resolveHistory(ctx, config, "20201109000001_test1.hcl", dryRun)
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		for _, dryRun := range []bool{true, false} {
+			desc := tc.desc
+			if dryRun {
+				desc += " (dry-run)"
+			}
+			t.Run(desc, func(t *testing.T) {
+				migrationDir := setupMigrationDir(t, migrations)
+				mockConfig := &mock.Config{
+					Data: historyFile,
+				}
+				config := &config.TfmigrateConfig{
+					MigrationDir: migrationDir,
+					History: &history.Config{
+						Storage: mockConfig,
+					},
+				}
+
+				out, err := tc.run(context.Background(), config, dryRun)
+				if tc.ok && err != nil {
+					t.Fatalf("unexpected err: %s", err)
+				}
+				if !tc.ok && err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				if !tc.ok {
+					return
+				}
+
+				if dryRun {
+					if !strings.Contains(out, tc.out) {
+						t.Errorf("expected a diff to contain %q, but got: %s", tc.out, out)
+					}
+					// The history should not be saved.
+					if got := mockConfig.Storage().Data(); got != historyFile {
+						t.Errorf("expected the history not to be changed, but got: %s", got)
+					}
+					return
+				}
+
+				saved := &history.Config{
+					Storage: &mock.Config{
+						Data: mockConfig.Storage().Data(),
+					},
+				}
+				hc, err := history.NewController(context.Background(), migrationDir, saved)
+				if err != nil {
+					t.Fatalf("failed to new history controller: %s", err)
+				}
+				if got := hc.HistoryLength(); got != len(tc.applied) {
+					t.Errorf("got length = %d, want = %d", got, len(tc.applied))
+				}
+				for _, filename := range tc.applied {
+					if !hc.AlreadyApplied(filename) {
+						t.Errorf("expected %s to be applied", filename)
+					}
+				}
+				if got := hc.IncompleteMigrations(); !reflect.DeepEqual(got, tc.incompletes) {
+					t.Errorf("got incompletes = %v, want = %v", got, tc.incompletes)
+				}
+			})
+		}
+	}
+}
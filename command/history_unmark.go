@@ -0,0 +1,91 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryUnmarkCommand is a command which deletes a record of a migration
+// from history.
+type HistoryUnmarkCommand struct {
+	Meta
+	dryRun bool
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryUnmarkCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history unmark", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.BoolVar(&c.dryRun, "dry-run", false, "Show a diff of the history file without saving it")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	filename := cmdFlags.Arg(0)
+	out, err := unmarkHistory(context.Background(), c.config, filename, c.dryRun)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(out)
+	return 0
+}
+
+// unmarkHistory deletes a record of a given migration from history.
+func unmarkHistory(ctx context.Context, config *config.TfmigrateConfig, filename string, dryRun bool) (string, error) {
+	out, err := updateHistory(ctx, config, dryRun, func(hc *history.Controller) error {
+		log.Printf("[INFO] [command] delete a record from history: %s\n", filename)
+		return hc.DeleteRecord(filename)
+	})
+	if err != nil || dryRun {
+		return out, err
+	}
+
+	return fmt.Sprintf("unmarked: %s", filename), nil
+}
+
+// Help returns long-form help text.
+func (c *HistoryUnmarkCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history unmark [options] <FILE>
+
+Delete a record of a migration from history.
+Note that this doesn't revert any state changes made by the migration.
+The migration will be applied again in the next tfmigrate apply.
+
+Arguments:
+  FILE               A file name of migration recorded in history
+
+Options:
+  --config           A path to tfmigrate config file
+  --dry-run          Show a diff of the history file without saving it
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryUnmarkCommand) Synopsis() string {
+	return "Delete a record of a migration from history"
+}
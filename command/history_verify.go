@@ -0,0 +1,90 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	flag "github.com/spf13/pflag"
+)
+
+// HistoryVerifyCommand is a command which verifies that applied migration
+// files have not been modified or deleted since they were applied.
+type HistoryVerifyCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *HistoryVerifyCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("history verify", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 0 {
+		c.UI.Error(fmt.Sprintf("The command expects no arguments, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	discrepancies, err := verifyHistory(context.Background(), c.config)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(discrepancies) == 0 {
+		c.UI.Output("All applied migrations match history.")
+		return 0
+	}
+
+	for _, d := range discrepancies {
+		c.UI.Error(d.String())
+	}
+	return 1
+}
+
+// verifyHistory returns a list of applied migrations which have been modified
+// or deleted since they were applied.
+func verifyHistory(ctx context.Context, config *config.TfmigrateConfig) ([]history.Discrepancy, error) {
+	hc, err := newHistoryController(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return hc.Verify()
+}
+
+// Help returns long-form help text.
+func (c *HistoryVerifyCommand) Help() string {
+	helpText := `
+Usage: tfmigrate history verify [options]
+
+Verify that applied migration files have not been modified or deleted since
+they were applied. It exits with a non-zero status if any discrepancies are
+found. Records written before checksums were introduced are only checked for
+deletion.
+
+Options:
+  --config           A path to tfmigrate config file
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *HistoryVerifyCommand) Synopsis() string {
+	return "Verify applied migrations have not been modified"
+}
@@ -17,6 +17,10 @@ type Meta struct {
 	// UI is a user interface representing input and output.
 	UI cli.Ui
 
+	// Version is a version of tfmigrate.
+	// It is recorded in history for auditing.
+	Version string
+
 	// A path to tfmigrate config file.
 	configFile string
 
@@ -0,0 +1,100 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+// stateMigrationTemplate is a template of a state migration file.
+const stateMigrationTemplate = `migration "state" %s {
+  dir = %s
+  actions = [
+%s  ]
+}
+`
+
+// multiStateMigrationTemplate is a template of a multi state migration file.
+const multiStateMigrationTemplate = `migration "multi_state" %s {
+  from_dir = %s
+  to_dir   = %s
+  actions = [
+%s  ]
+}
+`
+
+// migrationAction is an action written to a migration file.
+type migrationAction struct {
+	// action is an action string.
+	action string
+	// comment is an optional comment written above the action.
+	comment string
+}
+
+// plainActions converts a given list of actions into ones without comments.
+func plainActions(actions []string) []migrationAction {
+	r := make([]migrationAction, 0, len(actions))
+	for _, a := range actions {
+		r = append(r, migrationAction{action: a})
+	}
+	return r
+}
+
+// suggestedActions converts a given list of suggestions into actions
+// annotated with their confidence.
+func suggestedActions(suggestions []*tfmigrate.MoveSuggestion) []migrationAction {
+	r := make([]migrationAction, 0, len(suggestions))
+	for _, s := range suggestions {
+		r = append(r, migrationAction{action: s.Action(), comment: s.Annotation()})
+	}
+	return r
+}
+
+// renderStateMigration returns a content of a state migration file with
+// given actions.
+func renderStateMigration(name string, dir string, actions []migrationAction) string {
+	return fmt.Sprintf(stateMigrationTemplate, hclString(name), hclString(dir), renderActions(actions))
+}
+
+// renderMultiStateMigration returns a content of a multi state migration file
+// with given actions.
+func renderMultiStateMigration(name string, fromDir string, toDir string, actions []migrationAction) string {
+	return fmt.Sprintf(multiStateMigrationTemplate, hclString(name), hclString(fromDir), hclString(toDir), renderActions(actions))
+}
+
+// exampleAction is a commented out action written to a migration file which
+// has no actions yet.
+const exampleAction = "mv aws_security_group.foo aws_security_group.foo2"
+
+// renderActions returns elements of an actions list.
+// If no actions are given, a commented out example is returned instead so
+// that a generated file is still valid and easy to edit.
+func renderActions(actions []migrationAction) string {
+	if len(actions) == 0 {
+		return "    # " + hclString(exampleAction) + ",\n"
+	}
+
+	var b strings.Builder
+	for _, a := range actions {
+		if len(a.comment) > 0 {
+			b.WriteString("    # " + a.comment + "\n")
+		}
+		b.WriteString("    " + hclString(a.action) + ",\n")
+	}
+	return b.String()
+}
+
+// hclString returns a quoted string literal in HCL.
+// In addition to escaping quotes and backslashes, template sequences are
+// escaped so that they are not interpolated.
+func hclString(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"${", "$${",
+		"%{", "%%{",
+	)
+	return `"` + r.Replace(s) + `"`
+}
@@ -0,0 +1,183 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// NewCommand is a command which scaffolds a new migration file.
+type NewCommand struct {
+	Meta
+	migrationType string
+	dir           string
+	fromDir       string
+	toDir         string
+	fromPlan      bool
+}
+
+// Run runs the procedure of this command.
+func (c *NewCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("new", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+	cmdFlags.StringVar(&c.migrationType, "type", "state", "A type of migration")
+	cmdFlags.StringVar(&c.dir, "dir", ".", "A working directory for a state migration")
+	cmdFlags.StringVar(&c.fromDir, "from-dir", "", "A working directory where states of resources move from")
+	cmdFlags.StringVar(&c.toDir, "to-dir", "", "A working directory where states of resources move to")
+	cmdFlags.BoolVar(&c.fromPlan, "from-plan", false, "Pre-fill actions from pairs of destroy and create in terraform plan")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	name := cmdFlags.Arg(0)
+	content, err := renderNewMigration(context.Background(), name, c.migrationType, c.dir, c.fromDir, c.toDir, c.fromPlan, c.Option)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	path, err := writeNewMigration(c.config, name, content, time.Now())
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("created: %s", path))
+	return 0
+}
+
+// migrationNameRe is a regular expression which matches a valid name of
+// migration. The name is used as a part of file name.
+var migrationNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// migrationTimestampLayout is a layout of timestamp prefix of a migration
+// file name.
+const migrationTimestampLayout = "20060102150405"
+
+// renderNewMigration returns a content of a new migration file for a given
+// type. If fromPlan is true, actions are pre-filled from pairs of resources
+// planned to be destroyed and created.
+func renderNewMigration(ctx context.Context, name string, migrationType string, dir string, fromDir string, toDir string, fromPlan bool, option *tfmigrate.MigratorOption) (string, error) {
+	if !migrationNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid migration name: %q, it must consist of alphanumerics, underscores and hyphens", name)
+	}
+
+	switch migrationType {
+	case "state":
+		var suggestions []*tfmigrate.MoveSuggestion
+		if fromPlan {
+			var err error
+			suggestions, err = tfmigrate.SuggestStateMoves(ctx, dir, "default", option)
+			if err != nil {
+				return "", fmt.Errorf("failed to suggest actions: %s", err)
+			}
+		}
+		return renderStateMigration(name, dir, suggestedActions(suggestions)), nil
+
+	case "multi_state":
+		if len(fromDir) == 0 || len(toDir) == 0 {
+			return "", fmt.Errorf("both --from-dir and --to-dir are required for a multi_state migration")
+		}
+		var suggestions []*tfmigrate.MoveSuggestion
+		if fromPlan {
+			var err error
+			suggestions, err = tfmigrate.SuggestMultiStateMoves(ctx, fromDir, "default", toDir, "default", option)
+			if err != nil {
+				return "", fmt.Errorf("failed to suggest actions: %s", err)
+			}
+		}
+		return renderMultiStateMigration(name, fromDir, toDir, suggestedActions(suggestions)), nil
+
+	default:
+		return "", fmt.Errorf("unknown migration type: %s", migrationType)
+	}
+}
+
+// writeNewMigration writes a given content to a new migration file named
+// YYYYMMDDhhmmss_<name>.hcl in the migration dir and returns its path.
+// It never overwrites an existing file.
+func writeNewMigration(config *config.TfmigrateConfig, name string, content string, now time.Time) (string, error) {
+	filename := now.Format(migrationTimestampLayout) + "_" + name + ".hcl"
+	path := filepath.Join(config.MigrationDir, filename)
+
+	log.Printf("[INFO] [command] write a migration file: %s\n", path)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create a migration file: %s", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(content); err != nil {
+		return "", fmt.Errorf("failed to write a migration file: %s", err)
+	}
+
+	return path, nil
+}
+
+// Help returns long-form help text.
+func (c *NewCommand) Help() string {
+	helpText := `
+Usage: tfmigrate new [options] <NAME>
+
+Create a new migration file named YYYYMMDDhhmmss_<NAME>.hcl in the
+migration_dir. The NAME must consist of alphanumerics, underscores and
+hyphens.
+
+If the --from-plan flag is set, actions are pre-filled with mv actions
+which pair a resource planned to be destroyed with a resource of the same
+type planned to be created, in the same way as the suggest command.
+Review the generated file before applying. It requires terraform init in the
+working directories.
+
+Arguments:
+  NAME               A name of migration
+
+Options:
+  --config           A path to tfmigrate config file
+  --type             A type of migration: state or multi_state.
+                     Default to state.
+  --dir              A working directory for a state migration.
+                     Default to the current directory.
+  --from-dir         A working directory where states of resources move from.
+                     Required for a multi_state migration.
+  --to-dir           A working directory where states of resources move to.
+                     Required for a multi_state migration.
+  --from-plan        Pre-fill actions from pairs of destroy and create in
+                     terraform plan.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *NewCommand) Synopsis() string {
+	return "Create a new migration file"
+}
@@ -0,0 +1,129 @@
+package command
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestNewMigration(t *testing.T) {
+	cases := []struct {
+		desc          string
+		name          string
+		migrationType string
+		dir           string
+		fromDir       string
+		toDir         string
+		want          string
+		ok            bool
+	}{
+		{
+			desc:          "state",
+			name:          "foo",
+			migrationType: "state",
+			dir:           "dir1",
+			want: `migration "state" "foo" {
+  dir = "dir1"
+  actions = [
+    # "mv aws_security_group.foo aws_security_group.foo2",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc:          "multi_state",
+			name:          "bar",
+			migrationType: "multi_state",
+			fromDir:       "dir1",
+			toDir:         "dir2",
+			want: `migration "multi_state" "bar" {
+  from_dir = "dir1"
+  to_dir   = "dir2"
+  actions = [
+    # "mv aws_security_group.foo aws_security_group.foo2",
+  ]
+}
+`,
+			ok: true,
+		},
+		{
+			desc:          "multi_state without to_dir",
+			name:          "bar",
+			migrationType: "multi_state",
+			fromDir:       "dir1",
+			ok:            false,
+		},
+		{
+			desc:          "invalid name",
+			name:          "foo bar",
+			migrationType: "state",
+			dir:           "dir1",
+			ok:            false,
+		},
+		{
+			desc:          "unknown type",
+			name:          "foo",
+			migrationType: "baz",
+			ok:            false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, map[string]string{})
+			config := &config.TfmigrateConfig{
+				MigrationDir: migrationDir,
+			}
+
+			content, err := renderNewMigration(context.Background(), tc.name, tc.migrationType, tc.dir, tc.fromDir, tc.toDir, false, &tfmigrate.MigratorOption{})
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %s", content)
+				}
+				return
+			}
+			if content != tc.want {
+				t.Errorf("got: %s, want: %s", content, tc.want)
+			}
+
+			now := time.Date(2026, 10, 17, 1, 2, 3, 0, time.UTC)
+			path, err := writeNewMigration(config, tc.name, content, now)
+			if err != nil {
+				t.Fatalf("failed to write a migration file: %s", err)
+			}
+			wantPath := filepath.Join(migrationDir, "20261017010203_"+tc.name+".hcl")
+			if path != wantPath {
+				t.Errorf("got path: %s, want: %s", path, wantPath)
+			}
+
+			mc, err := loadMigrationFile(path)
+			if err != nil {
+				t.Fatalf("failed to load the generated migration file: %s", err)
+			}
+			if mc.Type != tc.migrationType || mc.Name != tc.name {
+				t.Errorf("got type: %s, name: %s", mc.Type, mc.Name)
+			}
+
+			if _, err := writeNewMigration(config, tc.name, content, now); err == nil {
+				t.Errorf("expected not to overwrite an existing file, but no error")
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read a migration file: %s", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("file was overwritten: %s", string(got))
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	flag "github.com/spf13/pflag"
 )
@@ -14,6 +15,8 @@ import (
 type PlanCommand struct {
 	Meta
 	backendConfig []string
+	skipVerify    bool
+	jsonReport    string
 	out           string
 }
 
@@ -22,6 +25,8 @@ func (c *PlanCommand) Run(args []string) int {
 	cmdFlags := flag.NewFlagSet("plan", flag.ContinueOnError)
 	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
 	cmdFlags.StringArrayVar(&c.backendConfig, "backend-config", nil, "A backend configuration for remote state")
+	cmdFlags.BoolVar(&c.skipVerify, "skip-verify", false, "Don't fail when applied migrations have been modified or deleted")
+	cmdFlags.StringVar(&c.jsonReport, "json-report", "", "Write a machine-readable report in JSON format to the given path")
 	cmdFlags.StringVar(&c.out, "out", "", "Save a plan file after dry-run migration to the given path")
 
 	if err := cmdFlags.Parse(args); err != nil {
@@ -52,7 +57,10 @@ func (c *PlanCommand) Run(args []string) int {
 		}
 
 		migrationFile := cmdFlags.Arg(0)
-		if err = c.planWithoutHistory(migrationFile); err != nil {
+		err = runWithReport(c.jsonReport, "plan", func(report *Report) error {
+			return c.planWithoutHistory(migrationFile, report)
+		})
+		if err != nil {
 			c.UI.Error(err.Error())
 			return 1
 		}
@@ -74,7 +82,10 @@ func (c *PlanCommand) Run(args []string) int {
 	}
 
 	// Plan all unapplied pending migrations.
-	if err = c.planWithHistory(migrationFile); err != nil {
+	err = runWithReport(c.jsonReport, "plan", func(report *Report) error {
+		return c.planWithHistory(migrationFile, report)
+	})
+	if err != nil {
 		c.UI.Error(err.Error())
 		return 1
 	}
@@ -83,22 +94,26 @@ func (c *PlanCommand) Run(args []string) int {
 }
 
 // planWithoutHistory is a helper function which plans a given migration file without history.
-func (c *PlanCommand) planWithoutHistory(filename string) error {
+func (c *PlanCommand) planWithoutHistory(filename string, report *Report) error {
 	fr, err := NewFileRunner(filename, c.config, c.Option)
 	if err != nil {
+		report.add(filename, nil, nil, time.Now(), err)
 		return err
 	}
+	fr.report = report
 
 	return fr.Plan(context.Background())
 }
 
 // planWithHistory is a helper function which plans all unapplied pending migrations.
-func (c *PlanCommand) planWithHistory(filename string) error {
+func (c *PlanCommand) planWithHistory(filename string, report *Report) error {
 	ctx := context.Background()
 	hr, err := NewHistoryRunner(ctx, filename, c.config, c.Option)
 	if err != nil {
 		return err
 	}
+	hr.skipVerify = c.skipVerify
+	hr.report = report
 
 	return hr.Plan(ctx)
 }
@@ -121,6 +136,16 @@ Options:
                            key=value format backend configuraion.
                            This option is passed to terraform init when switching backend to remote.
 
+  --skip-verify            Don't fail when applied migrations have been modified or deleted
+                           since they were applied. They are only reported as warnings.
+                           This option is ignored in non-history mode.
+
+  --json-report=path       Write a machine-readable report in JSON format to the given path.
+                           The report contains a result of each migration file such as
+                           expanded actions, resources added/removed per state, plan results,
+                           results of imports from an import manifest and timings.
+                           It is written even if the migration fails.
+
   --out=path               Save a plan file after dry-run migration to the given path.
                            Note that the saved plan file is not applicable in Terraform 1.1+.
                            It's intended to use only for static analysis.
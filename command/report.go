@@ -0,0 +1,175 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+const (
+	// ReportResultSuccess means that a migration succeeded.
+	ReportResultSuccess = "success"
+	// ReportResultError means that a migration failed.
+	ReportResultError = "error"
+)
+
+// Report is a machine-readable report of the plan or apply command.
+// It is intended to be consumed by CI to post results to pull requests.
+type Report struct {
+	// Command is a name of the command, either plan or apply.
+	Command string `json:"command"`
+	// Migrations is a list of reports for each migration file in the order
+	// of execution.
+	Migrations []*MigrationReport `json:"migrations"`
+}
+
+// MigrationReport is a report of a single migration file.
+type MigrationReport struct {
+	// Filename is a path of the migration file.
+	Filename string `json:"filename"`
+	// Type is a type of migration.
+	// It is empty if the migration file could not be loaded.
+	Type string `json:"type"`
+	// Name is an arbitrary name of migration.
+	Name string `json:"name"`
+	// Result is a result of the migration, either success or error.
+	Result string `json:"result"`
+	// Error is an error message if the migration failed.
+	Error string `json:"error,omitempty"`
+	// Actions is a list of actions which were actually run.
+	Actions []string `json:"actions"`
+	// Imports is a list of results for each row of an import manifest.
+	// It is omitted if the migration has no import manifest.
+	Imports []*ImportReport `json:"imports,omitempty"`
+	// States is a list of reports for each affected state.
+	States []*StateReport `json:"states"`
+	// StartedAt is a timestamp when the migration started.
+	StartedAt time.Time `json:"started_at"`
+	// DurationSeconds is a time taken to run the migration.
+	DurationSeconds float64 `json:"duration_seconds"`
+}
+
+// StateReport is a report of changes in a state.
+type StateReport struct {
+	// Dir is a working directory of the state.
+	Dir string `json:"dir"`
+	// Workspace is a workspace of the state.
+	Workspace string `json:"workspace"`
+	// PlanResult is a result of terraform plan, either no-changes, changes,
+	// error or skipped. It is empty if the plan has not been run.
+	PlanResult string `json:"plan_result"`
+	// Added is a list of resource addresses added to the state.
+	Added []string `json:"added"`
+	// Removed is a list of resource addresses removed from the state.
+	Removed []string `json:"removed"`
+}
+
+// ImportReport is a result of importing a row of an import manifest.
+type ImportReport struct {
+	// Address is an address to import resource to.
+	Address string `json:"address"`
+	// ID is a resource identifier to be imported.
+	ID string `json:"id"`
+	// Result is a result of the import, either success, error or skipped.
+	Result string `json:"result"`
+	// Error is an error message if the import failed.
+	Error string `json:"error,omitempty"`
+}
+
+// NewReport returns a new Report instance for a given command.
+func NewReport(command string) *Report {
+	return &Report{
+		Command:    command,
+		Migrations: []*MigrationReport{},
+	}
+}
+
+// add adds a report of a migration to the Report.
+// The mc and m are optional and can be nil if the migration file could not
+// be loaded. If the receiver is nil, it does nothing so that callers don't
+// need to check whether a report is requested.
+func (r *Report) add(filename string, mc *tfmigrate.MigrationConfig, m tfmigrate.Migrator, start time.Time, err error) {
+	if r == nil {
+		return
+	}
+
+	mr := &MigrationReport{
+		Filename:        filename,
+		Result:          ReportResultSuccess,
+		Actions:         []string{},
+		States:          []*StateReport{},
+		StartedAt:       start,
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		mr.Result = ReportResultError
+		mr.Error = err.Error()
+	}
+	if mc != nil {
+		mr.Type = mc.Type
+		mr.Name = mc.Name
+	}
+
+	if p, ok := m.(tfmigrate.ReportProvider); ok {
+		if report := p.Report(); report != nil {
+			mr.Actions = append(mr.Actions, report.Actions...)
+			for _, i := range report.Imports {
+				mr.Imports = append(mr.Imports, &ImportReport{
+					Address: i.Address,
+					ID:      i.ID,
+					Result:  i.Result,
+					Error:   i.Error,
+				})
+			}
+			for _, s := range report.States {
+				mr.States = append(mr.States, &StateReport{
+					Dir:        s.Dir,
+					Workspace:  s.Workspace,
+					PlanResult: s.PlanResult,
+					Added:      s.Added,
+					Removed:    s.Removed,
+				})
+			}
+		}
+	}
+
+	r.Migrations = append(r.Migrations, mr)
+}
+
+// Write writes the Report to a given path in JSON format.
+func (r *Report) Write(path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode json report: %s", err)
+	}
+
+	log.Printf("[INFO] [command] write json report: %s\n", path)
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write json report: %s", err)
+	}
+	return nil
+}
+
+// runWithReport runs a given function with a new Report and writes the
+// report to a given path even if the function fails. If the path is empty,
+// it runs the function without report.
+func runWithReport(path string, command string, run func(report *Report) error) error {
+	if len(path) == 0 {
+		return run(nil)
+	}
+
+	report := NewReport(command)
+	err := run(report)
+	if werr := report.Write(path); werr != nil {
+		if err == nil {
+			return werr
+		}
+		return fmt.Errorf("%v, %v", werr, err)
+	}
+
+	return err
+}
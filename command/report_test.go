@@ -0,0 +1,147 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestRunWithReport(t *testing.T) {
+	migrations := map[string]string{
+		"20201109000001_test.hcl": `
+migration "mock" "test1" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+		"20201109000002_test.hcl": `
+migration "mock" "test2" {
+	plan_error  = true
+	apply_error = false
+}
+`,
+		"20201109000003_test.hcl": `
+migration "mock" "test3" {
+`,
+	}
+	migrationDir := setupMigrationDir(t, migrations)
+	config := config.NewDefaultConfig()
+	config.MigrationDir = migrationDir
+
+	reportPath := filepath.Join(t.TempDir(), "report.json")
+	err := runWithReport(reportPath, "plan", func(report *Report) error {
+		for _, filename := range []string{"20201109000001_test.hcl", "20201109000002_test.hcl", "20201109000003_test.hcl"} {
+			fr, err := NewFileRunner(filename, config, nil)
+			if err != nil {
+				report.add(filename, nil, nil, time.Now(), err)
+				continue
+			}
+			fr.report = report
+			_ = fr.Plan(context.Background())
+		}
+		return fmt.Errorf("failed")
+	})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+
+	b, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("failed to read report: %s", err)
+	}
+	var got Report
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("failed to parse report: %s", err)
+	}
+
+	if got.Command != "plan" {
+		t.Errorf("got command: %s, want: plan", got.Command)
+	}
+	want := []struct {
+		filename string
+		typ      string
+		name     string
+		result   string
+	}{
+		{filename: "20201109000001_test.hcl", typ: "mock", name: "test1", result: ReportResultSuccess},
+		{filename: "20201109000002_test.hcl", typ: "mock", name: "test2", result: ReportResultError},
+		{filename: "20201109000003_test.hcl", typ: "", name: "", result: ReportResultError},
+	}
+	if len(got.Migrations) != len(want) {
+		t.Fatalf("got %d migrations, want: %d", len(got.Migrations), len(want))
+	}
+	for i, w := range want {
+		m := got.Migrations[i]
+		if m.Filename != w.filename || m.Type != w.typ || m.Name != w.name || m.Result != w.result {
+			t.Errorf("got: %#v, want: %#v", m, w)
+		}
+		if (m.Result == ReportResultError) != (len(m.Error) > 0) {
+			t.Errorf("unexpected error message: %#v", m)
+		}
+		if m.Actions == nil || m.States == nil {
+			t.Errorf("expected actions and states to be non-nil: %#v", m)
+		}
+	}
+}
+
+func TestRunWithReportNoPath(t *testing.T) {
+	called := false
+	err := runWithReport("", "apply", func(report *Report) error {
+		called = true
+		if report != nil {
+			t.Errorf("expected report to be nil, but got: %#v", report)
+		}
+		// a nil report can be used safely.
+		report.add("foo.hcl", nil, nil, time.Now(), nil)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if !called {
+		t.Error("expected to call a given function")
+	}
+}
+
+// reportStub is a Migrator which returns a fixed report for testing.
+type reportStub struct {
+	tfmigrate.Migrator
+	// report is a report returned by Report.
+	report *tfmigrate.Report
+}
+
+// Report returns a fixed report.
+func (m *reportStub) Report() *tfmigrate.Report {
+	return m.report
+}
+
+func TestReportAddImports(t *testing.T) {
+	m := &reportStub{
+		report: &tfmigrate.Report{
+			Actions: []string{"import aws_s3_bucket.foo foo"},
+			Imports: []tfmigrate.ImportResult{
+				{Address: "aws_s3_bucket.foo", ID: "foo", Result: tfmigrate.ImportResultSuccess},
+				{Address: "aws_s3_bucket.bar", ID: "bar", Result: tfmigrate.ImportResultError, Error: "not found"},
+			},
+		},
+	}
+
+	report := NewReport("plan")
+	report.add("20201109000001_test.hcl", nil, m, time.Now(), fmt.Errorf("failed"))
+
+	b, err := json.Marshal(report.Migrations[0].Imports)
+	if err != nil {
+		t.Fatalf("failed to encode imports: %s", err)
+	}
+	want := `[{"address":"aws_s3_bucket.foo","id":"foo","result":"success"},{"address":"aws_s3_bucket.bar","id":"bar","result":"error","error":"not found"}]`
+	if string(b) != want {
+		t.Errorf("got: %s, want: %s", string(b), want)
+	}
+}
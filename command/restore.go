@@ -0,0 +1,107 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// RestoreCommand is a command which pushes snapshots of states saved before
+// applying a migration back to remote.
+type RestoreCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *RestoreCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	c.Option = newOption()
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	filename := cmdFlags.Arg(0)
+	if err := restoreMigration(context.Background(), c.config, filename, c.Option); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("restored states before applying: %s", filename))
+	return 0
+}
+
+// restoreMigration pushes snapshots of states saved before applying a given
+// migration back to remote.
+func restoreMigration(ctx context.Context, config *config.TfmigrateConfig, filename string, option *tfmigrate.MigratorOption) error {
+	if config.Backup == nil {
+		return fmt.Errorf("no backup setting found in config file")
+	}
+
+	snapshots, err := loadSnapshots(ctx, config.Backup, filename)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range snapshots {
+		log.Printf("[INFO] [command] restore a state: dir=%s, workspace=%s\n", s.Dir, s.Workspace)
+		if err := tfmigrate.RestoreSnapshot(ctx, s, option); err != nil {
+			return fmt.Errorf("failed to restore a state: dir=%s, workspace=%s, err: %s", s.Dir, s.Workspace, err)
+		}
+	}
+
+	return nil
+}
+
+// Help returns long-form help text.
+func (c *RestoreCommand) Help() string {
+	helpText := `
+Usage: tfmigrate restore [options] <PATH>
+
+Restore states to snapshots saved before applying a given migration.
+The backup block must be configured in the config file to save snapshots on
+apply. Since Terraform refuses to push a state with an older serial, the
+serial of each snapshot is set to the current remote serial + 1.
+If a state didn't exist before applying, all resources are removed from the
+remote state, so that moved resources are not managed by two states.
+
+Note that this command doesn't update the migration history. If you want to
+re-apply the migration, run tfmigrate history unmark <PATH>.
+
+Arguments:
+  PATH               A path of migration file which was applied
+
+Options:
+  --config           A path to tfmigrate config file
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *RestoreCommand) Synopsis() string {
+	return "Restore states to snapshots before a migration"
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/backup"
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/storage/mock"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestMigrationBackup(t *testing.T) {
+	storage := &mock.Config{Data: ""}
+	b := newMigrationBackup(&backup.Config{Storage: storage}, "20201109000001_test.hcl")
+	snapshots := []tfmigrate.StateSnapshot{
+		{Dir: "foo", Workspace: "default", State: tfexec.NewState([]byte(`{"serial":1}`))},
+		{Dir: "bar", Workspace: "work1", State: tfexec.NewState([]byte{})},
+	}
+	if err := b.Save(context.Background(), snapshots); err != nil {
+		t.Fatalf("failed to save: %s", err)
+	}
+
+	// the mock storage returns the same named instance for the same config.
+	got, err := loadSnapshots(context.Background(), &backup.Config{Storage: storage}, "tfmigrate/20201109000001_test.hcl")
+	if err != nil {
+		t.Fatalf("failed to load: %s", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("got: %d snapshots, want: 2", len(got))
+	}
+	if got[0].Dir != "foo" || got[0].Workspace != "default" || got[0].State == nil {
+		t.Errorf("unexpected snapshot: %#v", got[0])
+	}
+	if got[1].Dir != "bar" || got[1].Workspace != "work1" || got[1].State != nil {
+		t.Errorf("unexpected snapshot: %#v", got[1])
+	}
+}
+
+func TestRestoreMigration(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *config.TfmigrateConfig
+		ok     bool
+	}{
+		{
+			desc:   "no backup setting",
+			config: &config.TfmigrateConfig{},
+			ok:     false,
+		},
+		{
+			desc: "no backup found",
+			config: &config.TfmigrateConfig{
+				Backup: &backup.Config{Storage: &mock.Config{Data: ""}},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := restoreMigration(context.Background(), tc.config, "20201109000001_test.hcl", nil)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
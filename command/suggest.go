@@ -0,0 +1,127 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// SuggestCommand is a command which suggests a migration from pairs of
+// resources planned to be destroyed and created.
+type SuggestCommand struct {
+	Meta
+	name  string
+	out   string
+	toDir string
+}
+
+// Run runs the procedure of this command.
+func (c *SuggestCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("suggest", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.name, "name", "suggested", "A name of migration")
+	cmdFlags.StringVar(&c.out, "out", "", "A path to write the migration file")
+	cmdFlags.StringVar(&c.toDir, "to-dir", "", "A working directory where states of resources move to")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 1 {
+		c.UI.Error(fmt.Sprintf("The command expects 1 argument, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	c.Option = newOption()
+	// The option may contains sensitive values such as environment variables.
+	// So logging the option set log level to DEBUG instead of INFO.
+	log.Printf("[DEBUG] [command] option: %#v\n", c.Option)
+
+	dir := cmdFlags.Arg(0)
+	migration, err := suggestMigration(context.Background(), dir, c.toDir, c.name, c.Option)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(c.out) == 0 {
+		c.UI.Output(strings.TrimSuffix(migration, "\n"))
+		return 0
+	}
+
+	log.Printf("[INFO] [command] write a migration file: %s\n", c.out)
+	if err := os.WriteFile(c.out, []byte(migration), 0644); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to write a migration file: %s", err))
+		return 1
+	}
+
+	return 0
+}
+
+// suggestMigration returns a content of a candidate migration file with mv
+// actions suggested from terraform plan. If toDir is empty, it suggests a
+// state migration in a given dir. Otherwise, it suggests a multi state
+// migration from dir to toDir.
+func suggestMigration(ctx context.Context, dir string, toDir string, name string, option *tfmigrate.MigratorOption) (string, error) {
+	var suggestions []*tfmigrate.MoveSuggestion
+	var err error
+	if len(toDir) == 0 {
+		suggestions, err = tfmigrate.SuggestStateMoves(ctx, dir, "default", option)
+	} else {
+		suggestions, err = tfmigrate.SuggestMultiStateMoves(ctx, dir, "default", toDir, "default", option)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to suggest actions: %s", err)
+	}
+	if len(suggestions) == 0 {
+		return "", fmt.Errorf("no pairs of destroy and create found in terraform plan")
+	}
+
+	if len(toDir) == 0 {
+		return renderStateMigration(name, dir, suggestedActions(suggestions)), nil
+	}
+	return renderMultiStateMigration(name, dir, toDir, suggestedActions(suggestions)), nil
+}
+
+// Help returns long-form help text.
+func (c *SuggestCommand) Help() string {
+	helpText := `
+Usage: tfmigrate suggest [options] <DIR>
+
+Suggest a candidate migration file from terraform plan in a given directory.
+It pairs a resource planned to be destroyed with a resource of the same type
+planned to be created by comparing their attribute values, and emits mv
+actions annotated with confidence:
+
+  high      An identifying attribute (id, arn or name) has the same value.
+  medium    At least half of comparable attributes have the same values.
+  low       Less than half of comparable attributes have the same values,
+            or it is the only pair of the type without any matches.
+
+Note that it's just a suggestion. Review the generated file and run
+tfmigrate plan before applying. It requires terraform init in the directory.
+
+Arguments:
+  DIR                A working directory to run terraform plan
+
+Options:
+  --name             A name of migration. Default to suggested.
+  --out              A path to write the migration file.
+                     Default to stdout.
+  --to-dir           A working directory where states of resources move to.
+                     If set, a multi_state migration which moves resources
+                     destroyed in DIR to ones created in --to-dir is suggested.
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *SuggestCommand) Synopsis() string {
+	return "Suggest a migration from terraform plan"
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+)
+
+func TestRenderSuggestedActions(t *testing.T) {
+	suggestions := []*tfmigrate.MoveSuggestion{
+		{
+			From:              "aws_s3_bucket.foo",
+			To:                "module.s3.aws_s3_bucket.this",
+			Confidence:        tfmigrate.ConfidenceHigh,
+			MatchedAttributes: []string{"arn", "bucket"},
+		},
+		{
+			From:       `aws_security_group.foo["a"]`,
+			To:         "aws_security_group.bar",
+			Confidence: tfmigrate.ConfidenceLow,
+		},
+	}
+
+	got := renderStateMigration("suggested", "dir1", suggestedActions(suggestions))
+	want := `migration "state" "suggested" {
+  dir = "dir1"
+  actions = [
+    # confidence: high (matched: arn, bucket)
+    "mv aws_s3_bucket.foo module.s3.aws_s3_bucket.this",
+    # confidence: low (the only pair of the type)
+    "mv 'aws_security_group.foo[\"a\"]' aws_security_group.bar",
+  ]
+}
+`
+	if got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+
+	mc, err := config.ParseMigrationFile("test.hcl", []byte(got))
+	if err != nil {
+		t.Fatalf("failed to parse the rendered migration: %s", err)
+	}
+	sc := mc.Migrator.(*tfmigrate.StateMigratorConfig)
+	if len(sc.Actions) != 2 {
+		t.Errorf("got actions: %#v", sc.Actions)
+	}
+}
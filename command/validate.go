@@ -0,0 +1,136 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/config"
+	"github.com/minamijoyo/tfmigrate/history"
+	"github.com/minamijoyo/tfmigrate/tfmigrate"
+	flag "github.com/spf13/pflag"
+)
+
+// ValidateCommand is a command which validates migration files statically.
+type ValidateCommand struct {
+	Meta
+}
+
+// Run runs the procedure of this command.
+func (c *ValidateCommand) Run(args []string) int {
+	cmdFlags := flag.NewFlagSet("validate", flag.ContinueOnError)
+	cmdFlags.StringVar(&c.configFile, "config", defaultConfigFile, "A path to tfmigrate config file")
+
+	if err := cmdFlags.Parse(args); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to parse arguments: %s", err))
+		return 1
+	}
+
+	if len(cmdFlags.Args()) != 0 {
+		c.UI.Error(fmt.Sprintf("The command expects no arguments, but got %d", len(cmdFlags.Args())))
+		c.UI.Error(c.Help())
+		return 1
+	}
+
+	var err error
+	if c.config, err = newConfig(c.configFile); err != nil {
+		c.UI.Error(fmt.Sprintf("failed to load config file: %s", err))
+		return 1
+	}
+	log.Printf("[DEBUG] [command] config: %#v\n", c.config)
+
+	migrations, errs, err := validateMigrations(c.config.MigrationDir)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	if len(errs) == 0 {
+		c.UI.Output(fmt.Sprintf("All %d migration files are valid.", len(migrations)))
+		return 0
+	}
+
+	for _, e := range errs {
+		c.UI.Error(e)
+	}
+	c.UI.Error(fmt.Sprintf("Found %d errors in %d migration files.", len(errs), len(migrations)))
+	return 1
+}
+
+// validateMigrations validates all migration files in a given dir statically.
+// It returns a list of migration files and a list of errors found in them.
+// Each error is prefixed with its position in the form of file:line,col.
+func validateMigrations(migrationDir string) ([]string, []string, error) {
+	migrations, err := history.LoadMigrationFileNames(migrationDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list migration files: %s", err)
+	}
+
+	errs := []string{}
+	// names is a map of migration name to the position where it is defined.
+	names := make(map[string]string)
+	for _, filename := range migrations {
+		path := resolveMigrationFile(migrationDir, filename)
+		log.Printf("[INFO] [command] validate migration file: %s\n", path)
+		source, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", path, err))
+			continue
+		}
+
+		// Errors in HCL diagnostics already contain their positions.
+		mc, err := config.ParseMigrationFile(path, source)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		ranges, err := config.ParseMigrationRanges(path, source)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if defined, ok := names[mc.Name]; ok {
+			errs = append(errs, fmt.Sprintf("%s: duplicate migration name: %s, already defined at %s", ranges.Name, mc.Name, defined))
+		} else {
+			names[mc.Name] = ranges.Name.String()
+		}
+
+		v, ok := mc.Migrator.(tfmigrate.Validator)
+		if !ok {
+			continue
+		}
+		for _, e := range v.Validate() {
+			errs = append(errs, fmt.Sprintf("%s: %s", ranges.Lookup(e.Attribute, e.Index), e))
+		}
+	}
+
+	return migrations, errs, nil
+}
+
+// Help returns long-form help text.
+func (c *ValidateCommand) Help() string {
+	helpText := `
+Usage: tfmigrate validate [options]
+
+Validate all migration files in the migration_dir statically.
+It doesn't run the terraform command, so it requires neither credentials
+nor terraform init. It is intended to be used as a fast lint before merge.
+
+It checks syntax errors, missing directories, unknown action types,
+malformed addresses, references to wildcards in xmv destinations,
+duplicate migration names and resources moved twice in a migration.
+It exits with a non-zero status if any errors are found.
+
+Options:
+  --config           A path to tfmigrate config file
+`
+	return strings.TrimSpace(helpText)
+}
+
+// Synopsis returns one-line help text.
+func (c *ValidateCommand) Synopsis() string {
+	return "Validate migration files statically"
+}
@@ -0,0 +1,86 @@
+package command
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateMigrations(t *testing.T) {
+	cases := []struct {
+		desc       string
+		migrations map[string]string
+		want       []string
+	}{
+		{
+			desc: "valid",
+			migrations: map[string]string{
+				"20201109000001_test.hcl": `
+migration "state" "test1" {
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+}
+`,
+				"20201109000002_test.hcl": `
+migration "mock" "test2" {
+	plan_error  = false
+	apply_error = false
+}
+`,
+			},
+			want: []string{},
+		},
+		{
+			desc: "invalid",
+			migrations: map[string]string{
+				"20201109000001_test.hcl": `
+migration "state" "test" {
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+		"mv null_resource.foo null_resource.foo3",
+	]
+}
+`,
+				"20201109000002_test.hcl": `
+migration "state" "test" {
+	actions = [
+		"foo null_resource.foo",
+	]
+}
+`,
+				"20201109000003_test.hcl": `
+migration "state" "test3" {
+`,
+			},
+			want: []string{
+				"20201109000001_test.hcl:5,3-44: resource is moved twice: null_resource.foo",
+				"20201109000002_test.hcl:2,19-25: duplicate migration name: test",
+				"20201109000002_test.hcl:4,3-26: unknown state action type: foo null_resource.foo",
+				"20201109000003_test.hcl:",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := setupMigrationDir(t, tc.migrations)
+			migrations, got, err := validateMigrations(migrationDir)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if len(migrations) != len(tc.migrations) {
+				t.Errorf("got %d migrations, want: %d", len(migrations), len(tc.migrations))
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("got: %v, want: %v", got, tc.want)
+			}
+			for i, want := range tc.want {
+				// errors are prefixed by a path of migration file.
+				if !strings.Contains(got[i], filepath.Join(migrationDir, want)) {
+					t.Errorf("got: %s, want to contain: %s", got[i], want)
+				}
+			}
+		})
+	}
+}
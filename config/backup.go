@@ -0,0 +1,56 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/backup"
+)
+
+// BackupBlock represents a block for backups of states in HCL.
+type BackupBlock struct {
+	// Storage is a block for backup data store.
+	Storage StorageBlock `hcl:"storage,block"`
+	// Retention is a duration to keep snapshots, such as 720h.
+	// This is optional. If not set, snapshots are kept forever.
+	Retention string `hcl:"retention,optional"`
+}
+
+// parseBackupBlock parses a backup block and returns a *backup.Config.
+func parseBackupBlock(b BackupBlock) (*backup.Config, error) {
+	storage, err := parseStorageBlock(b.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := parseRetention(b.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := &backup.Config{
+		Storage:   storage,
+		Retention: retention,
+	}
+
+	return backup, nil
+}
+
+// parseRetention parses a given string as a duration of retention.
+// If it is empty, returns zero, which means snapshots are kept forever.
+func parseRetention(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	retention, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse retention: %s", err)
+	}
+
+	if retention < 0 {
+		return 0, fmt.Errorf("retention must not be negative: %s", s)
+	}
+
+	return retention, nil
+}
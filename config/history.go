@@ -6,6 +6,9 @@ import "github.com/minamijoyo/tfmigrate/history"
 type HistoryBlock struct {
 	// Storage is a block for migration history data store.
 	Storage StorageBlock `hcl:"storage,block"`
+	// Lock is a block for a lock of history-mode runs.
+	// This is optional. If not set, no lock is acquired.
+	Lock *LockBlock `hcl:"lock,block"`
 }
 
 // parseHistoryBlock parses a history block and returns a *history.Config.
@@ -19,5 +22,13 @@ func parseHistoryBlock(b HistoryBlock) (*history.Config, error) {
 		Storage: storage,
 	}
 
+	if b.Lock != nil {
+		lock, err := parseLockBlock(*b.Lock)
+		if err != nil {
+			return nil, err
+		}
+		history.Lock = lock
+	}
+
 	return history, nil
 }
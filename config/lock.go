@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/minamijoyo/tfmigrate/lock"
+
+	"github.com/minamijoyo/tfmigrate/lock/local"
+	"github.com/minamijoyo/tfmigrate/lock/mock"
+	"github.com/minamijoyo/tfmigrate/lock/s3"
+)
+
+// LockBlock represents a block for a lock of history-mode runs in HCL.
+type LockBlock struct {
+	// Type is a type for lock.
+	// Valid values are as follows:
+	// - mock
+	// - local
+	// - s3
+	Type string `hcl:"type,label"`
+	// Remain is a body of lock block.
+	// We first decode only a block header and then decode schema depending on
+	// its type label.
+	Remain hcl.Body `hcl:",remain"`
+}
+
+// parseLockBlock parses a lock block and returns a lock.Config.
+func parseLockBlock(b LockBlock) (lock.Config, error) {
+	switch b.Type {
+	case "mock": // only for testing
+		return parseMockLockBlock(b)
+
+	case "local":
+		return parseLocalLockBlock(b)
+
+	case "s3":
+		return parseS3LockBlock(b)
+
+	default:
+		return nil, fmt.Errorf("unknown history lock type: %s", b.Type)
+	}
+}
+
+// parseMockLockBlock parses a lock block for mock and returns a lock.Config.
+func parseMockLockBlock(b LockBlock) (lock.Config, error) {
+	var config mock.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
+
+// parseLocalLockBlock parses a lock block for local and returns a lock.Config.
+func parseLocalLockBlock(b LockBlock) (lock.Config, error) {
+	var config local.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if _, err := lock.ParseTTL(config.TTL); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// parseS3LockBlock parses a lock block for s3 and returns a lock.Config.
+func parseS3LockBlock(b LockBlock) (lock.Config, error) {
+	var config s3.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	if _, err := lock.ParseTTL(config.TTL); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
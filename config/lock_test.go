@@ -0,0 +1,171 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/lock"
+	"github.com/minamijoyo/tfmigrate/lock/local"
+	"github.com/minamijoyo/tfmigrate/lock/mock"
+	"github.com/minamijoyo/tfmigrate/lock/s3"
+)
+
+func TestParseLockBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   lock.Config
+		ok     bool
+	}{
+		{
+			desc: "mock",
+			source: `
+tfmigrate {
+  history {
+    storage "mock" {
+      data        = "foo"
+      write_error = false
+      read_error  = false
+    }
+    lock "mock" {
+      locked = true
+    }
+  }
+}
+`,
+			want: &mock.Config{
+				Locked: true,
+			},
+			ok: true,
+		},
+		{
+			desc: "local",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    lock "local" {
+      path = "tmp/history.lock"
+      ttl  = "1h"
+    }
+  }
+}
+`,
+			want: &local.Config{
+				Path: "tmp/history.lock",
+				TTL:  "1h",
+			},
+			ok: true,
+		},
+		{
+			desc: "s3",
+			source: `
+tfmigrate {
+  history {
+    storage "s3" {
+      bucket = "tfmigrate-test"
+      key    = "tfmigrate/history.json"
+    }
+    lock "s3" {
+      bucket = "tfmigrate-test"
+      key    = "tfmigrate/history.lock"
+      region = "ap-northeast-1"
+      ttl    = "30m"
+    }
+  }
+}
+`,
+			want: &s3.Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+				Region: "ap-northeast-1",
+				TTL:    "30m",
+			},
+			ok: true,
+		},
+		{
+			desc: "no lock",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   true,
+		},
+		{
+			desc: "invalid ttl",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    lock "local" {
+      path = "tmp/history.lock"
+      ttl  = "foo"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "missing required attribute (path)",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    lock "local" {
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "unknown type",
+			source: `
+tfmigrate {
+  history {
+    storage "local" {
+      path = "tmp/history.json"
+    }
+    lock "foo" {
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Lock
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
@@ -24,7 +25,7 @@ type MigrationFile struct {
 // MigrationBlock represents a migration block in HCL.
 type MigrationBlock struct {
 	// Type is a type for migration.
-	// Valid values are `state` and `multi_state`.
+	// Valid values are `state`, `multi_state`, `split` and `merge_state`.
 	Type string `hcl:"type,label"`
 	// Name is an arbitrary name for migration.
 	Name string `hcl:"name,label"`
@@ -47,6 +48,7 @@ func envVarMap() cty.Value {
 // ParseMigrationFile parses a given source of migration file and returns a *tfmigrate.MigrationConfig.
 // Note that this method does not read a file and you should pass source of config in bytes.
 // The filename is used for error message and selecting HCL syntax (.hcl and .json).
+// A relative path of import_from is resolved from the directory of filename.
 func ParseMigrationFile(filename string, source []byte) (*tfmigrate.MigrationConfig, error) {
 	// Decode migration block header.
 	var f MigrationFile
@@ -62,7 +64,7 @@ func ParseMigrationFile(filename string, source []byte) (*tfmigrate.MigrationCon
 		return nil, fmt.Errorf("failed to decode migration file: %s, err: %s", filename, err)
 	}
 
-	migrator, err := parseMigrationBlock(f.Migration, ctx)
+	migrator, err := parseMigrationBlock(f.Migration, ctx, filepath.Dir(filename))
 	if err != nil {
 		return nil, err
 	}
@@ -77,17 +79,24 @@ func ParseMigrationFile(filename string, source []byte) (*tfmigrate.MigrationCon
 }
 
 // parseMigrationBlock parses a migration block and returns a tfmigrate.MigratorConfig.
-func parseMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+// The baseDir is a directory of the migration file.
+func parseMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext, baseDir string) (tfmigrate.MigratorConfig, error) {
 	switch b.Type {
 	case "mock": // only for testing
 		return parseMockMigrationBlock(b, ctx)
 
 	case "state":
-		return parseStateMigrationBlock(b, ctx)
+		return parseStateMigrationBlock(b, ctx, baseDir)
 
 	case "multi_state":
 		return parseMultiStateMigrationBlock(b, ctx)
 
+	case "split":
+		return parseSplitMigrationBlock(b, ctx)
+
+	case "merge_state":
+		return parseMergeStateMigrationBlock(b, ctx)
+
 	default:
 		return nil, fmt.Errorf("unknown migration type: %s", b.Type)
 	}
@@ -105,13 +114,28 @@ func parseMockMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.
 }
 
 // parseStateMigrationBlock parses a migration block for state and returns a tfmigrate.MigratorConfig.
-func parseStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+// A relative path of import_from is resolved from a given baseDir.
+func parseStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext, baseDir string) (tfmigrate.MigratorConfig, error) {
 	var config tfmigrate.StateMigratorConfig
 	diags := gohcl.DecodeBody(b.Remain, ctx, &config)
 	if diags.HasErrors() {
 		return nil, diags
 	}
 
+	// The actions attribute is optional only if the import_from is set.
+	if config.Actions == nil && len(config.ImportFrom) == 0 {
+		return nil, hcl.Diagnostics{&hcl.Diagnostic{
+			Severity: hcl.DiagError,
+			Summary:  "Missing required argument",
+			Detail:   `The argument "actions" is required unless "import_from" is set.`,
+			Subject:  b.Remain.MissingItemRange().Ptr(),
+		}}
+	}
+
+	if len(config.ImportFrom) > 0 && !filepath.IsAbs(config.ImportFrom) {
+		config.ImportFrom = filepath.Join(baseDir, config.ImportFrom)
+	}
+
 	return &config, nil
 }
 
@@ -126,3 +150,27 @@ func parseMultiStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmi
 
 	return &config, nil
 }
+
+// parseSplitMigrationBlock parses a migration block for split and returns a
+// tfmigrate.MigratorConfig.
+func parseSplitMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+	var config tfmigrate.SplitMigratorConfig
+	diags := gohcl.DecodeBody(b.Remain, ctx, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
+
+// parseMergeStateMigrationBlock parses a migration block for merge_state and
+// returns a tfmigrate.MigratorConfig.
+func parseMergeStateMigrationBlock(b MigrationBlock, ctx *hcl.EvalContext) (tfmigrate.MigratorConfig, error) {
+	var config tfmigrate.MergeStateMigratorConfig
+	diags := gohcl.DecodeBody(b.Remain, ctx, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+)
+
+// migrationRangeAttributes is a list of attribute names in a migration block
+// whose source ranges are recorded.
+var migrationRangeAttributes = []string{
+	"dir",
+	"from_dir",
+	"to_dir",
+	"actions",
+	"import_from",
+	"parallelism",
+	"engine",
+}
+
+// migrationRangeBlocks is a list of nested block headers in a migration
+// block whose source ranges are recorded.
+var migrationRangeBlocks = []hcl.BlockHeaderSchema{
+	{Type: "allow_changes"},
+	{Type: "to", LabelNames: []string{"name"}},
+	{Type: "from", LabelNames: []string{"name"}},
+	{Type: "precondition"},
+	{Type: "postcondition"},
+}
+
+// MigrationRanges is a set of source ranges in a migration file.
+// It is used to report errors found by validation with file:line positions.
+type MigrationRanges struct {
+	// Block is a range of the migration block header.
+	Block hcl.Range
+	// Name is a range of the name label of the migration block.
+	Name hcl.Range
+	// attributes is a map of attribute name to its range.
+	// A nested block is also recorded with the range of its first header.
+	attributes map[string]hcl.Range
+	// elements is a map of attribute name to ranges of its elements.
+	// It is only recorded for list attributes such as actions and nested
+	// blocks such as to.
+	elements map[string][]hcl.Range
+}
+
+// ParseMigrationRanges parses a given source of migration file and returns
+// source ranges in it. Unlike ParseMigrationFile, it only parses the syntax
+// and doesn't evaluate expressions.
+// The filename is used for ranges and selecting HCL syntax (.hcl and .json).
+func ParseMigrationRanges(filename string, source []byte) (*MigrationRanges, error) {
+	parser := hclparse.NewParser()
+	var file *hcl.File
+	var diags hcl.Diagnostics
+	if filepath.Ext(filename) == ".json" {
+		file, diags = parser.ParseJSON(source, filename)
+	} else {
+		file, diags = parser.ParseHCL(source, filename)
+	}
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	content, _, diags := file.Body.PartialContent(&hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{
+			{Type: "migration", LabelNames: []string{"type", "name"}},
+		},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	r := &MigrationRanges{
+		attributes: make(map[string]hcl.Range),
+		elements:   make(map[string][]hcl.Range),
+	}
+	if len(content.Blocks) == 0 {
+		return r, nil
+	}
+	block := content.Blocks[0]
+	r.Block = block.DefRange
+	r.Name = block.LabelRanges[1]
+
+	schema := &hcl.BodySchema{}
+	for _, name := range migrationRangeAttributes {
+		schema.Attributes = append(schema.Attributes, hcl.AttributeSchema{Name: name})
+	}
+	schema.Blocks = append(schema.Blocks, migrationRangeBlocks...)
+	// Ignore diagnostics here because errors in the body are reported by
+	// ParseMigrationFile. We record ranges as much as possible.
+	body, _, _ := block.Body.PartialContent(schema)
+	for name, attr := range body.Attributes {
+		r.attributes[name] = attr.Expr.Range()
+		if exprs, diags := hcl.ExprList(attr.Expr); !diags.HasErrors() {
+			for _, e := range exprs {
+				r.elements[name] = append(r.elements[name], e.Range())
+			}
+		}
+	}
+	for _, b := range body.Blocks {
+		if _, ok := r.attributes[b.Type]; !ok {
+			r.attributes[b.Type] = b.DefRange
+		}
+		r.elements[b.Type] = append(r.elements[b.Type], b.DefRange)
+	}
+
+	return r, nil
+}
+
+// Lookup returns a source range for a given attribute and index of element.
+// If the index is negative or out of range, it returns the range of the
+// attribute. If the attribute is not found, it returns the range of the
+// migration block.
+func (r *MigrationRanges) Lookup(attribute string, index int) hcl.Range {
+	if elements, ok := r.elements[attribute]; ok && 0 <= index && index < len(elements) {
+		return elements[index]
+	}
+	if rng, ok := r.attributes[attribute]; ok {
+		return rng
+	}
+	return r.Block
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseMigrationRanges(t *testing.T) {
+	cases := []struct {
+		desc      string
+		filename  string
+		source    string
+		attribute string
+		index     int
+		want      string
+	}{
+		{
+			desc:     "action element",
+			filename: "test.hcl",
+			source: `
+migration "state" "test" {
+  dir = "dir1"
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+    "rm null_resource.bar",
+  ]
+}
+`,
+			attribute: "actions",
+			index:     1,
+			want:      "test.hcl:6,5-27",
+		},
+		{
+			desc:     "attribute",
+			filename: "test.hcl",
+			source: `
+migration "state" "test" {
+  dir = "dir1"
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+  ]
+}
+`,
+			attribute: "dir",
+			index:     -1,
+			want:      "test.hcl:3,9-15",
+		},
+		{
+			desc:     "not found",
+			filename: "test.hcl",
+			source: `
+migration "state" "test" {
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+  ]
+}
+`,
+			attribute: "dir",
+			index:     -1,
+			want:      "test.hcl:2,1-25",
+		},
+		{
+			desc:     "import_from",
+			filename: "test.hcl",
+			source: `
+migration "state" "test" {
+  dir         = "dir1"
+  import_from = "imports.csv"
+}
+`,
+			attribute: "import_from",
+			index:     -1,
+			want:      "test.hcl:4,17-30",
+		},
+		{
+			desc:     "nested block",
+			filename: "test.hcl",
+			source: `
+migration "state" "test" {
+  actions = [
+    "mv null_resource.foo null_resource.foo2",
+  ]
+  precondition {
+    resource_exists = ["null_resource.foo"]
+  }
+  precondition {
+    resource_absent = ["null_resource.foo2"]
+  }
+}
+`,
+			attribute: "precondition",
+			index:     1,
+			want:      "test.hcl:9,3-15",
+		},
+		{
+			desc:      "json",
+			filename:  "test.json",
+			source:    `{"migration": {"state": {"test": {"actions": ["mv a.b a.c", "rm a.d"]}}}}`,
+			attribute: "actions",
+			index:     1,
+			want:      "test.json:1,61-69",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			r, err := ParseMigrationRanges(tc.filename, []byte(tc.source))
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			got := r.Lookup(tc.attribute, tc.index).String()
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -61,6 +62,45 @@ migration "state" "test" {
 			},
 			ok: true,
 		},
+		{
+			desc: "state with allow_changes",
+			source: `
+migration "state" "test" {
+	actions = [
+		"mv aws_instance.foo aws_instance.foo2",
+	]
+	allow_changes {
+		resource_types = ["aws_*"]
+		actions        = ["update"]
+		attributes     = ["tags", "tags_all"]
+	}
+	allow_changes {
+		actions = ["read"]
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "",
+					Actions: []string{
+						"mv aws_instance.foo aws_instance.foo2",
+					},
+					AllowChanges: []tfmigrate.AllowChangeRule{
+						{
+							ResourceTypes: []string{"aws_*"},
+							Actions:       []string{"update"},
+							Attributes:    []string{"tags", "tags_all"},
+						},
+						{
+							Actions: []string{"read"},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
 		{
 			desc: "state without dir",
 			source: `
@@ -124,6 +164,89 @@ migration "state" "test" {
 			},
 			ok: true,
 		},
+		{
+			desc: "state with import_from",
+			source: `
+migration "state" "test" {
+	dir         = "dir1"
+	import_from = "imports.csv"
+	parallelism = 4
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir:         "dir1",
+					ImportFrom:  "imports.csv",
+					Parallelism: 4,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with conditions",
+			source: `
+migration "state" "test" {
+	actions = [
+		"mv aws_instance.foo aws_instance.foo2",
+	]
+	precondition {
+		resource_exists = ["aws_instance.foo"]
+		resource_absent = ["aws_instance.foo2"]
+		resource_count {
+			pattern = "module.x.*"
+			equals  = 12
+		}
+	}
+	postcondition {
+		resource_exists = ["aws_instance.foo2"]
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "state",
+				Name: "test",
+				Migrator: &tfmigrate.StateMigratorConfig{
+					Dir: "",
+					Actions: []string{
+						"mv aws_instance.foo aws_instance.foo2",
+					},
+					Preconditions: []tfmigrate.StateCondition{
+						{
+							ResourceExists: []string{"aws_instance.foo"},
+							ResourceAbsent: []string{"aws_instance.foo2"},
+							ResourceCount: []tfmigrate.ResourceCountCondition{
+								{Pattern: "module.x.*", Equals: 12},
+							},
+						},
+					},
+					Postconditions: []tfmigrate.StateCondition{
+						{
+							ResourceExists: []string{"aws_instance.foo2"},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "state with resource_count without equals",
+			source: `
+migration "state" "test" {
+	actions = [
+		"mv aws_instance.foo aws_instance.foo2",
+	]
+	precondition {
+		resource_count {
+			pattern = "module.x.*"
+		}
+	}
+}
+`,
+			want: nil,
+			ok:   false,
+		},
 		{
 			desc: "state without actions",
 			source: `
@@ -196,6 +319,50 @@ migration "multi_state" "mv_dir1_dir2" {
 			},
 			ok: true,
 		},
+		{
+			desc: "multi state with conditions",
+			source: `
+migration "multi_state" "mv_dir1_dir2" {
+	from_dir = "dir1"
+	to_dir   = "dir2"
+	actions = [
+		"mv null_resource.foo null_resource.foo2",
+	]
+	precondition {
+		state           = "from"
+		resource_exists = ["null_resource.foo"]
+	}
+	postcondition {
+		state           = "to"
+		resource_exists = ["null_resource.foo2"]
+	}
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "multi_state",
+				Name: "mv_dir1_dir2",
+				Migrator: &tfmigrate.MultiStateMigratorConfig{
+					FromDir: "dir1",
+					ToDir:   "dir2",
+					Actions: []string{
+						"mv null_resource.foo null_resource.foo2",
+					},
+					Preconditions: []tfmigrate.StateCondition{
+						{
+							State:          "from",
+							ResourceExists: []string{"null_resource.foo"},
+						},
+					},
+					Postconditions: []tfmigrate.StateCondition{
+						{
+							State:          "to",
+							ResourceExists: []string{"null_resource.foo2"},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
 		{
 			desc: "multi state without from_dir",
 			source: `
@@ -263,6 +430,78 @@ migration "multi_state" "mv_dir1_dir2" {
 			},
 			ok: true,
 		},
+		{
+			desc: "split",
+			source: `
+migration "split" "test" {
+	from_dir       = "dir1"
+	from_skip_plan = true
+	to "network" {
+		dir = "dir2"
+	}
+	to "app" {
+		dir       = "dir3"
+		workspace = "work1"
+		skip_plan = true
+	}
+	actions = [
+		"mv aws_vpc.main network:aws_vpc.main",
+		"xmv aws_instance.* app:aws_instance.$${1}",
+	]
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "split",
+				Name: "test",
+				Migrator: &tfmigrate.SplitMigratorConfig{
+					FromDir:      "dir1",
+					FromSkipPlan: true,
+					To: []tfmigrate.SplitDestinationConfig{
+						{Name: "network", Dir: "dir2"},
+						{Name: "app", Dir: "dir3", Workspace: "work1", SkipPlan: true},
+					},
+					Actions: []string{
+						"mv aws_vpc.main network:aws_vpc.main",
+						"xmv aws_instance.* app:aws_instance.${1}",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "merge_state",
+			source: `
+migration "merge_state" "test" {
+	from "dev" {
+		dir    = "dir1"
+		module = "module.dev"
+	}
+	from "prod" {
+		dir       = "dir2"
+		workspace = "work1"
+		skip_plan = true
+		resources = ["aws_s3_bucket.*"]
+	}
+	to_dir       = "dir3"
+	to_workspace = "work2"
+	force        = true
+}
+`,
+			want: &tfmigrate.MigrationConfig{
+				Type: "merge_state",
+				Name: "test",
+				Migrator: &tfmigrate.MergeStateMigratorConfig{
+					From: []tfmigrate.MergeSourceConfig{
+						{Name: "dev", Dir: "dir1", Module: "module.dev"},
+						{Name: "prod", Dir: "dir2", Workspace: "work1", SkipPlan: true, Resources: []string{"aws_s3_bucket.*"}},
+					},
+					ToDir:       "dir3",
+					ToWorkspace: "work2",
+					Force:       true,
+				},
+			},
+			ok: true,
+		},
 		{
 			desc: "unknown migration type",
 			source: `
@@ -385,6 +624,59 @@ migration "state" "test" {
 	}
 }
 
+func TestParseMigrationFileImportFrom(t *testing.T) {
+	cases := []struct {
+		desc       string
+		filename   string
+		importFrom string
+		want       string
+	}{
+		{
+			desc:       "current directory",
+			filename:   "test.hcl",
+			importFrom: "imports.csv",
+			want:       "imports.csv",
+		},
+		{
+			desc:       "relative to migration file",
+			filename:   "tfmigrate/test.hcl",
+			importFrom: "imports.csv",
+			want:       "tfmigrate/imports.csv",
+		},
+		{
+			desc:       "parent directory",
+			filename:   "tfmigrate/test.hcl",
+			importFrom: "../data/imports.json",
+			want:       "data/imports.json",
+		},
+		{
+			desc:       "absolute path",
+			filename:   "tfmigrate/test.hcl",
+			importFrom: "/tmp/imports.csv",
+			want:       "/tmp/imports.csv",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			source := fmt.Sprintf(`
+migration "state" "test" {
+	dir         = "dir1"
+	import_from = %q
+}
+`, tc.importFrom)
+			got, err := ParseMigrationFile(tc.filename, []byte(source))
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			c := got.Migrator.(*tfmigrate.StateMigratorConfig)
+			if c.ImportFrom != tc.want {
+				t.Errorf("got: %s, want: %s", c.ImportFrom, tc.want)
+			}
+		})
+	}
+}
+
 func TestParseMigrationFileWithJsonSyntax(t *testing.T) {
 	cases := []struct {
 		desc   string
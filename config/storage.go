@@ -7,6 +7,8 @@ import (
 	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/minamijoyo/tfmigrate/storage"
 
+	"github.com/minamijoyo/tfmigrate/storage/azurerm"
+	"github.com/minamijoyo/tfmigrate/storage/gcs"
 	"github.com/minamijoyo/tfmigrate/storage/local"
 	"github.com/minamijoyo/tfmigrate/storage/mock"
 	"github.com/minamijoyo/tfmigrate/storage/s3"
@@ -19,6 +21,8 @@ type StorageBlock struct {
 	// - mock
 	// - local
 	// - s3
+	// - gcs
+	// - azurerm
 	Type string `hcl:"type,label"`
 	// Remain is a body of storage block.
 	// We first decode only a block header and then decode schema depending on
@@ -38,6 +42,12 @@ func parseStorageBlock(b StorageBlock) (storage.Config, error) {
 	case "s3":
 		return parseS3StorageBlock(b)
 
+	case "gcs":
+		return parseGCSStorageBlock(b)
+
+	case "azurerm":
+		return parseAzureRMStorageBlock(b)
+
 	default:
 		return nil, fmt.Errorf("unknown history storage type: %s", b.Type)
 	}
@@ -75,3 +85,25 @@ func parseS3StorageBlock(b StorageBlock) (storage.Config, error) {
 
 	return &config, nil
 }
+
+// parseGCSStorageBlock parses a storage block for gcs and returns a storage.Config.
+func parseGCSStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config gcs.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
+
+// parseAzureRMStorageBlock parses a storage block for azurerm and returns a storage.Config.
+func parseAzureRMStorageBlock(b StorageBlock) (storage.Config, error) {
+	var config azurerm.Config
+	diags := gohcl.DecodeBody(b.Remain, nil, &config)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+	"github.com/minamijoyo/tfmigrate/storage/azurerm"
+)
+
+func TestParseAzureRMStorageBlock(t *testing.T) {
+	cases := []struct {
+		desc   string
+		source string
+		want   storage.Config
+		ok     bool
+	}{
+		{
+			desc: "valid (required)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "tfmigrate"
+      container_name       = "tfstate-test"
+      key                  = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: &azurerm.Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfstate-test",
+				Key:                "tfmigrate/history.json",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (with optional)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "devstoreaccount1"
+      container_name       = "tfstate-test"
+      key                  = "tfmigrate/history.json"
+
+      access_key = "dummy"
+      sas_token  = "dummy"
+      use_msi    = true
+      client_id  = "00000000-0000-0000-0000-000000000000"
+      endpoint   = "http://azurite:10000/devstoreaccount1"
+    }
+  }
+}
+`,
+			want: &azurerm.Config{
+				StorageAccountName: "devstoreaccount1",
+				ContainerName:      "tfstate-test",
+				Key:                "tfmigrate/history.json",
+				AccessKey:          "dummy",
+				SASToken:           "dummy",
+				UseMSI:             true,
+				ClientID:           "00000000-0000-0000-0000-000000000000",
+				Endpoint:           "http://azurite:10000/devstoreaccount1",
+			},
+			ok: true,
+		},
+		{
+			desc: "missing required attribute (storage_account_name)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      container_name = "tfstate-test"
+      key            = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "missing required attribute (container_name)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "tfmigrate"
+      key                  = "tfmigrate/history.json"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "missing required attribute (key)",
+			source: `
+tfmigrate {
+  history {
+    storage "azurerm" {
+      storage_account_name = "tfmigrate"
+      container_name       = "tfstate-test"
+    }
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config, err := ParseConfigurationFile("test.hcl", []byte(tc.source))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", config)
+			}
+			if tc.ok {
+				got := config.History.Storage
+				if !reflect.DeepEqual(got, tc.want) {
+					t.Errorf("got: %#v, want: %#v", got, tc.want)
+				}
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 
 	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/minamijoyo/tfmigrate/backup"
 	"github.com/minamijoyo/tfmigrate/history"
 )
 
@@ -25,6 +26,9 @@ type TfmigrateBlock struct {
 	IsBackendTerraformCloud bool `hcl:"is_backend_terraform_cloud,optional"`
 	// History is a block for migration history management.
 	History *HistoryBlock `hcl:"history,block"`
+	// Backup is a block for backups of states before applying migrations.
+	// This is optional. If not set, no backup is saved.
+	Backup *BackupBlock `hcl:"backup,block"`
 }
 
 // TfmigrateConfig is a config for top-level CLI settings.
@@ -39,6 +43,9 @@ type TfmigrateConfig struct {
 	IsBackendTerraformCloud bool
 	// History is a config for migration history management.
 	History *history.Config
+	// Backup is a config for backups of states before applying migrations.
+	// This is optional. If nil, no backup is saved.
+	Backup *backup.Config
 }
 
 // LoadConfigurationFile is a helper function which reads and parses a given configuration file.
@@ -79,6 +86,14 @@ func ParseConfigurationFile(filename string, source []byte) (*TfmigrateConfig, e
 		config.History = history
 	}
 
+	if f.Tfmigrate.Backup != nil {
+		backup, err := parseBackupBlock(*f.Tfmigrate.Backup)
+		if err != nil {
+			return nil, err
+		}
+		config.Backup = backup
+	}
+
 	return config, nil
 }
 
@@ -3,7 +3,9 @@ package config
 import (
 	"reflect"
 	"testing"
+	"time"
 
+	"github.com/minamijoyo/tfmigrate/backup"
 	"github.com/minamijoyo/tfmigrate/history"
 	"github.com/minamijoyo/tfmigrate/storage/local"
 )
@@ -58,6 +60,68 @@ tfmigrate {
 			},
 			ok: true,
 		},
+		{
+			desc: "with backup",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  backup {
+    storage "local" {
+      path = "tmp/backup"
+    }
+  }
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: "tfmigrate",
+				Backup: &backup.Config{
+					Storage: &local.Config{
+						Path: "tmp/backup",
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "with backup retention",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  backup {
+    storage "local" {
+      path = "tmp/backup"
+    }
+    retention = "720h"
+  }
+}
+`,
+			want: &TfmigrateConfig{
+				MigrationDir: "tfmigrate",
+				Backup: &backup.Config{
+					Storage: &local.Config{
+						Path: "tmp/backup",
+					},
+					Retention: 720 * time.Hour,
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid backup retention",
+			source: `
+tfmigrate {
+  migration_dir = "tfmigrate"
+  backup {
+    storage "local" {
+      path = "tmp/backup"
+    }
+    retention = "-1h"
+  }
+}
+`,
+			want: nil,
+			ok:   false,
+		},
 		{
 			desc: "missing block (history)",
 			source: `
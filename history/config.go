@@ -1,6 +1,7 @@
 package history
 
 import (
+	"github.com/minamijoyo/tfmigrate/lock"
 	"github.com/minamijoyo/tfmigrate/storage"
 )
 
@@ -10,4 +11,7 @@ type Config struct {
 	MigrationDir string
 	// Storage is an interface of factory method for Storage
 	Storage storage.Config
+	// Lock is an interface of factory method for Locker.
+	// This is optional. If nil, no lock is acquired.
+	Lock lock.Config
 }
@@ -2,6 +2,8 @@ package history
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
@@ -24,6 +26,24 @@ type Controller struct {
 	migrations []string
 	// history is a list of applied migration logs which is persisted to a storage.
 	history History
+	// version is a version of the history file loaded from storage.
+	// It is used for detecting concurrent updates of the history on Save().
+	version storage.Version
+	// added is a set of records added since the history was loaded.
+	// When the history has been changed by someone else, they are re-merged
+	// into the latest history.
+	added map[string]Record
+	// deleted is a set of file names whose records have been deleted since
+	// the history was loaded. When the history has been changed by someone
+	// else, they are deleted from the latest history again.
+	deleted map[string]bool
+	// incompleted is a set of migrations marked as incomplete since the
+	// history was loaded. They are re-merged in the same way as added.
+	incompleted map[string]Incomplete
+	// resolved is a set of file names whose incomplete marks have been
+	// deleted since the history was loaded. They are re-merged in the same
+	// way as deleted.
+	resolved map[string]bool
 	// config customizes behavior of history management.
 	config Config
 }
@@ -31,13 +51,13 @@ type Controller struct {
 // NewController returns a new Controller instance.
 func NewController(ctx context.Context, migrationDir string, config *Config) (*Controller, error) {
 	log.Printf("[DEBUG] [history] load migration dir: %s\n", migrationDir)
-	migrations, err := loadMigrationFileNames(migrationDir)
+	migrations, err := LoadMigrationFileNames(migrationDir)
 	if err != nil {
 		return nil, err
 	}
 
 	log.Print("[DEBUG] [history] load history\n")
-	h, err := loadHistory(ctx, config.Storage)
+	h, version, err := loadHistory(ctx, config.Storage)
 	if err != nil {
 		return nil, err
 	}
@@ -46,15 +66,20 @@ func NewController(ctx context.Context, migrationDir string, config *Config) (*C
 		migrationDir: migrationDir,
 		migrations:   migrations,
 		history:      *h,
+		version:      version,
+		added:        make(map[string]Record),
+		deleted:      make(map[string]bool),
+		incompleted:  make(map[string]Incomplete),
+		resolved:     make(map[string]bool),
 		config:       *config,
 	}
 
 	return c, nil
 }
 
-// loadMigrationDir loads a migration directory and lists migration files from local.
+// LoadMigrationFileNames loads a migration directory and lists migration files from local.
 // The returned slice is sorted alphabetically.
-func loadMigrationFileNames(dir string) ([]string, error) {
+func LoadMigrationFileNames(dir string) ([]string, error) {
 	migrations := []string{}
 
 	files, err := os.ReadDir(dir)
@@ -83,50 +108,141 @@ func loadMigrationFileNames(dir string) ([]string, error) {
 
 // loadHistory loads a history file from a storage.
 // If a given history is not found, create a new one.
-func loadHistory(ctx context.Context, c storage.Config) (*History, error) {
+// It also returns a version of the history file.
+func loadHistory(ctx context.Context, c storage.Config) (*History, storage.Version, error) {
 	s, err := c.NewStorage()
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	return readHistory(ctx, s)
+}
+
+// readHistory reads a history file from a given storage and returns it with
+// a version of the history file.
+func readHistory(ctx context.Context, s storage.Storage) (*History, storage.Version, error) {
 	log.Printf("[DEBUG] [history] read storage %#v\n", s)
-	b, err := s.Read(ctx)
+	b, version, err := s.ReadWithVersion(ctx)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	log.Printf("[TRACE] [history] read history file: %#v\n", b)
+	log.Printf("[TRACE] [history] read history file: %#v, version: %s\n", b, version)
 
-	// If a given history is not found, s.Read returns empty bytes with no error.
+	// If a given history is not found, s.ReadWithVersion returns empty bytes with no error.
 	// In this case, we assume that it's the first use and create a new history.
 	if len(b) == 0 {
 		log.Print("[DEBUG] [history] new empty history\n")
-		return newEmptyHistory(), nil
+		return newEmptyHistory(), version, nil
 	}
 
 	h, err := ParseHistoryFile(b)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return h, nil
+	return h, version, nil
 }
 
+// maxSaveRetries is a maximum number of retries to re-merge the history when
+// the history has been changed by someone else since loaded.
+const maxSaveRetries = 3
+
 // Save persists a current state of historyFile to storage.
+// To avoid overwriting records written by someone else concurrently, it
+// writes the history only if it hasn't been changed since loaded.
+// If it has been changed, records added in this session are re-merged into
+// the latest history and retry to save it. If a record for the same migration
+// has been added concurrently, it returns an error.
 func (c *Controller) Save(ctx context.Context) error {
 	s, err := c.config.Storage.NewStorage()
 	if err != nil {
 		return err
 	}
 
-	f := newFileV1(c.history)
-	b, err := f.Serialize()
+	for i := 0; ; i++ {
+		b, err := c.Serialize()
+		if err != nil {
+			return err
+		}
+
+		log.Printf("[DEBUG] [history] write storage: %#v\n", s)
+		log.Printf("[TRACE] [history] write history file: %#v, version: %s\n", b, c.version)
+		version, err := s.WriteIfMatch(ctx, b, c.version)
+		if err == nil {
+			c.version = version
+			c.added = make(map[string]Record)
+			c.deleted = make(map[string]bool)
+			c.incompleted = make(map[string]Incomplete)
+			c.resolved = make(map[string]bool)
+			return nil
+		}
+
+		if !errors.Is(err, storage.ErrVersionConflict) {
+			return err
+		}
+		if i >= maxSaveRetries {
+			return fmt.Errorf("failed to save history after %d retries: %s", maxSaveRetries, err)
+		}
+
+		log.Printf("[WARN] [history] the history has been changed since loaded, re-merge it: %s\n", err)
+		if err := c.remerge(ctx, s); err != nil {
+			return err
+		}
+	}
+}
+
+// remerge reloads the latest history from a given storage and merges records
+// added in this session into it.
+func (c *Controller) remerge(ctx context.Context, s storage.Storage) error {
+	latest, version, err := readHistory(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	for filename, r := range c.added {
+		if latest.Contains(filename) {
+			return fmt.Errorf("a migration has been recorded in history concurrently by someone else: %s", filename)
+		}
+		latest.Add(filename, r)
+	}
+
+	for filename := range c.deleted {
+		latest.Delete(filename)
+	}
+
+	for filename, i := range c.incompleted {
+		latest.AddIncomplete(filename, i)
+	}
+
+	for filename := range c.resolved {
+		latest.DeleteIncomplete(filename)
+	}
+
+	c.history = *latest
+	c.version = version
+	return nil
+}
+
+// Serialize encodes the current history to bytes.
+// The history is always encoded in the latest file format.
+// If it was loaded from v1, it is upgraded to v2 here.
+func (c *Controller) Serialize() ([]byte, error) {
+	f := newFileV2(c.history)
+	return f.Serialize()
+}
+
+// Reload reloads the latest history from storage.
+// It is intended to be called just after acquiring a lock, because the
+// history may have been updated by someone else before the lock is acquired.
+// Records added in this session are merged into the latest history.
+func (c *Controller) Reload(ctx context.Context) error {
+	s, err := c.config.Storage.NewStorage()
 	if err != nil {
 		return err
 	}
 
-	log.Printf("[DEBUG] [history] write storage: %#v\n", s)
-	log.Printf("[TRACE] [history] write history file: %#v\n", b)
-	return s.Write(ctx, b)
+	log.Print("[DEBUG] [history] reload history\n")
+	return c.remerge(ctx, s)
 }
 
 // Migrations returns a list of all migration file names.
@@ -160,17 +276,131 @@ func (c *Controller) AlreadyApplied(filename string) bool {
 // AddRecord adds a record to history.
 // This method doesn't persist history. Call Save() to save the history.
 // If appliedAt is nil, a timestamp is automatically set to time.Now().
-func (c *Controller) AddRecord(filename string, migrationType string, name string, appliedAt *time.Time) {
+// The metadata is optional and can be nil.
+// A checksum of the migration file is also recorded to detect modifications
+// after it was applied.
+func (c *Controller) AddRecord(filename string, migrationType string, name string, appliedAt *time.Time, metadata *Metadata) {
 	timestamp := appliedAt
 	if timestamp == nil {
 		now := time.Now()
 		timestamp = &now
 	}
+
+	checksum, err := c.checksum(filename)
+	if err != nil {
+		// Failing to record the checksum should not fail the migration which
+		// has already been applied. It is just not verified later.
+		log.Printf("[WARN] [history] failed to compute a checksum of migration file: %s\n", err)
+	}
+
 	r := Record{
 		Type:      migrationType,
 		Name:      name,
 		AppliedAt: *timestamp,
+		Checksum:  checksum,
+		Metadata:  metadata,
 	}
 
 	c.history.Add(filename, r)
+
+	if c.added == nil {
+		c.added = make(map[string]Record)
+	}
+	c.added[filename] = r
+	delete(c.deleted, filename)
+}
+
+// DeleteRecord deletes a record from history.
+// This method doesn't persist history. Call Save() to save the history.
+// It returns an error if a given migration has not been applied.
+func (c *Controller) DeleteRecord(filename string) error {
+	if !c.history.Contains(filename) {
+		return fmt.Errorf("a migration has not been applied: %s", filename)
+	}
+
+	c.history.Delete(filename)
+	delete(c.added, filename)
+
+	if c.deleted == nil {
+		c.deleted = make(map[string]bool)
+	}
+	c.deleted[filename] = true
+
+	return nil
+}
+
+// PruneRecords deletes records whose migration files no longer exist.
+// This method doesn't persist history. Call Save() to save the history.
+// It returns a list of file names of deleted records sorted alphabetically.
+func (c *Controller) PruneRecords() []string {
+	exists := make(map[string]bool)
+	for _, m := range c.migrations {
+		exists[m] = true
+	}
+
+	pruned := []string{}
+	for filename := range c.history.records {
+		if !exists[filename] {
+			pruned = append(pruned, filename)
+		}
+	}
+	sort.Strings(pruned)
+
+	for _, filename := range pruned {
+		// The record always exists here.
+		_ = c.DeleteRecord(filename)
+	}
+
+	return pruned
+}
+
+// MarkIncomplete marks a given migration as incomplete, which means that its
+// apply failed in the middle and left states inconsistent.
+// This method doesn't persist history. Call Save() to save the history.
+func (c *Controller) MarkIncomplete(filename string, migrationType string, name string, reason string) {
+	i := Incomplete{
+		Type:     migrationType,
+		Name:     name,
+		FailedAt: time.Now(),
+		Reason:   reason,
+	}
+
+	c.history.AddIncomplete(filename, i)
+
+	if c.incompleted == nil {
+		c.incompleted = make(map[string]Incomplete)
+	}
+	c.incompleted[filename] = i
+	delete(c.resolved, filename)
+}
+
+// ResolveIncomplete deletes an incomplete mark of a given migration.
+// This method doesn't persist history. Call Save() to save the history.
+// It returns an error if a given migration is not marked as incomplete.
+func (c *Controller) ResolveIncomplete(filename string) error {
+	if !c.history.ContainsIncomplete(filename) {
+		return fmt.Errorf("a migration is not marked as incomplete: %s", filename)
+	}
+
+	c.history.DeleteIncomplete(filename)
+	delete(c.incompleted, filename)
+
+	if c.resolved == nil {
+		c.resolved = make(map[string]bool)
+	}
+	c.resolved[filename] = true
+
+	return nil
+}
+
+// IncompleteMigrations returns a list of migration file names marked as
+// incomplete. The returned slice is sorted alphabetically.
+func (c *Controller) IncompleteMigrations() []string {
+	incompletes := []string{}
+	for filename := range c.history.incompletes {
+		incompletes = append(incompletes, filename)
+	}
+	sort.Strings(incompletes)
+
+	return incompletes
 }
@@ -94,7 +94,7 @@ func TestLoadMigrationFileNames(t *testing.T) {
 				}
 			}
 
-			got, err := loadMigrationFileNames(migrationDir)
+			got, err := LoadMigrationFileNames(migrationDir)
 
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %#v", err)
@@ -190,7 +190,7 @@ func TestLoadHistory(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
-			got, err := loadHistory(context.Background(), tc.config)
+			got, _, err := loadHistory(context.Background(), tc.config)
 			if tc.ok && err != nil {
 				t.Fatalf("unexpected err: %#v", err)
 			}
@@ -224,7 +224,7 @@ func TestControllerSave(t *testing.T) {
 			},
 			h: newEmptyHistory(),
 			want: []byte(`{
-    "version": 1,
+    "version": 2,
     "records": {}
 }`),
 			ok: true,
@@ -238,7 +238,7 @@ func TestControllerSave(t *testing.T) {
 			},
 			h: newEmptyHistory(),
 			want: []byte(`{
-    "version": 1,
+    "version": 2,
     "records": {}
 }`),
 			ok: false,
@@ -271,6 +271,166 @@ func TestControllerSave(t *testing.T) {
 	}
 }
 
+func TestControllerSaveConflict(t *testing.T) {
+	loaded := `{
+    "version": 1,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        }
+    }
+}`
+	cases := []struct {
+		desc     string
+		loaded   string
+		latest   string
+		conflict bool
+		filename string
+		want     []byte
+		ok       bool
+	}{
+		{
+			desc:     "not changed",
+			loaded:   loaded,
+			latest:   loaded,
+			conflict: false,
+			filename: "20201012030303_foo.hcl",
+			want: []byte(`{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012030303_foo.hcl": {
+            "type": "state",
+            "name": "baz",
+            "applied_at": "2020-10-13T07:08:09Z"
+        }
+    }
+}`),
+			ok: true,
+		},
+		{
+			desc:   "changed by someone else",
+			loaded: loaded,
+			latest: `{
+    "version": 1,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z"
+        }
+    }
+}`,
+			conflict: false,
+			filename: "20201012030303_foo.hcl",
+			want: []byte(`{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z"
+        },
+        "20201012030303_foo.hcl": {
+            "type": "state",
+            "name": "baz",
+            "applied_at": "2020-10-13T07:08:09Z"
+        }
+    }
+}`),
+			ok: true,
+		},
+		{
+			desc:   "the same migration applied by someone else",
+			loaded: loaded,
+			latest: `{
+    "version": 1,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012030303_foo.hcl": {
+            "type": "state",
+            "name": "baz",
+            "applied_at": "2020-10-13T04:05:06Z"
+        }
+    }
+}`,
+			conflict: false,
+			filename: "20201012030303_foo.hcl",
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc:     "always conflict",
+			loaded:   loaded,
+			latest:   loaded,
+			conflict: true,
+			filename: "20201012030303_foo.hcl",
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &mock.Config{
+				Data: tc.loaded,
+			}
+			h, version, err := loadHistory(context.Background(), config)
+			if err != nil {
+				t.Fatalf("failed to load history: %s", err)
+			}
+			c := &Controller{
+				history: *h,
+				version: version,
+				config: Config{
+					Storage: config,
+				},
+			}
+
+			// simulate a concurrent update.
+			config.Data = tc.latest
+			config.WriteConflict = tc.conflict
+
+			appliedAt := time.Date(2020, 10, 13, 7, 8, 9, 0, time.UTC)
+			c.AddRecord(tc.filename, "state", "baz", &appliedAt, nil)
+			err = c.Save(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				got := []byte(config.Storage().Data())
+				if string(got) != string(tc.want) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.want))
+				}
+			}
+		})
+	}
+}
+
 func TestUnappliedMigrations(t *testing.T) {
 	cases := []struct {
 		desc       string
@@ -547,7 +707,7 @@ func TestControllerAddRecord(t *testing.T) {
 				history:    tc.history,
 			}
 
-			c.AddRecord(tc.filename, tc.migrationType, currentTC.name, &currentTC.appliedAt)
+			c.AddRecord(tc.filename, tc.migrationType, currentTC.name, &currentTC.appliedAt, nil)
 			got := tc.history
 			if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(got)); diff != "" {
 				t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
@@ -555,3 +715,278 @@ func TestControllerAddRecord(t *testing.T) {
 		})
 	}
 }
+
+func TestControllerDeleteRecord(t *testing.T) {
+	cases := []struct {
+		desc     string
+		history  History
+		filename string
+		want     History
+		ok       bool
+	}{
+		{
+			desc: "delete",
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_foo.hcl": Record{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+					},
+				},
+			},
+			filename: "20201012020202_foo.hcl",
+			want: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "not applied",
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+				},
+			},
+			filename: "20201012020202_foo.hcl",
+			want:     History{},
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &Controller{
+				history: tc.history,
+			}
+
+			err := c.DeleteRecord(tc.filename)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				got := c.history
+				if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(got)); diff != "" {
+					t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
+				}
+				if !c.deleted[tc.filename] {
+					t.Errorf("expected %s to be tracked as deleted", tc.filename)
+				}
+			}
+		})
+	}
+}
+
+func TestControllerPruneRecords(t *testing.T) {
+	c := &Controller{
+		migrations: []string{
+			"20201012010101_foo.hcl",
+			"20201012030303_foo.hcl",
+		},
+		history: History{
+			records: map[string]Record{
+				"20201012010101_foo.hcl": Record{
+					Type:      "state",
+					Name:      "foo",
+					AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+				},
+				"20201012020202_foo.hcl": Record{
+					Type:      "state",
+					Name:      "bar",
+					AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+				},
+				"20201012000000_foo.hcl": Record{
+					Type:      "state",
+					Name:      "baz",
+					AppliedAt: time.Date(2020, 10, 13, 7, 8, 9, 0, time.UTC),
+				},
+			},
+		},
+	}
+
+	got := c.PruneRecords()
+	want := []string{
+		"20201012000000_foo.hcl",
+		"20201012020202_foo.hcl",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", got, want, diff)
+	}
+
+	wantHistory := History{
+		records: map[string]Record{
+			"20201012010101_foo.hcl": Record{
+				Type:      "state",
+				Name:      "foo",
+				AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+			},
+		},
+	}
+	if diff := cmp.Diff(c.history, wantHistory, cmp.AllowUnexported(c.history)); diff != "" {
+		t.Errorf("got = %#v, want = %#v, diff = %s", c.history, wantHistory, diff)
+	}
+}
+
+func TestControllerSaveConflictWithDelete(t *testing.T) {
+	loaded := `{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        }
+    }
+}`
+	latest := `{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z"
+        }
+    }
+}`
+	want := `{
+    "version": 2,
+    "records": {
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z"
+        }
+    }
+}`
+
+	config := &mock.Config{
+		Data: loaded,
+	}
+	h, version, err := loadHistory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to load history: %s", err)
+	}
+	c := &Controller{
+		history: *h,
+		version: version,
+		config: Config{
+			Storage: config,
+		},
+	}
+
+	if err := c.DeleteRecord("20201012010101_foo.hcl"); err != nil {
+		t.Fatalf("failed to delete record: %s", err)
+	}
+
+	// simulate a concurrent update.
+	config.Data = latest
+
+	if err := c.Save(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got := config.Storage().Data()
+	if got != want {
+		t.Errorf("got: %s, want: %s", got, want)
+	}
+}
+
+func TestControllerSaveConflictWithIncomplete(t *testing.T) {
+	loaded := `{
+    "version": 2,
+    "records": {},
+    "incompletes": {
+        "20201012010101_foo.hcl": {
+            "type": "multi_state",
+            "name": "foo",
+            "failed_at": "2020-10-13T01:02:03Z",
+            "reason": "failed to push"
+        }
+    }
+}`
+	latest := `{
+    "version": 2,
+    "records": {
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z"
+        }
+    },
+    "incompletes": {
+        "20201012010101_foo.hcl": {
+            "type": "multi_state",
+            "name": "foo",
+            "failed_at": "2020-10-13T01:02:03Z",
+            "reason": "failed to push"
+        }
+    }
+}`
+
+	config := &mock.Config{
+		Data: loaded,
+	}
+	h, version, err := loadHistory(context.Background(), config)
+	if err != nil {
+		t.Fatalf("failed to load history: %s", err)
+	}
+	c := &Controller{
+		history: *h,
+		version: version,
+		config: Config{
+			Storage: config,
+		},
+	}
+
+	if err := c.ResolveIncomplete("20201012010101_foo.hcl"); err != nil {
+		t.Fatalf("failed to resolve incomplete: %s", err)
+	}
+	if err := c.ResolveIncomplete("20201012010101_foo.hcl"); err == nil {
+		t.Fatal("expected to return an error for resolving twice, but no error")
+	}
+	c.MarkIncomplete("20201012030303_foo.hcl", "multi_state", "baz", "failed to push")
+
+	// simulate a concurrent update.
+	config.Data = latest
+
+	if err := c.Save(context.Background()); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	saved, _, err := loadHistory(context.Background(), &mock.Config{Data: config.Storage().Data()})
+	if err != nil {
+		t.Fatalf("failed to load saved history: %s", err)
+	}
+	got := &Controller{history: *saved}
+	if !got.AlreadyApplied("20201012020202_foo.hcl") {
+		t.Errorf("expected a record added concurrently to be kept, got: %s", config.Storage().Data())
+	}
+	want := []string{"20201012030303_foo.hcl"}
+	if !reflect.DeepEqual(got.IncompleteMigrations(), want) {
+		t.Errorf("got incompletes: %v, want: %v", got.IncompleteMigrations(), want)
+	}
+}
@@ -47,6 +47,29 @@ func TestParseHistoryFile(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc: "v2",
+			b: []byte(`{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        }
+    }
+}`),
+			want: &History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+				},
+			},
+			ok: true,
+		},
 		{
 			desc: "unknown version",
 			b: []byte(`{
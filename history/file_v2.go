@@ -0,0 +1,232 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FileV2 represents a data structure for history file format v2.
+// In addition to v1, it records metadata about applied migrations for
+// auditing. A history file v1 is automatically upgraded to v2 on save.
+type FileV2 struct {
+	// Version is a file format version. It is always set to 2.
+	Version int `json:"version"`
+	// Records is a set of applied migration log.
+	// Only success migrations are recorded.
+	// A key is migration file name.
+	// We record only the file name not to invalidate history when the migration
+	// directory is moved.
+	Records map[string]RecordV2 `json:"records"`
+	// Incompletes is a set of migrations whose apply failed in the middle and
+	// left states inconsistent. A key is migration file name.
+	// It is omitted if there are no incomplete migrations.
+	Incompletes map[string]IncompleteV2 `json:"incompletes,omitempty"`
+}
+
+// RecordV2 represents an applied migration log.
+type RecordV2 struct {
+	// Type is a migration type.
+	Type string `json:"type"`
+	// Name is a migration name.
+	Name string `json:"name"`
+	// AppliedAt is a timestamp when the migration was applied.
+	// Note that we only record it when the migration was succeed.
+	AppliedAt time.Time `json:"applied_at"`
+	// Checksum is a hex-encoded SHA-256 of the migration file content.
+	// It is omitted for records written before checksums were introduced.
+	Checksum string `json:"checksum,omitempty"`
+	// Metadata is additional information about the applied migration.
+	// It is omitted for records upgraded from v1.
+	Metadata *MetadataV2 `json:"metadata,omitempty"`
+}
+
+// IncompleteV2 represents a migration whose apply failed in the middle.
+type IncompleteV2 struct {
+	// Type is a migration type.
+	Type string `json:"type"`
+	// Name is a migration name.
+	Name string `json:"name"`
+	// FailedAt is a timestamp when the migration failed.
+	FailedAt time.Time `json:"failed_at"`
+	// Reason is an error message including an instruction to recover.
+	Reason string `json:"reason"`
+}
+
+// MetadataV2 represents additional information about an applied migration.
+type MetadataV2 struct {
+	// TfmigrateVersion is a version of tfmigrate which applied the migration.
+	TfmigrateVersion string `json:"tfmigrate_version"`
+	// ExecType is a type of executable, either terraform or opentofu.
+	ExecType string `json:"exec_type"`
+	// ExecVersion is a version of executable.
+	ExecVersion string `json:"exec_version"`
+	// User is a name of user who applied the migration.
+	User string `json:"user"`
+	// Hostname is a hostname where the migration was applied.
+	Hostname string `json:"hostname"`
+	// Duration is a time taken to apply the migration in Go's duration format
+	// such as "1m23.456s".
+	Duration string `json:"duration"`
+	// Actions is a list of actions which were actually run.
+	Actions []string `json:"actions"`
+	// States is a list of affected states.
+	States []StateMetadataV2 `json:"states"`
+}
+
+// StateMetadataV2 represents information about a state affected by a migration.
+type StateMetadataV2 struct {
+	// Dir is a working directory of the state.
+	Dir string `json:"dir"`
+	// Workspace is a workspace of the state.
+	Workspace string `json:"workspace"`
+	// BeforeSerial is a serial of the state before applying the migration.
+	BeforeSerial int64 `json:"before_serial"`
+	// AfterSerial is a serial of the state pushed by the migration.
+	AfterSerial int64 `json:"after_serial"`
+}
+
+// newFileV2 converts a History to a FileV2 instance.
+func newFileV2(h History) *FileV2 {
+	m := make(map[string]RecordV2)
+	for k, v := range h.records {
+		r := newRecordV2(v)
+		m[k] = r
+	}
+
+	var incompletes map[string]IncompleteV2
+	if len(h.incompletes) > 0 {
+		incompletes = make(map[string]IncompleteV2)
+		for k, v := range h.incompletes {
+			incompletes[k] = IncompleteV2(v)
+		}
+	}
+
+	return &FileV2{
+		Version:     2,
+		Records:     m,
+		Incompletes: incompletes,
+	}
+}
+
+// newRecordV2 converts a Record to a RecordV2 instance.
+func newRecordV2(r Record) RecordV2 {
+	record := RecordV2{
+		Type:      r.Type,
+		Name:      r.Name,
+		AppliedAt: r.AppliedAt,
+		Checksum:  r.Checksum,
+	}
+
+	if r.Metadata == nil {
+		return record
+	}
+
+	states := []StateMetadataV2{}
+	for _, s := range r.Metadata.States {
+		states = append(states, StateMetadataV2(s))
+	}
+
+	actions := []string{}
+	actions = append(actions, r.Metadata.Actions...)
+
+	record.Metadata = &MetadataV2{
+		TfmigrateVersion: r.Metadata.TfmigrateVersion,
+		ExecType:         r.Metadata.ExecType,
+		ExecVersion:      r.Metadata.ExecVersion,
+		User:             r.Metadata.User,
+		Hostname:         r.Metadata.Hostname,
+		Duration:         r.Metadata.Duration.String(),
+		Actions:          actions,
+		States:           states,
+	}
+
+	return record
+}
+
+// Serialize encodes a FileV2 instance to bytes.
+func (f *FileV2) Serialize() ([]byte, error) {
+	return json.MarshalIndent(f, "", "    ")
+}
+
+// parseHistoryFileV2 parses bytes and returns a History instance.
+func parseHistoryFileV2(b []byte) (*History, error) {
+	var f FileV2
+
+	err := json.Unmarshal(b, &f)
+	if err != nil {
+		return nil, err
+	}
+
+	h, err := f.toHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+// toHistory converts a FileV2 to a History instance.
+func (f *FileV2) toHistory() (History, error) {
+	m := make(map[string]Record)
+	for k, v := range f.Records {
+		r, err := v.toRecord()
+		if err != nil {
+			return History{}, fmt.Errorf("failed to parse a record of %s: %s", k, err)
+		}
+		m[k] = r
+	}
+
+	var incompletes map[string]Incomplete
+	if len(f.Incompletes) > 0 {
+		incompletes = make(map[string]Incomplete)
+		for k, v := range f.Incompletes {
+			incompletes[k] = Incomplete(v)
+		}
+	}
+
+	return History{
+		records:     m,
+		incompletes: incompletes,
+	}, nil
+}
+
+// toRecord converts a RecordV2 to a Record instance.
+func (r RecordV2) toRecord() (Record, error) {
+	record := Record{
+		Type:      r.Type,
+		Name:      r.Name,
+		AppliedAt: r.AppliedAt,
+		Checksum:  r.Checksum,
+	}
+
+	if r.Metadata == nil {
+		return record, nil
+	}
+
+	duration, err := time.ParseDuration(r.Metadata.Duration)
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to parse duration: %s", err)
+	}
+
+	states := []StateMetadata{}
+	for _, s := range r.Metadata.States {
+		states = append(states, StateMetadata(s))
+	}
+
+	actions := []string{}
+	actions = append(actions, r.Metadata.Actions...)
+
+	record.Metadata = &Metadata{
+		TfmigrateVersion: r.Metadata.TfmigrateVersion,
+		ExecType:         r.Metadata.ExecType,
+		ExecVersion:      r.Metadata.ExecVersion,
+		User:             r.Metadata.User,
+		Hostname:         r.Metadata.Hostname,
+		Duration:         duration,
+		Actions:          actions,
+		States:           states,
+	}
+
+	return record, nil
+}
@@ -0,0 +1,285 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewFileV2(t *testing.T) {
+	cases := []struct {
+		desc string
+		h    History
+		want *FileV2
+	}{
+		{
+			desc: "simple",
+			h: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_foo.hcl": Record{
+						Type:      "multi_state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						Metadata: &Metadata{
+							TfmigrateVersion: "0.3.23",
+							ExecType:         "terraform",
+							ExecVersion:      "1.7.5",
+							User:             "foo",
+							Hostname:         "bar",
+							Duration:         1500 * time.Millisecond,
+							Actions:          []string{"mv null_resource.foo null_resource.foo2"},
+							States: []StateMetadata{
+								{Dir: "dir1", Workspace: "default", BeforeSerial: 1, AfterSerial: 2},
+								{Dir: "dir2", Workspace: "default", BeforeSerial: 3, AfterSerial: 4},
+							},
+						},
+					},
+				},
+			},
+			want: &FileV2{
+				Version: 2,
+				Records: map[string]RecordV2{
+					"20201012010101_foo.hcl": RecordV2{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_foo.hcl": RecordV2{
+						Type:      "multi_state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						Metadata: &MetadataV2{
+							TfmigrateVersion: "0.3.23",
+							ExecType:         "terraform",
+							ExecVersion:      "1.7.5",
+							User:             "foo",
+							Hostname:         "bar",
+							Duration:         "1.5s",
+							Actions:          []string{"mv null_resource.foo null_resource.foo2"},
+							States: []StateMetadataV2{
+								{Dir: "dir1", Workspace: "default", BeforeSerial: 1, AfterSerial: 2},
+								{Dir: "dir2", Workspace: "default", BeforeSerial: 3, AfterSerial: 4},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := newFileV2(tc.h)
+
+			if diff := cmp.Diff(*got, *tc.want, cmp.AllowUnexported(*got)); diff != "" {
+				t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
+			}
+		})
+	}
+}
+
+func TestFileV2Serialize(t *testing.T) {
+	cases := []struct {
+		desc string
+		f    FileV2
+		want string
+	}{
+		{
+			desc: "simple",
+			f: FileV2{
+				Version: 2,
+				Records: map[string]RecordV2{
+					"20201012010101_foo.hcl": RecordV2{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_foo.hcl": RecordV2{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						Metadata: &MetadataV2{
+							TfmigrateVersion: "0.3.23",
+							ExecType:         "terraform",
+							ExecVersion:      "1.7.5",
+							User:             "foo",
+							Hostname:         "bar",
+							Duration:         "1.5s",
+							Actions:          []string{"mv null_resource.foo null_resource.foo2"},
+							States: []StateMetadataV2{
+								{Dir: "dir1", Workspace: "default", BeforeSerial: 1, AfterSerial: 2},
+							},
+						},
+					},
+				},
+			},
+			want: `{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z",
+            "metadata": {
+                "tfmigrate_version": "0.3.23",
+                "exec_type": "terraform",
+                "exec_version": "1.7.5",
+                "user": "foo",
+                "hostname": "bar",
+                "duration": "1.5s",
+                "actions": [
+                    "mv null_resource.foo null_resource.foo2"
+                ],
+                "states": [
+                    {
+                        "dir": "dir1",
+                        "workspace": "default",
+                        "before_serial": 1,
+                        "after_serial": 2
+                    }
+                ]
+            }
+        }
+    }
+}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.f.Serialize()
+			if err != nil {
+				t.Fatalf("failed to serialize: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got = %s, want = %s", string(got), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseHistoryFileV2(t *testing.T) {
+	cases := []struct {
+		desc string
+		b    []byte
+		want *History
+		ok   bool
+	}{
+		{
+			desc: "valid",
+			b: []byte(`{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z"
+        },
+        "20201012020202_foo.hcl": {
+            "type": "state",
+            "name": "bar",
+            "applied_at": "2020-10-13T04:05:06Z",
+            "metadata": {
+                "tfmigrate_version": "0.3.23",
+                "exec_type": "terraform",
+                "exec_version": "1.7.5",
+                "user": "foo",
+                "hostname": "bar",
+                "duration": "1.5s",
+                "actions": [
+                    "mv null_resource.foo null_resource.foo2"
+                ],
+                "states": [
+                    {
+                        "dir": "dir1",
+                        "workspace": "default",
+                        "before_serial": 1,
+                        "after_serial": 2
+                    }
+                ]
+            }
+        }
+    }
+}`),
+			want: &History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_foo.hcl": Record{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+						Metadata: &Metadata{
+							TfmigrateVersion: "0.3.23",
+							ExecType:         "terraform",
+							ExecVersion:      "1.7.5",
+							User:             "foo",
+							Hostname:         "bar",
+							Duration:         1500 * time.Millisecond,
+							Actions:          []string{"mv null_resource.foo null_resource.foo2"},
+							States: []StateMetadata{
+								{Dir: "dir1", Workspace: "default", BeforeSerial: 1, AfterSerial: 2},
+							},
+						},
+					},
+				},
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid duration",
+			b: []byte(`{
+    "version": 2,
+    "records": {
+        "20201012010101_foo.hcl": {
+            "type": "state",
+            "name": "foo",
+            "applied_at": "2020-10-13T01:02:03Z",
+            "metadata": {
+                "duration": "foo"
+            }
+        }
+    }
+}`),
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "invalid (broken)",
+			b:    []byte(`{`),
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseHistoryFileV2(tc.b)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if diff := cmp.Diff(*got, *tc.want, cmp.AllowUnexported(*got)); diff != "" {
+					t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
+				}
+			}
+		})
+	}
+}
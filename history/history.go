@@ -10,6 +10,10 @@ type History struct {
 	// We record only the file name not to invalidate history when the migration
 	// directory is moved.
 	records map[string]Record
+	// incompletes is a set of migrations whose apply failed in the middle and
+	// left states inconsistent. A key is migration file name.
+	// It is nil if there are no incomplete migrations.
+	incompletes map[string]Incomplete
 }
 
 // Record represents an applied migration log.
@@ -21,6 +25,59 @@ type Record struct {
 	// AppliedAt is a timestamp when the migration was applied.
 	// Note that we only record it when the migration was succeed.
 	AppliedAt time.Time
+	// Checksum is a hex-encoded SHA-256 of the migration file content at the
+	// time it was applied. It is used for detecting modified migrations.
+	// It is empty for records written before checksums were introduced.
+	Checksum string
+	// Metadata is additional information about the applied migration for
+	// auditing. It is nil for records upgraded from the history file v1.
+	Metadata *Metadata
+}
+
+// Metadata represents additional information about an applied migration.
+type Metadata struct {
+	// TfmigrateVersion is a version of tfmigrate which applied the migration.
+	TfmigrateVersion string
+	// ExecType is a type of executable, either terraform or opentofu.
+	ExecType string
+	// ExecVersion is a version of executable.
+	ExecVersion string
+	// User is a name of user who applied the migration.
+	User string
+	// Hostname is a hostname where the migration was applied.
+	Hostname string
+	// Duration is a time taken to apply the migration.
+	Duration time.Duration
+	// Actions is a list of actions which were actually run.
+	// Actions containing wildcards such as xmv are expanded.
+	Actions []string
+	// States is a list of affected states.
+	States []StateMetadata
+}
+
+// StateMetadata represents information about a state affected by a migration.
+type StateMetadata struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// BeforeSerial is a serial of the state before applying the migration.
+	BeforeSerial int64
+	// AfterSerial is a serial of the state pushed by the migration.
+	AfterSerial int64
+}
+
+// Incomplete represents a migration whose apply failed in the middle and
+// left states inconsistent. It has to be resolved manually.
+type Incomplete struct {
+	// Type is a migration type.
+	Type string
+	// Name is a migration name.
+	Name string
+	// FailedAt is a timestamp when the migration failed.
+	FailedAt time.Time
+	// Reason is an error message including an instruction to recover.
+	Reason string
 }
 
 // newEmptyHistory initializes a new History.
@@ -52,6 +109,31 @@ func (h *History) Delete(filename string) {
 // Clear deletes all records from history.
 func (h *History) Clear() {
 	h.records = make(map[string]Record)
+	h.incompletes = nil
+}
+
+// AddIncomplete marks a given migration as incomplete.
+// If a given filename already exists, it updates the existing one.
+func (h *History) AddIncomplete(filename string, i Incomplete) {
+	if h.incompletes == nil {
+		h.incompletes = make(map[string]Incomplete)
+	}
+	h.incompletes[filename] = i
+}
+
+// ContainsIncomplete returns true if a given migration is marked as incomplete.
+func (h *History) ContainsIncomplete(filename string) bool {
+	_, ok := h.incompletes[filename]
+	return ok
+}
+
+// DeleteIncomplete unmarks a given migration as incomplete.
+// If a given filename doesn't exist, no-op.
+func (h *History) DeleteIncomplete(filename string) {
+	delete(h.incompletes, filename)
+	if len(h.incompletes) == 0 {
+		h.incompletes = nil
+	}
 }
 
 // Length returns a number of records in history.
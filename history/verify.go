@@ -0,0 +1,113 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DiscrepancyKind is a kind of discrepancy between history and migration files.
+type DiscrepancyKind string
+
+const (
+	// DiscrepancyModified means that an applied migration file has been
+	// modified after it was applied.
+	DiscrepancyModified DiscrepancyKind = "modified"
+	// DiscrepancyDeleted means that an applied migration file has been deleted.
+	DiscrepancyDeleted DiscrepancyKind = "deleted"
+)
+
+// Discrepancy represents an applied migration whose file no longer matches
+// the record in history.
+type Discrepancy struct {
+	// Filename is a migration file name.
+	Filename string
+	// Kind is a kind of discrepancy.
+	Kind DiscrepancyKind
+	// Expected is a checksum recorded in history.
+	Expected string
+	// Actual is a checksum of the current migration file.
+	// It is empty if the migration file has been deleted.
+	Actual string
+}
+
+// String returns a human-readable description of the discrepancy.
+func (d Discrepancy) String() string {
+	switch d.Kind {
+	case DiscrepancyModified:
+		return fmt.Sprintf("%s: modified after applied (expected checksum: %s, actual: %s)", d.Filename, d.Expected, d.Actual)
+	case DiscrepancyDeleted:
+		return fmt.Sprintf("%s: deleted after applied", d.Filename)
+	default:
+		return fmt.Sprintf("%s: %s", d.Filename, d.Kind)
+	}
+}
+
+// Verify checks whether applied migration files have been modified or
+// deleted since they were applied, and returns a list of discrepancies
+// sorted by file name.
+// Records without checksums are only checked for deletion, because we cannot
+// know their original content.
+func (c *Controller) Verify() ([]Discrepancy, error) {
+	exists := make(map[string]bool)
+	for _, m := range c.migrations {
+		exists[m] = true
+	}
+
+	filenames := make([]string, 0, len(c.history.records))
+	for filename := range c.history.records {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	discrepancies := []Discrepancy{}
+	for _, filename := range filenames {
+		r := c.history.records[filename]
+		if !exists[filename] {
+			discrepancies = append(discrepancies, Discrepancy{
+				Filename: filename,
+				Kind:     DiscrepancyDeleted,
+				Expected: r.Checksum,
+			})
+			continue
+		}
+
+		if len(r.Checksum) == 0 {
+			continue
+		}
+
+		actual, err := c.checksum(filename)
+		if err != nil {
+			return nil, err
+		}
+		if actual != r.Checksum {
+			discrepancies = append(discrepancies, Discrepancy{
+				Filename: filename,
+				Kind:     DiscrepancyModified,
+				Expected: r.Checksum,
+				Actual:   actual,
+			})
+		}
+	}
+
+	return discrepancies, nil
+}
+
+// checksum returns a hex-encoded SHA-256 of a given migration file.
+func (c *Controller) checksum(filename string) (string, error) {
+	b, err := os.ReadFile(filepath.Join(c.migrationDir, filename))
+	if err != nil {
+		return "", err
+	}
+
+	return Checksum(b), nil
+}
+
+// Checksum returns a hex-encoded SHA-256 of a given migration file content.
+func Checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,143 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestControllerVerify(t *testing.T) {
+	foo := `migration "state" "foo" {}`
+	bar := `migration "state" "bar" {}`
+	cases := []struct {
+		desc    string
+		files   map[string]string
+		history History
+		want    []Discrepancy
+	}{
+		{
+			desc: "no discrepancies",
+			files: map[string]string{
+				"20201012010101_foo.hcl": foo,
+				"20201012020202_bar.hcl": bar,
+			},
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+						Checksum:  Checksum([]byte(foo)),
+					},
+				},
+			},
+			want: []Discrepancy{},
+		},
+		{
+			desc: "modified",
+			files: map[string]string{
+				"20201012010101_foo.hcl": foo + "\n",
+				"20201012020202_bar.hcl": bar,
+			},
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+						Checksum:  Checksum([]byte(foo)),
+					},
+				},
+			},
+			want: []Discrepancy{
+				{
+					Filename: "20201012010101_foo.hcl",
+					Kind:     DiscrepancyModified,
+					Expected: Checksum([]byte(foo)),
+					Actual:   Checksum([]byte(foo + "\n")),
+				},
+			},
+		},
+		{
+			desc: "deleted",
+			files: map[string]string{
+				"20201012020202_bar.hcl": bar,
+			},
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+						Checksum:  Checksum([]byte(foo)),
+					},
+				},
+			},
+			want: []Discrepancy{
+				{
+					Filename: "20201012010101_foo.hcl",
+					Kind:     DiscrepancyDeleted,
+					Expected: Checksum([]byte(foo)),
+				},
+			},
+		},
+		{
+			desc: "no checksum",
+			files: map[string]string{
+				"20201012010101_foo.hcl": foo + "\n",
+			},
+			history: History{
+				records: map[string]Record{
+					"20201012010101_foo.hcl": Record{
+						Type:      "state",
+						Name:      "foo",
+						AppliedAt: time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC),
+					},
+					"20201012020202_bar.hcl": Record{
+						Type:      "state",
+						Name:      "bar",
+						AppliedAt: time.Date(2020, 10, 13, 4, 5, 6, 0, time.UTC),
+					},
+				},
+			},
+			want: []Discrepancy{
+				{
+					Filename: "20201012020202_bar.hcl",
+					Kind:     DiscrepancyDeleted,
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			migrationDir := t.TempDir()
+			for filename, source := range tc.files {
+				if err := os.WriteFile(filepath.Join(migrationDir, filename), []byte(source), 0600); err != nil {
+					t.Fatalf("failed to write migration file: %s", err)
+				}
+			}
+			migrations, err := LoadMigrationFileNames(migrationDir)
+			if err != nil {
+				t.Fatalf("failed to load migration file names: %s", err)
+			}
+
+			c := &Controller{
+				migrationDir: migrationDir,
+				migrations:   migrations,
+				history:      tc.history,
+			}
+
+			got, err := c.Verify()
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got = %#v, want = %#v, diff = %s", got, tc.want, diff)
+			}
+		})
+	}
+}
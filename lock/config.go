@@ -0,0 +1,7 @@
+package lock
+
+// Config is an interface of factory method for Locker
+type Config interface {
+	// NewLocker returns a new instance of Locker.
+	NewLocker() (Locker, error)
+}
@@ -0,0 +1,143 @@
+package lock
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// Info represents information about a lock.
+// It is serialized as JSON and stored in a lock file.
+type Info struct {
+	// ID is a unique identifier of the lock.
+	// It is used for releasing the lock with `tfmigrate force-unlock`.
+	ID string `json:"id"`
+	// Owner is an identity of a process which acquired the lock.
+	// It consists of a user name and a hostname.
+	Owner string `json:"owner"`
+	// CreatedAt is a timestamp when the lock was acquired.
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is a timestamp when the lock expires.
+	// If it is nil, the lock never expires.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// NewInfo returns a new instance of Info with a random ID.
+// If a given ttl is zero, the lock never expires.
+func NewInfo(ttl time.Duration) (*Info, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	info := &Info{
+		ID:        id,
+		Owner:     currentOwner(),
+		CreatedAt: now,
+	}
+
+	info.Renew(now, ttl)
+
+	return info, nil
+}
+
+// Renew extends the expiry of the lock to a given time plus ttl.
+// If a given ttl is zero, the lock never expires and it does nothing.
+func (i *Info) Renew(now time.Time, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	expiresAt := now.UTC().Truncate(time.Second).Add(ttl)
+	i.ExpiresAt = &expiresAt
+}
+
+// TTL returns a duration between the creation and the expiry of the lock.
+// If the lock never expires, it returns zero.
+// Note that it is only equal to the TTL of the locker before the lock is
+// renewed.
+func (i *Info) TTL() time.Duration {
+	if i.ExpiresAt == nil {
+		return 0
+	}
+	return i.ExpiresAt.Sub(i.CreatedAt)
+}
+
+// ParseInfo parses a given source of lock file and returns an Info.
+func ParseInfo(b []byte) (*Info, error) {
+	var info Info
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse lock info: %s", err)
+	}
+
+	return &info, nil
+}
+
+// Serialize encodes an Info to bytes.
+func (i *Info) Serialize() ([]byte, error) {
+	return json.MarshalIndent(i, "", "    ")
+}
+
+// Expired returns true if the lock has expired at a given time.
+func (i *Info) Expired(now time.Time) bool {
+	return i.ExpiresAt != nil && now.After(*i.ExpiresAt)
+}
+
+// String returns a human readable representation of the lock.
+func (i *Info) String() string {
+	s := fmt.Sprintf("ID = %s, Owner = %s, CreatedAt = %s", i.ID, i.Owner, i.CreatedAt.Format(time.RFC3339))
+	if i.ExpiresAt != nil {
+		s += fmt.Sprintf(", ExpiresAt = %s", i.ExpiresAt.Format(time.RFC3339))
+	}
+	return s
+}
+
+// ParseTTL parses a given string as a duration of TTL.
+// If it is empty, returns zero, which means the lock never expires.
+func ParseTTL(s string) (time.Duration, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+
+	ttl, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse ttl: %s", err)
+	}
+
+	if ttl < 0 {
+		return 0, fmt.Errorf("ttl must not be negative: %s", s)
+	}
+
+	return ttl, nil
+}
+
+// newID generates a random ID for a lock.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate a lock ID: %s", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// currentOwner returns an identity of the current process in the form of
+// user@hostname. If it fails to get them, fallback to unknown.
+func currentOwner() string {
+	username := "unknown"
+	if u, err := user.Current(); err == nil {
+		username = u.Username
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return fmt.Sprintf("%s@%s", username, hostname)
+}
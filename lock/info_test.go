@@ -0,0 +1,208 @@
+package lock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewInfo(t *testing.T) {
+	cases := []struct {
+		desc    string
+		ttl     time.Duration
+		expires bool
+	}{
+		{
+			desc:    "no ttl",
+			ttl:     0,
+			expires: false,
+		},
+		{
+			desc:    "with ttl",
+			ttl:     time.Hour,
+			expires: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := NewInfo(tc.ttl)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if len(got.ID) != 32 {
+				t.Errorf("unexpected ID: %s", got.ID)
+			}
+			if len(got.Owner) == 0 {
+				t.Error("owner is empty")
+			}
+			if tc.expires {
+				if got.ExpiresAt == nil {
+					t.Fatal("expected to expire, but ExpiresAt is nil")
+				}
+				if d := got.ExpiresAt.Sub(got.CreatedAt); d != tc.ttl {
+					t.Errorf("got ttl: %s, want: %s", d, tc.ttl)
+				}
+			} else if got.ExpiresAt != nil {
+				t.Errorf("expected not to expire, but got: %s", got.ExpiresAt)
+			}
+		})
+	}
+}
+
+func TestInfoExpired(t *testing.T) {
+	createdAt := time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC)
+	expiresAt := createdAt.Add(time.Hour)
+	cases := []struct {
+		desc string
+		info *Info
+		now  time.Time
+		want bool
+	}{
+		{
+			desc: "never expires",
+			info: &Info{CreatedAt: createdAt},
+			now:  createdAt.Add(24 * time.Hour),
+			want: false,
+		},
+		{
+			desc: "not expired",
+			info: &Info{CreatedAt: createdAt, ExpiresAt: &expiresAt},
+			now:  createdAt.Add(30 * time.Minute),
+			want: false,
+		},
+		{
+			desc: "expired",
+			info: &Info{CreatedAt: createdAt, ExpiresAt: &expiresAt},
+			now:  createdAt.Add(2 * time.Hour),
+			want: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.info.Expired(tc.now)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestInfoRenew(t *testing.T) {
+	now := time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC)
+	cases := []struct {
+		desc    string
+		ttl     time.Duration
+		want    *time.Time
+		wantTTL time.Duration
+	}{
+		{
+			desc:    "with ttl",
+			ttl:     time.Hour,
+			want:    func() *time.Time { t := now.Add(2 * time.Hour); return &t }(),
+			wantTTL: 2 * time.Hour,
+		},
+		{
+			desc:    "no ttl",
+			ttl:     0,
+			want:    nil,
+			wantTTL: 0,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			info := &Info{ID: "foo", CreatedAt: now}
+			info.Renew(now.Add(time.Hour), tc.ttl)
+			if (info.ExpiresAt == nil) != (tc.want == nil) || (tc.want != nil && !info.ExpiresAt.Equal(*tc.want)) {
+				t.Errorf("got: %v, want: %v", info.ExpiresAt, tc.want)
+			}
+			if got := info.TTL(); got != tc.wantTTL {
+				t.Errorf("got ttl: %s, want: %s", got, tc.wantTTL)
+			}
+		})
+	}
+}
+
+func TestInfoSerializeAndParse(t *testing.T) {
+	createdAt := time.Date(2020, 10, 13, 1, 2, 3, 0, time.UTC)
+	expiresAt := createdAt.Add(time.Hour)
+	info := &Info{
+		ID:        "0123456789abcdef0123456789abcdef",
+		Owner:     "foo@bar",
+		CreatedAt: createdAt,
+		ExpiresAt: &expiresAt,
+	}
+
+	b, err := info.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize: %s", err)
+	}
+
+	want := `{
+    "id": "0123456789abcdef0123456789abcdef",
+    "owner": "foo@bar",
+    "created_at": "2020-10-13T01:02:03Z",
+    "expires_at": "2020-10-13T02:02:03Z"
+}`
+	if string(b) != want {
+		t.Errorf("got: %s, want: %s", string(b), want)
+	}
+
+	got, err := ParseInfo(b)
+	if err != nil {
+		t.Fatalf("failed to parse: %s", err)
+	}
+	if got.ID != info.ID || got.Owner != info.Owner || !got.CreatedAt.Equal(info.CreatedAt) || !got.ExpiresAt.Equal(*info.ExpiresAt) {
+		t.Errorf("got: %#v, want: %#v", got, info)
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	cases := []struct {
+		desc string
+		s    string
+		want time.Duration
+		ok   bool
+	}{
+		{
+			desc: "empty",
+			s:    "",
+			want: 0,
+			ok:   true,
+		},
+		{
+			desc: "valid",
+			s:    "1h30m",
+			want: 90 * time.Minute,
+			ok:   true,
+		},
+		{
+			desc: "invalid",
+			s:    "foo",
+			want: 0,
+			ok:   false,
+		},
+		{
+			desc: "negative",
+			s:    "-1h",
+			want: 0,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseTTL(tc.s)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if tc.ok && got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
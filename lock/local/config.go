@@ -0,0 +1,20 @@
+package local
+
+import "github.com/minamijoyo/tfmigrate/lock"
+
+// Config is a config for local lock.
+type Config struct {
+	// Path to a lock file. Relative to the current working directory.
+	Path string `hcl:"path"`
+	// TTL is a duration after which the lock expires (e.g. "1h").
+	// If not set, the lock never expires.
+	TTL string `hcl:"ttl,optional"`
+}
+
+// Config implements a lock.Config.
+var _ lock.Config = (*Config)(nil)
+
+// NewLocker returns a new instance of lock.Locker.
+func (c *Config) NewLocker() (lock.Locker, error) {
+	return NewLocker(c)
+}
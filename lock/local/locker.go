@@ -0,0 +1,225 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/lock"
+)
+
+// errLockClaimed is an error returned when a claim for a lock ID is held by
+// another process.
+var errLockClaimed = errors.New("the lock is being changed by another process")
+
+// errLockIDMismatch is an error returned when the lock file doesn't have an
+// expected lock ID.
+var errLockIDMismatch = errors.New("lock ID mismatch")
+
+// Locker is a lock.Locker implementation for local file.
+// It creates a lock file exclusively, so it is only effective among processes
+// which share the same filesystem. An expired lock file is replaced atomically
+// by renaming a temporary file to it.
+type Locker struct {
+	// config is a lock config for local.
+	config *Config
+	// ttl is a parsed duration of config.TTL.
+	ttl time.Duration
+}
+
+var _ lock.Locker = (*Locker)(nil)
+
+// NewLocker returns a new instance of Locker.
+func NewLocker(config *Config) (*Locker, error) {
+	ttl, err := lock.ParseTTL(config.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Locker{
+		config: config,
+		ttl:    ttl,
+	}
+	return l, nil
+}
+
+// Lock acquires a lock by creating a lock file exclusively.
+// If the lock file already exists and it has expired, it is taken over.
+func (l *Locker) Lock(_ context.Context) (*lock.Info, error) {
+	info, err := lock.NewInfo(l.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := info.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.create(b)
+	if err == nil {
+		return info, nil
+	}
+	if !os.IsExist(err) {
+		return nil, fmt.Errorf("failed to create a lock file: %s", err)
+	}
+
+	current, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	if !current.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w: path = %s, %s", lock.ErrLocked, l.config.Path, current)
+	}
+
+	log.Printf("[WARN] [lock] take over an expired lock: %s\n", current)
+	if err := l.takeOver(current.ID, b); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// takeOver replaces an expired lock file with a given ID by a new lock.
+// The lock file is replaced atomically while holding its claim, so that only
+// one of processes which see the same expired lock can take it over.
+func (l *Locker) takeOver(expiredID string, b []byte) error {
+	err := l.withClaim(expiredID, func(_ *lock.Info) error {
+		return l.write(b)
+	})
+	if errors.Is(err, errLockClaimed) || errors.Is(err, errLockIDMismatch) {
+		return fmt.Errorf("%w: path = %s, the expired lock is taken over by another process: %s", lock.ErrLocked, l.config.Path, err)
+	}
+	return err
+}
+
+// Unlock releases a lock with a given ID by removing the lock file.
+func (l *Locker) Unlock(_ context.Context, id string) error {
+	// Remove the lock file while holding its claim, so that we never remove
+	// a lock file which has been taken over in the meantime.
+	return l.withClaim(id, func(_ *lock.Info) error {
+		if err := os.Remove(l.config.Path); err != nil {
+			return fmt.Errorf("failed to remove a lock file: %s", err)
+		}
+		return nil
+	})
+}
+
+// Renew extends the expiry of a lock with a given ID by rewriting the lock
+// file. The lock file is replaced while holding its claim, so that we never
+// overwrite a lock file which has been taken over in the meantime.
+func (l *Locker) Renew(_ context.Context, id string) error {
+	return l.withClaim(id, func(current *lock.Info) error {
+		current.Renew(time.Now(), l.ttl)
+		b, err := current.Serialize()
+		if err != nil {
+			return err
+		}
+		return l.write(b)
+	})
+}
+
+// withClaim calls a given function, which replaces or removes the lock file,
+// only if the lock file still has a given ID. The function is called with
+// the current lock.
+// Since there is no compare-and-swap operation for files, any changes of an
+// existing lock file require a claim file for its ID, which is created
+// exclusively. A lock file with the ID can't be changed by others while the
+// claim is held, and the ID is checked again after acquiring the claim.
+func (l *Locker) withClaim(id string, f func(current *lock.Info) error) error {
+	claimPath := l.claimPath(id)
+	// nolint gosec
+	// G302: Expect file permissions to be 0600 or less
+	// We ignore it because a claim file doesn't contains sensitive data.
+	c, err := os.OpenFile(claimPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("%w: path = %s, if no process is running, remove %s", errLockClaimed, l.config.Path, claimPath)
+		}
+		return fmt.Errorf("failed to create a claim file: %s", err)
+	}
+	c.Close()
+	defer os.Remove(claimPath)
+
+	current, err := l.read()
+	if err != nil {
+		return err
+	}
+	if current.ID != id {
+		return fmt.Errorf("%w: given = %s, current: %s", errLockIDMismatch, id, current)
+	}
+
+	return f(current)
+}
+
+// claimPath returns a path of a claim file for a given lock ID.
+func (l *Locker) claimPath(id string) string {
+	return fmt.Sprintf("%s.%s.claim", l.config.Path, id)
+}
+
+// create is a helper method which creates a lock file only if it doesn't
+// exist. If it already exists, the returned error satisfies os.IsExist.
+func (l *Locker) create(b []byte) error {
+	// nolint gosec
+	// G302: Expect file permissions to be 0600 or less
+	// We ignore it because a lock file doesn't contains sensitive data.
+	f, err := os.OpenFile(l.config.Path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(l.config.Path)
+		return err
+	}
+
+	return f.Close()
+}
+
+// write is a helper method which replaces a lock file atomically by writing
+// a temporary file and renaming it to the lock file.
+func (l *Locker) write(b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(l.config.Path), filepath.Base(l.config.Path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create a temporary lock file: %s", err)
+	}
+	tmpPath := f.Name()
+
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// nolint gosec
+		// G302: Expect file permissions to be 0600 or less
+		// We ignore it because a lock file doesn't contains sensitive data.
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, l.config.Path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write a lock file: %s", err)
+	}
+
+	return nil
+}
+
+// read is a helper method which reads a lock file and parses it.
+func (l *Locker) read() (*lock.Info, error) {
+	b, err := os.ReadFile(l.config.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("lock not found: path = %s", l.config.Path)
+		}
+		return nil, err
+	}
+
+	return lock.ParseInfo(b)
+}
@@ -0,0 +1,462 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minamijoyo/tfmigrate/lock"
+)
+
+func TestLockerLock(t *testing.T) {
+	expiresAt := time.Now().Add(-time.Hour)
+	cases := []struct {
+		desc    string
+		config  *Config
+		current *lock.Info
+		locked  bool
+		ok      bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Path: "history.lock",
+			},
+			current: nil,
+			locked:  false,
+			ok:      true,
+		},
+		{
+			desc: "already locked",
+			config: &Config{
+				Path: "history.lock",
+			},
+			current: &lock.Info{
+				ID:    "foo",
+				Owner: "someone-else",
+			},
+			locked: true,
+			ok:     false,
+		},
+		{
+			desc: "take over an expired lock",
+			config: &Config{
+				Path: "history.lock",
+				TTL:  "1h",
+			},
+			current: &lock.Info{
+				ID:        "foo",
+				Owner:     "someone-else",
+				ExpiresAt: &expiresAt,
+			},
+			locked: false,
+			ok:     true,
+		},
+		{
+			desc: "dir does not exist",
+			config: &Config{
+				Path: "not_exist/history.lock",
+			},
+			current: nil,
+			locked:  false,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			localDir := t.TempDir()
+			tc.config.Path = filepath.Join(localDir, tc.config.Path)
+			if tc.current != nil {
+				b, err := tc.current.Serialize()
+				if err != nil {
+					t.Fatalf("failed to serialize lock info: %s", err)
+				}
+				if err := os.WriteFile(tc.config.Path, b, 0600); err != nil {
+					t.Fatalf("failed to write lock file: %s", err)
+				}
+			}
+
+			l, err := NewLocker(tc.config)
+			if err != nil {
+				t.Fatalf("failed to NewLocker: %s", err)
+			}
+			got, err := l.Lock(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if tc.locked != errors.Is(err, lock.ErrLocked) {
+				t.Errorf("unexpected err: %v, locked: %t", err, tc.locked)
+			}
+
+			if tc.ok {
+				b, err := os.ReadFile(tc.config.Path)
+				if err != nil {
+					t.Fatalf("failed to read lock file: %s", err)
+				}
+				stored, err := lock.ParseInfo(b)
+				if err != nil {
+					t.Fatalf("failed to parse lock file: %s", err)
+				}
+				if stored.ID != got.ID {
+					t.Errorf("got: %s, want: %s", stored.ID, got.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestLockerUnlock(t *testing.T) {
+	cases := []struct {
+		desc    string
+		config  *Config
+		current *lock.Info
+		id      string
+		ok      bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Path: "history.lock",
+			},
+			current: &lock.Info{
+				ID: "foo",
+			},
+			id: "foo",
+			ok: true,
+		},
+		{
+			desc: "ID mismatch",
+			config: &Config{
+				Path: "history.lock",
+			},
+			current: &lock.Info{
+				ID: "foo",
+			},
+			id: "bar",
+			ok: false,
+		},
+		{
+			desc: "not locked",
+			config: &Config{
+				Path: "history.lock",
+			},
+			current: nil,
+			id:      "foo",
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			localDir := t.TempDir()
+			tc.config.Path = filepath.Join(localDir, tc.config.Path)
+			if tc.current != nil {
+				b, err := tc.current.Serialize()
+				if err != nil {
+					t.Fatalf("failed to serialize lock info: %s", err)
+				}
+				if err := os.WriteFile(tc.config.Path, b, 0600); err != nil {
+					t.Fatalf("failed to write lock file: %s", err)
+				}
+			}
+
+			l, err := NewLocker(tc.config)
+			if err != nil {
+				t.Fatalf("failed to NewLocker: %s", err)
+			}
+			err = l.Unlock(context.Background(), tc.id)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			_, err = os.Stat(tc.config.Path)
+			if tc.ok && !os.IsNotExist(err) {
+				t.Errorf("expected the lock file to be removed, but got: %v", err)
+			}
+			if !tc.ok && tc.current != nil && err != nil {
+				t.Errorf("expected the lock file to be kept, but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLockerLockConcurrentTakeover(t *testing.T) {
+	localDir := t.TempDir()
+	config := &Config{
+		Path: filepath.Join(localDir, "history.lock"),
+		TTL:  "1h",
+	}
+	expiresAt := time.Now().Add(-time.Hour)
+	expired := &lock.Info{
+		ID:        "foo",
+		Owner:     "someone-else",
+		ExpiresAt: &expiresAt,
+	}
+	b, err := expired.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize lock info: %s", err)
+	}
+	if err := os.WriteFile(config.Path, b, 0600); err != nil {
+		t.Fatalf("failed to write lock file: %s", err)
+	}
+
+	const n = 20
+	infos := make([]*lock.Info, n)
+	errs := make([]error, n)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l, err := NewLocker(config)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			<-start
+			infos[i], errs[i] = l.Lock(context.Background())
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	var winner *lock.Info
+	for i, err := range errs {
+		if err == nil {
+			if winner != nil {
+				t.Fatalf("multiple processes acquired the lock: %s, %s", winner, infos[i])
+			}
+			winner = infos[i]
+			continue
+		}
+		if !errors.Is(err, lock.ErrLocked) {
+			t.Errorf("unexpected err: %s", err)
+		}
+	}
+	if winner == nil {
+		t.Fatal("no process acquired the lock")
+	}
+
+	b, err = os.ReadFile(config.Path)
+	if err != nil {
+		t.Fatalf("failed to read lock file: %s", err)
+	}
+	stored, err := lock.ParseInfo(b)
+	if err != nil {
+		t.Fatalf("failed to parse lock file: %s", err)
+	}
+	if stored.ID != winner.ID {
+		t.Errorf("got: %s, want: %s", stored.ID, winner.ID)
+	}
+
+	entries, err := os.ReadDir(localDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %s", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the lock file to be left, but got: %v", entries)
+	}
+}
+
+func TestLockerTakeOverStale(t *testing.T) {
+	localDir := t.TempDir()
+	config := &Config{
+		Path: filepath.Join(localDir, "history.lock"),
+		TTL:  "1h",
+	}
+	expiresAt := time.Now().Add(-time.Hour)
+	expired := &lock.Info{
+		ID:        "foo",
+		Owner:     "someone-else",
+		ExpiresAt: &expiresAt,
+	}
+	b, err := expired.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize lock info: %s", err)
+	}
+	if err := os.WriteFile(config.Path, b, 0600); err != nil {
+		t.Fatalf("failed to write lock file: %s", err)
+	}
+
+	// Both processes have seen the same expired lock, and the first one has
+	// taken it over before the second one tries.
+	l1, err := NewLocker(config)
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	info, err := l1.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	l2, err := NewLocker(config)
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	other, err := lock.NewInfo(time.Hour)
+	if err != nil {
+		t.Fatalf("failed to NewInfo: %s", err)
+	}
+	b, err = other.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize lock info: %s", err)
+	}
+	err = l2.takeOver(expired.ID, b)
+	if !errors.Is(err, lock.ErrLocked) {
+		t.Fatalf("expected to return ErrLocked, but got: %v", err)
+	}
+
+	b, err = os.ReadFile(config.Path)
+	if err != nil {
+		t.Fatalf("failed to read lock file: %s", err)
+	}
+	stored, err := lock.ParseInfo(b)
+	if err != nil {
+		t.Fatalf("failed to parse lock file: %s", err)
+	}
+	if stored.ID != info.ID {
+		t.Errorf("got: %s, want: %s", stored.ID, info.ID)
+	}
+}
+
+func TestLockerUnlockWhileTakingOver(t *testing.T) {
+	localDir := t.TempDir()
+	config := &Config{
+		Path: filepath.Join(localDir, "history.lock"),
+	}
+	current := &lock.Info{ID: "foo"}
+	b, err := current.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize lock info: %s", err)
+	}
+	if err := os.WriteFile(config.Path, b, 0600); err != nil {
+		t.Fatalf("failed to write lock file: %s", err)
+	}
+
+	l, err := NewLocker(config)
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	// simulate another process which is taking over the lock.
+	if err := os.WriteFile(l.claimPath("foo"), []byte{}, 0600); err != nil {
+		t.Fatalf("failed to write claim file: %s", err)
+	}
+
+	if err := l.Unlock(context.Background(), "foo"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+	if _, err := os.Stat(config.Path); err != nil {
+		t.Errorf("expected the lock file to be kept, but got: %v", err)
+	}
+}
+
+func TestLockerRenew(t *testing.T) {
+	localDir := t.TempDir()
+	config := &Config{
+		Path: filepath.Join(localDir, "history.lock"),
+		TTL:  "1h",
+	}
+	l1, err := NewLocker(config)
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	info, err := l1.Lock(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	// Pretend that applying has taken longer than the TTL and the lock has
+	// expired before renewal.
+	expiresAt := time.Now().Add(-time.Minute)
+	expired := *info
+	expired.ExpiresAt = &expiresAt
+	b, err := expired.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize lock info: %s", err)
+	}
+	if err := os.WriteFile(config.Path, b, 0600); err != nil {
+		t.Fatalf("failed to write lock file: %s", err)
+	}
+
+	if err := l1.Renew(context.Background(), info.ID); err != nil {
+		t.Fatalf("failed to renew: %s", err)
+	}
+
+	// The renewed lock must not be taken over.
+	l2, err := NewLocker(config)
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	got, err := l2.Lock(context.Background())
+	if !errors.Is(err, lock.ErrLocked) {
+		t.Fatalf("expected to return ErrLocked, but got: %v, %v", got, err)
+	}
+
+	b, err = os.ReadFile(config.Path)
+	if err != nil {
+		t.Fatalf("failed to read lock file: %s", err)
+	}
+	stored, err := lock.ParseInfo(b)
+	if err != nil {
+		t.Fatalf("failed to parse lock file: %s", err)
+	}
+	if stored.ID != info.ID {
+		t.Errorf("got: %s, want: %s", stored.ID, info.ID)
+	}
+	if stored.Expired(time.Now().Add(30 * time.Minute)) {
+		t.Errorf("expected the lock to be renewed, but got: %s", stored)
+	}
+
+	// The original holder can still release it.
+	if err := l1.Unlock(context.Background(), info.ID); err != nil {
+		t.Fatalf("failed to unlock: %s", err)
+	}
+}
+
+func TestLockerRenewIDMismatch(t *testing.T) {
+	localDir := t.TempDir()
+	config := &Config{
+		Path: filepath.Join(localDir, "history.lock"),
+		TTL:  "1h",
+	}
+	current := &lock.Info{ID: "foo"}
+	b, err := current.Serialize()
+	if err != nil {
+		t.Fatalf("failed to serialize lock info: %s", err)
+	}
+	if err := os.WriteFile(config.Path, b, 0600); err != nil {
+		t.Fatalf("failed to write lock file: %s", err)
+	}
+
+	l, err := NewLocker(config)
+	if err != nil {
+		t.Fatalf("failed to NewLocker: %s", err)
+	}
+	if err := l.Renew(context.Background(), "bar"); err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+
+	b, err = os.ReadFile(config.Path)
+	if err != nil {
+		t.Fatalf("failed to read lock file: %s", err)
+	}
+	stored, err := lock.ParseInfo(b)
+	if err != nil {
+		t.Fatalf("failed to parse lock file: %s", err)
+	}
+	if stored.ID != "foo" || stored.ExpiresAt != nil {
+		t.Errorf("expected the lock not to be changed, but got: %s", stored)
+	}
+}
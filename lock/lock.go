@@ -0,0 +1,31 @@
+package lock
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrLocked is an error returned by Lock when the lock has already been
+// acquired by someone else.
+var ErrLocked = errors.New("the lock has already been acquired by someone else")
+
+// Locker is an abstraction layer for mutual exclusion of history-mode runs.
+// It prevents multiple tfmigrate processes from applying migrations to the
+// same states at the same time.
+// As with Storage, to support multiple cloud storages, the implementation
+// should be as simple as possible and a domain specific logic should not be
+// included.
+type Locker interface {
+	// Lock acquires a lock and returns information about it.
+	// If the lock has already been acquired by someone else and it has not
+	// expired yet, it returns an error wrapping ErrLocked.
+	Lock(ctx context.Context) (*Info, error)
+	// Unlock releases a lock with a given ID.
+	// If the lock is not held with the ID, it returns an error.
+	Unlock(ctx context.Context, id string) error
+	// Renew extends the expiry of a lock with a given ID by the TTL from now.
+	// It is called periodically while the lock is held, so that the lock is
+	// not taken over even if the process runs longer than the TTL.
+	// If the lock is not held with the ID, it returns an error.
+	Renew(ctx context.Context, id string) error
+}
@@ -0,0 +1,32 @@
+package mock
+
+import "github.com/minamijoyo/tfmigrate/lock"
+
+// Config is a config for mock lock.
+type Config struct {
+	// Locked is a flag to pretend that the lock has already been acquired by
+	// someone else.
+	Locked bool `hcl:"locked,optional"`
+	// UnlockError is a flag to return an error on Unlock().
+	UnlockError bool `hcl:"unlock_error,optional"`
+
+	// A reference to an instance of mock locker for testing.
+	l *Locker
+}
+
+// Config implements a lock.Config.
+var _ lock.Config = (*Config)(nil)
+
+// NewLocker returns a new instance of lock.Locker.
+func (c *Config) NewLocker() (lock.Locker, error) {
+	l, err := NewLocker(c)
+
+	// store a reference for test assertion.
+	c.l = l
+	return l, err
+}
+
+// Locker returns a reference to mock locker for testing.
+func (c *Config) Locker() *Locker {
+	return c.l
+}
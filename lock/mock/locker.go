@@ -0,0 +1,97 @@
+package mock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/lock"
+)
+
+// Locker is a lock.Locker implementation for mock.
+// It holds a lock in memory.
+type Locker struct {
+	// config is a lock config for mock.
+	config *Config
+	// info is information about the current lock.
+	// If it is nil, the lock is not held.
+	info *lock.Info
+	// history is a list of operations for testing.
+	history []string
+}
+
+var _ lock.Locker = (*Locker)(nil)
+
+// NewLocker returns a new instance of Locker.
+func NewLocker(config *Config) (*Locker, error) {
+	l := &Locker{
+		config: config,
+	}
+
+	if config.Locked {
+		info, err := lock.NewInfo(0)
+		if err != nil {
+			return nil, err
+		}
+		info.Owner = "someone-else"
+		l.info = info
+	}
+
+	return l, nil
+}
+
+// Held returns true if the lock is held for testing.
+func (l *Locker) Held() bool {
+	return l.info != nil
+}
+
+// History returns a list of operations for testing.
+func (l *Locker) History() []string {
+	return l.history
+}
+
+// Lock acquires a lock.
+func (l *Locker) Lock(_ context.Context) (*lock.Info, error) {
+	if l.info != nil {
+		return nil, fmt.Errorf("%w: %s", lock.ErrLocked, l.info)
+	}
+
+	info, err := lock.NewInfo(0)
+	if err != nil {
+		return nil, err
+	}
+
+	l.info = info
+	l.history = append(l.history, "lock")
+	return info, nil
+}
+
+// Unlock releases a lock with a given ID.
+func (l *Locker) Unlock(_ context.Context, id string) error {
+	if l.config.UnlockError {
+		return fmt.Errorf("failed to unlock mock lock: unlockError = %t", l.config.UnlockError)
+	}
+	if l.info == nil {
+		return fmt.Errorf("lock not found")
+	}
+	if l.info.ID != id {
+		return fmt.Errorf("lock ID mismatch: given = %s, current: %s", id, l.info)
+	}
+
+	l.info = nil
+	l.history = append(l.history, "unlock")
+	return nil
+}
+
+// Renew extends the expiry of a lock with a given ID.
+// Since the mock lock never expires, it only checks the ID.
+func (l *Locker) Renew(_ context.Context, id string) error {
+	if l.info == nil {
+		return fmt.Errorf("lock not found")
+	}
+	if l.info.ID != id {
+		return fmt.Errorf("lock ID mismatch: given = %s, current: %s", id, l.info)
+	}
+
+	l.history = append(l.history, "renew")
+	return nil
+}
@@ -0,0 +1,43 @@
+package s3
+
+import "github.com/minamijoyo/tfmigrate/lock"
+
+// Config is a config for s3 lock.
+// It has almost the same options as the s3 storage.
+type Config struct {
+	// Name of the bucket.
+	Bucket string `hcl:"bucket"`
+	// Path to the lock object.
+	Key string `hcl:"key"`
+	// TTL is a duration after which the lock expires (e.g. "1h").
+	// If not set, the lock never expires.
+	TTL string `hcl:"ttl,optional"`
+
+	// AWS region.
+	Region string `hcl:"region,optional"`
+	// Custom endpoint for the AWS S3 API.
+	Endpoint string `hcl:"endpoint,optional"`
+	// AWS access key.
+	AccessKey string `hcl:"access_key,optional"`
+	// AWS secret key.
+	SecretKey string `hcl:"secret_key,optional"`
+	// Name of AWS profile in AWS shared credentials file.
+	Profile string `hcl:"profile,optional"`
+	// Amazon Resource Name (ARN) of the IAM Role to assume.
+	RoleARN string `hcl:"role_arn,optional"`
+	// Skip credentials validation via the STS API.
+	SkipCredentialsValidation bool `hcl:"skip_credentials_validation,optional"`
+	// Skip usage of EC2 Metadata API.
+	SkipMetadataAPICheck bool `hcl:"skip_metadata_api_check,optional"`
+	// Enable path-style S3 URLs (https://<HOST>/<BUCKET>
+	// instead of https://<BUCKET>.<HOST>).
+	ForcePathStyle bool `hcl:"force_path_style,optional"`
+}
+
+// Config implements a lock.Config.
+var _ lock.Config = (*Config)(nil)
+
+// NewLocker returns a new instance of lock.Locker.
+func (c *Config) NewLocker() (lock.Locker, error) {
+	return NewLocker(c, nil)
+}
@@ -0,0 +1,226 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minamijoyo/tfmigrate/lock"
+)
+
+// Locker is a lock.Locker implementation for AWS S3.
+// It relies on the S3 conditional writes to create a lock object exclusively.
+type Locker struct {
+	// config is a lock config for s3.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+	// ttl is a parsed duration of config.TTL.
+	ttl time.Duration
+}
+
+var _ lock.Locker = (*Locker)(nil)
+
+// NewLocker returns a new instance of Locker.
+func NewLocker(config *Config, client Client) (*Locker, error) {
+	ttl, err := lock.ParseTTL(config.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l := &Locker{
+		config: config,
+		client: client,
+		ttl:    ttl,
+	}
+
+	return l, nil
+}
+
+// Lock acquires a lock by creating a lock object only if it doesn't exist.
+// If the lock object already exists and it has expired, it is taken over.
+func (l *Locker) Lock(ctx context.Context) (*lock.Info, error) {
+	info, err := lock.NewInfo(l.ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := info.Serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.create(ctx, b)
+	if err == nil {
+		return info, nil
+	}
+	if !isConditionalWriteConflict(err) {
+		return nil, fmt.Errorf("failed to create a lock object: %s", err)
+	}
+
+	current, etag, err := l.read(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !current.Expired(time.Now()) {
+		return nil, fmt.Errorf("%w: bucket = %s, key = %s, %s", lock.ErrLocked, l.config.Bucket, l.config.Key, current)
+	}
+
+	log.Printf("[WARN] [lock] take over an expired lock: %s\n", current)
+	if err := l.delete(ctx, etag); err != nil {
+		// If the lock has been renewed or taken over in the meantime, fail.
+		if isConditionalWriteConflict(err) {
+			return nil, fmt.Errorf("%w: bucket = %s, key = %s, the expired lock has been changed by another process", lock.ErrLocked, l.config.Bucket, l.config.Key)
+		}
+		return nil, fmt.Errorf("failed to delete an expired lock object: %s", err)
+	}
+
+	// If someone else has taken over it in the meantime, fail.
+	if err := l.create(ctx, b); err != nil {
+		if isConditionalWriteConflict(err) {
+			return nil, fmt.Errorf("%w: bucket = %s, key = %s", lock.ErrLocked, l.config.Bucket, l.config.Key)
+		}
+		return nil, fmt.Errorf("failed to create a lock object: %s", err)
+	}
+
+	return info, nil
+}
+
+// Unlock releases a lock with a given ID by deleting the lock object.
+func (l *Locker) Unlock(ctx context.Context, id string) error {
+	current, etag, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current.ID != id {
+		return fmt.Errorf("lock ID mismatch: given = %s, current: %s", id, current)
+	}
+
+	if err := l.delete(ctx, etag); err != nil {
+		return fmt.Errorf("failed to delete a lock object: %s", err)
+	}
+
+	return nil
+}
+
+// Renew extends the expiry of a lock with a given ID by rewriting the lock
+// object only if it has not been changed since read, so as not to overwrite
+// a lock taken over by someone else.
+func (l *Locker) Renew(ctx context.Context, id string) error {
+	current, etag, err := l.read(ctx)
+	if err != nil {
+		return err
+	}
+
+	if current.ID != id {
+		return fmt.Errorf("lock ID mismatch: given = %s, current: %s", id, current)
+	}
+
+	current.Renew(time.Now(), l.ttl)
+	b, err := current.Serialize()
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(l.config.Bucket),
+		Key:    aws.String(l.config.Key),
+		Body:   bytes.NewReader(b),
+	}
+
+	headers := map[string]string{"If-Match": etag}
+	_, err = l.client.PutObjectWithContext(ctx, input, request.WithSetRequestHeaders(headers))
+	if err != nil {
+		if isConditionalWriteConflict(err) {
+			return fmt.Errorf("the lock has been changed by another process: bucket = %s, key = %s", l.config.Bucket, l.config.Key)
+		}
+		return fmt.Errorf("failed to renew a lock object: %s", err)
+	}
+
+	return nil
+}
+
+// create is a helper method which puts a lock object only if it doesn't exist.
+func (l *Locker) create(ctx context.Context, b []byte) error {
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(l.config.Bucket),
+		Key:    aws.String(l.config.Key),
+		Body:   bytes.NewReader(b),
+	}
+
+	headers := map[string]string{"If-None-Match": "*"}
+	_, err := l.client.PutObjectWithContext(ctx, input, request.WithSetRequestHeaders(headers))
+	return err
+}
+
+// read is a helper method which gets a lock object and parses it.
+// It also returns an ETag of the lock object.
+func (l *Locker) read(ctx context.Context) (*lock.Info, string, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(l.config.Bucket),
+		Key:    aws.String(l.config.Key),
+	}
+
+	output, err := l.client.GetObjectWithContext(ctx, input)
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchKey" {
+			return nil, "", fmt.Errorf("lock not found: bucket = %s, key = %s", l.config.Bucket, l.config.Key)
+		}
+		return nil, "", err
+	}
+
+	defer output.Body.Close()
+
+	buf := bytes.NewBuffer(nil)
+	if _, err := buf.ReadFrom(output.Body); err != nil {
+		return nil, "", err
+	}
+
+	info, err := lock.ParseInfo(buf.Bytes())
+	if err != nil {
+		return nil, "", err
+	}
+
+	return info, aws.StringValue(output.ETag), nil
+}
+
+// delete is a helper method which deletes a lock object only if its ETag
+// matches a given one, so as not to delete a lock taken over by someone else.
+func (l *Locker) delete(ctx context.Context, etag string) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(l.config.Bucket),
+		Key:    aws.String(l.config.Key),
+	}
+
+	headers := map[string]string{"If-Match": etag}
+	_, err := l.client.DeleteObjectWithContext(ctx, input, request.WithSetRequestHeaders(headers))
+	return err
+}
+
+// isConditionalWriteConflict returns true if a given error indicates that a
+// conditional write failed.
+func isConditionalWriteConflict(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	// PreconditionFailed (412) is returned when the object already exists.
+	// ConditionalRequestConflict (409) is returned when a concurrent
+	// conditional write is in progress.
+	return awsErr.Code() == "PreconditionFailed" || awsErr.Code() == "ConditionalRequestConflict"
+}
@@ -0,0 +1,332 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minamijoyo/tfmigrate/lock"
+)
+
+// mockClient is a mock implementation for testing.
+type mockClient struct {
+	// putErrs is a list of errors returned by PutObjectWithContext in order.
+	// If it is exhausted, no error is returned.
+	putErrs   []error
+	getBody   string
+	getErr    error
+	deleteErr error
+	// deleted is a flag whether DeleteObjectWithContext is called.
+	deleted bool
+}
+
+// PutObjectWithContext returns a mocked response.
+func (c *mockClient) PutObjectWithContext(_ aws.Context, _ *s3.PutObjectInput, _ ...request.Option) (*s3.PutObjectOutput, error) {
+	if len(c.putErrs) == 0 {
+		return &s3.PutObjectOutput{}, nil
+	}
+	err := c.putErrs[0]
+	c.putErrs = c.putErrs[1:]
+	if err != nil {
+		return nil, err
+	}
+	return &s3.PutObjectOutput{}, nil
+}
+
+// GetObjectWithContext returns a mocked response.
+func (c *mockClient) GetObjectWithContext(_ aws.Context, _ *s3.GetObjectInput, _ ...request.Option) (*s3.GetObjectOutput, error) {
+	if c.getErr != nil {
+		return nil, c.getErr
+	}
+	return &s3.GetObjectOutput{
+		Body: io.NopCloser(strings.NewReader(c.getBody)),
+		ETag: aws.String(`"etag"`),
+	}, nil
+}
+
+// DeleteObjectWithContext returns a mocked response.
+func (c *mockClient) DeleteObjectWithContext(_ aws.Context, _ *s3.DeleteObjectInput, _ ...request.Option) (*s3.DeleteObjectOutput, error) {
+	if c.deleteErr != nil {
+		return nil, c.deleteErr
+	}
+	c.deleted = true
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func TestLockerLock(t *testing.T) {
+	preconditionFailed := awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+	cases := []struct {
+		desc    string
+		config  *Config
+		client  *mockClient
+		locked  bool
+		deleted bool
+		ok      bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+			},
+			client:  &mockClient{},
+			locked:  false,
+			deleted: false,
+			ok:      true,
+		},
+		{
+			desc: "already locked",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+			},
+			client: &mockClient{
+				putErrs: []error{preconditionFailed},
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z"}`,
+			},
+			locked:  true,
+			deleted: false,
+			ok:      false,
+		},
+		{
+			desc: "take over an expired lock",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+				TTL:    "1h",
+			},
+			client: &mockClient{
+				putErrs: []error{preconditionFailed, nil},
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z", "expires_at": "2020-10-13T02:02:03Z"}`,
+			},
+			locked:  false,
+			deleted: true,
+			ok:      true,
+		},
+		{
+			desc: "an expired lock has been taken over by someone else",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+				TTL:    "1h",
+			},
+			client: &mockClient{
+				putErrs: []error{preconditionFailed, preconditionFailed},
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z", "expires_at": "2020-10-13T02:02:03Z"}`,
+			},
+			locked:  true,
+			deleted: true,
+			ok:      false,
+		},
+		{
+			desc: "an expired lock has been renewed by the holder",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+				TTL:    "1h",
+			},
+			client: &mockClient{
+				putErrs:   []error{preconditionFailed},
+				getBody:   `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z", "expires_at": "2020-10-13T02:02:03Z"}`,
+				deleteErr: preconditionFailed,
+			},
+			locked:  true,
+			deleted: false,
+			ok:      false,
+		},
+		{
+			desc: "bucket does not exist",
+			config: &Config{
+				Bucket: "not-exist-bucket",
+				Key:    "tfmigrate/history.lock",
+			},
+			client: &mockClient{
+				putErrs: []error{awserr.New("NoSuchBucket", "The specified bucket does not exist.", nil)},
+			},
+			locked:  false,
+			deleted: false,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			l, err := NewLocker(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewLocker: %s", err)
+			}
+			got, err := l.Lock(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if tc.locked != errors.Is(err, lock.ErrLocked) {
+				t.Errorf("unexpected err: %v, locked: %t", err, tc.locked)
+			}
+			if tc.client.deleted != tc.deleted {
+				t.Errorf("got deleted: %t, want: %t", tc.client.deleted, tc.deleted)
+			}
+		})
+	}
+}
+
+func TestLockerUnlock(t *testing.T) {
+	cases := []struct {
+		desc    string
+		config  *Config
+		client  *mockClient
+		id      string
+		deleted bool
+		ok      bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+			},
+			client: &mockClient{
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z"}`,
+			},
+			id:      "foo",
+			deleted: true,
+			ok:      true,
+		},
+		{
+			desc: "ID mismatch",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+			},
+			client: &mockClient{
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z"}`,
+			},
+			id:      "bar",
+			deleted: false,
+			ok:      false,
+		},
+		{
+			desc: "not locked",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+			},
+			client: &mockClient{
+				getErr: awserr.New("NoSuchKey", "The specified key does not exist.", nil),
+			},
+			id:      "foo",
+			deleted: false,
+			ok:      false,
+		},
+		{
+			desc: "lock has been changed since read",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+			},
+			client: &mockClient{
+				getBody:   `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z"}`,
+				deleteErr: awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil),
+			},
+			id:      "foo",
+			deleted: false,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			l, err := NewLocker(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewLocker: %s", err)
+			}
+			err = l.Unlock(context.Background(), tc.id)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.client.deleted != tc.deleted {
+				t.Errorf("got deleted: %t, want: %t", tc.client.deleted, tc.deleted)
+			}
+		})
+	}
+}
+
+func TestLockerRenew(t *testing.T) {
+	preconditionFailed := awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil)
+	cases := []struct {
+		desc   string
+		client *mockClient
+		id     string
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z", "expires_at": "2020-10-13T02:02:03Z"}`,
+			},
+			id: "foo",
+			ok: true,
+		},
+		{
+			desc: "ID mismatch",
+			client: &mockClient{
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z", "expires_at": "2020-10-13T02:02:03Z"}`,
+			},
+			id: "bar",
+			ok: false,
+		},
+		{
+			desc: "lock has been changed since read",
+			client: &mockClient{
+				putErrs: []error{preconditionFailed},
+				getBody: `{"id": "foo", "owner": "someone-else", "created_at": "2020-10-13T01:02:03Z", "expires_at": "2020-10-13T02:02:03Z"}`,
+			},
+			id: "foo",
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.lock",
+				TTL:    "1h",
+			}
+			l, err := NewLocker(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewLocker: %s", err)
+			}
+			err = l.Renew(context.Background(), tc.id)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestNewLockerInvalidTTL(t *testing.T) {
+	config := &Config{
+		Bucket: "tfmigrate-test",
+		Key:    "tfmigrate/history.lock",
+		TTL:    "foo",
+	}
+	_, err := NewLocker(config, &mockClient{})
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
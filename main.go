@@ -67,7 +67,8 @@ func logOutput() io.Writer {
 
 func initCommands(ui cli.Ui) map[string]cli.CommandFactory {
 	meta := command.Meta{
-		UI: ui,
+		UI:      ui,
+		Version: version,
 	}
 
 	commands := map[string]cli.CommandFactory{
@@ -86,6 +87,81 @@ func initCommands(ui cli.Ui) map[string]cli.CommandFactory {
 				Meta: meta,
 			}, nil
 		},
+		"validate": func() (cli.Command, error) {
+			return &command.ValidateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"new": func() (cli.Command, error) {
+			return &command.NewCommand{
+				Meta: meta,
+			}, nil
+		},
+		"restore": func() (cli.Command, error) {
+			return &command.RestoreCommand{
+				Meta: meta,
+			}, nil
+		},
+		"generate": func() (cli.Command, error) {
+			return &command.GenerateCommand{
+				Meta: meta,
+			}, nil
+		},
+		"generate from-moved": func() (cli.Command, error) {
+			return &command.GenerateFromMovedCommand{
+				Meta: meta,
+			}, nil
+		},
+		"export": func() (cli.Command, error) {
+			return &command.ExportCommand{
+				Meta: meta,
+			}, nil
+		},
+		"suggest": func() (cli.Command, error) {
+			return &command.SuggestCommand{
+				Meta: meta,
+			}, nil
+		},
+		"force-unlock": func() (cli.Command, error) {
+			return &command.ForceUnlockCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history": func() (cli.Command, error) {
+			return &command.HistoryCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history show": func() (cli.Command, error) {
+			return &command.HistoryShowCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history mark": func() (cli.Command, error) {
+			return &command.HistoryMarkCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history unmark": func() (cli.Command, error) {
+			return &command.HistoryUnmarkCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history resolve": func() (cli.Command, error) {
+			return &command.HistoryResolveCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history prune": func() (cli.Command, error) {
+			return &command.HistoryPruneCommand{
+				Meta: meta,
+			}, nil
+		},
+		"history verify": func() (cli.Command, error) {
+			return &command.HistoryVerifyCommand{
+				Meta: meta,
+			}, nil
+		},
 	}
 
 	return commands
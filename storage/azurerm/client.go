@@ -0,0 +1,172 @@
+package azurerm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+)
+
+// Client is an abstraction layer for Azure Blob Storage API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Read reads a blob from a container and returns it with its ETag.
+	Read(ctx context.Context) ([]byte, string, error)
+	// Write writes a blob to a container.
+	Write(ctx context.Context, b []byte) error
+	// WriteIfMatch writes a blob to a container only if the current ETag of
+	// the blob matches a given ETag.
+	// If the given ETag is empty, it writes only if the blob doesn't exist.
+	// It returns a new ETag of the written blob.
+	WriteIfMatch(ctx context.Context, b []byte, etag string) (string, error)
+	// Delete deletes a blob from a container.
+	Delete(ctx context.Context) error
+}
+
+// client is a real implementation of the Client.
+type client struct {
+	// config is a storage config for azurerm.
+	config *Config
+	// blobClient is an Azure Blob Storage client which is delegated actual
+	// operations.
+	blobClient *azblob.Client
+}
+
+// newClient returns a new instance of Client.
+// Credentials are resolved in the following order:
+// 1. access_key (or the ARM_ACCESS_KEY environment variable)
+// 2. sas_token (or the ARM_SAS_TOKEN environment variable)
+// 3. use_msi
+// 4. the default credential chain of the Azure SDK for Go (environment
+// variables, workload identity, managed identity and Azure CLI).
+func newClient(config *Config) (Client, error) {
+	serviceURL := serviceURL(config)
+
+	accessKey := config.AccessKey
+	if len(accessKey) == 0 {
+		accessKey = os.Getenv("ARM_ACCESS_KEY")
+	}
+	sasToken := config.SASToken
+	if len(sasToken) == 0 {
+		sasToken = os.Getenv("ARM_SAS_TOKEN")
+	}
+
+	var blobClient *azblob.Client
+	var err error
+	switch {
+	case len(accessKey) > 0:
+		cred, cerr := azblob.NewSharedKeyCredential(config.StorageAccountName, accessKey)
+		if cerr != nil {
+			return nil, fmt.Errorf("failed to new azurerm shared key credential: %s", cerr)
+		}
+		blobClient, err = azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+
+	case len(sasToken) > 0:
+		blobClient, err = azblob.NewClientWithNoCredential(serviceURL+"?"+strings.TrimPrefix(sasToken, "?"), nil)
+
+	case config.UseMSI:
+		opts := &azidentity.ManagedIdentityCredentialOptions{}
+		if len(config.ClientID) > 0 {
+			opts.ID = azidentity.ClientID(config.ClientID)
+		}
+		cred, cerr := azidentity.NewManagedIdentityCredential(opts)
+		if cerr != nil {
+			return nil, fmt.Errorf("failed to new azurerm managed identity credential: %s", cerr)
+		}
+		blobClient, err = azblob.NewClient(serviceURL, cred, nil)
+
+	default:
+		cred, cerr := azidentity.NewDefaultAzureCredential(nil)
+		if cerr != nil {
+			return nil, fmt.Errorf("failed to new azurerm default credential: %s", cerr)
+		}
+		blobClient, err = azblob.NewClient(serviceURL, cred, nil)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to new azurerm client: %s", err)
+	}
+
+	return &client{
+		config:     config,
+		blobClient: blobClient,
+	}, nil
+}
+
+// serviceURL returns a URL of the blob service endpoint.
+func serviceURL(config *Config) string {
+	if len(config.Endpoint) > 0 {
+		return strings.TrimRight(config.Endpoint, "/") + "/"
+	}
+	return fmt.Sprintf("https://%s.blob.core.windows.net/", config.StorageAccountName)
+}
+
+// Read reads a blob from a container and returns it with its ETag.
+func (c *client) Read(ctx context.Context) ([]byte, string, error) {
+	resp, err := c.blobClient.DownloadStream(ctx, c.config.ContainerName, c.config.Key, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	etag := ""
+	if resp.ETag != nil {
+		etag = string(*resp.ETag)
+	}
+
+	return b, etag, nil
+}
+
+// Write writes a blob to a container.
+func (c *client) Write(ctx context.Context, b []byte) error {
+	_, err := c.blobClient.UploadBuffer(ctx, c.config.ContainerName, c.config.Key, b, nil)
+	return err
+}
+
+// WriteIfMatch writes a blob to a container only if the current ETag of
+// the blob matches a given ETag.
+// If the given ETag is empty, it writes only if the blob doesn't exist.
+// It returns a new ETag of the written blob.
+func (c *client) WriteIfMatch(ctx context.Context, b []byte, etag string) (string, error) {
+	conds := &blob.ModifiedAccessConditions{}
+	if len(etag) == 0 {
+		conds.IfNoneMatch = to.Ptr(azcore.ETagAny)
+	} else {
+		conds.IfMatch = to.Ptr(azcore.ETag(etag))
+	}
+	opts := &azblob.UploadBufferOptions{
+		AccessConditions: &blob.AccessConditions{
+			ModifiedAccessConditions: conds,
+		},
+	}
+
+	resp, err := c.blobClient.UploadBuffer(ctx, c.config.ContainerName, c.config.Key, b, opts)
+	if err != nil {
+		return "", err
+	}
+
+	newETag := ""
+	if resp.ETag != nil {
+		newETag = string(*resp.ETag)
+	}
+
+	return newETag, nil
+}
+
+// Delete deletes a blob from a container.
+func (c *client) Delete(ctx context.Context) error {
+	_, err := c.blobClient.DeleteBlob(ctx, c.config.ContainerName, c.config.Key, nil)
+	return err
+}
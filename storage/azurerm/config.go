@@ -0,0 +1,50 @@
+package azurerm
+
+import (
+	"path"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// Config is a config for Azure Blob Storage.
+// This is expected to have almost the same options as Terraform azurerm backend.
+// https://www.terraform.io/language/settings/backends/azurerm
+// However, it has many minor options and it's a pain to test all options from
+// first, so we added only options we need for now.
+type Config struct {
+	// Name of the storage account.
+	StorageAccountName string `hcl:"storage_account_name"`
+	// Name of the blob container.
+	ContainerName string `hcl:"container_name"`
+	// Name of the blob for the migration history file.
+	Key string `hcl:"key"`
+
+	// Access key of the storage account for shared key authorization.
+	AccessKey string `hcl:"access_key,optional"`
+	// SAS token used to access the blob container.
+	SASToken string `hcl:"sas_token,optional"`
+	// Use a managed identity for authentication.
+	UseMSI bool `hcl:"use_msi,optional"`
+	// Client ID of a user assigned managed identity.
+	// If not set, the system assigned managed identity is used.
+	ClientID string `hcl:"client_id,optional"`
+	// Custom endpoint for the Azure Blob Storage API.
+	// Default to https://<storage_account_name>.blob.core.windows.net/
+	Endpoint string `hcl:"endpoint,optional"`
+}
+
+// Config implements a storage.NamedConfig.
+var _ storage.NamedConfig = (*Config)(nil)
+
+// NewStorage returns a new instance of storage.Storage.
+func (c *Config) NewStorage() (storage.Storage, error) {
+	return NewStorage(c, nil)
+}
+
+// NewNamedStorage returns a new instance of storage.Storage for a blob with a
+// given name under a prefix of the Key.
+func (c *Config) NewNamedStorage(name string) (storage.Storage, error) {
+	named := *c
+	named.Key = path.Join(c.Key, name)
+	return NewStorage(&named, nil)
+}
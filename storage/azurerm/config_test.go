@@ -0,0 +1,95 @@
+package azurerm
+
+import "testing"
+
+// testAzuriteAccessKey is a well-known access key for the Azurite emulator.
+// https://learn.microsoft.com/en-us/azure/storage/common/storage-use-azurite
+const testAzuriteAccessKey = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+
+func TestConfigNewStorage(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		ok     bool
+	}{
+		{
+			desc: "valid (access_key)",
+			config: &Config{
+				StorageAccountName: "devstoreaccount1",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+				AccessKey:          testAzuriteAccessKey,
+				Endpoint:           "http://azurite:10000/devstoreaccount1",
+			},
+			ok: true,
+		},
+		{
+			desc: "valid (sas_token)",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+				SASToken:           "?sv=2022-11-02&ss=b&srt=co&sp=rwl&sig=dummy",
+			},
+			ok: true,
+		},
+		{
+			desc: "invalid access_key",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+				AccessKey:          "not base64",
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.config.NewStorage()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				_ = got.(*Storage)
+			}
+		})
+	}
+}
+
+func TestServiceURL(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *Config
+		want   string
+	}{
+		{
+			desc: "default",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+			},
+			want: "https://tfmigrate.blob.core.windows.net/",
+		},
+		{
+			desc: "custom endpoint",
+			config: &Config{
+				StorageAccountName: "devstoreaccount1",
+				Endpoint:           "http://azurite:10000/devstoreaccount1",
+			},
+			want: "http://azurite:10000/devstoreaccount1/",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := serviceURL(tc.config)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
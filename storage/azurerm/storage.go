@@ -0,0 +1,95 @@
+package azurerm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// Storage is a storage.Storage implementation for Azure Blob Storage.
+type Storage struct {
+	// config is a storage config for azurerm.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.Deleter = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(config *Config, client Client) (*Storage, error) {
+	if client == nil {
+		var err error
+		client, err = newClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Storage{
+		config: config,
+		client: client,
+	}
+
+	return s, nil
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	return s.client.Write(ctx, b)
+}
+
+// WriteIfMatch writes migration history data to storage only if the
+// current version of the stored data matches a given version.
+// The version is an ETag of the blob.
+func (s *Storage) WriteIfMatch(ctx context.Context, b []byte, version storage.Version) (storage.Version, error) {
+	etag, err := s.client.WriteIfMatch(ctx, b, string(version))
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.ConditionNotMet, bloberror.BlobAlreadyExists) {
+			return "", fmt.Errorf("%w: container = %s, key = %s, expected version = %q: %s", storage.ErrVersionConflict, s.config.ContainerName, s.config.Key, version, err)
+		}
+		return "", err
+	}
+
+	return storage.Version(etag), nil
+}
+
+// Delete deletes migration history data from storage.
+// If the blob does not exist, it does nothing.
+func (s *Storage) Delete(ctx context.Context) error {
+	err := s.client.Delete(ctx)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return err
+	}
+	return nil
+}
+
+// Read reads migration history data from storage.
+// If the blob does not exist, it is assumed to be uninitialized and returns
+// an empty array instead of an error.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data from storage and returns it
+// with a version of the data. The version is an ETag of the blob.
+// If the blob does not exist, it is assumed to be uninitialized and returns
+// an empty array and an empty version instead of an error.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, storage.Version, error) {
+	b, etag, err := s.client.Read(ctx)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			// If the blob does not exist
+			return []byte{}, "", nil
+		}
+		// unexpected error
+		return nil, "", err
+	}
+
+	return b, storage.Version(etag), nil
+}
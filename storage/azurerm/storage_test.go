@@ -0,0 +1,400 @@
+package azurerm
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/minamijoyo/tfmigrate/storage"
+)
+
+// mockClient is a mock implementation for testing.
+type mockClient struct {
+	dataToRead []byte
+	etag       string
+	err        error
+}
+
+// Read returns a mocked response.
+func (c *mockClient) Read(_ context.Context) ([]byte, string, error) {
+	return c.dataToRead, c.etag, c.err
+}
+
+// Write returns a mocked response.
+func (c *mockClient) Write(_ context.Context, _ []byte) error {
+	return c.err
+}
+
+// WriteIfMatch returns a mocked response.
+func (c *mockClient) WriteIfMatch(_ context.Context, _ []byte, _ string) (string, error) {
+	return c.etag, c.err
+}
+
+// Delete returns a mocked response.
+func (c *mockClient) Delete(_ context.Context) error {
+	return c.err
+}
+
+func TestStorageWrite(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   Client
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: nil,
+			},
+			contents: []byte("foo"),
+			ok:       true,
+		},
+		{
+			desc: "container does not exist",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "not-exist-container",
+				Key:                "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: &azcore.ResponseError{ErrorCode: string(bloberror.ContainerNotFound)},
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Write(context.Background(), tc.contents)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestStorageRead(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   Client
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				dataToRead: []byte("foo"),
+				err:        nil,
+			},
+			contents: []byte("foo"),
+			ok:       true,
+		},
+		{
+			desc: "container does not exist",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "not-exist-container",
+				Key:                "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				dataToRead: nil,
+				err:        &azcore.ResponseError{ErrorCode: string(bloberror.ContainerNotFound)},
+			},
+			contents: nil,
+			ok:       false,
+		},
+		{
+			desc: "blob does not exist",
+			config: &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "not_exist.json",
+			},
+			client: &mockClient{
+				dataToRead: nil,
+				err:        &azcore.ResponseError{ErrorCode: string(bloberror.BlobNotFound)},
+			},
+			contents: []byte{},
+			ok:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.Read(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				if string(got) != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
+				}
+			}
+		})
+	}
+}
+
+func TestStorageReadWithVersion(t *testing.T) {
+	cases := []struct {
+		desc        string
+		client      Client
+		contents    []byte
+		wantVersion storage.Version
+		ok          bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				dataToRead: []byte("foo"),
+				etag:       `"0x8DC0000000000"`,
+				err:        nil,
+			},
+			contents:    []byte("foo"),
+			wantVersion: `"0x8DC0000000000"`,
+			ok:          true,
+		},
+		{
+			desc: "blob does not exist",
+			client: &mockClient{
+				dataToRead: nil,
+				err:        &azcore.ResponseError{ErrorCode: string(bloberror.BlobNotFound)},
+			},
+			contents:    []byte{},
+			wantVersion: "",
+			ok:          true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, version, err := s.ReadWithVersion(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				if string(got) != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
+				}
+				if version != tc.wantVersion {
+					t.Errorf("got version: %s, want: %s", version, tc.wantVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfMatch(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   Client
+		conflict bool
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				err: nil,
+			},
+			conflict: false,
+			ok:       true,
+		},
+		{
+			desc: "condition not met",
+			client: &mockClient{
+				err: &azcore.ResponseError{ErrorCode: string(bloberror.ConditionNotMet)},
+			},
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "blob already exists",
+			client: &mockClient{
+				err: &azcore.ResponseError{ErrorCode: string(bloberror.BlobAlreadyExists)},
+			},
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "container does not exist",
+			client: &mockClient{
+				err: &azcore.ResponseError{ErrorCode: string(bloberror.ContainerNotFound)},
+			},
+			conflict: false,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.WriteIfMatch(context.Background(), []byte("foo"), `"0x8DC0000000000"`)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if got := errors.Is(err, storage.ErrVersionConflict); got != tc.conflict {
+				t.Errorf("got conflict: %t, want: %t, err: %v", got, tc.conflict, err)
+			}
+		})
+	}
+}
+
+func TestStorageDelete(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client Client
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				err: nil,
+			},
+			ok: true,
+		},
+		{
+			desc: "blob does not exist",
+			client: &mockClient{
+				err: &azcore.ResponseError{ErrorCode: string(bloberror.BlobNotFound)},
+			},
+			ok: true,
+		},
+		{
+			desc: "unexpected error",
+			client: &mockClient{
+				err: &azcore.ResponseError{ErrorCode: string(bloberror.ContainerNotFound)},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				StorageAccountName: "tfmigrate",
+				ContainerName:      "tfmigrate-test",
+				Key:                "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Delete(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestAccStorageWriteRead(t *testing.T) {
+	if os.Getenv("TEST_ACC") != "1" {
+		t.Skip("skip acceptance tests")
+	}
+	// The Azurite emulator is expected to be running for acceptance tests.
+	// See docker-compose.yml for details.
+	endpoint := os.Getenv("AZURITE_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("skip acceptance tests for azurerm because AZURITE_ENDPOINT is not set")
+	}
+
+	config := &Config{
+		StorageAccountName: "devstoreaccount1",
+		ContainerName:      "tfstate-test",
+		Key:                "tfmigrate/" + t.Name() + "/history.json",
+		AccessKey:          testAzuriteAccessKey,
+		Endpoint:           endpoint,
+	}
+
+	// create a container if not exists.
+	cred, err := azblob.NewSharedKeyCredential(config.StorageAccountName, config.AccessKey)
+	if err != nil {
+		t.Fatalf("failed to new shared key credential: %s", err)
+	}
+	c, err := azblob.NewClientWithSharedKeyCredential(serviceURL(config), cred, nil)
+	if err != nil {
+		t.Fatalf("failed to new azblob client: %s", err)
+	}
+	ctx := context.Background()
+	_, err = c.CreateContainer(ctx, config.ContainerName, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		t.Fatalf("failed to create container: %s", err)
+	}
+
+	s, err := NewStorage(config, nil)
+	if err != nil {
+		t.Fatalf("failed to NewStorage: %s", err)
+	}
+
+	want := []byte("foo")
+	if err := s.Write(ctx, want); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+
+	got, err := s.Read(ctx)
+	if err != nil {
+		t.Fatalf("failed to read: %s", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got: %s, want: %s", string(got), string(want))
+	}
+}
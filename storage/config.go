@@ -5,3 +5,12 @@ type Config interface {
 	// NewStorage returns a new instance of Storage.
 	NewStorage() (Storage, error)
 }
+
+// NamedConfig is an optional interface of Config for storages which can store
+// multiple objects. The configured path or key is used as a prefix of them.
+type NamedConfig interface {
+	Config
+	// NewNamedStorage returns a new instance of Storage for an object with a
+	// given name under the prefix.
+	NewNamedStorage(name string) (Storage, error)
+}
@@ -0,0 +1,110 @@
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// Client is an abstraction layer for GCS API.
+// It is intended to be replaced with a mock for testing.
+type Client interface {
+	// Read reads an object from a GCS bucket and returns it with its
+	// generation number.
+	Read(ctx context.Context) ([]byte, int64, error)
+	// Write writes an object to a GCS bucket.
+	Write(ctx context.Context, b []byte) error
+	// WriteIfGenerationMatch writes an object to a GCS bucket only if the
+	// current generation of the object matches a given generation.
+	// If the given generation is 0, it writes only if the object doesn't exist.
+	// It returns a new generation of the written object.
+	WriteIfGenerationMatch(ctx context.Context, b []byte, generation int64) (int64, error)
+	// Delete deletes an object from a GCS bucket.
+	Delete(ctx context.Context) error
+}
+
+// client is a real implementation of the Client.
+type client struct {
+	// config is a storage config for gcs.
+	config *Config
+	// gcsClient is a GCS client which is delegated actual operations.
+	gcsClient *storage.Client
+}
+
+// newClient returns a new instance of Client.
+// The underlying GCS client refers the Application Default Credentials (ADC)
+// for authentication. If the STORAGE_EMULATOR_HOST environment variable is
+// set, it connects to the emulator without authentication.
+func newClient(ctx context.Context, config *Config) (Client, error) {
+	gcsClient, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to new gcs client: %s", err)
+	}
+
+	return &client{
+		config:    config,
+		gcsClient: gcsClient,
+	}, nil
+}
+
+// Read reads an object from a GCS bucket and returns it with its generation
+// number.
+func (c *client) Read(ctx context.Context) ([]byte, int64, error) {
+	r, err := c.gcsClient.Bucket(c.config.Bucket).Object(c.config.Name).NewReader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer r.Close()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return b, r.Attrs.Generation, nil
+}
+
+// Write writes an object to a GCS bucket.
+func (c *client) Write(ctx context.Context, b []byte) error {
+	o := c.gcsClient.Bucket(c.config.Bucket).Object(c.config.Name)
+	_, err := writeObject(ctx, o, b)
+	return err
+}
+
+// WriteIfGenerationMatch writes an object to a GCS bucket only if the
+// current generation of the object matches a given generation.
+// If the given generation is 0, it writes only if the object doesn't exist.
+// It returns a new generation of the written object.
+func (c *client) WriteIfGenerationMatch(ctx context.Context, b []byte, generation int64) (int64, error) {
+	conds := storage.Conditions{GenerationMatch: generation}
+	if generation == 0 {
+		conds = storage.Conditions{DoesNotExist: true}
+	}
+	o := c.gcsClient.Bucket(c.config.Bucket).Object(c.config.Name).If(conds)
+	return writeObject(ctx, o, b)
+}
+
+// Delete deletes an object from a GCS bucket.
+func (c *client) Delete(ctx context.Context) error {
+	return c.gcsClient.Bucket(c.config.Bucket).Object(c.config.Name).Delete(ctx)
+}
+
+// writeObject is a helper function which writes bytes to a given object and
+// returns a new generation of the object.
+func writeObject(ctx context.Context, o *storage.ObjectHandle, b []byte) (int64, error) {
+	w := o.NewWriter(ctx)
+	if _, err := w.Write(b); err != nil {
+		// Close the writer to release resources, but return the original error.
+		w.Close()
+		return 0, err
+	}
+
+	// The object is not committed until the writer is closed successfully.
+	if err := w.Close(); err != nil {
+		return 0, err
+	}
+
+	return w.Attrs().Generation, nil
+}
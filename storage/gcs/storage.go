@@ -0,0 +1,109 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	gcStorage "cloud.google.com/go/storage"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// Storage is a storage.Storage implementation for GCS.
+type Storage struct {
+	// config is a storage config for gcs.
+	config *Config
+	// client is an instance of Client interface to call API.
+	// It is intended to be replaced with a mock for testing.
+	client Client
+}
+
+var _ storage.Storage = (*Storage)(nil)
+var _ storage.Deleter = (*Storage)(nil)
+
+// NewStorage returns a new instance of Storage.
+func NewStorage(config *Config, client Client) (*Storage, error) {
+	if client == nil {
+		var err error
+		client, err = newClient(context.Background(), config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Storage{
+		config: config,
+		client: client,
+	}
+
+	return s, nil
+}
+
+// Write writes migration history data to storage.
+func (s *Storage) Write(ctx context.Context, b []byte) error {
+	return s.client.Write(ctx, b)
+}
+
+// WriteIfMatch writes migration history data to storage only if the
+// current version of the stored data matches a given version.
+// The version is a generation number of the object.
+func (s *Storage) WriteIfMatch(ctx context.Context, b []byte, version storage.Version) (storage.Version, error) {
+	var generation int64
+	if len(version) > 0 {
+		var err error
+		generation, err = strconv.ParseInt(string(version), 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse a generation of gcs object: %s", err)
+		}
+	}
+
+	newGeneration, err := s.client.WriteIfGenerationMatch(ctx, b, generation)
+	if err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return "", fmt.Errorf("%w: bucket = %s, name = %s, expected version = %q: %s", storage.ErrVersionConflict, s.config.Bucket, s.config.Name, version, err)
+		}
+		return "", err
+	}
+
+	return storage.Version(strconv.FormatInt(newGeneration, 10)), nil
+}
+
+// Delete deletes migration history data from storage.
+// If the object does not exist, it does nothing.
+func (s *Storage) Delete(ctx context.Context) error {
+	err := s.client.Delete(ctx)
+	if err != nil && !errors.Is(err, gcStorage.ErrObjectNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Read reads migration history data from storage.
+// If the object does not exist, it is assumed to be uninitialized and returns
+// an empty array instead of an error.
+func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data from storage and returns it
+// with a version of the data. The version is a generation number of the object.
+// If the object does not exist, it is assumed to be uninitialized and returns
+// an empty array and an empty version instead of an error.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, storage.Version, error) {
+	b, generation, err := s.client.Read(ctx)
+	if err != nil {
+		if errors.Is(err, gcStorage.ErrObjectNotExist) {
+			// If the object does not exist
+			return []byte{}, "", nil
+		}
+		// unexpected error
+		return nil, "", err
+	}
+
+	return b, storage.Version(strconv.FormatInt(generation, 10)), nil
+}
@@ -0,0 +1,369 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"testing"
+
+	gcStorage "cloud.google.com/go/storage"
+	"github.com/minamijoyo/tfmigrate/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// mockClient is a mock implementation for testing.
+type mockClient struct {
+	dataToRead []byte
+	generation int64
+	err        error
+}
+
+// Read returns a mocked response.
+func (c *mockClient) Read(_ context.Context) ([]byte, int64, error) {
+	return c.dataToRead, c.generation, c.err
+}
+
+// Write returns a mocked response.
+func (c *mockClient) Write(_ context.Context, _ []byte) error {
+	return c.err
+}
+
+// WriteIfGenerationMatch returns a mocked response.
+func (c *mockClient) WriteIfGenerationMatch(_ context.Context, _ []byte, _ int64) (int64, error) {
+	return c.generation, c.err
+}
+
+// Delete returns a mocked response.
+func (c *mockClient) Delete(_ context.Context) error {
+	return c.err
+}
+
+func TestStorageWrite(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   Client
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: nil,
+			},
+			contents: []byte("foo"),
+			ok:       true,
+		},
+		{
+			desc: "bucket does not exist",
+			config: &Config{
+				Bucket: "not-exist-bucket",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				err: gcStorage.ErrBucketNotExist,
+			},
+			contents: []byte("foo"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Write(context.Background(), tc.contents)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestStorageRead(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		client   Client
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				dataToRead: []byte("foo"),
+				err:        nil,
+			},
+			contents: []byte("foo"),
+			ok:       true,
+		},
+		{
+			desc: "bucket does not exist",
+			config: &Config{
+				Bucket: "not-exist-bucket",
+				Name:   "tfmigrate/history.json",
+			},
+			client: &mockClient{
+				dataToRead: nil,
+				err:        gcStorage.ErrBucketNotExist,
+			},
+			contents: nil,
+			ok:       false,
+		},
+		{
+			desc: "object does not exist",
+			config: &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "not_exist.json",
+			},
+			client: &mockClient{
+				dataToRead: nil,
+				err:        gcStorage.ErrObjectNotExist,
+			},
+			contents: []byte{},
+			ok:       true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, err := s.Read(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				if string(got) != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
+				}
+			}
+		})
+	}
+}
+
+func TestStorageReadWithVersion(t *testing.T) {
+	cases := []struct {
+		desc        string
+		client      Client
+		contents    []byte
+		wantVersion storage.Version
+		ok          bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				dataToRead: []byte("foo"),
+				generation: 1234,
+				err:        nil,
+			},
+			contents:    []byte("foo"),
+			wantVersion: "1234",
+			ok:          true,
+		},
+		{
+			desc: "object does not exist",
+			client: &mockClient{
+				dataToRead: nil,
+				err:        gcStorage.ErrObjectNotExist,
+			},
+			contents:    []byte{},
+			wantVersion: "",
+			ok:          true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, version, err := s.ReadWithVersion(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				if string(got) != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
+				}
+				if version != tc.wantVersion {
+					t.Errorf("got version: %s, want: %s", version, tc.wantVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfMatch(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   Client
+		version  storage.Version
+		conflict bool
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				err: nil,
+			},
+			version:  "1234",
+			conflict: false,
+			ok:       true,
+		},
+		{
+			desc: "precondition failed",
+			client: &mockClient{
+				err: &googleapi.Error{Code: http.StatusPreconditionFailed},
+			},
+			version:  "1234",
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "invalid version",
+			client: &mockClient{
+				err: nil,
+			},
+			version:  "foo",
+			conflict: false,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.WriteIfMatch(context.Background(), []byte("foo"), tc.version)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if got := errors.Is(err, storage.ErrVersionConflict); got != tc.conflict {
+				t.Errorf("got conflict: %t, want: %t, err: %v", got, tc.conflict, err)
+			}
+		})
+	}
+}
+
+func TestStorageDelete(t *testing.T) {
+	cases := []struct {
+		desc   string
+		client Client
+		ok     bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				err: nil,
+			},
+			ok: true,
+		},
+		{
+			desc: "object does not exist",
+			client: &mockClient{
+				err: gcStorage.ErrObjectNotExist,
+			},
+			ok: true,
+		},
+		{
+			desc: "unexpected error",
+			client: &mockClient{
+				err: errors.New("failed"),
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Bucket: "tfmigrate-test",
+				Name:   "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Delete(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestAccStorageWriteRead(t *testing.T) {
+	if os.Getenv("TEST_ACC") != "1" {
+		t.Skip("skip acceptance tests")
+	}
+	// The fake-gcs-server is expected to be running for acceptance tests.
+	// See docker-compose.yml for details.
+	if os.Getenv("STORAGE_EMULATOR_HOST") == "" {
+		t.Skip("skip acceptance tests for gcs because STORAGE_EMULATOR_HOST is not set")
+	}
+
+	config := &Config{
+		Bucket: "tfstate-test",
+		Name:   "tfmigrate/" + t.Name() + "/history.json",
+	}
+	s, err := NewStorage(config, nil)
+	if err != nil {
+		t.Fatalf("failed to NewStorage: %s", err)
+	}
+
+	ctx := context.Background()
+	want := []byte("foo")
+	if err := s.Write(ctx, want); err != nil {
+		t.Fatalf("failed to write: %s", err)
+	}
+
+	got, err := s.Read(ctx)
+	if err != nil {
+		t.Fatalf("failed to read: %s", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("got: %s, want: %s", string(got), string(want))
+	}
+}
@@ -1,6 +1,11 @@
 package local
 
-import "github.com/minamijoyo/tfmigrate/storage"
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
 
 // Config is a config for local storage.
 type Config struct {
@@ -8,10 +13,25 @@ type Config struct {
 	Path string `hcl:"path"`
 }
 
-// Config implements a storage.Config.
-var _ storage.Config = (*Config)(nil)
+// Config implements a storage.NamedConfig.
+var _ storage.NamedConfig = (*Config)(nil)
 
 // NewStorage returns a new instance of storage.Storage.
 func (c *Config) NewStorage() (storage.Storage, error) {
 	return NewStorage(c)
 }
+
+// NewNamedStorage returns a new instance of storage.Storage for a file with a
+// given name in a directory of the Path. The name can contain a slash to
+// represent a sub directory. A parent directory of the file is created if it
+// doesn't exist.
+func (c *Config) NewNamedStorage(name string) (storage.Storage, error) {
+	path := filepath.Join(c.Path, filepath.FromSlash(name))
+	// nolint gosec
+	// G301: Expect directory permissions to be 0750 or less
+	// We ignore it to be consistent with a permission of files.
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return NewStorage(&Config{Path: path})
+}
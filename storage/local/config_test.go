@@ -1,6 +1,10 @@
 package local
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
 
 func TestConfigNewStorage(t *testing.T) {
 	cases := []struct {
@@ -32,3 +36,27 @@ func TestConfigNewStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigNewNamedStorage(t *testing.T) {
+	localDir, err := os.MkdirTemp("", "localDir")
+	if err != nil {
+		t.Fatalf("failed to craete temp dir: %s", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(localDir) })
+
+	prefix := filepath.Join(localDir, "backup")
+	config := &Config{Path: prefix}
+	got, err := config.NewNamedStorage("snapshots/foo.json")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	s := got.(*Storage)
+	want := filepath.Join(prefix, "snapshots", "foo.json")
+	if s.config.Path != want {
+		t.Errorf("got: %s, want: %s", s.config.Path, want)
+	}
+	if _, err := os.Stat(filepath.Dir(want)); err != nil {
+		t.Errorf("expected to create a parent directory: %s", err)
+	}
+}
@@ -2,6 +2,8 @@ package local
 
 import (
 	"context"
+	"crypto/sha256"
+	"fmt"
 	"os"
 
 	"github.com/minamijoyo/tfmigrate/storage"
@@ -17,6 +19,7 @@ type Storage struct {
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.Deleter = (*Storage)(nil)
 
 // NewStorage returns a new instance of Storage.
 func NewStorage(config *Config) (*Storage, error) {
@@ -45,3 +48,67 @@ func (s *Storage) Read(_ context.Context) ([]byte, error) {
 	}
 	return os.ReadFile(s.config.Path)
 }
+
+// ReadWithVersion reads migration history data from storage and returns it
+// with a version of the data.
+// The version is computed from a modification time and a SHA-256 hash of the
+// file.
+// If the key does not exist, it is assumed to be uninitialized and returns
+// an empty array and an empty version instead of an error.
+func (s *Storage) ReadWithVersion(_ context.Context) ([]byte, storage.Version, error) {
+	return s.readWithVersion()
+}
+
+// WriteIfMatch writes migration history data to storage only if the
+// current version of the stored data matches a given version.
+// Note that the local filesystem doesn't support an atomic conditional write,
+// so there is a small window between the version check and the write.
+// It is intended to detect a conflict in most practical cases, not to
+// guarantee it.
+func (s *Storage) WriteIfMatch(ctx context.Context, b []byte, version storage.Version) (storage.Version, error) {
+	_, current, err := s.readWithVersion()
+	if err != nil {
+		return "", err
+	}
+
+	if current != version {
+		return "", fmt.Errorf("%w: path = %s, expected version = %q, current version = %q", storage.ErrVersionConflict, s.config.Path, version, current)
+	}
+
+	if err := s.Write(ctx, b); err != nil {
+		return "", err
+	}
+
+	_, newVersion, err := s.readWithVersion()
+	return newVersion, err
+}
+
+// Delete deletes a file.
+// If the file does not exist, it does nothing.
+func (s *Storage) Delete(_ context.Context) error {
+	err := os.Remove(s.config.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// readWithVersion is a helper method which reads a file and computes its version.
+func (s *Storage) readWithVersion() ([]byte, storage.Version, error) {
+	info, err := os.Stat(s.config.Path)
+	if os.IsNotExist(err) {
+		// If the key does not exist
+		return []byte{}, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	b, err := os.ReadFile(s.config.Path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	version := storage.Version(fmt.Sprintf("%d-%x", info.ModTime().UnixNano(), sha256.Sum256(b)))
+	return b, version, nil
+}
@@ -2,9 +2,12 @@ package local
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
 )
 
 func TestStorageWrite(t *testing.T) {
@@ -125,3 +128,156 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageWriteIfMatch(t *testing.T) {
+	cases := []struct {
+		desc     string
+		current  []byte
+		modify   bool
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc:     "not changed",
+			current:  []byte("foo"),
+			modify:   false,
+			contents: []byte("bar"),
+			ok:       true,
+		},
+		{
+			desc:     "changed",
+			current:  []byte("foo"),
+			modify:   true,
+			contents: []byte("bar"),
+			ok:       false,
+		},
+		{
+			desc:     "file does not exist",
+			current:  nil,
+			modify:   false,
+			contents: []byte("bar"),
+			ok:       true,
+		},
+		{
+			desc:     "file created",
+			current:  nil,
+			modify:   true,
+			contents: []byte("bar"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			localDir, err := os.MkdirTemp("", "localDir")
+			if err != nil {
+				t.Fatalf("failed to craete temp dir: %s", err)
+			}
+			t.Cleanup(func() { os.RemoveAll(localDir) })
+
+			path := filepath.Join(localDir, "history.json")
+			if tc.current != nil {
+				err = os.WriteFile(path, tc.current, 0600)
+				if err != nil {
+					t.Fatalf("failed to write contents: %s", err)
+				}
+			}
+
+			s, err := NewStorage(&Config{Path: path})
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			ctx := context.Background()
+			_, version, err := s.ReadWithVersion(ctx)
+			if err != nil {
+				t.Fatalf("failed to ReadWithVersion: %s", err)
+			}
+
+			if tc.modify {
+				// simulate a concurrent update.
+				err = os.WriteFile(path, []byte("baz"), 0600)
+				if err != nil {
+					t.Fatalf("failed to modify contents: %s", err)
+				}
+			}
+
+			_, err = s.WriteIfMatch(ctx, tc.contents, version)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("expected to return ErrVersionConflict, but got: %s", err)
+				}
+			}
+
+			if tc.ok {
+				got, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("failed to read contents: %s", err)
+				}
+				if string(got) != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
+				}
+			}
+		})
+	}
+}
+
+func TestStorageDelete(t *testing.T) {
+	cases := []struct {
+		desc    string
+		current []byte
+		ok      bool
+	}{
+		{
+			desc:    "simple",
+			current: []byte("foo"),
+			ok:      true,
+		},
+		{
+			desc:    "file does not exist",
+			current: nil,
+			ok:      true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			localDir, err := os.MkdirTemp("", "localDir")
+			if err != nil {
+				t.Fatalf("failed to craete temp dir: %s", err)
+			}
+			t.Cleanup(func() { os.RemoveAll(localDir) })
+
+			path := filepath.Join(localDir, "history.json")
+			if tc.current != nil {
+				err = os.WriteFile(path, tc.current, 0600)
+				if err != nil {
+					t.Fatalf("failed to write contents: %s", err)
+				}
+			}
+
+			s, err := NewStorage(&Config{Path: path})
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			err = s.Delete(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("expected the file to be deleted, but got: %s", err)
+				}
+			}
+		})
+	}
+}
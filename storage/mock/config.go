@@ -10,13 +10,20 @@ type Config struct {
 	WriteError bool `hcl:"write_error"`
 	// ReadError is a flag to return an error on Read().
 	ReadError bool `hcl:"read_error"`
+	// WriteConflict is a flag to simulate a version conflict on WriteIfMatch().
+	// It pretends that someone else has updated the data concurrently.
+	WriteConflict bool `hcl:"write_conflict,optional"`
 
 	// A reference to an instance of mock storage for testing.
 	s *Storage
+	// A map of references to named instances of mock storage for testing.
+	// The same instance is returned for the same name so that it behaves like
+	// a persistent storage.
+	named map[string]*Storage
 }
 
-// Config implements a storage.Config.
-var _ storage.Config = (*Config)(nil)
+// Config implements a storage.NamedConfig.
+var _ storage.NamedConfig = (*Config)(nil)
 
 // NewStorage returns a new instance of storage.Storage.
 func (c *Config) NewStorage() (storage.Storage, error) {
@@ -31,3 +38,34 @@ func (c *Config) NewStorage() (storage.Storage, error) {
 func (c *Config) Storage() *Storage {
 	return c.s
 }
+
+// NewNamedStorage returns a new instance of storage.Storage for a given name.
+// If the storage for the name already exists, it returns the same instance.
+// A new named storage inherits flags for errors, but is initialized with
+// empty data.
+func (c *Config) NewNamedStorage(name string) (storage.Storage, error) {
+	if s, ok := c.named[name]; ok {
+		return s, nil
+	}
+	s, err := NewStorage(&Config{
+		WriteError:    c.WriteError,
+		ReadError:     c.ReadError,
+		WriteConflict: c.WriteConflict,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// store a reference for test assertion.
+	if c.named == nil {
+		c.named = make(map[string]*Storage)
+	}
+	c.named[name] = s
+	return s, nil
+}
+
+// NamedStorage returns a reference to named mock storage for testing.
+// It returns nil if not found.
+func (c *Config) NamedStorage(name string) *Storage {
+	return c.named[name]
+}
@@ -34,3 +34,30 @@ func TestConfigNewStorage(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigNewNamedStorage(t *testing.T) {
+	config := &Config{Data: "foo"}
+	got, err := config.NewNamedStorage("bar.json")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	s := got.(*Storage)
+	if s.Data() != "" {
+		t.Errorf("expected a new named storage to be empty, but got: %s", s.Data())
+	}
+	if config.NamedStorage("bar.json") != s {
+		t.Errorf("expected to store a reference to the named storage")
+	}
+
+	again, err := config.NewNamedStorage("bar.json")
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+	if again != got {
+		t.Errorf("expected to return the same instance for the same name")
+	}
+	if config.NamedStorage("baz.json") != nil {
+		t.Errorf("expected to return nil for an unknown name")
+	}
+}
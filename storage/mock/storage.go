@@ -2,6 +2,7 @@ package mock
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 
 	"github.com/minamijoyo/tfmigrate/storage"
@@ -17,6 +18,7 @@ type Storage struct {
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.Deleter = (*Storage)(nil)
 
 // NewStorage returns a new instance of Storage.
 func NewStorage(config *Config) (*Storage, error) {
@@ -48,3 +50,48 @@ func (s *Storage) Read(_ context.Context) ([]byte, error) {
 	}
 	return []byte(s.data), nil
 }
+
+// Delete deletes data in storage.
+func (s *Storage) Delete(_ context.Context) error {
+	if s.config.WriteError {
+		return fmt.Errorf("failed to delete mock storage: writeError = %t", s.config.WriteError)
+	}
+	s.data = ""
+	return nil
+}
+
+// ReadWithVersion reads migration history data from storage and returns it
+// with a version of the data.
+// The version is a SHA-256 hash of the data.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, storage.Version, error) {
+	b, err := s.Read(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, s.version(), nil
+}
+
+// WriteIfMatch writes migration history data to storage only if the
+// current version of the stored data matches a given version.
+func (s *Storage) WriteIfMatch(ctx context.Context, b []byte, version storage.Version) (storage.Version, error) {
+	if s.config.WriteConflict {
+		return "", fmt.Errorf("%w: writeConflict = %t", storage.ErrVersionConflict, s.config.WriteConflict)
+	}
+	if current := s.version(); current != version {
+		return "", fmt.Errorf("%w: expected version = %q, current version = %q", storage.ErrVersionConflict, version, current)
+	}
+	if err := s.Write(ctx, b); err != nil {
+		return "", err
+	}
+	return s.version(), nil
+}
+
+// version returns a version of the current data.
+// If the data is empty, it is assumed to be uninitialized and returns an
+// empty version.
+func (s *Storage) version() storage.Version {
+	if len(s.data) == 0 {
+		return ""
+	}
+	return storage.Version(fmt.Sprintf("%x", sha256.Sum256([]byte(s.data))))
+}
@@ -2,7 +2,10 @@ package mock
 
 import (
 	"context"
+	"errors"
 	"testing"
+
+	"github.com/minamijoyo/tfmigrate/storage"
 )
 
 func TestStorageWrite(t *testing.T) {
@@ -112,3 +115,81 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageWriteIfMatch(t *testing.T) {
+	cases := []struct {
+		desc     string
+		config   *Config
+		modify   bool
+		contents []byte
+		ok       bool
+	}{
+		{
+			desc: "not changed",
+			config: &Config{
+				Data: "foo",
+			},
+			modify:   false,
+			contents: []byte("bar"),
+			ok:       true,
+		},
+		{
+			desc: "changed",
+			config: &Config{
+				Data: "foo",
+			},
+			modify:   true,
+			contents: []byte("bar"),
+			ok:       false,
+		},
+		{
+			desc: "write conflict",
+			config: &Config{
+				Data:          "foo",
+				WriteConflict: true,
+			},
+			modify:   false,
+			contents: []byte("bar"),
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewStorage(tc.config)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			ctx := context.Background()
+			_, version, err := s.ReadWithVersion(ctx)
+			if err != nil {
+				t.Fatalf("failed to ReadWithVersion: %s", err)
+			}
+
+			if tc.modify {
+				// simulate a concurrent update.
+				s.data = "baz"
+			}
+
+			_, err = s.WriteIfMatch(ctx, tc.contents, version)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatal("expected to return an error, but no error")
+				}
+				if !errors.Is(err, storage.ErrVersionConflict) {
+					t.Fatalf("expected to return ErrVersionConflict, but got: %s", err)
+				}
+			}
+
+			if tc.ok {
+				got := s.Data()
+				if got != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", got, string(tc.contents))
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ type Client interface {
 	PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error)
 	// GetObjectWithContext gets a file from S3.
 	GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error)
+	// DeleteObjectWithContext deletes a file from S3.
+	DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error)
 }
 
 // client is a real implementation of the Client.
@@ -58,3 +60,8 @@ func (c *client) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput,
 func (c *client) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
 	return c.s3api.GetObjectWithContext(ctx, input, opts...)
 }
+
+// DeleteObjectWithContext deletes a file from S3.
+func (c *client) DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error) {
+	return c.s3api.DeleteObjectWithContext(ctx, input, opts...)
+}
@@ -1,6 +1,10 @@
 package s3
 
-import "github.com/minamijoyo/tfmigrate/storage"
+import (
+	"path"
+
+	"github.com/minamijoyo/tfmigrate/storage"
+)
 
 // Config is a config for s3 storage.
 // This is expected to have almost the same options as Terraform s3 backend.
@@ -36,10 +40,18 @@ type Config struct {
 	KmsKeyID string `hcl:"kms_key_id,optional"`
 }
 
-// Config implements a storage.Config.
-var _ storage.Config = (*Config)(nil)
+// Config implements a storage.NamedConfig.
+var _ storage.NamedConfig = (*Config)(nil)
 
 // NewStorage returns a new instance of storage.Storage.
 func (c *Config) NewStorage() (storage.Storage, error) {
 	return NewStorage(c, nil)
 }
+
+// NewNamedStorage returns a new instance of storage.Storage for an object
+// with a given name under a prefix of the Key.
+func (c *Config) NewNamedStorage(name string) (storage.Storage, error) {
+	named := *c
+	named.Key = path.Join(c.Key, name)
+	return NewStorage(&named, nil)
+}
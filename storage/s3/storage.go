@@ -3,9 +3,11 @@ package s3
 import (
 	"bytes"
 	"context"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/minamijoyo/tfmigrate/storage"
 )
@@ -20,6 +22,7 @@ type Storage struct {
 }
 
 var _ storage.Storage = (*Storage)(nil)
+var _ storage.Deleter = (*Storage)(nil)
 
 // NewStorage returns a new instance of Storage.
 func NewStorage(config *Config, client Client) (*Storage, error) {
@@ -41,6 +44,49 @@ func NewStorage(config *Config, client Client) (*Storage, error) {
 
 // Write writes migration history data to storage.
 func (s *Storage) Write(ctx context.Context, b []byte) error {
+	_, err := s.client.PutObjectWithContext(ctx, s.newPutObjectInput(b))
+
+	return err
+}
+
+// WriteIfMatch writes migration history data to storage only if the
+// current version of the stored data matches a given version.
+// The version is an ETag of the object. It relies on the S3 conditional
+// writes with the If-Match and If-None-Match headers.
+func (s *Storage) WriteIfMatch(ctx context.Context, b []byte, version storage.Version) (storage.Version, error) {
+	headers := map[string]string{}
+	if len(version) == 0 {
+		// The object is expected not to exist.
+		headers["If-None-Match"] = "*"
+	} else {
+		headers["If-Match"] = string(version)
+	}
+
+	output, err := s.client.PutObjectWithContext(ctx, s.newPutObjectInput(b), request.WithSetRequestHeaders(headers))
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && isConditionalWriteConflict(awsErr.Code()) {
+			return "", fmt.Errorf("%w: bucket = %s, key = %s, expected version = %q: %s", storage.ErrVersionConflict, s.config.Bucket, s.config.Key, version, err)
+		}
+		return "", err
+	}
+
+	return storage.Version(aws.StringValue(output.ETag)), nil
+}
+
+// Delete deletes migration history data from storage.
+// Note that S3 doesn't return an error even if the key does not exist.
+func (s *Storage) Delete(ctx context.Context) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(s.config.Key),
+	}
+
+	_, err := s.client.DeleteObjectWithContext(ctx, input)
+	return err
+}
+
+// newPutObjectInput is a helper method which builds an input for PutObject.
+func (s *Storage) newPutObjectInput(b []byte) *s3.PutObjectInput {
 	input := &s3.PutObjectInput{
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(s.config.Key),
@@ -50,16 +96,31 @@ func (s *Storage) Write(ctx context.Context, b []byte) error {
 		input.SSEKMSKeyId = &s.config.KmsKeyID
 		input.ServerSideEncryption = aws.String("aws:kms")
 	}
+	return input
+}
 
-	_, err := s.client.PutObjectWithContext(ctx, input)
-
-	return err
+// isConditionalWriteConflict returns true if a given error code indicates
+// that a conditional write failed.
+func isConditionalWriteConflict(code string) bool {
+	// PreconditionFailed (412) is returned when the ETag doesn't match.
+	// ConditionalRequestConflict (409) is returned when a concurrent
+	// conditional write is in progress.
+	return code == "PreconditionFailed" || code == "ConditionalRequestConflict"
 }
 
 // Read reads migration history data from storage.
 // If the key does not exist, it is assumed to be uninitialized and returns
 // an empty array instead of an error.
 func (s *Storage) Read(ctx context.Context) ([]byte, error) {
+	b, _, err := s.ReadWithVersion(ctx)
+	return b, err
+}
+
+// ReadWithVersion reads migration history data from storage and returns it
+// with a version of the data. The version is an ETag of the object.
+// If the key does not exist, it is assumed to be uninitialized and returns
+// an empty array and an empty version instead of an error.
+func (s *Storage) ReadWithVersion(ctx context.Context) ([]byte, storage.Version, error) {
 	input := &s3.GetObjectInput{
 		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(s.config.Key),
@@ -69,10 +130,10 @@ func (s *Storage) Read(ctx context.Context) ([]byte, error) {
 	if err != nil {
 		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NoSuchKey" {
 			// If the key does not exist
-			return []byte{}, nil
+			return []byte{}, "", nil
 		}
 		// unexpected error
-		return nil, err
+		return nil, "", err
 	}
 
 	defer output.Body.Close()
@@ -80,8 +141,8 @@ func (s *Storage) Read(ctx context.Context) ([]byte, error) {
 	buf := bytes.NewBuffer(nil)
 	_, err = buf.ReadFrom(output.Body)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	return buf.Bytes(), nil
+	return buf.Bytes(), storage.Version(aws.StringValue(output.ETag)), nil
 }
@@ -2,6 +2,7 @@ package s3
 
 import (
 	"context"
+	"errors"
 	"io"
 	"strings"
 	"testing"
@@ -10,12 +11,14 @@ import (
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minamijoyo/tfmigrate/storage"
 )
 
 // mockClient is a mock implementation for testing.
 type mockClient struct {
 	putOutput *s3.PutObjectOutput
 	getOutput *s3.GetObjectOutput
+	delOutput *s3.DeleteObjectOutput
 	err       error
 }
 
@@ -29,6 +32,11 @@ func (c *mockClient) GetObjectWithContext(_ aws.Context, _ *s3.GetObjectInput, _
 	return c.getOutput, c.err
 }
 
+// DeleteObjectWithContext returns a mocked response.
+func (c *mockClient) DeleteObjectWithContext(_ aws.Context, _ *s3.DeleteObjectInput, _ ...request.Option) (*s3.DeleteObjectOutput, error) {
+	return c.delOutput, c.err
+}
+
 func TestStorageWrite(t *testing.T) {
 	cases := []struct {
 		desc     string
@@ -155,3 +163,135 @@ func TestStorageRead(t *testing.T) {
 		})
 	}
 }
+
+func TestStorageReadWithVersion(t *testing.T) {
+	cases := []struct {
+		desc        string
+		client      Client
+		contents    []byte
+		wantVersion storage.Version
+		ok          bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				getOutput: &s3.GetObjectOutput{
+					Body: io.NopCloser(strings.NewReader("foo")),
+					ETag: aws.String(`"acbd18db4cc2f85cedef654fccc4a4d8"`),
+				},
+				err: nil,
+			},
+			contents:    []byte("foo"),
+			wantVersion: `"acbd18db4cc2f85cedef654fccc4a4d8"`,
+			ok:          true,
+		},
+		{
+			desc: "key does not exist",
+			client: &mockClient{
+				getOutput: nil,
+				err:       awserr.New("NoSuchKey", "The specified key does not exist.", nil),
+			},
+			contents:    []byte{},
+			wantVersion: "",
+			ok:          true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			got, version, err := s.ReadWithVersion(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if tc.ok {
+				if string(got) != string(tc.contents) {
+					t.Errorf("got: %s, want: %s", string(got), string(tc.contents))
+				}
+				if version != tc.wantVersion {
+					t.Errorf("got version: %s, want: %s", version, tc.wantVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestStorageWriteIfMatch(t *testing.T) {
+	cases := []struct {
+		desc     string
+		client   Client
+		conflict bool
+		ok       bool
+	}{
+		{
+			desc: "simple",
+			client: &mockClient{
+				putOutput: &s3.PutObjectOutput{},
+				err:       nil,
+			},
+			conflict: false,
+			ok:       true,
+		},
+		{
+			desc: "precondition failed",
+			client: &mockClient{
+				putOutput: nil,
+				err:       awserr.New("PreconditionFailed", "At least one of the pre-conditions you specified did not hold", nil),
+			},
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "conditional request conflict",
+			client: &mockClient{
+				putOutput: nil,
+				err:       awserr.New("ConditionalRequestConflict", "A conflicting operation occurred.", nil),
+			},
+			conflict: true,
+			ok:       false,
+		},
+		{
+			desc: "bucket does not exist",
+			client: &mockClient{
+				putOutput: nil,
+				err:       awserr.New("NoSuchBucket", "The specified bucket does not exist.", nil),
+			},
+			conflict: false,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			config := &Config{
+				Bucket: "tfmigrate-test",
+				Key:    "tfmigrate/history.json",
+			}
+			s, err := NewStorage(config, tc.client)
+			if err != nil {
+				t.Fatalf("failed to NewStorage: %s", err)
+			}
+			_, err = s.WriteIfMatch(context.Background(), []byte("foo"), `"acbd18db4cc2f85cedef654fccc4a4d8"`)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if got := errors.Is(err, storage.ErrVersionConflict); got != tc.conflict {
+				t.Errorf("got conflict: %t, want: %t, err: %v", got, tc.conflict, err)
+			}
+		})
+	}
+}
@@ -1,6 +1,19 @@
 package storage
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrVersionConflict is an error returned by WriteIfMatch when the stored
+// data has been changed since it was read.
+var ErrVersionConflict = errors.New("the stored data has been changed since it was read")
+
+// Version is an opaque token which identifies a revision of stored data.
+// Its format depends on a storage implementation (e.g. an ETag for S3, a
+// generation number for GCS). The empty Version means that the data does not
+// exist.
+type Version string
 
 // Storage is an abstraction layer for migration history data store.
 // As you know, this is the equivalent of Terraform's backend, but we have
@@ -14,4 +27,22 @@ type Storage interface {
 	// If the key does not exist, it is assumed to be uninitialized and returns
 	// an empty array instead of an error.
 	Read(ctx context.Context) ([]byte, error)
+	// ReadWithVersion reads migration history data from storage and returns it
+	// with a version of the data.
+	// If the key does not exist, it is assumed to be uninitialized and returns
+	// an empty array and an empty version instead of an error.
+	ReadWithVersion(ctx context.Context) ([]byte, Version, error)
+	// WriteIfMatch writes migration history data to storage only if the
+	// current version of the stored data matches a given version.
+	// If the given version is empty, it writes only if the data doesn't exist.
+	// If the version doesn't match, it returns ErrVersionConflict.
+	// It returns a new version of the written data.
+	WriteIfMatch(ctx context.Context, b []byte, version Version) (Version, error)
+}
+
+// Deleter is an optional interface of Storage which can delete stored data.
+type Deleter interface {
+	// Delete deletes stored data.
+	// If the key does not exist, it does nothing.
+	Delete(ctx context.Context) error
 }
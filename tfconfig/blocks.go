@@ -0,0 +1,121 @@
+package tfconfig
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Block is an interface of a configuration block which declares a change of
+// state, such as moved, removed and import blocks.
+type Block interface {
+	// appendTo appends the block to a given body.
+	appendTo(body *hclwrite.Body) error
+}
+
+var _ Block = (*MovedBlock)(nil)
+var _ Block = (*RemovedBlock)(nil)
+var _ Block = (*ImportBlock)(nil)
+
+// appendTo appends a moved block to a given body.
+func (b *MovedBlock) appendTo(body *hclwrite.Body) error {
+	block := body.AppendNewBlock("moved", nil).Body()
+	if err := setAddress(block, "from", b.From); err != nil {
+		return err
+	}
+	return setAddress(block, "to", b.To)
+}
+
+// RemovedBlock represents a removed block which removes a resource or module
+// from state without destroying it. It requires Terraform v1.7 or later.
+type RemovedBlock struct {
+	// From is an address to be removed. It must not have an instance key.
+	From string
+}
+
+// appendTo appends a removed block to a given body.
+func (b *RemovedBlock) appendTo(body *hclwrite.Body) error {
+	addr, err := tfstate.ParseAddress(b.From)
+	if err != nil {
+		return err
+	}
+	if addr.IsInstance() || hasModuleKey(addr) {
+		return fmt.Errorf("a removed block cannot have an instance key: %s", b.From)
+	}
+
+	block := body.AppendNewBlock("removed", nil).Body()
+	if err := setAddress(block, "from", b.From); err != nil {
+		return err
+	}
+	lifecycle := block.AppendNewBlock("lifecycle", nil).Body()
+	lifecycle.SetAttributeValue("destroy", cty.False)
+	return nil
+}
+
+// ImportBlock represents an import block which imports an existing resource
+// into state. It requires Terraform v1.5 or later.
+type ImportBlock struct {
+	// To is an address to import the resource to.
+	To string
+	// ID is a resource identifier to be imported.
+	ID string
+}
+
+// appendTo appends an import block to a given body.
+func (b *ImportBlock) appendTo(body *hclwrite.Body) error {
+	block := body.AppendNewBlock("import", nil).Body()
+	if err := setAddress(block, "to", b.To); err != nil {
+		return err
+	}
+	block.SetAttributeValue("id", cty.StringVal(b.ID))
+	return nil
+}
+
+// FormatBlocks returns a content of a configuration file which contains
+// given blocks separated by blank lines.
+func FormatBlocks(blocks []Block) ([]byte, error) {
+	f := hclwrite.NewEmptyFile()
+	body := f.Body()
+	for i, b := range blocks {
+		if i > 0 {
+			body.AppendNewline()
+		}
+		if err := b.appendTo(body); err != nil {
+			return nil, err
+		}
+	}
+
+	return hclwrite.Format(f.Bytes()), nil
+}
+
+// setAddress sets an attribute of a given name to an address as a traversal.
+func setAddress(body *hclwrite.Body, name string, address string) error {
+	// validate and normalize the address.
+	addr, err := tfstate.ParseAddress(address)
+	if err != nil {
+		return err
+	}
+
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(addr.String()), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return fmt.Errorf("failed to parse address: %s, err: %s", address, diags)
+	}
+
+	body.SetAttributeTraversal(name, traversal)
+	return nil
+}
+
+// hasModuleKey returns true if a module path of a given address has an
+// instance key.
+func hasModuleKey(addr *tfstate.Address) bool {
+	for _, m := range addr.Module {
+		if m.Key != tfstate.NoKey() {
+			return true
+		}
+	}
+	return false
+}
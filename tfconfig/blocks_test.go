@@ -0,0 +1,86 @@
+package tfconfig
+
+import (
+	"testing"
+)
+
+func TestFormatBlocks(t *testing.T) {
+	cases := []struct {
+		desc   string
+		blocks []Block
+		want   string
+		ok     bool
+	}{
+		{
+			desc: "all types",
+			blocks: []Block{
+				&MovedBlock{From: "aws_instance.foo", To: `module.foo["a"].aws_instance.foo[0]`},
+				&RemovedBlock{From: "module.bar"},
+				&ImportBlock{To: "aws_instance.baz", ID: "i-1234"},
+			},
+			want: `moved {
+  from = aws_instance.foo
+  to   = module.foo["a"].aws_instance.foo[0]
+}
+
+removed {
+  from = module.bar
+  lifecycle {
+    destroy = false
+  }
+}
+
+import {
+  to = aws_instance.baz
+  id = "i-1234"
+}
+`,
+			ok: true,
+		},
+		{
+			desc:   "empty",
+			blocks: []Block{},
+			want:   "",
+			ok:     true,
+		},
+		{
+			desc: "removed with instance key",
+			blocks: []Block{
+				&RemovedBlock{From: "aws_instance.foo[0]"},
+			},
+			ok: false,
+		},
+		{
+			desc: "removed with module key",
+			blocks: []Block{
+				&RemovedBlock{From: "module.foo[0].aws_instance.foo"},
+			},
+			ok: false,
+		},
+		{
+			desc: "invalid address",
+			blocks: []Block{
+				&MovedBlock{From: "aws_instance", To: "aws_instance.foo"},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := FormatBlocks(tc.blocks)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %s", string(got))
+				}
+				return
+			}
+			if string(got) != tc.want {
+				t.Errorf("got: %s, want: %s", string(got), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package tfconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// maxModuleDepth is the maximum depth of nested local modules to follow.
+// It prevents an infinite recursion caused by a module calling itself.
+const maxModuleDepth = 32
+
+// moduleSchema is a schema of a module block.
+var moduleSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "source", Required: true},
+		{Name: "count"},
+		{Name: "for_each"},
+	},
+}
+
+// moduleCall represents a module block which calls a local module.
+type moduleCall struct {
+	// name is a name of the module.
+	name string
+	// dir is a path to the directory of the called module.
+	// It is empty if the module is not a local module.
+	dir string
+	// multi is true if the module has count or for_each.
+	multi bool
+}
+
+// listFiles returns a list of paths of .tf files in a given directory
+// sorted by the file name.
+func listFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".tf" {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, e.Name()))
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// loadFiles parses all .tf files in a given directory and returns them in
+// the order of the file name.
+func loadFiles(dir string) ([]*hcl.File, error) {
+	paths, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	parser := hclparse.NewParser()
+	files := []*hcl.File{}
+	for _, path := range paths {
+		f, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		files = append(files, f)
+	}
+
+	return files, nil
+}
+
+// decodeModuleCall decodes a given module block. If the module is not a local
+// module, the dir of the result is empty, because we cannot read
+// configurations of remote modules.
+func decodeModuleCall(dir string, block *hcl.Block) (*moduleCall, error) {
+	content, _, diags := block.Body.PartialContent(moduleSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	v, diags := content.Attributes["source"].Expr.Value(nil)
+	if diags.HasErrors() || !v.Type().Equals(cty.String) {
+		return nil, fmt.Errorf("%s: the source of module %s must be a literal string", block.DefRange, block.Labels[0])
+	}
+
+	_, hasCount := content.Attributes["count"]
+	_, hasForEach := content.Attributes["for_each"]
+	call := &moduleCall{
+		name:  block.Labels[0],
+		multi: hasCount || hasForEach,
+	}
+
+	source := v.AsString()
+	if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+		call.dir = filepath.Join(dir, source)
+	}
+
+	return call, nil
+}
+
+// modulePrefix returns a prefix of addresses in a called module.
+// If the module has count or for_each, its instance key cannot be determined
+// statically, so it is represented by a wildcard.
+func modulePrefix(parent string, call *moduleCall) string {
+	key := ""
+	if call.multi {
+		key = "[*]"
+	}
+	return parent + "module." + call.name + key + "."
+}
@@ -0,0 +1,353 @@
+package tfconfig
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// rootSchema is a schema of blocks we are interested in at the top level
+// of configuration files.
+var rootSchema = &hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{Type: "moved"},
+		{Type: "module", LabelNames: []string{"name"}},
+	},
+}
+
+// movedSchema is a schema of a moved block.
+var movedSchema = &hcl.BodySchema{
+	Attributes: []hcl.AttributeSchema{
+		{Name: "from", Required: true},
+		{Name: "to", Required: true},
+	},
+}
+
+// MovedBlock represents a moved block in Terraform configuration.
+type MovedBlock struct {
+	// From is an address moved from, which is relative to the root module.
+	From string
+	// To is an address moved to, which is relative to the root module.
+	To string
+	// Range is a location of the block.
+	Range hcl.Range
+}
+
+// HasWildcard returns true if addresses contain wildcards.
+// An instance key of a module with count or for_each is represented by a
+// wildcard because it cannot be determined statically.
+func (b *MovedBlock) HasWildcard() bool {
+	return strings.Contains(b.From, "[*]")
+}
+
+// LoadMovedBlocks reads .tf files in a given directory and returns all moved
+// blocks in the order of appearance. It also follows local modules
+// recursively and returns moved blocks in them with addresses prefixed by
+// the module path.
+func LoadMovedBlocks(dir string) ([]*MovedBlock, error) {
+	return loadMovedBlocks(dir, "", 0)
+}
+
+// loadMovedBlocks is a recursive implementation of LoadMovedBlocks.
+func loadMovedBlocks(dir string, prefix string, depth int) ([]*MovedBlock, error) {
+	if depth > maxModuleDepth {
+		return nil, fmt.Errorf("too deep nested modules: %s", dir)
+	}
+
+	files, err := loadFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []*hcl.Block{}
+	calls := []*moduleCall{}
+	for _, f := range files {
+		content, _, diags := f.Body.PartialContent(rootSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		for _, block := range content.Blocks {
+			switch block.Type {
+			case "moved":
+				blocks = append(blocks, block)
+
+			case "module":
+				call, err := decodeModuleCall(dir, block)
+				if err != nil {
+					return nil, err
+				}
+				calls = append(calls, call)
+			}
+		}
+	}
+
+	// Moved blocks are decoded after all module calls in the directory are
+	// known, because they may refer to a module call with count or for_each.
+	multi := map[string]bool{}
+	for _, call := range calls {
+		multi[call.name] = call.multi
+	}
+	moved := []*MovedBlock{}
+	for _, block := range blocks {
+		m, err := decodeMovedBlock(block, prefix, multi)
+		if err != nil {
+			return nil, err
+		}
+		moved = append(moved, m)
+	}
+
+	for _, call := range calls {
+		if len(call.dir) == 0 {
+			log.Printf("[DEBUG] [tfconfig] skip a non-local module: %s\n", call.name)
+			continue
+		}
+		children, err := loadMovedBlocks(call.dir, modulePrefix(prefix, call), depth+1)
+		if err != nil {
+			return nil, err
+		}
+		moved = append(moved, children...)
+	}
+
+	return moved, nil
+}
+
+// decodeMovedBlock decodes a given moved block and prefixes addresses with
+// a given module path. A map of multi is a set of names of module calls with
+// count or for_each in the same directory.
+func decodeMovedBlock(block *hcl.Block, prefix string, multi map[string]bool) (*MovedBlock, error) {
+	content, diags := block.Body.Content(movedSchema)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	from, err := decodeAddress(content.Attributes["from"])
+	if err != nil {
+		return nil, err
+	}
+	to, err := decodeAddress(content.Attributes["to"])
+	if err != nil {
+		return nil, err
+	}
+	from, to = expandModuleCallKeys(from, to, multi)
+
+	return &MovedBlock{
+		From:  prefix + from,
+		To:    prefix + to,
+		Range: block.DefRange,
+	}, nil
+}
+
+// expandModuleCallKeys returns addresses of a moved block between module
+// calls with wildcards of instance keys.
+// In Terraform, a moved block between module calls without instance keys
+// moves all instances of a module with count or for_each keeping their keys,
+// but the keys cannot be determined statically. If either of the module calls
+// has count or for_each, each call is represented by a wildcard.
+// (e.g.) `module.a` => `module.b` becomes `module.a[*]` => `module.b[*]`
+func expandModuleCallKeys(from string, to string, multi map[string]bool) (string, string) {
+	fromName, ok := unkeyedModuleCall(from)
+	if !ok {
+		return from, to
+	}
+	toName, ok := unkeyedModuleCall(to)
+	if !ok {
+		return from, to
+	}
+	if !multi[fromName] && !multi[toName] {
+		return from, to
+	}
+
+	return insertWildcard(from, fromName), insertWildcard(to, toName)
+}
+
+// unkeyedModuleCall returns a name of module call if a given address starts
+// with a module call without an instance key.
+func unkeyedModuleCall(addr string) (string, bool) {
+	a, err := tfstate.ParseAddress(addr)
+	if err != nil || len(a.Module) == 0 || a.Module[0].Key != tfstate.NoKey() {
+		return "", false
+	}
+	return a.Module[0].Name, true
+}
+
+// insertWildcard inserts a wildcard of an instance key after the first module
+// call of a given address.
+func insertWildcard(addr string, name string) string {
+	call := "module." + name
+	return call + "[*]" + strings.TrimPrefix(addr, call)
+}
+
+// decodeAddress decodes a given attribute as an address.
+func decodeAddress(attr *hcl.Attribute) (string, error) {
+	traversal, diags := hcl.AbsTraversalForExpr(attr.Expr)
+	if diags.HasErrors() {
+		return "", diags
+	}
+
+	s, err := traversalString(traversal)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", attr.Expr.Range(), err)
+	}
+
+	// normalize the address.
+	addr, err := tfstate.ParseAddress(s)
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", attr.Expr.Range(), err)
+	}
+	return addr.String(), nil
+}
+
+// traversalString returns a string representation of a given traversal.
+func traversalString(traversal hcl.Traversal) (string, error) {
+	var b strings.Builder
+	for _, step := range traversal {
+		switch s := step.(type) {
+		case hcl.TraverseRoot:
+			b.WriteString(s.Name)
+
+		case hcl.TraverseAttr:
+			b.WriteString("." + s.Name)
+
+		case hcl.TraverseIndex:
+			switch s.Key.Type() {
+			case cty.Number:
+				i, acc := s.Key.AsBigFloat().Int64()
+				if acc != big.Exact {
+					return "", fmt.Errorf("invalid index key: %s", s.Key.AsBigFloat())
+				}
+				fmt.Fprintf(&b, "[%d]", i)
+			case cty.String:
+				b.WriteString("[" + strconv.Quote(s.Key.AsString()) + "]")
+			default:
+				return "", fmt.Errorf("invalid type of key: %s", s.Key.Type().FriendlyName())
+			}
+
+		default:
+			return "", fmt.Errorf("unsupported traversal: %T", step)
+		}
+	}
+
+	return b.String(), nil
+}
+
+// StripMovedBlocks removes all moved blocks from .tf files in a given
+// directory and local modules called from it recursively.
+// It returns a list of paths of modified files.
+func StripMovedBlocks(dir string) ([]string, error) {
+	dirs, err := localModuleDirs(dir, map[string]bool{}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	modified := []string{}
+	for _, d := range dirs {
+		paths, err := listFiles(d)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			ok, err := stripMovedBlocksInFile(path)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				modified = append(modified, path)
+			}
+		}
+	}
+
+	return modified, nil
+}
+
+// localModuleDirs returns a list of a given directory and directories of
+// local modules called from it recursively. Each directory appears only once
+// even if it is called multiple times.
+func localModuleDirs(dir string, visited map[string]bool, depth int) ([]string, error) {
+	if depth > maxModuleDepth {
+		return nil, fmt.Errorf("too deep nested modules: %s", dir)
+	}
+
+	clean := filepath.Clean(dir)
+	if visited[clean] {
+		return []string{}, nil
+	}
+	visited[clean] = true
+
+	files, err := loadFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{clean}
+	for _, f := range files {
+		content, _, diags := f.Body.PartialContent(rootSchema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		for _, block := range content.Blocks {
+			if block.Type != "module" {
+				continue
+			}
+			call, err := decodeModuleCall(dir, block)
+			if err != nil {
+				return nil, err
+			}
+			if len(call.dir) == 0 {
+				continue
+			}
+			children, err := localModuleDirs(call.dir, visited, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			dirs = append(dirs, children...)
+		}
+	}
+
+	return dirs, nil
+}
+
+// stripMovedBlocksInFile removes all moved blocks from a given file.
+// It returns true if the file has been modified.
+func stripMovedBlocksInFile(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	f, diags := hclwrite.ParseConfig(src, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return false, diags
+	}
+
+	removed := false
+	body := f.Body()
+	for _, block := range body.Blocks() {
+		if block.Type() == "moved" {
+			body.RemoveBlock(block)
+			removed = true
+		}
+	}
+	if !removed {
+		return false, nil
+	}
+
+	log.Printf("[INFO] [tfconfig] strip moved blocks: %s\n", path)
+	if err := os.WriteFile(path, f.Bytes(), info.Mode().Perm()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,297 @@
+package tfconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupDir is a test helper function which writes given files to a temporary
+// directory and returns the path.
+func setupDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %s", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %s", err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMovedBlocks(t *testing.T) {
+	cases := []struct {
+		desc  string
+		files map[string]string
+		want  [][2]string
+		ok    bool
+	}{
+		{
+			desc: "simple",
+			files: map[string]string{
+				"main.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+
+moved {
+  from = data.aws_ami.foo
+  to   = data.aws_ami.bar
+}
+`,
+			},
+			want: [][2]string{
+				{"aws_instance.foo", "aws_instance.bar"},
+				{"data.aws_ami.foo", "data.aws_ami.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "instance keys",
+			files: map[string]string{
+				"main.tf": `
+moved {
+  from = aws_instance.foo[0]
+  to   = aws_instance.foo["a"]
+}
+
+moved {
+  from = module.foo
+  to   = module.bar["a.b"]
+}
+`,
+			},
+			want: [][2]string{
+				{"aws_instance.foo[0]", `aws_instance.foo["a"]`},
+				{"module.foo", `module.bar["a.b"]`},
+			},
+			ok: true,
+		},
+		{
+			desc: "local modules",
+			files: map[string]string{
+				"a.tf": `
+module "foo" {
+  source = "./modules/foo"
+}
+
+module "bar" {
+  source = "./modules/bar"
+  count  = 2
+}
+
+module "remote" {
+  source = "terraform-aws-modules/vpc/aws"
+}
+`,
+				"b.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+`,
+				"modules/foo/main.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+
+module "baz" {
+  source = "../baz"
+}
+`,
+				"modules/bar/main.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+`,
+				"modules/baz/main.tf": `
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+`,
+			},
+			want: [][2]string{
+				{"aws_instance.foo", "aws_instance.bar"},
+				{"module.foo.aws_instance.foo", "module.foo.aws_instance.bar"},
+				{"module.foo.module.baz.aws_instance.foo", "module.foo.module.baz.aws_instance.bar"},
+				{"module.bar[*].aws_instance.foo", "module.bar[*].aws_instance.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "module calls with count or for_each",
+			files: map[string]string{
+				"main.tf": `
+module "b" {
+  source = "./modules/foo"
+  count  = 2
+}
+
+module "d" {
+  source   = "terraform-aws-modules/vpc/aws"
+  for_each = toset(["x", "y"])
+}
+
+module "f" {
+  source = "./modules/foo"
+}
+
+moved {
+  from = module.a
+  to   = module.b
+}
+
+moved {
+  from = module.c
+  to   = module.d
+}
+
+moved {
+  from = module.e
+  to   = module.f
+}
+
+moved {
+  from = module.a[0]
+  to   = module.b[0]
+}
+`,
+				"modules/foo/main.tf": `resource "aws_instance" "foo" {}`,
+			},
+			want: [][2]string{
+				{"module.a[*]", "module.b[*]"},
+				{"module.c[*]", "module.d[*]"},
+				{"module.e", "module.f"},
+				{"module.a[0]", "module.b[0]"},
+			},
+			ok: true,
+		},
+		{
+			desc: "no moved blocks",
+			files: map[string]string{
+				"main.tf": `resource "aws_instance" "foo" {}`,
+			},
+			want: [][2]string{},
+			ok:   true,
+		},
+		{
+			desc: "invalid address",
+			files: map[string]string{
+				"main.tf": `
+moved {
+  from = aws_instance
+  to   = aws_instance.bar
+}
+`,
+			},
+			ok: false,
+		},
+		{
+			desc: "syntax error",
+			files: map[string]string{
+				"main.tf": `moved {`,
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			dir := setupDir(t, tc.files)
+			moved, err := LoadMovedBlocks(dir)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %#v", moved)
+				}
+				return
+			}
+
+			got := [][2]string{}
+			for _, m := range moved {
+				got = append(got, [2]string{m.From, m.To})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStripMovedBlocks(t *testing.T) {
+	dir := setupDir(t, map[string]string{
+		"main.tf": `resource "aws_instance" "bar" {}
+
+moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+
+module "foo" {
+  source = "./modules/foo"
+}
+
+module "foo2" {
+  source = "./modules/foo"
+}
+`,
+		"modules/foo/main.tf": `moved {
+  from = aws_instance.foo
+  to   = aws_instance.bar
+}
+`,
+		"other.tf": `resource "aws_instance" "baz" {}
+`,
+	})
+
+	modified, err := StripMovedBlocks(dir)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "main.tf"),
+		filepath.Join(dir, "modules/foo/main.tf"),
+	}
+	if !reflect.DeepEqual(modified, want) {
+		t.Errorf("got: %v, want: %v", modified, want)
+	}
+
+	for _, path := range want {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %s", err)
+		}
+		if strings.Contains(string(b), "moved") {
+			t.Errorf("expected moved blocks to be removed from %s, but got: %s", path, string(b))
+		}
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "main.tf"))
+	if err != nil {
+		t.Fatalf("failed to read file: %s", err)
+	}
+	if !strings.Contains(string(b), `resource "aws_instance" "bar" {}`) {
+		t.Errorf("expected other blocks to be kept, but got: %s", string(b))
+	}
+
+	moved, err := LoadMovedBlocks(dir)
+	if err != nil {
+		t.Fatalf("failed to load moved blocks: %s", err)
+	}
+	if len(moved) != 0 {
+		t.Errorf("expected no moved blocks, but got: %#v", moved)
+	}
+}
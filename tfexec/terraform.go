@@ -83,6 +83,10 @@ type TerraformCLI interface {
 	// their provider requirements.
 	Providers(ctx context.Context) (string, error)
 
+	// Show returns a human-readable or machine-readable representation of a plan.
+	// If a plan is given, use it for the input plan.
+	Show(ctx context.Context, plan *Plan, opts ...string) (string, error)
+
 	// StateList shows a list of resources.
 	// If a state is given, use it for the input state.
 	StateList(ctx context.Context, state *State, addresses []string, opts ...string) ([]string, error)
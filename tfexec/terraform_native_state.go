@@ -0,0 +1,105 @@
+package tfexec
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+// nativeStateCLI implements the TerraformCLI interface.
+// It manipulates a given tfstate in-process for the state list, mv and rm
+// subcommands without spawning the terraform command, and falls back to the
+// embedded TerraformCLI for everything else.
+type nativeStateCLI struct {
+	// TerraformCLI is used for commands which cannot be handled natively.
+	TerraformCLI
+}
+
+var _ TerraformCLI = (*nativeStateCLI)(nil)
+
+// NewNativeStateCLI returns a TerraformCLI which handles the state list, mv
+// and rm subcommands natively if possible.
+// The native implementation is used only when a state is given in memory and
+// no options other than -backup= are given, because the other options such
+// as -state= and -lock= only make sense for the terraform command.
+func NewNativeStateCLI(tf TerraformCLI) TerraformCLI {
+	return &nativeStateCLI{
+		TerraformCLI: tf,
+	}
+}
+
+// StateList shows a list of resources.
+func (c *nativeStateCLI) StateList(ctx context.Context, state *State, addresses []string, opts ...string) ([]string, error) {
+	if state == nil || len(opts) > 0 {
+		return c.TerraformCLI.StateList(ctx, state, addresses, opts...)
+	}
+
+	s, err := tfstate.ParseState(state.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	return s.List(addresses)
+}
+
+// StateMv moves resources from source to destination address.
+// Moving resources to another state is delegated to the terraform command.
+func (c *nativeStateCLI) StateMv(ctx context.Context, state *State, stateOut *State, source string, destination string, opts ...string) (*State, *State, error) {
+	if state == nil || stateOut != nil || !onlyBackupOptions(opts) {
+		return c.TerraformCLI.StateMv(ctx, state, stateOut, source, destination, opts...)
+	}
+
+	log.Printf("[DEBUG] [tfexec] native state mv %s %s\n", source, destination)
+	s, err := tfstate.ParseState(state.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := s.Move(source, destination); err != nil {
+		return nil, nil, err
+	}
+
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return NewState(b), nil, nil
+}
+
+// StateRm removes resources from state.
+func (c *nativeStateCLI) StateRm(ctx context.Context, state *State, addresses []string, opts ...string) (*State, error) {
+	if state == nil || !onlyBackupOptions(opts) {
+		return c.TerraformCLI.StateRm(ctx, state, addresses, opts...)
+	}
+
+	log.Printf("[DEBUG] [tfexec] native state rm %s\n", strings.Join(addresses, " "))
+	s, err := tfstate.ParseState(state.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Remove(addresses); err != nil {
+		return nil, err
+	}
+
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewState(b), nil
+}
+
+// onlyBackupOptions returns true if given options contain only -backup=.
+// Since we never write a backup file in-process, it can be safely ignored.
+func onlyBackupOptions(opts []string) bool {
+	for _, opt := range opts {
+		if !strings.HasPrefix(opt, "-backup=") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,127 @@
+package tfexec
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// testNativeState is a tfstate for testing the nativeStateCLI.
+const testNativeState = `{
+  "version": 4,
+  "terraform_version": "1.9.0",
+  "serial": 1,
+  "lineage": "de2ea2c2-e0e2-4c8e-9a5f-6b0e7f8c0e2a",
+  "outputs": {},
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "foo"
+          }
+        }
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "bar",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "bar"
+          }
+        }
+      ]
+    }
+  ],
+  "check_results": null
+}
+`
+
+func TestNativeStateCLIStateMvAndRm(t *testing.T) {
+	// The native implementation never calls the terraform command.
+	e := NewMockExecutor([]*mockCommand{})
+	terraformCLI := NewNativeStateCLI(NewTerraformCLI(e))
+	state := NewState([]byte(testNativeState))
+
+	updated, stateOut, err := terraformCLI.StateMv(context.Background(), state, nil, "null_resource.foo", "null_resource.baz", "-backup=/dev/null")
+	if err != nil {
+		t.Fatalf("failed to run state mv: %s", err)
+	}
+	if stateOut != nil {
+		t.Errorf("expected stateOut to be nil, but got: %v", stateOut)
+	}
+
+	updated, err = terraformCLI.StateRm(context.Background(), updated, []string{"null_resource.bar"}, "-backup=/dev/null")
+	if err != nil {
+		t.Fatalf("failed to run state rm: %s", err)
+	}
+
+	got, err := terraformCLI.StateList(context.Background(), updated, nil)
+	if err != nil {
+		t.Fatalf("failed to run state list: %s", err)
+	}
+
+	want := []string{"null_resource.baz"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v, want: %v", got, want)
+	}
+}
+
+func TestNativeStateCLIFallback(t *testing.T) {
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		state        *State
+		opts         []string
+	}{
+		{
+			desc: "no state",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "rm", "-backup=/dev/null", "null_resource.foo"},
+					exitCode: 0,
+				},
+			},
+			state: nil,
+			opts:  []string{"-backup=/dev/null"},
+		},
+		{
+			desc: "with opts",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "state", "rm", "-state=/path/to/tempfile", "-lock=false", "null_resource.foo"},
+					argsRe:   regexp.MustCompile(`^terraform state rm -state=.+ -lock=false null_resource.foo$`),
+					exitCode: 0,
+				},
+			},
+			state: NewState([]byte(testNativeState)),
+			opts:  []string{"-lock=false"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewNativeStateCLI(NewTerraformCLI(e))
+			terraformCLI.SetExecPath("terraform")
+			_, err := terraformCLI.StateRm(context.Background(), tc.state, []string{"null_resource.foo"}, tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if e.(*mockExecutor).runCalls != 1 {
+				t.Errorf("expected to call the terraform command once, but got: %d", e.(*mockExecutor).runCalls)
+			}
+		})
+	}
+}
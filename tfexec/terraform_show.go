@@ -0,0 +1,29 @@
+package tfexec
+
+import (
+	"context"
+	"os"
+)
+
+// Show returns a human-readable or machine-readable representation of a plan.
+// If a plan is given, write it to a temporary file and show it.
+func (c *terraformCLI) Show(ctx context.Context, plan *Plan, opts ...string) (string, error) {
+	args := []string{"show"}
+	args = append(args, opts...)
+
+	if plan != nil {
+		tmpPlan, err := writeTempFile(plan.Bytes())
+		defer os.Remove(tmpPlan.Name())
+		if err != nil {
+			return "", err
+		}
+		args = append(args, tmpPlan.Name())
+	}
+
+	stdout, _, err := c.Run(ctx, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return stdout, nil
+}
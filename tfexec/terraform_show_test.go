@@ -0,0 +1,69 @@
+package tfexec
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestTerraformCLIShow(t *testing.T) {
+	plan := NewPlan([]byte("dummy plan"))
+	planJSON := `{"format_version":"1.2","resource_changes":[]}`
+
+	cases := []struct {
+		desc         string
+		mockCommands []*mockCommand
+		plan         *Plan
+		opts         []string
+		want         string
+		ok           bool
+	}{
+		{
+			desc: "with plan",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "show", "-json", "/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform show -json \S+$`),
+					stdout:   planJSON,
+					exitCode: 0,
+				},
+			},
+			plan: plan,
+			opts: []string{"-json"},
+			want: planJSON,
+			ok:   true,
+		},
+		{
+			desc: "failed to run terraform show",
+			mockCommands: []*mockCommand{
+				{
+					args:     []string{"terraform", "show", "-json", "/path/to/planfile"},
+					argsRe:   regexp.MustCompile(`^terraform show -json \S+$`),
+					exitCode: 1,
+				},
+			},
+			plan: plan,
+			opts: []string{"-json"},
+			want: "",
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := NewMockExecutor(tc.mockCommands)
+			terraformCLI := NewTerraformCLI(e)
+			terraformCLI.SetExecPath("terraform")
+			got, err := terraformCLI.Show(context.Background(), tc.plan, tc.opts...)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.ok && got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package tfmigrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// validPlanChangeActions is a list of action names of resource changes which
+// can be used in allow_changes rules.
+var validPlanChangeActions = []string{"create", "read", "update", "delete", "replace"}
+
+// AllowChangeRule is a rule which allows specific changes in terraform plan.
+// A change is allowed if it matches all the conditions of the rule.
+type AllowChangeRule struct {
+	// ResourceTypes is a list of glob patterns for resource types.
+	// (e.g.) `aws_*`
+	// If empty, the rule matches any resource type.
+	ResourceTypes []string `hcl:"resource_types,optional"`
+	// Actions is a list of allowed actions.
+	// Valid values are create, read, update, delete and replace.
+	Actions []string `hcl:"actions"`
+	// Attributes is a list of glob patterns for top-level attribute names
+	// which are allowed to change. It only applies to the update action.
+	// If empty, changes of any attributes are allowed.
+	Attributes []string `hcl:"attributes,optional"`
+}
+
+// Validate checks if the rule is valid.
+func (r *AllowChangeRule) Validate() error {
+	if len(r.Actions) == 0 {
+		return fmt.Errorf("allow_changes requires at least one action")
+	}
+	for _, a := range r.Actions {
+		if !containsString(validPlanChangeActions, a) {
+			return fmt.Errorf("unknown action in allow_changes: %s, valid actions are %s", a, strings.Join(validPlanChangeActions, ", "))
+		}
+	}
+	for _, p := range append(append([]string{}, r.ResourceTypes...), r.Attributes...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern in allow_changes: %s", p)
+		}
+	}
+	return nil
+}
+
+// allows returns true if a given change matches the rule.
+func (r *AllowChangeRule) allows(c *planChange) bool {
+	if !containsString(r.Actions, c.action) {
+		return false
+	}
+	if len(r.ResourceTypes) > 0 && !matchAnyPattern(r.ResourceTypes, c.resourceType) {
+		return false
+	}
+	if c.action == "update" && len(r.Attributes) > 0 {
+		for _, attr := range c.attributes {
+			if !matchAnyPattern(r.Attributes, attr) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// planChange is a summary of a resource change in terraform plan.
+type planChange struct {
+	// address is an address of the resource instance.
+	address string
+	// resourceType is a type of the resource.
+	resourceType string
+	// action is a name of the change action.
+	action string
+	// attributes is a sorted list of changed top-level attribute names.
+	// It is only set for the update action.
+	attributes []string
+	// values is a map of top-level attribute values known at plan time.
+	// It is the values before the change for the delete action, and the
+	// values after the change for the create action.
+	values map[string]interface{}
+}
+
+// String returns a concise summary of the change.
+// (e.g.) `aws_instance.foo (update: tags, tags_all)`
+func (c *planChange) String() string {
+	if len(c.attributes) == 0 {
+		return fmt.Sprintf("%s (%s)", c.address, c.action)
+	}
+	return fmt.Sprintf("%s (%s: %s)", c.address, c.action, strings.Join(c.attributes, ", "))
+}
+
+// parsePlanChanges parses the output of terraform show -json for a plan and
+// returns a list of resource changes except for no-op.
+func parsePlanChanges(planJSON []byte) ([]*planChange, error) {
+	var plan struct {
+		ResourceChanges []struct {
+			Address string `json:"address"`
+			Type    string `json:"type"`
+			Change  struct {
+				Actions      []string               `json:"actions"`
+				Before       map[string]interface{} `json:"before"`
+				After        map[string]interface{} `json:"after"`
+				AfterUnknown map[string]interface{} `json:"after_unknown"`
+			} `json:"change"`
+		} `json:"resource_changes"`
+	}
+	if err := json.Unmarshal(planJSON, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan json: %s", err)
+	}
+
+	changes := []*planChange{}
+	for _, rc := range plan.ResourceChanges {
+		action := planChangeAction(rc.Change.Actions)
+		if action == "no-op" {
+			continue
+		}
+		c := &planChange{
+			address:      rc.Address,
+			resourceType: rc.Type,
+			action:       action,
+		}
+		switch action {
+		case "update":
+			c.attributes = changedAttributes(rc.Change.Before, rc.Change.After, rc.Change.AfterUnknown)
+		case "delete":
+			c.values = rc.Change.Before
+		case "create":
+			c.values = rc.Change.After
+		}
+		changes = append(changes, c)
+	}
+
+	return changes, nil
+}
+
+// planChangeAction converts a list of actions in plan json to a single
+// action name. A pair of delete and create is converted to replace.
+func planChangeAction(actions []string) string {
+	if len(actions) == 2 && containsString(actions, "delete") && containsString(actions, "create") {
+		return "replace"
+	}
+	return strings.Join(actions, ",")
+}
+
+// changedAttributes returns a sorted list of top-level attribute names which
+// differ between before and after, or will be known after apply.
+func changedAttributes(before, after, afterUnknown map[string]interface{}) []string {
+	keys := make(map[string]bool)
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+
+	attrs := []string{}
+	for k := range keys {
+		if unknown, ok := afterUnknown[k]; ok && unknown != false {
+			attrs = append(attrs, k)
+			continue
+		}
+		if !reflect.DeepEqual(before[k], after[k]) {
+			attrs = append(attrs, k)
+		}
+	}
+	sort.Strings(attrs)
+	return attrs
+}
+
+// verifyPlanChanges shows a given plan as json and checks if all resource
+// changes are allowed by any of given rules. It logs a summary of changes and
+// returns an error if some changes are not allowed.
+func verifyPlanChanges(ctx context.Context, tf tfexec.TerraformCLI, plan *tfexec.Plan, rules []AllowChangeRule) error {
+	planJSON, err := tf.Show(ctx, plan, "-json", "-no-color")
+	if err != nil {
+		return err
+	}
+
+	changes, err := parsePlanChanges([]byte(planJSON))
+	if err != nil {
+		return err
+	}
+
+	violations := []string{}
+	for _, c := range changes {
+		if allowedByAny(rules, c) {
+			log.Printf("[INFO] [migrator@%s] allowed change: %s\n", tf.Dir(), c)
+		} else {
+			log.Printf("[ERROR] [migrator@%s] change not allowed: %s\n", tf.Dir(), c)
+			violations = append(violations, c.String())
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("terraform plan command returns changes not allowed by allow_changes: %s", strings.Join(violations, ", "))
+	}
+	return nil
+}
+
+// allowedByAny returns true if a given change is allowed by any of rules.
+func allowedByAny(rules []AllowChangeRule, c *planChange) bool {
+	for _, r := range rules {
+		if r.allows(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchAnyPattern returns true if a given name matches any of glob patterns.
+func matchAnyPattern(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString returns true if a given list contains a given string.
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
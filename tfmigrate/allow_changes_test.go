@@ -0,0 +1,183 @@
+package tfmigrate
+
+import (
+	"testing"
+)
+
+// testPlanJSON is an output of terraform show -json for testing.
+const testPlanJSON = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "aws_instance.foo",
+      "type": "aws_instance",
+      "change": {
+        "actions": ["update"],
+        "before": {"ami": "ami-1", "tags": {"Name": "foo"}, "tags_all": {"Name": "foo"}},
+        "after": {"ami": "ami-1", "tags": {"Name": "foo2"}, "tags_all": {"Name": "foo2"}},
+        "after_unknown": {"tags": false, "tags_all": false}
+      }
+    },
+    {
+      "address": "aws_instance.bar",
+      "type": "aws_instance",
+      "change": {
+        "actions": ["update"],
+        "before": {"ami": "ami-1", "arn": "arn-1"},
+        "after": {"ami": "ami-2"},
+        "after_unknown": {"arn": true}
+      }
+    },
+    {
+      "address": "aws_s3_bucket.baz",
+      "type": "aws_s3_bucket",
+      "change": {
+        "actions": ["delete", "create"],
+        "before": {"bucket": "baz"},
+        "after": {"bucket": "baz2"},
+        "after_unknown": {}
+      }
+    },
+    {
+      "address": "null_resource.qux",
+      "type": "null_resource",
+      "change": {
+        "actions": ["no-op"],
+        "before": {},
+        "after": {},
+        "after_unknown": {}
+      }
+    }
+  ]
+}`
+
+func TestParsePlanChanges(t *testing.T) {
+	changes, err := parsePlanChanges([]byte(testPlanJSON))
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got := []string{}
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"aws_instance.foo (update: tags, tags_all)",
+		"aws_instance.bar (update: ami, arn)",
+		"aws_s3_bucket.baz (replace)",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got: %v, want: %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+}
+
+func TestAllowChangeRuleAllows(t *testing.T) {
+	changes, err := parsePlanChanges([]byte(testPlanJSON))
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	cases := []struct {
+		desc string
+		rule AllowChangeRule
+		want []bool
+	}{
+		{
+			desc: "update tags for aws_*",
+			rule: AllowChangeRule{
+				ResourceTypes: []string{"aws_*"},
+				Actions:       []string{"update"},
+				Attributes:    []string{"tags", "tags_all"},
+			},
+			want: []bool{true, false, false},
+		},
+		{
+			desc: "update any attributes for any resources",
+			rule: AllowChangeRule{
+				Actions: []string{"update"},
+			},
+			want: []bool{true, true, false},
+		},
+		{
+			desc: "replace for aws_s3_bucket",
+			rule: AllowChangeRule{
+				ResourceTypes: []string{"aws_s3_bucket"},
+				Actions:       []string{"replace"},
+			},
+			want: []bool{false, false, true},
+		},
+		{
+			desc: "delete does not match replace",
+			rule: AllowChangeRule{
+				Actions: []string{"delete"},
+			},
+			want: []bool{false, false, false},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			for i, c := range changes {
+				got := tc.rule.allows(c)
+				if got != tc.want[i] {
+					t.Errorf("%s: got: %t, want: %t", c, got, tc.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestAllowChangeRuleValidate(t *testing.T) {
+	cases := []struct {
+		desc string
+		rule AllowChangeRule
+		ok   bool
+	}{
+		{
+			desc: "valid",
+			rule: AllowChangeRule{
+				ResourceTypes: []string{"aws_*"},
+				Actions:       []string{"update", "create"},
+				Attributes:    []string{"tags*"},
+			},
+			ok: true,
+		},
+		{
+			desc: "no actions",
+			rule: AllowChangeRule{},
+			ok:   false,
+		},
+		{
+			desc: "unknown action",
+			rule: AllowChangeRule{
+				Actions: []string{"destroy"},
+			},
+			ok: false,
+		},
+		{
+			desc: "invalid pattern",
+			rule: AllowChangeRule{
+				ResourceTypes: []string{"aws_[*"},
+				Actions:       []string{"update"},
+			},
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.rule.Validate()
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package tfmigrate
+
+import (
+	"context"
+	"log"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// ApplyMetadata is a set of metadata about an applied migration.
+// It is intended to be recorded in history for auditing.
+type ApplyMetadata struct {
+	// ExecType is a type of executable, either terraform or opentofu.
+	ExecType string
+	// ExecVersion is a version of executable.
+	ExecVersion string
+	// Actions is a list of actions which were actually run.
+	// Actions containing wildcards such as xmv are expanded.
+	Actions []string
+	// States is a list of metadata for each affected state.
+	States []StateApplyMetadata
+}
+
+// StateApplyMetadata is a set of metadata about an affected state.
+type StateApplyMetadata struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// BeforeSerial is a serial of the state before applying the migration.
+	BeforeSerial int64
+	// AfterSerial is a serial of the state pushed by the migration.
+	AfterSerial int64
+}
+
+// ApplyMetadataProvider is an optional interface of Migrator which provides
+// metadata about the last applied migration.
+type ApplyMetadataProvider interface {
+	// ApplyMetadata returns metadata about the last applied migration.
+	// If the migration has not been applied successfully, it returns nil.
+	ApplyMetadata() *ApplyMetadata
+}
+
+// newStateApplyMetadata returns a new StateApplyMetadata from given states.
+// The metadata is only for auditing, so we don't want to fail the migration
+// even if it fails to parse states. In that case, it logs a warning and
+// leaves the serial as 0.
+func newStateApplyMetadata(tf tfexec.TerraformCLI, workspace string, before *tfexec.State, after *tfexec.State) StateApplyMetadata {
+	beforeIdentity, err := parseStateIdentity(before)
+	if err != nil {
+		log.Printf("[WARN] [migrator@%s] %s\n", tf.Dir(), err)
+	}
+
+	afterIdentity, err := parseStateIdentity(after)
+	if err != nil {
+		log.Printf("[WARN] [migrator@%s] %s\n", tf.Dir(), err)
+	}
+
+	return StateApplyMetadata{
+		Dir:          tf.Dir(),
+		Workspace:    workspace,
+		BeforeSerial: beforeIdentity.Serial,
+		AfterSerial:  afterIdentity.Serial,
+	}
+}
+
+// newApplyMetadata returns a new ApplyMetadata with a version of executable.
+// If it fails to get the version, it logs a warning and leaves it empty for
+// the same reason as newStateApplyMetadata.
+func newApplyMetadata(ctx context.Context, tf tfexec.TerraformCLI, actions []string, states []StateApplyMetadata) *ApplyMetadata {
+	md := &ApplyMetadata{
+		Actions: actions,
+		States:  states,
+	}
+
+	execType, version, err := tf.Version(ctx)
+	if err != nil {
+		log.Printf("[WARN] [migrator@%s] failed to get a version of executable: %s\n", tf.Dir(), err)
+		return md
+	}
+	md.ExecType = execType
+	md.ExecVersion = version.String()
+
+	return md
+}
@@ -0,0 +1,129 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+// StateSnapshot is a snapshot of a state before applying a migration.
+type StateSnapshot struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// State is a content of the state pulled from remote.
+	// It can be empty if the state didn't exist.
+	State *tfexec.State
+}
+
+// Backup is an interface which saves snapshots of states before pushing new
+// states to remote, so that we can restore them to undo a bad migration.
+type Backup interface {
+	// Save saves snapshots of states affected by a migration.
+	Save(ctx context.Context, snapshots []StateSnapshot) error
+}
+
+// saveSnapshots saves given snapshots if a backup is configured in a given
+// option. It is called before pushing new states to remote.
+func saveSnapshots(ctx context.Context, o *MigratorOption, snapshots []StateSnapshot) error {
+	if o == nil || o.Backup == nil {
+		return nil
+	}
+
+	log.Printf("[INFO] [migrator] save snapshots of states before push\n")
+	if err := o.Backup.Save(ctx, snapshots); err != nil {
+		return fmt.Errorf("failed to save snapshots of states: %s", err)
+	}
+	return nil
+}
+
+// RestoreSnapshot pushes a given snapshot back to remote state.
+// Since Terraform refuses to push a state with an older serial, it sets the
+// serial of the snapshot to the current remote serial + 1.
+// If the state didn't exist before the migration, it pushes a state which has
+// no resources, so that resources moved into the state by the migration are
+// not managed by two states after restoring the others.
+func RestoreSnapshot(ctx context.Context, snapshot StateSnapshot, o *MigratorOption) error {
+	tf, err := newRemoteTerraformCLI(ctx, snapshot.Dir, snapshot.Workspace, o)
+	if err != nil {
+		return err
+	}
+
+	return restoreSnapshot(ctx, tf, snapshot)
+}
+
+// restoreSnapshot pushes a given snapshot to remote state with a given
+// TerraformCLI. See RestoreSnapshot for details.
+func restoreSnapshot(ctx context.Context, tf tfexec.TerraformCLI, snapshot StateSnapshot) error {
+	log.Printf("[INFO] [migrator@%s] get the current remote state\n", tf.Dir())
+	currentState, err := tf.StatePull(ctx)
+	if err != nil {
+		return err
+	}
+
+	var restored *tfexec.State
+	if snapshot.State == nil || len(snapshot.State.Bytes()) == 0 {
+		if currentState == nil || len(currentState.Bytes()) == 0 {
+			log.Printf("[INFO] [migrator@%s] skip restoring an empty state in workspace %s, the remote state is also empty\n", tf.Dir(), snapshot.Workspace)
+			return nil
+		}
+		log.Printf("[INFO] [migrator@%s] the state didn't exist before the migration, remove all resources from the remote state\n", tf.Dir())
+		restored, err = emptyState(currentState)
+	} else {
+		restored, err = bumpSerial(snapshot.State, currentState)
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] [migrator@%s] push the snapshot to remote\n", tf.Dir())
+	return tf.StatePush(ctx, restored)
+}
+
+// emptyState returns a copy of a given current state which has no resources
+// and a serial greater than the current one. The lineage is kept so that
+// Terraform accepts it.
+func emptyState(current *tfexec.State) (*tfexec.State, error) {
+	s, err := tfstate.ParseState(current.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	serial, err := s.Serial()
+	if err != nil {
+		return nil, err
+	}
+	empty := s.WithoutResources()
+	empty.SetSerial(serial + 1)
+
+	b, err := empty.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return tfexec.NewState(b), nil
+}
+
+// bumpSerial returns a copy of a given snapshot whose serial is greater than
+// the one of a given current state.
+func bumpSerial(snapshot *tfexec.State, current *tfexec.State) (*tfexec.State, error) {
+	currentIdentity, err := parseStateIdentity(current)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := tfstate.ParseState(snapshot.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	s.SetSerial(uint64(currentIdentity.Serial) + 1)
+
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return tfexec.NewState(b), nil
+}
@@ -0,0 +1,260 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+// mockBackup is a mock implementation of the Backup interface for testing.
+type mockBackup struct {
+	snapshots []StateSnapshot
+	err       error
+}
+
+// Save records given snapshots.
+func (b *mockBackup) Save(_ context.Context, snapshots []StateSnapshot) error {
+	if b.err != nil {
+		return b.err
+	}
+	b.snapshots = snapshots
+	return nil
+}
+
+func TestSaveSnapshots(t *testing.T) {
+	snapshots := []StateSnapshot{
+		{Dir: "foo", Workspace: "default", State: tfexec.NewState([]byte(`{"serial":1}`))},
+	}
+
+	cases := []struct {
+		desc   string
+		backup *mockBackup
+		want   int
+		ok     bool
+	}{
+		{
+			desc:   "no backup",
+			backup: nil,
+			want:   0,
+			ok:     true,
+		},
+		{
+			desc:   "saved",
+			backup: &mockBackup{},
+			want:   1,
+			ok:     true,
+		},
+		{
+			desc:   "error",
+			backup: &mockBackup{err: fmt.Errorf("failed to write")},
+			want:   0,
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			o := &MigratorOption{}
+			if tc.backup != nil {
+				o.Backup = tc.backup
+			}
+			err := saveSnapshots(context.Background(), o, snapshots)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if tc.backup != nil && len(tc.backup.snapshots) != tc.want {
+				t.Errorf("got: %d snapshots, want: %d", len(tc.backup.snapshots), tc.want)
+			}
+		})
+	}
+}
+
+func TestBumpSerial(t *testing.T) {
+	cases := []struct {
+		desc     string
+		snapshot string
+		current  string
+		want     int64
+		ok       bool
+	}{
+		{
+			desc:     "bump",
+			snapshot: `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			current:  `{"version": 4, "serial": 5, "lineage": "foo", "resources": []}`,
+			want:     6,
+			ok:       true,
+		},
+		{
+			desc:     "current state is empty",
+			snapshot: `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			current:  ``,
+			want:     1,
+			ok:       true,
+		},
+		{
+			desc:     "invalid snapshot",
+			snapshot: `{"version": 3, "serial": 3, "modules": []}`,
+			current:  `{"version": 4, "serial": 5, "lineage": "foo", "resources": []}`,
+			want:     0,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := bumpSerial(tfexec.NewState([]byte(tc.snapshot)), tfexec.NewState([]byte(tc.current)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %s", string(got.Bytes()))
+				}
+				return
+			}
+			identity, err := parseStateIdentity(got)
+			if err != nil {
+				t.Fatalf("failed to get serial: %s", err)
+			}
+			if identity.Serial != tc.want {
+				t.Errorf("got: %d, want: %d", identity.Serial, tc.want)
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	cases := []struct {
+		desc     string
+		snapshot *tfexec.State
+		current  *tfexec.State
+		// pushed is true if a state is expected to be pushed.
+		pushed bool
+		// serial is an expected serial of the pushed state.
+		serial uint64
+		// empty is true if the pushed state is expected to have no resources.
+		empty bool
+		ok    bool
+	}{
+		{
+			desc:     "restore a snapshot",
+			snapshot: tfexec.NewState([]byte(`{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`)),
+			current:  tfexec.NewState([]byte(`{"version": 4, "serial": 5, "lineage": "foo", "resources": []}`)),
+			pushed:   true,
+			serial:   6,
+			empty:    true,
+			ok:       true,
+		},
+		{
+			desc:     "to_dir state didn't exist before the migration",
+			snapshot: tfexec.NewState([]byte{}),
+			current:  tfexec.NewState([]byte(testRestoreCurrentState)),
+			pushed:   true,
+			serial:   8,
+			empty:    true,
+			ok:       true,
+		},
+		{
+			desc:     "nil snapshot",
+			snapshot: nil,
+			current:  tfexec.NewState([]byte(testRestoreCurrentState)),
+			pushed:   true,
+			serial:   8,
+			empty:    true,
+			ok:       true,
+		},
+		{
+			desc:     "both are empty",
+			snapshot: tfexec.NewState([]byte{}),
+			current:  tfexec.NewState([]byte{}),
+			pushed:   false,
+			ok:       true,
+		},
+		{
+			desc:     "invalid current state",
+			snapshot: tfexec.NewState([]byte{}),
+			current:  tfexec.NewState([]byte(`{"version": 3, "serial": 3, "modules": []}`)),
+			pushed:   false,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tf := &pushRecorder{TerraformCLI: &pullStub{state: tc.current}, dir: "dir2"}
+			snapshot := StateSnapshot{Dir: "dir2", Workspace: "default", State: tc.snapshot}
+			err := restoreSnapshot(context.Background(), tf, snapshot)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if !tc.pushed {
+				if len(tf.pushed) != 0 {
+					t.Fatalf("expected no push, but got: %d", len(tf.pushed))
+				}
+				return
+			}
+			if len(tf.pushed) != 1 {
+				t.Fatalf("expected to push once, but got: %d", len(tf.pushed))
+			}
+			s, err := tfstate.ParseState(tf.pushed[0].Bytes())
+			if err != nil {
+				t.Fatalf("failed to parse the pushed state: %s", err)
+			}
+			serial, err := s.Serial()
+			if err != nil {
+				t.Fatalf("failed to get serial: %s", err)
+			}
+			if serial != tc.serial {
+				t.Errorf("got serial: %d, want: %d", serial, tc.serial)
+			}
+			list, err := s.List(nil)
+			if err != nil {
+				t.Fatalf("failed to list resources: %s", err)
+			}
+			if tc.empty && len(list) != 0 {
+				t.Errorf("expected no resources, but got: %v", list)
+			}
+			if !strings.Contains(string(tf.pushed[0].Bytes()), `"lineage": "foo"`) {
+				t.Errorf("expected the lineage to be kept, but got: %s", string(tf.pushed[0].Bytes()))
+			}
+		})
+	}
+}
+
+// testRestoreCurrentState is a remote state of to_dir after a migration which
+// moved a resource into it.
+const testRestoreCurrentState = `{
+  "version": 4,
+  "terraform_version": "1.9.0",
+  "serial": 7,
+  "lineage": "foo",
+  "outputs": {},
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "foo"
+          }
+        }
+      ]
+    }
+  ]
+}
+`
@@ -0,0 +1,173 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+const (
+	// ImportResultSuccess means that a row was imported successfully.
+	ImportResultSuccess = "success"
+	// ImportResultError means that a row failed to import.
+	ImportResultError = "error"
+	// ImportResultSkipped means that a row was not imported because another
+	// row failed.
+	ImportResultSkipped = "skipped"
+)
+
+// errImportCanceled is an error of a batch which was stopped because another
+// batch failed.
+var errImportCanceled = fmt.Errorf("import was canceled because another import failed")
+
+// ImportResult is a result of importing a row of an import manifest.
+type ImportResult struct {
+	// Address is an address to import resource to.
+	Address string
+	// ID is a resource identifier to be imported.
+	ID string
+	// Result is either success, error or skipped.
+	Result string
+	// Error is an error message if the row failed to import.
+	Error string
+}
+
+// bulkImport imports resources for given import actions to a given state and
+// returns a new state with a result for each action in the same order.
+// If parallelism is greater than 1, actions are split into that number of
+// batches, and each batch is imported concurrently into a separate temporary
+// state which has no resources. They are merged into the given state after
+// all batches succeed. Once any action fails, the remaining actions are
+// skipped and it returns an error.
+func bulkImport(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, imports []*StateImportAction, parallelism int) (*tfexec.State, []ImportResult, error) {
+	results := make([]ImportResult, len(imports))
+	for i, a := range imports {
+		results[i] = ImportResult{Address: a.address, ID: a.id, Result: ImportResultSkipped}
+	}
+
+	var failed atomic.Bool
+	if parallelism <= 1 || len(imports) <= 1 {
+		newState, err := importBatch(ctx, tf, state, imports, results, &failed)
+		if err != nil {
+			return nil, results, err
+		}
+		return newState, results, nil
+	}
+
+	base, err := stateWithoutResources(state)
+	if err != nil {
+		return nil, results, err
+	}
+
+	batchSize := (len(imports) + parallelism - 1) / parallelism
+	batches := (len(imports) + batchSize - 1) / batchSize
+	batchStates := make([]*tfexec.State, batches)
+	batchErrs := make([]error, batches)
+	var wg sync.WaitGroup
+	for i := 0; i < batches; i++ {
+		start := i * batchSize
+		end := min(start+batchSize, len(imports))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			batchStates[i], batchErrs[i] = importBatch(ctx, tf, base, imports[start:end], results[start:end], &failed)
+		}()
+	}
+	wg.Wait()
+
+	// return an error of the failed row rather than the canceled ones.
+	for _, err := range batchErrs {
+		if err != nil && err != errImportCanceled {
+			return nil, results, err
+		}
+	}
+	if failed.Load() {
+		return nil, results, errImportCanceled
+	}
+
+	log.Printf("[INFO] [migrator@%s] merge %d imported states\n", tf.Dir(), len(batchStates))
+	newState, err := mergeStates(state, batchStates)
+	if err != nil {
+		return nil, results, err
+	}
+	return newState, results, nil
+}
+
+// importBatch imports resources for given import actions to a given state
+// sequentially, and sets a result for each action to results with the same
+// index. It stops when another batch has failed.
+func importBatch(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, imports []*StateImportAction, results []ImportResult, failed *atomic.Bool) (*tfexec.State, error) {
+	for i, a := range imports {
+		if failed.Load() {
+			return nil, errImportCanceled
+		}
+
+		newState, err := a.StateUpdate(ctx, tf, state)
+		if err != nil {
+			failed.Store(true)
+			results[i].Result = ImportResultError
+			results[i].Error = err.Error()
+			log.Printf("[ERROR] [migrator@%s] failed to import %s: %s\n", tf.Dir(), a.address, err)
+			return nil, fmt.Errorf("failed to import %s with id %s: %s", a.address, a.id, err)
+		}
+		results[i].Result = ImportResultSuccess
+		log.Printf("[INFO] [migrator@%s] imported %s\n", tf.Dir(), a.address)
+		state = tfexec.NewState(newState.Bytes())
+	}
+	return state, nil
+}
+
+// stateWithoutResources returns a copy of a given state which has no
+// resources. An empty state is returned as it is.
+func stateWithoutResources(state *tfexec.State) (*tfexec.State, error) {
+	if len(state.Bytes()) == 0 {
+		return state, nil
+	}
+
+	s, err := tfstate.ParseState(state.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.WithoutResources().Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return tfexec.NewState(b), nil
+}
+
+// mergeStates merges resources in given states into a base state and returns
+// a new state. If the base state is empty, the first state is used as a base.
+func mergeStates(base *tfexec.State, states []*tfexec.State) (*tfexec.State, error) {
+	if len(base.Bytes()) == 0 {
+		if len(states) == 0 {
+			return base, nil
+		}
+		base, states = states[0], states[1:]
+	}
+
+	s, err := tfstate.ParseState(base.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range states {
+		other, err := tfstate.ParseState(state.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.Merge(other); err != nil {
+			return nil, err
+		}
+	}
+
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	return tfexec.NewState(b), nil
+}
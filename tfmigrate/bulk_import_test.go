@@ -0,0 +1,168 @@
+package tfmigrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+// importStub is a TerraformCLI which imports a resource by appending it to a
+// given state for testing. It overrides only Import and Dir.
+// The address must be in the form of `<type>.<name>` in the root module.
+// It fails if the id is `fail`.
+type importStub struct {
+	tfexec.TerraformCLI
+}
+
+// Import appends a resource to a given state.
+func (tf *importStub) Import(_ context.Context, state *tfexec.State, address string, id string, _ ...string) (*tfexec.State, error) {
+	if id == "fail" {
+		return nil, fmt.Errorf("failed to import %s", address)
+	}
+
+	s := map[string]interface{}{
+		"version":   4,
+		"serial":    0,
+		"lineage":   "test",
+		"resources": []interface{}{},
+	}
+	if len(state.Bytes()) > 0 {
+		if err := json.Unmarshal(state.Bytes(), &s); err != nil {
+			return nil, err
+		}
+	}
+
+	typ, name, _ := strings.Cut(address, ".")
+	s["resources"] = append(s["resources"].([]interface{}), map[string]interface{}{
+		"mode":     "managed",
+		"type":     typ,
+		"name":     name,
+		"provider": `provider["registry.terraform.io/hashicorp/null"]`,
+		"instances": []interface{}{
+			map[string]interface{}{"attributes": map[string]interface{}{"id": id}},
+		},
+	})
+
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return tfexec.NewState(b), nil
+}
+
+// Dir returns a working directory.
+func (tf *importStub) Dir() string {
+	return "dir1"
+}
+
+func TestBulkImport(t *testing.T) {
+	cases := []struct {
+		desc        string
+		state       string
+		ids         []string
+		parallelism int
+		want        []string
+		wantResults []string
+		ok          bool
+	}{
+		{
+			desc:        "sequential",
+			state:       `{"version": 4, "serial": 1, "lineage": "test", "resources": []}`,
+			ids:         []string{"a", "b", "c"},
+			parallelism: 0,
+			want:        []string{"null_resource.r0", "null_resource.r1", "null_resource.r2"},
+			wantResults: []string{ImportResultSuccess, ImportResultSuccess, ImportResultSuccess},
+			ok:          true,
+		},
+		{
+			desc:        "parallel",
+			state:       `{"version": 4, "serial": 1, "lineage": "test", "resources": []}`,
+			ids:         []string{"a", "b", "c", "d", "e"},
+			parallelism: 2,
+			want:        []string{"null_resource.r0", "null_resource.r1", "null_resource.r2", "null_resource.r3", "null_resource.r4"},
+			wantResults: []string{ImportResultSuccess, ImportResultSuccess, ImportResultSuccess, ImportResultSuccess, ImportResultSuccess},
+			ok:          true,
+		},
+		{
+			desc:        "parallel into an empty state",
+			state:       "",
+			ids:         []string{"a", "b", "c"},
+			parallelism: 3,
+			want:        []string{"null_resource.r0", "null_resource.r1", "null_resource.r2"},
+			wantResults: []string{ImportResultSuccess, ImportResultSuccess, ImportResultSuccess},
+			ok:          true,
+		},
+		{
+			desc:        "sequential failure",
+			state:       `{"version": 4, "serial": 1, "lineage": "test", "resources": []}`,
+			ids:         []string{"a", "fail", "c"},
+			parallelism: 1,
+			want:        nil,
+			wantResults: []string{ImportResultSuccess, ImportResultError, ImportResultSkipped},
+			ok:          false,
+		},
+		{
+			desc:        "parallel failure",
+			state:       `{"version": 4, "serial": 1, "lineage": "test", "resources": []}`,
+			ids:         []string{"fail", "b"},
+			parallelism: 2,
+			want:        nil,
+			wantResults: nil,
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			imports := []*StateImportAction{}
+			for i, id := range tc.ids {
+				imports = append(imports, NewStateImportAction(fmt.Sprintf("null_resource.r%d", i), id))
+			}
+
+			got, results, err := bulkImport(context.Background(), &importStub{}, tfexec.NewState([]byte(tc.state)), imports, tc.parallelism)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", string(got.Bytes()))
+			}
+
+			if len(results) != len(tc.ids) {
+				t.Fatalf("got %d results, want: %d", len(results), len(tc.ids))
+			}
+			gotResults := []string{}
+			for _, r := range results {
+				gotResults = append(gotResults, r.Result)
+			}
+			if tc.wantResults != nil && !reflect.DeepEqual(gotResults, tc.wantResults) {
+				t.Errorf("got results: %v, want: %v", gotResults, tc.wantResults)
+			}
+			for i, id := range tc.ids {
+				if id == "fail" && (results[i].Result != ImportResultError || results[i].Error == "") {
+					t.Errorf("got result of a failed row: %#v", results[i])
+				}
+			}
+
+			if !tc.ok {
+				return
+			}
+			s, err := tfstate.ParseState(got.Bytes())
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+			list, err := s.List(nil)
+			if err != nil {
+				t.Fatalf("failed to list state: %s", err)
+			}
+			if !reflect.DeepEqual(list, tc.want) {
+				t.Errorf("got: %v, want: %v", list, tc.want)
+			}
+		})
+	}
+}
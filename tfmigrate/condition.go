@@ -0,0 +1,196 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+const (
+	// conditionStateFrom means that a condition is evaluated against a state
+	// in from_dir of a multi state migration.
+	conditionStateFrom = "from"
+	// conditionStateTo means that a condition is evaluated against a state
+	// in to_dir of a multi state migration.
+	conditionStateTo = "to"
+)
+
+// StateCondition is a set of assertions on resources in a state.
+// It is used for precondition and postcondition blocks of a migration.
+// A precondition is evaluated against the state pulled from remote before
+// any actions, and a postcondition is evaluated against the computed state
+// before terraform plan.
+// All patterns can contain wildcards `*` and must match a whole address.
+// A pattern without an instance key also matches instances of the resource.
+// (e.g.) `aws_instance.foo` matches `aws_instance.foo[0]`
+type StateCondition struct {
+	// State is a state which the condition is evaluated against.
+	// Valid values are from and to. It is required for a multi state
+	// migration, and must be empty for a single state migration.
+	State string `hcl:"state,optional"`
+	// ResourceExists is a list of patterns, each of which must match at least
+	// one resource in the state.
+	ResourceExists []string `hcl:"resource_exists,optional"`
+	// ResourceAbsent is a list of patterns, each of which must not match any
+	// resources in the state.
+	ResourceAbsent []string `hcl:"resource_absent,optional"`
+	// ResourceCount is a list of assertions on the number of resources
+	// matching a pattern.
+	ResourceCount []ResourceCountCondition `hcl:"resource_count,block"`
+}
+
+// ResourceCountCondition is an assertion on the number of resources matching
+// a pattern.
+type ResourceCountCondition struct {
+	// Pattern is a pattern of resources to be counted.
+	Pattern string `hcl:"pattern"`
+	// Equals is an expected number of resources matching the pattern.
+	Equals int `hcl:"equals"`
+}
+
+// Validate checks if the condition is valid.
+// If multi is true, the condition is for a multi state migration and
+// requires the state attribute.
+func (c *StateCondition) Validate(multi bool) error {
+	if multi {
+		if c.State != conditionStateFrom && c.State != conditionStateTo {
+			return fmt.Errorf("condition requires state to be either %s or %s: %q", conditionStateFrom, conditionStateTo, c.State)
+		}
+	} else if len(c.State) > 0 {
+		return fmt.Errorf("condition of a single state migration must not have state: %s", c.State)
+	}
+
+	if len(c.ResourceExists) == 0 && len(c.ResourceAbsent) == 0 && len(c.ResourceCount) == 0 {
+		return fmt.Errorf("condition requires at least one of resource_exists, resource_absent and resource_count")
+	}
+
+	patterns := append(append([]string{}, c.ResourceExists...), c.ResourceAbsent...)
+	for _, rc := range c.ResourceCount {
+		if rc.Equals < 0 {
+			return fmt.Errorf("resource_count %s must not expect a negative number: %d", rc.Pattern, rc.Equals)
+		}
+		patterns = append(patterns, rc.Pattern)
+	}
+	for _, p := range patterns {
+		if len(p) == 0 {
+			return fmt.Errorf("condition has an empty pattern")
+		}
+		if _, err := makeResourcePatternRegex(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluate returns a list of failed assertions for a given list of addresses.
+// It returns an empty list if all assertions are satisfied.
+func (c *StateCondition) evaluate(stateList []string) ([]string, error) {
+	failures := []string{}
+	for _, p := range c.ResourceExists {
+		matched, err := matchConditionPattern(p, stateList)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) == 0 {
+			failures = append(failures, fmt.Sprintf("resource_exists %s matches no resources", p))
+		}
+	}
+	for _, p := range c.ResourceAbsent {
+		matched, err := matchConditionPattern(p, stateList)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) > 0 {
+			failures = append(failures, fmt.Sprintf("resource_absent %s matches resources: %v", p, matched))
+		}
+	}
+	for _, rc := range c.ResourceCount {
+		matched, err := matchConditionPattern(rc.Pattern, stateList)
+		if err != nil {
+			return nil, err
+		}
+		if len(matched) != rc.Equals {
+			failures = append(failures, fmt.Sprintf("resource_count %s expected %d resources, but got %d", rc.Pattern, rc.Equals, len(matched)))
+		}
+	}
+	return failures, nil
+}
+
+// matchConditionPattern returns a list of addresses in a given stateList
+// matching a given pattern. An address matches if either the whole address
+// or the address without its instance key matches the pattern.
+func matchConditionPattern(pattern string, stateList []string) ([]string, error) {
+	re, err := makeResourcePatternRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := []string{}
+	for _, addr := range stateList {
+		if matchConditionAddress(re, addr) {
+			matched = append(matched, addr)
+		}
+	}
+	return matched, nil
+}
+
+// matchConditionAddress returns true if a given address matches a given
+// regex with or without its instance key.
+func matchConditionAddress(re *regexp.Regexp, addr string) bool {
+	if re.MatchString(addr) {
+		return true
+	}
+
+	a, err := tfstate.ParseAddress(addr)
+	if err != nil || !a.IsInstance() {
+		return false
+	}
+	return re.MatchString(a.ResourcePath())
+}
+
+// filterConditions returns a list of conditions for a given state of a multi
+// state migration.
+func filterConditions(conditions []StateCondition, state string) []StateCondition {
+	filtered := []StateCondition{}
+	for _, c := range conditions {
+		if c.State == state {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// checkStateConditions evaluates given conditions against a given state.
+// The kind is either precondition or postcondition, which is used for
+// messages. All failed assertions are reported in a single error.
+func checkStateConditions(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, conditions []StateCondition, kind string) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	log.Printf("[INFO] [migrator@%s] check %ss\n", tf.Dir(), kind)
+	stateList, err := tf.StateList(ctx, state, nil)
+	if err != nil {
+		return err
+	}
+
+	failures := []string{}
+	for _, c := range conditions {
+		f, err := c.evaluate(stateList)
+		if err != nil {
+			return err
+		}
+		failures = append(failures, f...)
+	}
+
+	if len(failures) > 0 {
+		log.Printf("[ERROR] [migrator@%s] %s failed\n", tf.Dir(), kind)
+		return fmt.Errorf("%s failed in %s: %s", kind, tf.Dir(), strings.Join(failures, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestStateConditionValidate(t *testing.T) {
+	cases := []struct {
+		desc  string
+		cond  StateCondition
+		multi bool
+		ok    bool
+	}{
+		{
+			desc: "single state",
+			cond: StateCondition{
+				ResourceExists: []string{"aws_instance.foo"},
+				ResourceAbsent: []string{"aws_instance.bar"},
+				ResourceCount:  []ResourceCountCondition{{Pattern: "module.x.*", Equals: 12}},
+			},
+			multi: false,
+			ok:    true,
+		},
+		{
+			desc:  "single state with state",
+			cond:  StateCondition{State: "from", ResourceExists: []string{"aws_instance.foo"}},
+			multi: false,
+			ok:    false,
+		},
+		{
+			desc:  "multi state",
+			cond:  StateCondition{State: "to", ResourceAbsent: []string{"aws_instance.foo"}},
+			multi: true,
+			ok:    true,
+		},
+		{
+			desc:  "multi state without state",
+			cond:  StateCondition{ResourceExists: []string{"aws_instance.foo"}},
+			multi: true,
+			ok:    false,
+		},
+		{
+			desc:  "multi state with unknown state",
+			cond:  StateCondition{State: "*", ResourceExists: []string{"aws_instance.foo"}},
+			multi: true,
+			ok:    false,
+		},
+		{
+			desc:  "no assertions",
+			cond:  StateCondition{},
+			multi: false,
+			ok:    false,
+		},
+		{
+			desc:  "empty pattern",
+			cond:  StateCondition{ResourceExists: []string{""}},
+			multi: false,
+			ok:    false,
+		},
+		{
+			desc:  "negative count",
+			cond:  StateCondition{ResourceCount: []ResourceCountCondition{{Pattern: "aws_instance.*", Equals: -1}}},
+			multi: false,
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.cond.Validate(tc.multi)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
+
+func TestStateConditionEvaluate(t *testing.T) {
+	stateList := []string{
+		"aws_instance.foo[0]",
+		"aws_instance.foo[1]",
+		`aws_iam_user.bar["a"]`,
+		"module.x.aws_s3_bucket.baz",
+		"module.x.aws_s3_bucket.qux",
+	}
+	cases := []struct {
+		desc string
+		cond StateCondition
+		want []string
+	}{
+		{
+			desc: "satisfied",
+			cond: StateCondition{
+				ResourceExists: []string{"aws_instance.foo", `aws_iam_user.bar["a"]`, "module.x.*"},
+				ResourceAbsent: []string{"aws_instance.foo2", "aws_instance.foo[2]", "module.y.*"},
+				ResourceCount: []ResourceCountCondition{
+					{Pattern: "aws_instance.foo", Equals: 2},
+					{Pattern: "module.x.*", Equals: 2},
+					{Pattern: "module.y.*", Equals: 0},
+				},
+			},
+			want: []string{},
+		},
+		{
+			desc: "failed",
+			cond: StateCondition{
+				ResourceExists: []string{"aws_instance.foo2"},
+				ResourceAbsent: []string{"module.x.*"},
+				ResourceCount:  []ResourceCountCondition{{Pattern: "aws_*", Equals: 2}},
+			},
+			want: []string{
+				"resource_exists aws_instance.foo2 matches no resources",
+				"resource_absent module.x.* matches resources: [module.x.aws_s3_bucket.baz module.x.aws_s3_bucket.qux]",
+				"resource_count aws_* expected 2 resources, but got 3",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.cond.evaluate(stateList)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckStateConditions(t *testing.T) {
+	tf := &stateListStub{dir: "dir1", list: []string{"aws_instance.foo", "aws_instance.bar"}}
+	cases := []struct {
+		desc       string
+		conditions []StateCondition
+		want       string
+	}{
+		{
+			desc:       "no conditions",
+			conditions: []StateCondition{},
+			want:       "",
+		},
+		{
+			desc: "satisfied",
+			conditions: []StateCondition{
+				{ResourceExists: []string{"aws_instance.foo"}},
+				{ResourceCount: []ResourceCountCondition{{Pattern: "aws_instance.*", Equals: 2}}},
+			},
+			want: "",
+		},
+		{
+			desc: "failed",
+			conditions: []StateCondition{
+				{ResourceAbsent: []string{"aws_instance.foo"}},
+				{ResourceExists: []string{"aws_instance.baz"}},
+			},
+			want: "precondition failed in dir1: resource_absent aws_instance.foo matches resources: [aws_instance.foo]; resource_exists aws_instance.baz matches no resources",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := checkStateConditions(context.Background(), tf, tfexec.NewState([]byte{}), tc.conditions, "precondition")
+			if tc.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected err: %s", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected to return an error, but no error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got: %s, want: %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestFilterConditions(t *testing.T) {
+	conditions := []StateCondition{
+		{State: "from", ResourceExists: []string{"aws_instance.foo"}},
+		{State: "to", ResourceAbsent: []string{"aws_instance.foo"}},
+		{State: "from", ResourceAbsent: []string{"aws_instance.bar"}},
+	}
+
+	got := filterConditions(conditions, "from")
+	want := []StateCondition{conditions[0], conditions[2]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
@@ -3,7 +3,7 @@ package tfmigrate
 // MigrationConfig is a config for a migration.
 type MigrationConfig struct {
 	// Type is a type for migration.
-	// Valid values are `state` and `multi_state`.
+	// Valid values are `state`, `multi_state`, `split` and `merge_state`.
 	Type string
 	// Name is an arbitrary name for migration.
 	Name string
@@ -34,4 +34,8 @@ type MigratorOption struct {
 
 	// BackendConfig is a -backend-config option for remote state
 	BackendConfig []string
+
+	// Backup saves snapshots of states before pushing new states to remote.
+	// This is optional. If nil, no snapshot is saved.
+	Backup Backup
 }
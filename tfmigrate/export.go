@@ -0,0 +1,187 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfconfig"
+)
+
+// ExportBlocks converts actions of the state migration into equivalent
+// configuration blocks, that is, mv to moved, rm to removed and import to
+// import blocks.
+// Since xmv actions with wildcards, rmv and xrm actions have no equivalent,
+// they are expanded against a given list of addresses in the current state.
+// The list is updated by each action in order, so that an action is expanded
+// against the state after applying the preceding actions.
+// If the stateList is nil, they are refused. The replace-provider actions are
+// always refused. Rows of the import manifest are converted into import blocks
+// after the actions.
+func (c *StateMigratorConfig) ExportBlocks(stateList []string) ([]tfconfig.Block, error) {
+	blocks := []tfconfig.Block{}
+	// current is a list of addresses after applying the preceding actions.
+	var current []string
+	if stateList != nil {
+		current = append([]string{}, stateList...)
+	}
+	for _, cmdStr := range c.Actions {
+		action, err := NewStateActionFromString(cmdStr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch a := action.(type) {
+		case *StateMvAction:
+			blocks = append(blocks, &tfconfig.MovedBlock{From: a.source, To: a.destination})
+			current = moveAddresses(current, a.source, a.destination)
+
+		case *StateXmvAction:
+			e := newXmvExpander(a)
+			if e.nrOfWildcards() > 0 && stateList == nil {
+				return nil, fmt.Errorf("an xmv action with wildcards cannot be exported without expanding it against the current state: %s", cmdStr)
+			}
+			expanded, err := e.expand(current)
+			if err != nil {
+				return nil, err
+			}
+			if len(expanded) == 0 {
+				log.Printf("[WARN] [migrator] no resources match an xmv action: %s\n", cmdStr)
+			}
+			for _, mv := range expanded {
+				blocks = append(blocks, &tfconfig.MovedBlock{From: mv.source, To: mv.destination})
+				current = moveAddresses(current, mv.source, mv.destination)
+			}
+
+		case *StateRmvAction:
+			if stateList == nil {
+				return nil, fmt.Errorf("an rmv action cannot be exported without expanding it against the current state: %s", cmdStr)
+			}
+			e, err := newRmvExpander(a.pattern, a.template)
+			if err != nil {
+				return nil, err
+			}
+			expanded, err := e.expand(current, current, true)
+			if err != nil {
+				return nil, err
+			}
+			if len(expanded) == 0 {
+				log.Printf("[WARN] [migrator] no resources match an rmv action: %s\n", cmdStr)
+			}
+			for _, mv := range expanded {
+				blocks = append(blocks, &tfconfig.MovedBlock{From: mv.source, To: mv.destination})
+				current = moveAddresses(current, mv.source, mv.destination)
+			}
+
+		case *StateRmAction:
+			for _, addr := range a.addresses {
+				blocks = append(blocks, &tfconfig.RemovedBlock{From: addr})
+				current = removeAddresses(current, addr)
+			}
+
+		case *StateXrmAction:
+			if stateList == nil {
+				return nil, fmt.Errorf("an xrm action cannot be exported without expanding it against the current state: %s", cmdStr)
+			}
+			addrs, err := a.expand(current)
+			if err != nil {
+				return nil, err
+			}
+			if len(addrs) == 0 {
+				log.Printf("[WARN] [migrator] no resources match an xrm action: %s\n", cmdStr)
+			}
+			for _, addr := range addrs {
+				blocks = append(blocks, &tfconfig.RemovedBlock{From: addr})
+				current = removeAddresses(current, addr)
+			}
+
+		case *StateImportAction:
+			blocks = append(blocks, &tfconfig.ImportBlock{To: a.address, ID: a.id})
+			if current != nil && !containsString(current, a.address) {
+				current = append(current, a.address)
+			}
+
+		default:
+			return nil, fmt.Errorf("an action has no configuration equivalent: %s", cmdStr)
+		}
+	}
+
+	if len(c.ImportFrom) > 0 {
+		imports, err := loadImportManifest(c.ImportFrom)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range imports {
+			blocks = append(blocks, &tfconfig.ImportBlock{To: a.address, ID: a.id})
+		}
+	}
+
+	return blocks, nil
+}
+
+// moveAddresses returns a copy of a given address list in which a source
+// address is moved to a destination in the same way as terraform state mv.
+// The source matches the address itself, its instances and resources in it.
+// If the list is nil, it returns nil.
+func moveAddresses(stateList []string, source string, destination string) []string {
+	if stateList == nil {
+		return nil
+	}
+
+	moved := make([]string, 0, len(stateList))
+	for _, addr := range stateList {
+		if suffix, ok := addressSuffix(addr, source); ok {
+			addr = destination + suffix
+		}
+		moved = append(moved, addr)
+	}
+	return moved
+}
+
+// removeAddresses returns a copy of a given address list without addresses
+// removed by a given address in the same way as terraform state rm.
+// If the list is nil, it returns nil.
+func removeAddresses(stateList []string, address string) []string {
+	if stateList == nil {
+		return nil
+	}
+
+	remains := make([]string, 0, len(stateList))
+	for _, addr := range stateList {
+		if _, ok := addressSuffix(addr, address); ok {
+			continue
+		}
+		remains = append(remains, addr)
+	}
+	return remains
+}
+
+// addressSuffix returns the rest of a given address after a given prefix if
+// the address is the prefix itself, its instance or a resource in it.
+// (e.g.) `module.a[0].aws_instance.foo` with `module.a` => `[0].aws_instance.foo`
+func addressSuffix(addr string, prefix string) (string, bool) {
+	if addr == prefix {
+		return "", true
+	}
+	if !strings.HasPrefix(addr, prefix) {
+		return "", false
+	}
+	suffix := addr[len(prefix):]
+	if strings.HasPrefix(suffix, "[") || strings.HasPrefix(suffix, ".") {
+		return suffix, true
+	}
+	return "", false
+}
+
+// ListRemoteState returns a list of addresses in a remote state of a given
+// directory and workspace.
+func ListRemoteState(ctx context.Context, dir string, workspace string, o *MigratorOption) ([]string, error) {
+	tf, err := newRemoteTerraformCLI(ctx, dir, workspace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] [migrator@%s] list resources in the remote state\n", tf.Dir())
+	return tf.StateList(ctx, nil, nil)
+}
@@ -0,0 +1,272 @@
+package tfmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfconfig"
+)
+
+func TestStateMigratorConfigExportBlocks(t *testing.T) {
+	cases := []struct {
+		desc      string
+		actions   []string
+		stateList []string
+		want      []tfconfig.Block
+		ok        bool
+	}{
+		{
+			desc: "mv, rm and import",
+			actions: []string{
+				"mv aws_security_group.foo aws_security_group.foo2",
+				"rm aws_security_group.bar aws_security_group.baz",
+				"import aws_security_group.qux sg-1234",
+			},
+			stateList: nil,
+			want: []tfconfig.Block{
+				&tfconfig.MovedBlock{From: "aws_security_group.foo", To: "aws_security_group.foo2"},
+				&tfconfig.RemovedBlock{From: "aws_security_group.bar"},
+				&tfconfig.RemovedBlock{From: "aws_security_group.baz"},
+				&tfconfig.ImportBlock{To: "aws_security_group.qux", ID: "sg-1234"},
+			},
+			ok: true,
+		},
+		{
+			desc: "xmv without wildcards",
+			actions: []string{
+				"xmv aws_security_group.foo aws_security_group.foo2",
+			},
+			stateList: nil,
+			want: []tfconfig.Block{
+				&tfconfig.MovedBlock{From: "aws_security_group.foo", To: "aws_security_group.foo2"},
+			},
+			ok: true,
+		},
+		{
+			desc: "xmv with wildcards not expanded",
+			actions: []string{
+				"xmv aws_security_group.* aws_security_group.${1}2",
+			},
+			stateList: nil,
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc: "xmv with wildcards expanded",
+			actions: []string{
+				"xmv aws_security_group.* aws_security_group.${1}2",
+			},
+			stateList: []string{"aws_security_group.foo", "aws_security_group.bar", "aws_instance.baz"},
+			want: []tfconfig.Block{
+				&tfconfig.MovedBlock{From: "aws_security_group.foo", To: "aws_security_group.foo2"},
+				&tfconfig.MovedBlock{From: "aws_security_group.bar", To: "aws_security_group.bar2"},
+			},
+			ok: true,
+		},
+		{
+			desc: "rmv not expanded",
+			actions: []string{
+				`rmv 'aws_security_group\.(\w+)' 'aws_security_group.${1}2'`,
+			},
+			stateList: nil,
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc: "rmv expanded",
+			actions: []string{
+				`rmv 'aws_security_group\.(?P<name>\w+)' 'aws_security_group.${upper(name)}'`,
+			},
+			stateList: []string{"aws_security_group.foo", "aws_instance.baz"},
+			want: []tfconfig.Block{
+				&tfconfig.MovedBlock{From: "aws_security_group.foo", To: "aws_security_group.FOO"},
+			},
+			ok: true,
+		},
+		{
+			desc: "xrm not expanded",
+			actions: []string{
+				"xrm aws_security_group.*",
+			},
+			stateList: nil,
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc: "xrm expanded",
+			actions: []string{
+				"xrm aws_security_group.* --expect 2",
+			},
+			stateList: []string{"aws_security_group.foo", "aws_security_group.bar", "aws_instance.baz"},
+			want: []tfconfig.Block{
+				&tfconfig.RemovedBlock{From: "aws_security_group.foo"},
+				&tfconfig.RemovedBlock{From: "aws_security_group.bar"},
+			},
+			ok: true,
+		},
+		{
+			desc: "expanded against the state after preceding actions",
+			actions: []string{
+				"mv aws_security_group.foo aws_security_group.tmp",
+				"xmv aws_security_group.* module.sg.aws_security_group.${1}",
+				"xrm module.sg.aws_security_group.bar",
+				`rmv 'module\.sg\.(?P<rest>.+)' '${rest}'`,
+				"rm aws_instance.baz",
+				"xrm aws_instance.*",
+			},
+			stateList: []string{"aws_security_group.foo", "aws_security_group.bar", "aws_instance.baz[0]", "aws_instance.baz[1]"},
+			want: []tfconfig.Block{
+				&tfconfig.MovedBlock{From: "aws_security_group.foo", To: "aws_security_group.tmp"},
+				&tfconfig.MovedBlock{From: "aws_security_group.tmp", To: "module.sg.aws_security_group.tmp"},
+				&tfconfig.MovedBlock{From: "aws_security_group.bar", To: "module.sg.aws_security_group.bar"},
+				&tfconfig.RemovedBlock{From: "module.sg.aws_security_group.bar"},
+				&tfconfig.MovedBlock{From: "module.sg.aws_security_group.tmp", To: "aws_security_group.tmp"},
+				&tfconfig.RemovedBlock{From: "aws_instance.baz"},
+			},
+			ok: true,
+		},
+		{
+			desc: "replace-provider",
+			actions: []string{
+				"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+			},
+			stateList: []string{},
+			want:      nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			c := &StateMigratorConfig{Actions: tc.actions}
+			got, err := c.ExportBlocks(tc.stateList)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok && !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMoveAndRemoveAddresses(t *testing.T) {
+	stateList := []string{
+		"aws_instance.foo",
+		"aws_instance.foo_bar",
+		"aws_instance.baz[0]",
+		`aws_instance.baz["a"]`,
+		"module.a[0].aws_instance.foo",
+		"module.ab.aws_instance.foo",
+	}
+	cases := []struct {
+		desc        string
+		source      string
+		destination string
+		moved       []string
+		removed     []string
+	}{
+		{
+			desc:        "resource",
+			source:      "aws_instance.foo",
+			destination: "aws_instance.qux",
+			moved: []string{
+				"aws_instance.qux",
+				"aws_instance.foo_bar",
+				"aws_instance.baz[0]",
+				`aws_instance.baz["a"]`,
+				"module.a[0].aws_instance.foo",
+				"module.ab.aws_instance.foo",
+			},
+			removed: []string{
+				"aws_instance.foo_bar",
+				"aws_instance.baz[0]",
+				`aws_instance.baz["a"]`,
+				"module.a[0].aws_instance.foo",
+				"module.ab.aws_instance.foo",
+			},
+		},
+		{
+			desc:        "instances",
+			source:      "aws_instance.baz",
+			destination: "aws_instance.qux",
+			moved: []string{
+				"aws_instance.foo",
+				"aws_instance.foo_bar",
+				"aws_instance.qux[0]",
+				`aws_instance.qux["a"]`,
+				"module.a[0].aws_instance.foo",
+				"module.ab.aws_instance.foo",
+			},
+			removed: []string{
+				"aws_instance.foo",
+				"aws_instance.foo_bar",
+				"module.a[0].aws_instance.foo",
+				"module.ab.aws_instance.foo",
+			},
+		},
+		{
+			desc:        "module",
+			source:      "module.a",
+			destination: "module.b",
+			moved: []string{
+				"aws_instance.foo",
+				"aws_instance.foo_bar",
+				"aws_instance.baz[0]",
+				`aws_instance.baz["a"]`,
+				"module.b[0].aws_instance.foo",
+				"module.ab.aws_instance.foo",
+			},
+			removed: []string{
+				"aws_instance.foo",
+				"aws_instance.foo_bar",
+				"aws_instance.baz[0]",
+				`aws_instance.baz["a"]`,
+				"module.ab.aws_instance.foo",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			moved := moveAddresses(stateList, tc.source, tc.destination)
+			if !reflect.DeepEqual(moved, tc.moved) {
+				t.Errorf("got moved: %#v, want: %#v", moved, tc.moved)
+			}
+			removed := removeAddresses(stateList, tc.source)
+			if !reflect.DeepEqual(removed, tc.removed) {
+				t.Errorf("got removed: %#v, want: %#v", removed, tc.removed)
+			}
+		})
+	}
+}
+
+func TestStateMigratorConfigExportBlocksWithImportFrom(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "imports.csv")
+	if err := os.WriteFile(manifest, []byte("address,id\naws_s3_bucket.foo,foo\naws_s3_bucket.bar,bar\n"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err)
+	}
+
+	c := &StateMigratorConfig{
+		Actions:    []string{"rm aws_s3_bucket.baz"},
+		ImportFrom: manifest,
+	}
+	got, err := c.ExportBlocks(nil)
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	want := []tfconfig.Block{
+		&tfconfig.RemovedBlock{From: "aws_s3_bucket.baz"},
+		&tfconfig.ImportBlock{To: "aws_s3_bucket.foo", ID: "foo"},
+		&tfconfig.ImportBlock{To: "aws_s3_bucket.bar", ID: "bar"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %#v, want: %#v", got, want)
+	}
+}
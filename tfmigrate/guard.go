@@ -0,0 +1,69 @@
+package tfmigrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// stateIdentity identifies a version of state.
+// Terraform increments the serial every time it writes the state, and the
+// lineage is assigned when the state is created.
+type stateIdentity struct {
+	// Lineage is a unique ID of the state assigned when it was created.
+	Lineage string `json:"lineage"`
+	// Serial is a sequence number incremented on every write.
+	Serial int64 `json:"serial"`
+}
+
+// String returns a string representation of the identity.
+func (i stateIdentity) String() string {
+	return fmt.Sprintf("lineage = %q, serial = %d", i.Lineage, i.Serial)
+}
+
+// parseStateIdentity returns an identity of a given state.
+// If the state is empty, it is assumed to be a new state and returns the
+// zero value.
+func parseStateIdentity(state *tfexec.State) (stateIdentity, error) {
+	var i stateIdentity
+	if state == nil || len(state.Bytes()) == 0 {
+		return i, nil
+	}
+
+	if err := json.Unmarshal(state.Bytes(), &i); err != nil {
+		return i, fmt.Errorf("failed to parse a lineage and serial of state: %s", err)
+	}
+	return i, nil
+}
+
+// ensureRemoteStateUnchanged pulls the remote state again and returns an
+// error if it has been changed since a given state was pulled.
+// It is intended to be called just before pushing a new state, so that we
+// never clobber a state written by someone else's terraform apply
+// concurrently while planning the migration.
+func ensureRemoteStateUnchanged(ctx context.Context, tf tfexec.TerraformCLI, pulled *tfexec.State) error {
+	before, err := parseStateIdentity(pulled)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] [migrator@%s] check if the remote state has not been changed\n", tf.Dir())
+	current, err := tf.StatePull(ctx)
+	if err != nil {
+		return err
+	}
+
+	after, err := parseStateIdentity(current)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[DEBUG] [migrator@%s] before: %s, current: %s\n", tf.Dir(), before, after)
+	if before != after {
+		return fmt.Errorf("the remote state in %s has been changed since it was pulled (pulled: %s, current: %s). Someone else may have updated the state concurrently. Please retry the migration", tf.Dir(), before, after)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// pullStub is a TerraformCLI which returns a given state on StatePull for
+// testing. Calling other methods except for Dir panics.
+type pullStub struct {
+	tfexec.TerraformCLI
+	// state is a state returned by StatePull.
+	state *tfexec.State
+	// err is an error returned by StatePull.
+	err error
+}
+
+// StatePull returns a given state or an error.
+func (tf *pullStub) StatePull(_ context.Context, _ ...string) (*tfexec.State, error) {
+	return tf.state, tf.err
+}
+
+// Dir returns a dummy working directory.
+func (tf *pullStub) Dir() string {
+	return "dir1"
+}
+
+func TestParseStateIdentity(t *testing.T) {
+	cases := []struct {
+		desc  string
+		state *tfexec.State
+		want  stateIdentity
+		ok    bool
+	}{
+		{
+			desc: "simple",
+			state: tfexec.NewState([]byte(`{
+  "version": 4,
+  "terraform_version": "1.7.5",
+  "serial": 3,
+  "lineage": "5c0fd9b1-5b3c-2f7c-4d1f-1f6f0a3b5a2e",
+  "outputs": {},
+  "resources": []
+}`)),
+			want: stateIdentity{Lineage: "5c0fd9b1-5b3c-2f7c-4d1f-1f6f0a3b5a2e", Serial: 3},
+			ok:   true,
+		},
+		{
+			desc:  "empty",
+			state: tfexec.NewState([]byte{}),
+			want:  stateIdentity{},
+			ok:    true,
+		},
+		{
+			desc:  "nil",
+			state: nil,
+			want:  stateIdentity{},
+			ok:    true,
+		},
+		{
+			desc:  "invalid",
+			state: tfexec.NewState([]byte(`{`)),
+			want:  stateIdentity{},
+			ok:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := parseStateIdentity(tc.state)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %s", got)
+			}
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEnsureRemoteStateUnchanged(t *testing.T) {
+	cases := []struct {
+		desc    string
+		pulled  string
+		current string
+		pullErr error
+		ok      bool
+	}{
+		{
+			desc:    "unchanged",
+			pulled:  `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			current: `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			ok:      true,
+		},
+		{
+			desc:    "both empty",
+			pulled:  ``,
+			current: ``,
+			ok:      true,
+		},
+		{
+			desc:    "serial changed",
+			pulled:  `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			current: `{"version": 4, "serial": 4, "lineage": "foo", "resources": []}`,
+			ok:      false,
+		},
+		{
+			desc:    "lineage changed",
+			pulled:  `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			current: `{"version": 4, "serial": 3, "lineage": "bar", "resources": []}`,
+			ok:      false,
+		},
+		{
+			desc:    "created concurrently",
+			pulled:  ``,
+			current: `{"version": 4, "serial": 1, "lineage": "foo", "resources": []}`,
+			ok:      false,
+		},
+		{
+			desc:    "failed to pull",
+			pulled:  `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			pullErr: fmt.Errorf("failed to pull"),
+			ok:      false,
+		},
+		{
+			desc:    "invalid state",
+			pulled:  `{"version": 4, "serial": 3, "lineage": "foo", "resources": []}`,
+			current: `foo`,
+			ok:      false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tf := &pullStub{state: tfexec.NewState([]byte(tc.current)), err: tc.pullErr}
+			err := ensureRemoteStateUnchanged(context.Background(), tf, tfexec.NewState([]byte(tc.pulled)))
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+		})
+	}
+}
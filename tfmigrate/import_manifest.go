@@ -0,0 +1,130 @@
+package tfmigrate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// importManifestRow is a row of an import manifest.
+type importManifestRow struct {
+	// Address is an address to import resource to.
+	Address string `json:"address"`
+	// ID is a resource identifier to be imported.
+	ID string `json:"id"`
+}
+
+// loadImportManifest reads an import manifest at a given path and returns a
+// list of import actions in the order of rows.
+// A relative path of import_from has already been resolved from the directory
+// of the migration file when parsing the migration file.
+// The format is detected by the file extension. Valid formats are:
+//
+// CSV (.csv): A header row with address and id columns is required.
+// Other columns are ignored.
+//
+//	address,id
+//	aws_s3_bucket.foo,foo
+//
+// JSON (.json): An array of objects with address and id keys.
+//
+//	[{"address": "aws_s3_bucket.foo", "id": "foo"}]
+func loadImportManifest(path string) ([]*StateImportAction, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import manifest: %s", err)
+	}
+
+	var rows []importManifestRow
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		rows, err = parseImportManifestCSV(b)
+	case ".json":
+		rows, err = parseImportManifestJSON(b)
+	default:
+		return nil, fmt.Errorf("unsupported format of import manifest: %s, valid extensions are .csv and .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse import manifest %s: %s", path, err)
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("import manifest has no rows: %s", path)
+	}
+
+	actions := make([]*StateImportAction, 0, len(rows))
+	seen := make(map[string]bool)
+	for i, row := range rows {
+		if len(row.Address) == 0 || len(row.ID) == 0 {
+			return nil, fmt.Errorf("row %d of import manifest %s requires both address and id", i+1, path)
+		}
+		if err := validateAddresses(row.Address); err != nil {
+			return nil, fmt.Errorf("row %d of import manifest %s has an invalid address: %s", i+1, path, err)
+		}
+		if seen[row.Address] {
+			return nil, fmt.Errorf("row %d of import manifest %s has a duplicated address: %s", i+1, path, row.Address)
+		}
+		seen[row.Address] = true
+		actions = append(actions, NewStateImportAction(row.Address, row.ID))
+	}
+
+	return actions, nil
+}
+
+// parseImportManifestCSV parses an import manifest in CSV format.
+func parseImportManifestCSV(b []byte) ([]importManifestRow, error) {
+	r := csv.NewReader(bytes.NewReader(b))
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return []importManifestRow{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	addressIndex, idIndex := -1, -1
+	for i, name := range header {
+		switch strings.TrimSpace(name) {
+		case "address":
+			addressIndex = i
+		case "id":
+			idIndex = i
+		}
+	}
+	if addressIndex < 0 || idIndex < 0 {
+		return nil, fmt.Errorf("header must have address and id columns: %s", strings.Join(header, ","))
+	}
+
+	rows := []importManifestRow{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, importManifestRow{
+			Address: strings.TrimSpace(record[addressIndex]),
+			ID:      strings.TrimSpace(record[idIndex]),
+		})
+	}
+
+	return rows, nil
+}
+
+// parseImportManifestJSON parses an import manifest in JSON format.
+func parseImportManifestJSON(b []byte) ([]importManifestRow, error) {
+	rows := []importManifestRow{}
+	if err := json.Unmarshal(b, &rows); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
@@ -0,0 +1,137 @@
+package tfmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadImportManifest(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filename string
+		content  string
+		want     []*StateImportAction
+		ok       bool
+	}{
+		{
+			desc:     "csv",
+			filename: "imports.csv",
+			content: `address,id
+aws_s3_bucket.foo,foo
+"aws_s3_bucket.bar[""x""]", bar
+`,
+			want: []*StateImportAction{
+				NewStateImportAction("aws_s3_bucket.foo", "foo"),
+				NewStateImportAction(`aws_s3_bucket.bar["x"]`, "bar"),
+			},
+			ok: true,
+		},
+		{
+			desc:     "csv with extra columns in any order",
+			filename: "imports.CSV",
+			content: `id,note,address
+foo,a bucket,aws_s3_bucket.foo
+`,
+			want: []*StateImportAction{
+				NewStateImportAction("aws_s3_bucket.foo", "foo"),
+			},
+			ok: true,
+		},
+		{
+			desc:     "csv without header",
+			filename: "imports.csv",
+			content: `aws_s3_bucket.foo,foo
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:     "json",
+			filename: "imports.json",
+			content:  `[{"address": "aws_s3_bucket.foo", "id": "foo"}, {"address": "module.m.aws_s3_bucket.bar", "id": "bar"}]`,
+			want: []*StateImportAction{
+				NewStateImportAction("aws_s3_bucket.foo", "foo"),
+				NewStateImportAction("module.m.aws_s3_bucket.bar", "bar"),
+			},
+			ok: true,
+		},
+		{
+			desc:     "invalid json",
+			filename: "imports.json",
+			content:  `{"address": "aws_s3_bucket.foo", "id": "foo"}`,
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc:     "no rows",
+			filename: "imports.json",
+			content:  `[]`,
+			want:     nil,
+			ok:       false,
+		},
+		{
+			desc:     "missing id",
+			filename: "imports.csv",
+			content: `address,id
+aws_s3_bucket.foo,
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:     "invalid address",
+			filename: "imports.csv",
+			content: `address,id
+aws_s3_bucket,foo
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:     "duplicated address",
+			filename: "imports.csv",
+			content: `address,id
+aws_s3_bucket.foo,foo
+aws_s3_bucket.foo,bar
+`,
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc:     "unsupported format",
+			filename: "imports.yaml",
+			content:  `- address: aws_s3_bucket.foo`,
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tc.filename)
+			if err := os.WriteFile(path, []byte(tc.content), 0644); err != nil {
+				t.Fatalf("failed to write manifest: %s", err)
+			}
+
+			got, err := loadImportManifest(path)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoadImportManifestNotFound(t *testing.T) {
+	_, err := loadImportManifest(filepath.Join(t.TempDir(), "not_found.csv"))
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
@@ -0,0 +1,421 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+// MergeStateMigratorConfig is a config for MergeStateMigrator.
+type MergeStateMigratorConfig struct {
+	// From is a list of named sources where states of resources move from.
+	From []MergeSourceConfig `hcl:"from,block"`
+	// ToDir is a working directory where states of resources move to.
+	ToDir string `hcl:"to_dir"`
+	// ToWorkspace is a workspace within ToDir.
+	ToWorkspace string `hcl:"to_workspace,optional"`
+	// ToSkipPlan controls whether or not to run and analyze Terraform plan
+	// within the to_dir.
+	ToSkipPlan bool `hcl:"to_skip_plan,optional"`
+	// Force option controls behaviour in case of unexpected diff in plan.
+	// When set forces applying even if plan shows diff.
+	Force bool `hcl:"force,optional"`
+}
+
+// MergeSourceConfig is a config for a named source of merge_state migration.
+type MergeSourceConfig struct {
+	// Name is a name of source.
+	Name string `hcl:"name,label"`
+	// Dir is a working directory where states of resources move from.
+	Dir string `hcl:"dir"`
+	// Workspace is a workspace within Dir.
+	Workspace string `hcl:"workspace,optional"`
+	// SkipPlan controls whether or not to run and analyze Terraform plan
+	// within the dir.
+	SkipPlan bool `hcl:"skip_plan,optional"`
+	// Module is an optional module address prefixed to addresses of resources
+	// moved from the source. (e.g.) `module.dev`
+	Module string `hcl:"module,optional"`
+	// Resources is a list of address patterns of resources to be moved.
+	// A wildcard `*` matches any characters.
+	// If empty, all managed resources are moved.
+	Resources []string `hcl:"resources,optional"`
+}
+
+// MergeStateMigratorConfig implements a MigratorConfig.
+var _ MigratorConfig = (*MergeStateMigratorConfig)(nil)
+
+// NewMigrator returns a new instance of MergeStateMigrator.
+func (c *MergeStateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
+	if len(c.From) == 0 {
+		return nil, fmt.Errorf("failed to NewMigrator with no sources")
+	}
+
+	// Since a working directory is switched to a local backend during plan,
+	// each directory can be used only once.
+	names := make(map[string]bool)
+	dirs := map[string]bool{filepath.Clean(c.ToDir): true}
+	for _, from := range c.From {
+		if err := validateMergeSource(from); err != nil {
+			return nil, err
+		}
+		if names[from.Name] {
+			return nil, fmt.Errorf("duplicate name of source: %s", from.Name)
+		}
+		names[from.Name] = true
+		dir := filepath.Clean(from.Dir)
+		if dirs[dir] {
+			return nil, fmt.Errorf("dir is used more than once in merge_state migration: %s", from.Dir)
+		}
+		dirs[dir] = true
+	}
+
+	// use default workspace if not specified by user
+	if len(c.ToWorkspace) == 0 {
+		c.ToWorkspace = "default"
+	}
+	for i := range c.From {
+		if len(c.From[i].Workspace) == 0 {
+			c.From[i].Workspace = "default"
+		}
+	}
+
+	return NewMergeStateMigrator(c.From, c.ToDir, c.ToWorkspace, c.ToSkipPlan, o, c.Force), nil
+}
+
+// validateMergeSource checks if a given source config is valid.
+func validateMergeSource(from MergeSourceConfig) error {
+	if !splitDestinationNameRe.MatchString(from.Name) {
+		return fmt.Errorf("invalid name of source: %s", from.Name)
+	}
+	if len(from.Module) > 0 {
+		a, err := tfstate.ParseAddress(from.Module)
+		if err != nil || !a.IsModule() {
+			return fmt.Errorf("invalid module of source %s: %s", from.Name, from.Module)
+		}
+	}
+	for _, p := range from.Resources {
+		if _, err := makeResourcePatternRegex(p); err != nil {
+			return fmt.Errorf("invalid resources of source %s: %s", from.Name, err)
+		}
+	}
+	return nil
+}
+
+// mergeSource is a named source of MergeStateMigrator.
+type mergeSource struct {
+	// name is a name of source.
+	name string
+	// workDir is a working directory of the source.
+	*workDir
+	// module is a module address prefixed to addresses of moved resources.
+	module string
+	// resources is a list of address patterns of resources to be moved.
+	resources []string
+}
+
+// destination returns a new address of a given address in the source.
+func (s *mergeSource) destination(addr string) string {
+	if len(s.module) == 0 {
+		return addr
+	}
+	return s.module + "." + addr
+}
+
+// matches returns true if a given address should be moved.
+// Data resources are never moved because they are read on every plan.
+func (s *mergeSource) matches(addr string) (bool, error) {
+	a, err := tfstate.ParseAddress(addr)
+	if err != nil {
+		return false, err
+	}
+	if a.Mode == "data" {
+		return false, nil
+	}
+	if len(s.resources) == 0 {
+		return true, nil
+	}
+	for _, p := range s.resources {
+		re, err := makeResourcePatternRegex(p)
+		if err != nil {
+			return false, err
+		}
+		if re.MatchString(addr) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MergeStateMigrator implements the Migrator interface.
+// It moves resources from multiple named sources to a single destination.
+type MergeStateMigrator struct {
+	// sources is a list of sources in the order of config.
+	sources []*mergeSource
+	// to is a working directory where states of resources move to.
+	to *workDir
+	// o is an option for migrator.
+	// It is used for shared settings across Migrator instances.
+	o *MigratorOption
+	// force operation in case of unexpected diff
+	force bool
+	// expandedActions is a list of actions actually run, which is set by plan.
+	expandedActions []string
+	// metadata is metadata about the last applied migration.
+	metadata *ApplyMetadata
+}
+
+var _ Migrator = (*MergeStateMigrator)(nil)
+var _ ApplyMetadataProvider = (*MergeStateMigrator)(nil)
+var _ ReportProvider = (*MergeStateMigrator)(nil)
+
+// NewMergeStateMigrator returns a new MergeStateMigrator instance.
+func NewMergeStateMigrator(from []MergeSourceConfig, toDir string, toWorkspace string, toSkipPlan bool,
+	o *MigratorOption, force bool) *MergeStateMigrator {
+	sources := make([]*mergeSource, 0, len(from))
+	for _, s := range from {
+		sources = append(sources, &mergeSource{
+			name:      s.Name,
+			workDir:   newWorkDir(s.Dir, s.Workspace, s.SkipPlan, fmt.Sprintf("from %q", s.Name), o),
+			module:    s.Module,
+			resources: s.Resources,
+		})
+	}
+
+	return &MergeStateMigrator{
+		sources: sources,
+		to:      newWorkDir(toDir, toWorkspace, toSkipPlan, "to_dir", o),
+		o:       o,
+		force:   force,
+	}
+}
+
+// mergeMove is a move of a resource from a source to the destination.
+type mergeMove struct {
+	// source is a source of the resource.
+	source *mergeSource
+	// action is a multi state mv action of the resource.
+	action *MultiStateMvAction
+}
+
+// String returns a string representation of the move.
+// The source address is prefixed with a name of the source.
+func (m *mergeMove) String() string {
+	return FormatAction("mv", m.source.name+":"+m.action.source, m.action.destination)
+}
+
+// plan computes new states by moving resources from sources to the
+// destination in temporary states. Each working directory is initialized
+// only once. It will fail if addresses of moved resources collide, or if
+// terraform plan detects any diffs with at least one new state.
+func (m *MergeStateMigrator) plan(ctx context.Context) (toCurrentState *tfexec.State, err error) {
+	// setup toDir.
+	toCurrentState, toSwitchBackToRemoteFunc, err := m.to.setup(ctx, m.o, false)
+	if err != nil {
+		return nil, err
+	}
+	// switch back it to remote on exit.
+	defer func() {
+		err = multierror.Append(err, toSwitchBackToRemoteFunc())
+	}()
+
+	m.expandedActions = []string{}
+
+	// setup dirs of sources.
+	fromCurrentStates := make(map[string]*tfexec.State)
+	for _, s := range m.sources {
+		var fromCurrentState *tfexec.State
+		var switchBackToRemoteFunc func() error
+		fromCurrentState, switchBackToRemoteFunc, err = s.setup(ctx, m.o, false)
+		if err != nil {
+			return nil, err
+		}
+		// switch back it to remote on exit.
+		defer func() {
+			err = multierror.Append(err, switchBackToRemoteFunc())
+		}()
+
+		fromCurrentStates[s.name] = fromCurrentState
+	}
+
+	// list moves and detect collisions before updating any states.
+	moves, err := m.listMoves(ctx, toCurrentState, fromCurrentStates)
+	if err != nil {
+		return nil, err
+	}
+
+	// computes new states by applying state migration operations to temporary states.
+	log.Printf("[INFO] [migrator] compute new states (%d sources => %s)\n", len(m.sources), m.to.tf.Dir())
+	for _, mv := range moves {
+		s := mv.source
+		fromNewState, toNewState, err := mv.action.MultiStateUpdate(ctx, s.tf, m.to.tf, fromCurrentStates[s.name], toCurrentState)
+		if err != nil {
+			return nil, err
+		}
+		fromCurrentStates[s.name] = tfexec.NewState(fromNewState.Bytes())
+		toCurrentState = tfexec.NewState(toNewState.Bytes())
+		m.expandedActions = append(m.expandedActions, mv.String())
+	}
+	m.to.afterState = toCurrentState
+	for _, s := range m.sources {
+		s.afterState = fromCurrentStates[s.name]
+	}
+
+	if err := m.to.checkPlan(ctx, m.force, m.o.PlanOut, nil); err != nil {
+		return nil, err
+	}
+	for _, s := range m.sources {
+		if err := s.checkPlan(ctx, m.force, m.o.PlanOut, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return toCurrentState, nil
+}
+
+// listMoves returns a list of moves from all sources to the destination.
+// It returns an error if any of destination addresses already exist in the
+// destination state or collide with each other.
+func (m *MergeStateMigrator) listMoves(ctx context.Context, toState *tfexec.State, fromStates map[string]*tfexec.State) ([]*mergeMove, error) {
+	toList, err := m.to.tf.StateList(ctx, toState, nil)
+	if err != nil {
+		return nil, err
+	}
+	// owners is a map of destination address to its owner for collision detection.
+	owners := make(map[string]string)
+	for _, addr := range toList {
+		owners[addr] = "to_dir"
+	}
+
+	moves := []*mergeMove{}
+	collisions := []string{}
+	for _, s := range m.sources {
+		fromList, err := s.tf.StateList(ctx, fromStates[s.name], nil)
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(fromList)
+		for _, addr := range fromList {
+			ok, err := s.matches(addr)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				log.Printf("[DEBUG] [migrator@%s] skip %s\n", s.tf.Dir(), addr)
+				continue
+			}
+			dst := s.destination(addr)
+			if owner, ok := owners[dst]; ok {
+				collisions = append(collisions, fmt.Sprintf("%s (from %q and %s)", dst, s.name, owner))
+				continue
+			}
+			owners[dst] = fmt.Sprintf("from %q", s.name)
+			moves = append(moves, &mergeMove{source: s, action: NewMultiStateMvAction(addr, dst)})
+		}
+	}
+
+	if len(collisions) > 0 {
+		return nil, fmt.Errorf("addresses collide in merge_state migration: %s", strings.Join(collisions, ", "))
+	}
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("no resources to move found in sources")
+	}
+	return moves, nil
+}
+
+// Plan computes new states by moving resources from sources to the
+// destination in temporary states.
+// It will fail if terraform plan detects any diffs with at least one new state.
+func (m *MergeStateMigrator) Plan(ctx context.Context) error {
+	log.Printf("[INFO] [migrator] start merge state migrator plan\n")
+	_, err := m.plan(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] [migrator] merge state migrator plan success!\n")
+	return nil
+}
+
+// Apply computes new states and pushes them to remote states.
+// It will fail if terraform plan detects any diffs with at least one new state.
+// We are intended to this is used for state refactoring.
+// Any state migration operations should not break any real resources.
+func (m *MergeStateMigrator) Apply(ctx context.Context) error {
+	// Check if new states don't have any diffs compared to real resources
+	// before push new states to remote.
+	log.Printf("[INFO] [migrator] start merge state migrator plan phase for apply\n")
+	if _, err := m.plan(ctx); err != nil {
+		return err
+	}
+
+	// push the new states to remote.
+	log.Printf("[INFO] [migrator] start merge state migrator apply phase\n")
+	if err := ensureRemoteStateUnchanged(ctx, m.to.tf, m.to.beforeState); err != nil {
+		return err
+	}
+	for _, s := range m.sources {
+		if err := ensureRemoteStateUnchanged(ctx, s.tf, s.beforeState); err != nil {
+			return err
+		}
+	}
+
+	snapshots := []StateSnapshot{m.to.snapshot()}
+	for _, s := range m.sources {
+		snapshots = append(snapshots, s.snapshot())
+	}
+	if err := saveSnapshots(ctx, m.o, snapshots); err != nil {
+		return err
+	}
+
+	if err := m.push(ctx); err != nil {
+		return err
+	}
+
+	states := []StateApplyMetadata{m.to.applyMetadata()}
+	for _, s := range m.sources {
+		states = append(states, s.applyMetadata())
+	}
+	m.metadata = newApplyMetadata(ctx, m.to.tf, m.expandedActions, states)
+
+	log.Printf("[INFO] [migrator] merge state migrator apply success!\n")
+	return nil
+}
+
+// push pushes new states to remote.
+// We push the state of the destination before states of sources, because
+// when moving resources across states, write them to new state first and
+// then remove them from old ones. If it fails to push a state, it rolls back
+// the states already pushed not to leave the resources in multiple states.
+func (m *MergeStateMigrator) push(ctx context.Context) error {
+	updates := []*stateUpdate{m.to.stateUpdate()}
+	for _, s := range m.sources {
+		updates = append(updates, s.stateUpdate())
+	}
+
+	return pushStates(ctx, updates)
+}
+
+// ApplyMetadata returns metadata about the last applied migration.
+// If the migration has not been applied successfully, it returns nil.
+func (m *MergeStateMigrator) ApplyMetadata() *ApplyMetadata {
+	return m.metadata
+}
+
+// Report returns a summary of the last plan or apply.
+func (m *MergeStateMigrator) Report() *Report {
+	states := []StateReport{m.to.report()}
+	for _, s := range m.sources {
+		states = append(states, s.report())
+	}
+	return &Report{
+		Actions: m.expandedActions,
+		States:  states,
+	}
+}
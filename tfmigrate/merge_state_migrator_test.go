@@ -0,0 +1,269 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestMergeStateMigratorConfigNewMigrator(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *MergeStateMigratorConfig
+		o      *MigratorOption
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			config: &MergeStateMigratorConfig{
+				From: []MergeSourceConfig{
+					{Name: "dev", Dir: "dir1", Module: "module.dev"},
+					{Name: "prod", Dir: "dir2", Workspace: "work1", Resources: []string{"aws_s3_bucket.*"}},
+				},
+				ToDir: "dir3",
+			},
+			o: &MigratorOption{
+				ExecPath: "direnv exec . terraform",
+			},
+			ok: true,
+		},
+		{
+			desc: "no sources",
+			config: &MergeStateMigratorConfig{
+				ToDir: "dir3",
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "duplicate source name",
+			config: &MergeStateMigratorConfig{
+				From: []MergeSourceConfig{
+					{Name: "dev", Dir: "dir1"},
+					{Name: "dev", Dir: "dir2"},
+				},
+				ToDir: "dir3",
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "dir used more than once",
+			config: &MergeStateMigratorConfig{
+				From: []MergeSourceConfig{
+					{Name: "dev", Dir: "dir1"},
+					{Name: "prod", Dir: "dir3/"},
+				},
+				ToDir: "dir3",
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "invalid module",
+			config: &MergeStateMigratorConfig{
+				From: []MergeSourceConfig{
+					{Name: "dev", Dir: "dir1", Module: "aws_s3_bucket.foo"},
+				},
+				ToDir: "dir3",
+			},
+			o:  nil,
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.config.NewMigrator(tc.o)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				_ = got.(*MergeStateMigrator)
+			}
+		})
+	}
+}
+
+// stateListStub is a TerraformCLI which returns a fixed list of resources
+// for testing. It overrides only StateList and Dir.
+type stateListStub struct {
+	tfexec.TerraformCLI
+	// dir is a working directory.
+	dir string
+	// list is a list of resources returned by StateList.
+	list []string
+}
+
+// StateList returns a fixed list of resources.
+func (tf *stateListStub) StateList(_ context.Context, _ *tfexec.State, _ []string, _ ...string) ([]string, error) {
+	return tf.list, nil
+}
+
+// Dir returns a working directory.
+func (tf *stateListStub) Dir() string {
+	return tf.dir
+}
+
+func TestMergeStateMigratorListMoves(t *testing.T) {
+	cases := []struct {
+		desc    string
+		toList  []string
+		sources []*mergeSource
+		want    []string
+		err     string
+	}{
+		{
+			desc:   "with module prefix",
+			toList: []string{"aws_vpc.main"},
+			sources: []*mergeSource{
+				{name: "dev", module: "module.dev", workDir: &workDir{tf: &stateListStub{dir: "dev", list: []string{"aws_s3_bucket.foo", "data.aws_caller_identity.current", "module.x.aws_iam_role.bar"}}}},
+				{name: "prod", module: `module.env["prod"]`, workDir: &workDir{tf: &stateListStub{dir: "prod", list: []string{"aws_s3_bucket.foo"}}}},
+			},
+			want: []string{
+				"mv dev:aws_s3_bucket.foo module.dev.aws_s3_bucket.foo",
+				"mv dev:module.x.aws_iam_role.bar module.dev.module.x.aws_iam_role.bar",
+				`mv prod:aws_s3_bucket.foo 'module.env["prod"].aws_s3_bucket.foo'`,
+			},
+		},
+		{
+			desc:   "with resource patterns",
+			toList: []string{},
+			sources: []*mergeSource{
+				{name: "dev", resources: []string{"aws_s3_bucket.*"}, workDir: &workDir{tf: &stateListStub{dir: "dev", list: []string{"aws_s3_bucket.foo", "aws_s3_bucket.bar", "module.x.aws_s3_bucket.baz"}}}},
+			},
+			want: []string{
+				"mv dev:aws_s3_bucket.bar aws_s3_bucket.bar",
+				"mv dev:aws_s3_bucket.foo aws_s3_bucket.foo",
+			},
+		},
+		{
+			desc:   "collision with destination",
+			toList: []string{"aws_s3_bucket.foo"},
+			sources: []*mergeSource{
+				{name: "dev", workDir: &workDir{tf: &stateListStub{dir: "dev", list: []string{"aws_s3_bucket.foo"}}}},
+			},
+			err: `aws_s3_bucket.foo (from "dev" and to_dir)`,
+		},
+		{
+			desc:   "collision between sources",
+			toList: []string{},
+			sources: []*mergeSource{
+				{name: "dev", workDir: &workDir{tf: &stateListStub{dir: "dev", list: []string{"aws_s3_bucket.foo"}}}},
+				{name: "prod", workDir: &workDir{tf: &stateListStub{dir: "prod", list: []string{"aws_s3_bucket.foo"}}}},
+			},
+			err: `aws_s3_bucket.foo (from "prod" and from "dev")`,
+		},
+		{
+			desc:   "no resources",
+			toList: []string{},
+			sources: []*mergeSource{
+				{name: "dev", workDir: &workDir{tf: &stateListStub{dir: "dev", list: []string{"data.aws_caller_identity.current"}}}},
+			},
+			err: "no resources to move found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			m := &MergeStateMigrator{
+				sources: tc.sources,
+				to:      &workDir{tf: &stateListStub{dir: "to", list: tc.toList}},
+			}
+
+			moves, err := m.listMoves(context.Background(), nil, map[string]*tfexec.State{})
+			if len(tc.err) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected an error containing %q, but got: %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+
+			got := []string{}
+			for _, mv := range moves {
+				got = append(got, mv.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccMergeStateMigratorApply(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	ctx := context.Background()
+
+	// setup the initial files and states
+	from1Backend := tfexec.GetTestAccBackendS3Config(t.Name() + "/from1Dir")
+	from1Tf := tfexec.SetupTestAccWithApply(t, "default", from1Backend+`
+resource "null_resource" "foo" {}
+`)
+	from2Backend := tfexec.GetTestAccBackendS3Config(t.Name() + "/from2Dir")
+	from2Tf := tfexec.SetupTestAccWithApply(t, "default", from2Backend+`
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`)
+	toBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/toDir")
+	toTf := tfexec.SetupTestAccWithApply(t, "default", toBackend+`
+resource "null_resource" "qux" {}
+`)
+
+	// update terraform resource files for migration
+	tfexec.UpdateTestAccSource(t, from1Tf, from1Backend)
+	tfexec.UpdateTestAccSource(t, from2Tf, from2Backend+`
+resource "null_resource" "baz" {}
+`)
+	tfexec.UpdateTestAccSource(t, toTf, toBackend+`
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "qux" {}
+`)
+
+	// perform state migration
+	from := []MergeSourceConfig{
+		{Name: "from1", Dir: from1Tf.Dir(), Workspace: "default"},
+		{Name: "from2", Dir: from2Tf.Dir(), Workspace: "default", Resources: []string{"null_resource.bar"}},
+	}
+	o := &MigratorOption{}
+	m := NewMergeStateMigrator(from, toTf.Dir(), "default", false, o, false)
+	err := m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	// verify state migration results
+	cases := []struct {
+		tf   tfexec.TerraformCLI
+		want []string
+	}{
+		{tf: from1Tf, want: []string{}},
+		{tf: from2Tf, want: []string{"null_resource.baz"}},
+		{tf: toTf, want: []string{"null_resource.bar", "null_resource.foo", "null_resource.qux"}},
+	}
+	for _, tc := range cases {
+		got, err := tc.tf.StateList(ctx, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to run terraform state list in %s: %s", tc.tf.Dir(), err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("got state: %v, want state: %v in %s", got, tc.want, tc.tf.Dir())
+		}
+	}
+}
@@ -2,7 +2,9 @@ package tfmigrate
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"os"
 	"strings"
 
 	"github.com/minamijoyo/tfmigrate/tfexec"
@@ -76,3 +78,151 @@ func setupWorkDir(ctx context.Context, tf tfexec.TerraformCLI, workspace string,
 	}
 	return currentState, switchBackToRemoteFunc, nil
 }
+
+// newTerraformCLI returns a new TerraformCLI instance for a given dir.
+func newTerraformCLI(dir string, o *MigratorOption) tfexec.TerraformCLI {
+	tf := tfexec.NewTerraformCLI(tfexec.NewExecutor(dir, os.Environ()))
+	if o != nil && len(o.ExecPath) > 0 {
+		// While NewTerraformCLI reads the environment variable TFMIGRATE_EXEC_PATH
+		// at initialization, the MigratorOption takes precedence over it.
+		tf.SetExecPath(o.ExecPath)
+	}
+	return tf
+}
+
+// workDir is a working directory of a state involved in a migration.
+// All migrators set up and plan each working directory through it, and
+// record states before and after the migration for apply and report.
+type workDir struct {
+	// tf is an instance of TerraformCLI which executes terraform command in
+	// the dir.
+	tf tfexec.TerraformCLI
+	// workspace is a workspace of the state.
+	workspace string
+	// skipPlan disables the running of Terraform plan in the dir.
+	skipPlan bool
+	// label identifies the dir in the migration file for error messages.
+	// (e.g.) from_dir
+	label string
+	// beforeState is a state before applying the migration, which is set by
+	// setup.
+	beforeState *tfexec.State
+	// afterState is a new state computed by plan.
+	afterState *tfexec.State
+	// planResult is a result of terraform plan, which is set by checkPlan.
+	planResult string
+}
+
+// newWorkDir returns a new workDir instance.
+func newWorkDir(dir string, workspace string, skipPlan bool, label string, o *MigratorOption) *workDir {
+	return &workDir{
+		tf:        newTerraformCLI(dir, o),
+		workspace: workspace,
+		skipPlan:  skipPlan,
+		label:     label,
+	}
+}
+
+// setup initializes the dir, pulls the current remote state and overrides
+// the backend to local. It resets the results of the last plan and returns
+// the current state and a switch back function.
+func (w *workDir) setup(ctx context.Context, o *MigratorOption, ignoreLegacyStateInitErr bool) (*tfexec.State, func() error, error) {
+	w.beforeState = nil
+	w.afterState = nil
+	w.planResult = ""
+
+	currentState, switchBackToRemoteFunc, err := setupWorkDir(ctx, w.tf, w.workspace, o.IsBackendTerraformCloud, o.BackendConfig, ignoreLegacyStateInitErr)
+	if err != nil {
+		return nil, nil, err
+	}
+	w.beforeState = currentState
+	return currentState, switchBackToRemoteFunc, nil
+}
+
+// checkPlan checks if a plan with the after state has no changes and records
+// a result of the plan. Changes allowed by given rules are ignored.
+// Unexpected diffs are also ignored if force is true.
+func (w *workDir) checkPlan(ctx context.Context, force bool, planOut string, allowChanges []AllowChangeRule) error {
+	if w.skipPlan {
+		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", w.tf.Dir())
+		w.planResult = PlanResultSkipped
+		return nil
+	}
+
+	// build plan options
+	planOpts := []string{"-input=false", "-no-color", "-detailed-exitcode"}
+	if planOut != "" {
+		planOpts = append(planOpts, "-out="+planOut)
+	}
+
+	log.Printf("[INFO] [migrator@%s] check diffs\n", w.tf.Dir())
+	plan, err := w.tf.Plan(ctx, w.afterState, planOpts...)
+	w.planResult = planResultOf(err)
+	if err == nil {
+		return nil
+	}
+	if exitErr, ok := err.(tfexec.ExitError); !ok || exitErr.ExitCode() != 2 {
+		return err
+	}
+
+	if len(allowChanges) > 0 {
+		log.Printf("[INFO] [migrator@%s] verify diffs with allow_changes rules\n", w.tf.Dir())
+		err = verifyPlanChanges(ctx, w.tf, plan, allowChanges)
+	} else {
+		err = fmt.Errorf("terraform plan command returns unexpected diffs in %s %s: %s", w.tf.Dir(), w.label, err)
+	}
+	if err == nil {
+		return nil
+	}
+	if !force {
+		log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", w.tf.Dir())
+		return err
+	}
+	log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", w.tf.Dir(), err)
+	return nil
+}
+
+// stateUpdate returns an update to push the after state to remote.
+func (w *workDir) stateUpdate() *stateUpdate {
+	return &stateUpdate{tf: w.tf, workspace: w.workspace, before: w.beforeState, after: w.afterState}
+}
+
+// applyMetadata returns metadata about the state affected by the migration.
+func (w *workDir) applyMetadata() StateApplyMetadata {
+	return newStateApplyMetadata(w.tf, w.workspace, w.beforeState, w.afterState)
+}
+
+// report returns a summary of the last plan or apply for the dir.
+func (w *workDir) report() StateReport {
+	return newStateReport(w.tf, w.workspace, w.beforeState, w.afterState, w.planResult)
+}
+
+// snapshot returns a snapshot of the state before the migration.
+func (w *workDir) snapshot() StateSnapshot {
+	return StateSnapshot{Dir: w.tf.Dir(), Workspace: w.workspace, State: w.beforeState}
+}
+
+// newRemoteTerraformCLI is a helper function which returns a TerraformCLI
+// initialized for reading or writing a remote state in a given directory
+// and workspace. Unlike setupWorkDir, it doesn't override the backend.
+func newRemoteTerraformCLI(ctx context.Context, dir string, workspace string, o *MigratorOption) (tfexec.TerraformCLI, error) {
+	tf := newTerraformCLI(dir, o)
+
+	log.Printf("[INFO] [migrator@%s] initialize work dir\n", tf.Dir())
+	if err := tf.Init(ctx, "-input=false", "-no-color"); err != nil {
+		return nil, err
+	}
+
+	currentWorkspace, err := tf.WorkspaceShow(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if currentWorkspace != workspace {
+		log.Printf("[INFO] [migrator@%s] switch to remote workspace %s\n", tf.Dir(), workspace)
+		if err := tf.WorkspaceSelect(ctx, workspace); err != nil {
+			return nil, err
+		}
+	}
+
+	return tf, nil
+}
@@ -14,6 +14,8 @@ type MockMigratorConfig struct {
 	PlanError bool `hcl:"plan_error"`
 	// ApplyError is a flag to return an error on Apply().
 	ApplyError bool `hcl:"apply_error"`
+	// ApplyIncomplete is a flag to return an IncompleteApplyError on Apply().
+	ApplyIncomplete bool `hcl:"apply_incomplete,optional"`
 }
 
 // MockMigratorConfig implements a MigratorConfig.
@@ -21,7 +23,9 @@ var _ MigratorConfig = (*MockMigratorConfig)(nil)
 
 // NewMigrator returns a new instance of MockMigrator.
 func (c *MockMigratorConfig) NewMigrator(_ *MigratorOption) (Migrator, error) {
-	return NewMockMigrator(c.PlanError, c.ApplyError), nil
+	m := NewMockMigrator(c.PlanError, c.ApplyError)
+	m.applyIncomplete = c.ApplyIncomplete
+	return m, nil
 }
 
 // MockMigrator implements the Migrator interface for testing.
@@ -31,9 +35,15 @@ type MockMigrator struct {
 	planError bool
 	// applyError is a flag to return an error on Apply().
 	applyError bool
+	// applyIncomplete is a flag to return an IncompleteApplyError on Apply().
+	applyIncomplete bool
+	// metadata is metadata about the last applied migration.
+	metadata *ApplyMetadata
 }
 
 var _ Migrator = (*MockMigrator)(nil)
+var _ ApplyMetadataProvider = (*MockMigrator)(nil)
+var _ ReportProvider = (*MockMigrator)(nil)
 
 // NewMockMigrator returns a new MockMigrator instance.
 func NewMockMigrator(planError bool, applyError bool) *MockMigrator {
@@ -77,6 +87,33 @@ func (m *MockMigrator) Apply(ctx context.Context) error {
 	if m.applyError {
 		return fmt.Errorf("failed to apply mock migrator: applyError = %t", m.applyError)
 	}
+	if m.applyIncomplete {
+		return &IncompleteApplyError{
+			Err:      fmt.Errorf("failed to apply mock migrator: applyIncomplete = %t", m.applyIncomplete),
+			Recovery: "To recover, do nothing.",
+		}
+	}
+	m.metadata = &ApplyMetadata{
+		ExecType:    "mock",
+		ExecVersion: "0.0.0",
+		Actions:     []string{},
+		States:      []StateApplyMetadata{},
+	}
 	log.Printf("[INFO] [migrator] state migrator apply success!\n")
 	return nil
 }
+
+// ApplyMetadata returns metadata about the last applied migration.
+// It returns dummy metadata if the migration has been applied successfully.
+func (m *MockMigrator) ApplyMetadata() *ApplyMetadata {
+	return m.metadata
+}
+
+// Report returns a summary of the last plan or apply.
+// It always returns a dummy report without any actions and states.
+func (m *MockMigrator) Report() *Report {
+	return &Report{
+		Actions: []string{},
+		States:  []StateReport{},
+	}
+}
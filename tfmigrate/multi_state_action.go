@@ -3,6 +3,7 @@ package tfmigrate
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
@@ -12,6 +13,9 @@ import (
 type MultiStateAction interface {
 	// MultiStateUpdate updates given two states and returns new two states.
 	MultiStateUpdate(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) (*tfexec.State, *tfexec.State, error)
+	// String returns a string representation of the action.
+	// It can be parsed by NewMultiStateActionFromString.
+	String() string
 }
 
 // NewMultiStateActionFromString is a factory method which returns a new
@@ -21,6 +25,13 @@ type MultiStateAction interface {
 // Valid formats are the following.
 // "mv <source> <destination>"
 // "xmv <source> <destination>"
+// "rmv <regex> <template>"
+// "<scope>:rm <addresses>..."
+// "<scope>:xrm <pattern> [--expect N]"
+// "<scope>:import <address> <id>"
+// "<scope>:replace-provider <source> <destination>"
+// The scope is one of from, to and *, where * means both states.
+// The import action cannot be applied to both states.
 func NewMultiStateActionFromString(cmdStr string) (MultiStateAction, error) {
 	args, err := splitStateAction(cmdStr)
 	if err != nil {
@@ -31,6 +42,13 @@ func NewMultiStateActionFromString(cmdStr string) (MultiStateAction, error) {
 		return nil, fmt.Errorf("multi state action is empty: %s", cmdStr)
 	}
 	actionType := args[0]
+	if scope, scopedType, ok := strings.Cut(actionType, ":"); ok {
+		action, err := newMultiStateScopedActionFromArgs(scope, scopedType, args, cmdStr)
+		if err != nil {
+			return nil, err
+		}
+		return action, nil
+	}
 
 	// switch by action type and parse arguments and build an action.
 	var action MultiStateAction
@@ -51,9 +69,56 @@ func NewMultiStateActionFromString(cmdStr string) (MultiStateAction, error) {
 		dst := args[2]
 		action = NewMultiStateXmvAction(src, dst)
 
+	case "rmv":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("multi state rmv action is invalid: %s", cmdStr)
+		}
+		pattern := args[1]
+		template := args[2]
+		action = NewMultiStateRmvAction(pattern, template)
+
 	default:
 		return nil, fmt.Errorf("unknown multi state action type: %s", cmdStr)
 	}
 
 	return action, nil
 }
+
+// expandMultiStateAction expands a given action into actions to be actually
+// run. An xmv or rmv action is expanded into mv actions matching the current
+// state.
+// A scoped xrm action is expanded into scoped rm actions for each state.
+// Other actions are returned as they are.
+func expandMultiStateAction(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State, action MultiStateAction) ([]MultiStateAction, error) {
+	switch a := action.(type) {
+	case *MultiStateXmvAction:
+		mvs, err := a.generateMvActions(ctx, fromTf, fromState)
+		if err != nil {
+			return nil, err
+		}
+
+		actions := make([]MultiStateAction, 0, len(mvs))
+		for _, mv := range mvs {
+			actions = append(actions, mv)
+		}
+		return actions, nil
+
+	case *MultiStateRmvAction:
+		mvs, err := a.generateMvActions(ctx, fromTf, toTf, fromState, toState)
+		if err != nil {
+			return nil, err
+		}
+
+		actions := make([]MultiStateAction, 0, len(mvs))
+		for _, mv := range mvs {
+			actions = append(actions, mv)
+		}
+		return actions, nil
+
+	case *MultiStateScopedAction:
+		return a.expand(ctx, fromTf, toTf, fromState, toState)
+
+	default:
+		return []MultiStateAction{action}, nil
+	}
+}
@@ -75,6 +75,81 @@ func TestNewMultiStateActionFromString(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc:   "from:rm action (valid)",
+			cmdStr: "from:rm null_resource.foo null_resource.bar",
+			want: &MultiStateScopedAction{
+				scope:  "from",
+				action: &StateRmAction{addresses: []string{"null_resource.foo", "null_resource.bar"}},
+			},
+			ok: true,
+		},
+		{
+			desc:   "rmv action (valid)",
+			cmdStr: `rmv 'null_resource\.(\w+)' 'null_resource.${1}2'`,
+			want: &MultiStateRmvAction{
+				pattern:  `null_resource\.(\w+)`,
+				template: "null_resource.${1}2",
+			},
+			ok: true,
+		},
+		{
+			desc:   "rmv action (3 args)",
+			cmdStr: `rmv 'null_resource\.(\w+)' null_resource.foo null_resource.bar`,
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "*:xrm action (valid)",
+			cmdStr: "*:xrm null_resource.* --expect 1",
+			want: &MultiStateScopedAction{
+				scope:  "*",
+				action: &StateXrmAction{pattern: "null_resource.*", expect: 1},
+			},
+			ok: true,
+		},
+		{
+			desc:   "to:import action (valid)",
+			cmdStr: "to:import time_static.foo 2006-01-02T15:04:05Z",
+			want: &MultiStateScopedAction{
+				scope:  "to",
+				action: &StateImportAction{address: "time_static.foo", id: "2006-01-02T15:04:05Z"},
+			},
+			ok: true,
+		},
+		{
+			desc:   "*:replace-provider action (valid)",
+			cmdStr: "*:replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+			want: &MultiStateScopedAction{
+				scope:  "*",
+				action: &StateReplaceProviderAction{source: "registry.terraform.io/-/null", destination: "registry.terraform.io/hashicorp/null"},
+			},
+			ok: true,
+		},
+		{
+			desc:   "*:import action",
+			cmdStr: "*:import time_static.foo 2006-01-02T15:04:05Z",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "scoped mv action",
+			cmdStr: "from:mv null_resource.foo null_resource.foo2",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "unknown scope",
+			cmdStr: "foo:rm null_resource.foo",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "from:rm action (no args)",
+			cmdStr: "from:rm",
+			want:   nil,
+			ok:     false,
+		},
 		{
 			desc:   "unknown type",
 			cmdStr: "foo bar baz",
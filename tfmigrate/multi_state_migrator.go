@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/minamijoyo/tfmigrate/tfexec"
@@ -30,10 +29,26 @@ type MultiStateMigratorConfig struct {
 	// Each action is a plain text for state operation.
 	// Valid formats are the following.
 	// "mv <source> <destination>"
+	// "xmv <source> <destination>"
+	// "rmv <regex> <template>"
+	// "<scope>:rm <addresses>..."
+	// "<scope>:xrm <pattern> [--expect N]"
+	// "<scope>:import <address> <id>"
+	// "<scope>:replace-provider <source> <destination>"
+	// The scope is one of from, to and *, where * means both states.
 	Actions []string `hcl:"actions"`
 	// Force option controls behaviour in case of unexpected diff in plan.
 	// When set forces applying even if plan shows diff.
 	Force bool `hcl:"force,optional"`
+	// Preconditions is a list of assertions on the states before the
+	// migration. Each of them has a state attribute to select either from or
+	// to state, and is evaluated against the state pulled from remote before
+	// any actions.
+	Preconditions []StateCondition `hcl:"precondition,block"`
+	// Postconditions is a list of assertions on the states after the
+	// migration. They are evaluated against the computed states before
+	// terraform plan.
+	Postconditions []StateCondition `hcl:"postcondition,block"`
 }
 
 // MultiStateMigratorConfig implements a MigratorConfig.
@@ -63,23 +78,24 @@ func (c *MultiStateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, err
 		c.ToWorkspace = "default"
 	}
 
-	return NewMultiStateMigrator(c.FromDir, c.ToDir, c.FromWorkspace, c.ToWorkspace, actions, o, c.Force, c.FromSkipPlan, c.ToSkipPlan), nil
+	for _, cond := range append(append([]StateCondition{}, c.Preconditions...), c.Postconditions...) {
+		if err := cond.Validate(true); err != nil {
+			return nil, err
+		}
+	}
+
+	m := NewMultiStateMigrator(c.FromDir, c.ToDir, c.FromWorkspace, c.ToWorkspace, actions, o, c.Force, c.FromSkipPlan, c.ToSkipPlan)
+	m.preconditions = c.Preconditions
+	m.postconditions = c.Postconditions
+	return m, nil
 }
 
 // MultiStateMigrator implements the Migrator interface.
 type MultiStateMigrator struct {
-	// fromTf is an instance of TerraformCLI which executes terraform command in a fromDir.
-	fromTf tfexec.TerraformCLI
-	// fromSkipPlan disables the running of Terraform plan in fromDir.
-	fromSkipPlan bool
-	// fromTf is an instance of TerraformCLI which executes terraform command in a toDir.
-	toTf tfexec.TerraformCLI
-	// toSkipPlan disables the running of Terraform plan in toDir.
-	toSkipPlan bool
-	//fromWorkspace is the workspace from which the resource will be migrated
-	fromWorkspace string
-	//toWorkspace is the workspace to which the resource will be migrated
-	toWorkspace string
+	// from is a working directory where states of resources move from.
+	from *workDir
+	// to is a working directory where states of resources move to.
+	to *workDir
 	// actions is a list of multi state migration operations.
 	actions []MultiStateAction
 	// o is an option for migrator.
@@ -87,32 +103,29 @@ type MultiStateMigrator struct {
 	o *MigratorOption
 	// force operation in case of unexpected diff
 	force bool
+	// preconditions is a list of assertions on the states before actions.
+	preconditions []StateCondition
+	// postconditions is a list of assertions on the computed states.
+	postconditions []StateCondition
+	// expandedActions is a list of actions actually run, which is set by plan.
+	expandedActions []string
+	// metadata is metadata about the last applied migration.
+	metadata *ApplyMetadata
 }
 
 var _ Migrator = (*MultiStateMigrator)(nil)
+var _ ApplyMetadataProvider = (*MultiStateMigrator)(nil)
+var _ ReportProvider = (*MultiStateMigrator)(nil)
 
 // NewMultiStateMigrator returns a new MultiStateMigrator instance.
 func NewMultiStateMigrator(fromDir string, toDir string, fromWorkspace string, toWorkspace string,
 	actions []MultiStateAction, o *MigratorOption, force bool, fromSkipPlan bool, toSkipPlan bool) *MultiStateMigrator {
-	fromTf := tfexec.NewTerraformCLI(tfexec.NewExecutor(fromDir, os.Environ()))
-	toTf := tfexec.NewTerraformCLI(tfexec.NewExecutor(toDir, os.Environ()))
-	if o != nil && len(o.ExecPath) > 0 {
-		// While NewTerraformCLI reads the environment variable TFMIGRATE_EXEC_PATH
-		// at initialization, the MigratorOption takes precedence over it.
-		fromTf.SetExecPath(o.ExecPath)
-		toTf.SetExecPath(o.ExecPath)
-	}
-
 	return &MultiStateMigrator{
-		fromTf:        fromTf,
-		fromSkipPlan:  fromSkipPlan,
-		toTf:          toTf,
-		toSkipPlan:    toSkipPlan,
-		fromWorkspace: fromWorkspace,
-		toWorkspace:   toWorkspace,
-		actions:       actions,
-		o:             o,
-		force:         force,
+		from:    newWorkDir(fromDir, fromWorkspace, fromSkipPlan, "from_dir", o),
+		to:      newWorkDir(toDir, toWorkspace, toSkipPlan, "to_dir", o),
+		actions: actions,
+		o:       o,
+		force:   force,
 	}
 }
 
@@ -121,8 +134,22 @@ func NewMultiStateMigrator(fromDir string, toDir string, fromWorkspace string, t
 // We intentionally make this method private to avoid exposing internal states and unify
 // the Migrator interface between a single and multi state migrator.
 func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec.State, toCurrentState *tfexec.State, err error) {
+	// When invoking `state replace-provider`, it's necessary to first invoke
+	// `terraform init`, which may return an error for a legacy state.
+	// See also the comment in StateMigrator.plan.
+	fromIgnoreLegacyStateInitErr := false
+	toIgnoreLegacyStateInitErr := false
+	for _, action := range m.actions {
+		if a, ok := action.(*MultiStateScopedAction); ok {
+			if _, ok := a.action.(*StateReplaceProviderAction); ok {
+				fromIgnoreLegacyStateInitErr = fromIgnoreLegacyStateInitErr || a.appliesToFrom()
+				toIgnoreLegacyStateInitErr = toIgnoreLegacyStateInitErr || a.appliesToTo()
+			}
+		}
+	}
+
 	// setup fromDir.
-	fromCurrentState, fromSwitchBackToRemoteFunc, err := setupWorkDir(ctx, m.fromTf, m.fromWorkspace, m.o.IsBackendTerraformCloud, m.o.BackendConfig, false)
+	fromCurrentState, fromSwitchBackToRemoteFunc, err := m.from.setup(ctx, m.o, fromIgnoreLegacyStateInitErr)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -132,7 +159,7 @@ func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec
 	}()
 
 	// setup toDir.
-	toCurrentState, toSwitchBackToRemoteFunc, err := setupWorkDir(ctx, m.toTf, m.toWorkspace, m.o.IsBackendTerraformCloud, m.o.BackendConfig, false)
+	toCurrentState, toSwitchBackToRemoteFunc, err := m.to.setup(ctx, m.o, toIgnoreLegacyStateInitErr)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -141,67 +168,55 @@ func (m *MultiStateMigrator) plan(ctx context.Context) (fromCurrentState *tfexec
 		err = multierror.Append(err, toSwitchBackToRemoteFunc())
 	}()
 
+	m.expandedActions = []string{}
+
+	if err := m.checkConditions(ctx, fromCurrentState, toCurrentState, m.preconditions, "precondition"); err != nil {
+		return nil, nil, err
+	}
+
 	// computes new states by applying state migration operations to temporary states.
-	log.Printf("[INFO] [migrator] compute new states (%s => %s)\n", m.fromTf.Dir(), m.toTf.Dir())
+	log.Printf("[INFO] [migrator] compute new states (%s => %s)\n", m.from.tf.Dir(), m.to.tf.Dir())
 	var fromNewState, toNewState *tfexec.State
 	for _, action := range m.actions {
-		fromNewState, toNewState, err = action.MultiStateUpdate(ctx, m.fromTf, m.toTf, fromCurrentState, toCurrentState)
+		// expand actions to record actions actually run.
+		expanded, err := expandMultiStateAction(ctx, m.from.tf, m.to.tf, fromCurrentState, toCurrentState, action)
 		if err != nil {
 			return nil, nil, err
 		}
-		fromCurrentState = tfexec.NewState(fromNewState.Bytes())
-		toCurrentState = tfexec.NewState(toNewState.Bytes())
-	}
-
-	// build plan options
-	planOpts := []string{"-input=false", "-no-color", "-detailed-exitcode"}
-	if m.o.PlanOut != "" {
-		planOpts = append(planOpts, "-out="+m.o.PlanOut)
-	}
-
-	if m.fromSkipPlan {
-		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", m.fromTf.Dir())
-	} else {
-		// check if a plan in fromDir has no changes.
-		log.Printf("[INFO] [migrator@%s] check diffs\n", m.fromTf.Dir())
-		_, err = m.fromTf.Plan(ctx, fromCurrentState, planOpts...)
-		if err != nil {
-			if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
-				if !m.force {
-					log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.fromTf.Dir())
-					return nil, nil, fmt.Errorf("terraform plan command returns unexpected diffs in %s from_dir: %s", m.fromTf.Dir(), err)
-				}
-				log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.fromTf.Dir(), err)
-				// reset err to nil to intentionally ignore unexpected diffs.
-				err = nil
-			} else {
+		for _, a := range expanded {
+			fromNewState, toNewState, err = a.MultiStateUpdate(ctx, m.from.tf, m.to.tf, fromCurrentState, toCurrentState)
+			if err != nil {
 				return nil, nil, err
 			}
+			fromCurrentState = tfexec.NewState(fromNewState.Bytes())
+			toCurrentState = tfexec.NewState(toNewState.Bytes())
+			m.expandedActions = append(m.expandedActions, a.String())
 		}
 	}
+	m.from.afterState = fromCurrentState
+	m.to.afterState = toCurrentState
 
-	if m.toSkipPlan {
-		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", m.toTf.Dir())
-	} else {
-		// check if a plan in toDir has no changes.
-		log.Printf("[INFO] [migrator@%s] check diffs\n", m.toTf.Dir())
-		_, err = m.toTf.Plan(ctx, toCurrentState, planOpts...)
-		if err != nil {
-			if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
-				if !m.force {
-					log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.toTf.Dir())
-					return nil, nil, fmt.Errorf("terraform plan command returns unexpected diffs in %s to_dir: %s", m.toTf.Dir(), err)
-				}
-				log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.toTf.Dir(), err)
-				// reset err to nil to intentionally ignore unexpected diffs.
-				err = nil
-			} else {
-				return nil, nil, err
-			}
-		}
+	if err := m.checkConditions(ctx, fromCurrentState, toCurrentState, m.postconditions, "postcondition"); err != nil {
+		return nil, nil, err
 	}
 
-	return fromCurrentState, toCurrentState, err
+	if err := m.from.checkPlan(ctx, m.force, m.o.PlanOut, nil); err != nil {
+		return nil, nil, err
+	}
+	if err := m.to.checkPlan(ctx, m.force, m.o.PlanOut, nil); err != nil {
+		return nil, nil, err
+	}
+
+	return fromCurrentState, toCurrentState, nil
+}
+
+// checkConditions evaluates given conditions against either fromState or
+// toState selected by the state attribute of each condition.
+func (m *MultiStateMigrator) checkConditions(ctx context.Context, fromState *tfexec.State, toState *tfexec.State, conditions []StateCondition, kind string) error {
+	if err := checkStateConditions(ctx, m.from.tf, fromState, filterConditions(conditions, conditionStateFrom), kind); err != nil {
+		return err
+	}
+	return checkStateConditions(ctx, m.to.tf, toState, filterConditions(conditions, conditionStateTo), kind)
 }
 
 // Plan computes new states by applying multi state migration operations to temporary states.
@@ -224,25 +239,56 @@ func (m *MultiStateMigrator) Apply(ctx context.Context) error {
 	// Check if new states don't have any diffs compared to real resources
 	// before push new states to remote.
 	log.Printf("[INFO] [migrator] start multi state migrator plan phase for apply\n")
-	fromState, toState, err := m.plan(ctx)
-	if err != nil {
+	if _, _, err := m.plan(ctx); err != nil {
 		return err
 	}
 
 	// push the new states to remote.
-	// We push toState before fromState, because when moving resources across
-	// states, write them to new state first and then remove them from old one.
 	log.Printf("[INFO] [migrator] start multi state migrator apply phase\n")
-	log.Printf("[INFO] [migrator@%s] push the new state to remote\n", m.toTf.Dir())
-	err = m.toTf.StatePush(ctx, toState)
-	if err != nil {
+	if err := ensureRemoteStateUnchanged(ctx, m.from.tf, m.from.beforeState); err != nil {
 		return err
 	}
-	log.Printf("[INFO] [migrator@%s] push the new state to remote\n", m.fromTf.Dir())
-	err = m.fromTf.StatePush(ctx, fromState)
-	if err != nil {
+	if err := ensureRemoteStateUnchanged(ctx, m.to.tf, m.to.beforeState); err != nil {
+		return err
+	}
+
+	snapshots := []StateSnapshot{m.from.snapshot(), m.to.snapshot()}
+	if err := saveSnapshots(ctx, m.o, snapshots); err != nil {
 		return err
 	}
+
+	if err := m.push(ctx); err != nil {
+		return err
+	}
+
+	states := []StateApplyMetadata{m.from.applyMetadata(), m.to.applyMetadata()}
+	m.metadata = newApplyMetadata(ctx, m.from.tf, m.expandedActions, states)
+
 	log.Printf("[INFO] [migrator] multi state migrator apply success!\n")
 	return nil
 }
+
+// push pushes new states to remote.
+// We push toState before fromState, because when moving resources across
+// states, write them to new state first and then remove them from old one.
+// If it fails to push fromState, it rolls back toState not to leave the
+// resources in both states.
+func (m *MultiStateMigrator) push(ctx context.Context) error {
+	updates := []*stateUpdate{m.to.stateUpdate(), m.from.stateUpdate()}
+
+	return pushStates(ctx, updates)
+}
+
+// ApplyMetadata returns metadata about the last applied migration.
+// If the migration has not been applied successfully, it returns nil.
+func (m *MultiStateMigrator) ApplyMetadata() *ApplyMetadata {
+	return m.metadata
+}
+
+// Report returns a summary of the last plan or apply.
+func (m *MultiStateMigrator) Report() *Report {
+	return &Report{
+		Actions: m.expandedActions,
+		States:  []StateReport{m.from.report(), m.to.report()},
+	}
+}
@@ -127,6 +127,39 @@ func TestMultiStateMigratorConfigNewMigrator(t *testing.T) {
 			o:  nil,
 			ok: false,
 		},
+		{
+			desc: "with conditions",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Preconditions: []StateCondition{
+					{State: "from", ResourceExists: []string{"null_resource.foo"}},
+				},
+				Postconditions: []StateCondition{
+					{State: "to", ResourceExists: []string{"null_resource.foo2"}},
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "condition without state",
+			config: &MultiStateMigratorConfig{
+				FromDir: "dir1",
+				ToDir:   "dir2",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Preconditions: []StateCondition{
+					{ResourceExists: []string{"null_resource.foo"}},
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
 		{
 			desc: "force true",
 			config: &MultiStateMigratorConfig{
@@ -0,0 +1,83 @@
+package tfmigrate
+
+import (
+	"context"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// MultiStateRmvAction implements the MultiStateAction interface.
+// MultiStateRmvAction is a regex version of MultiStateXmvAction.
+// It allows you to move multiple resources matching a regex anchored to a
+// whole address from a dir to another.
+type MultiStateRmvAction struct {
+	// pattern is a regex of addresses of resources to be moved.
+	pattern string
+	// template is a template of new addresses which can contain references to
+	// capture groups and transform functions.
+	template string
+}
+
+var _ MultiStateAction = (*MultiStateRmvAction)(nil)
+
+// NewMultiStateRmvAction returns a new MultiStateRmvAction instance.
+func NewMultiStateRmvAction(pattern string, template string) *MultiStateRmvAction {
+	return &MultiStateRmvAction{
+		pattern:  pattern,
+		template: template,
+	}
+}
+
+// MultiStateUpdate updates given two states and returns new two states.
+// All moves are validated for collisions before any of them is executed.
+func (a *MultiStateRmvAction) MultiStateUpdate(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) (*tfexec.State, *tfexec.State, error) {
+	multiStateMvActions, err := a.generateMvActions(ctx, fromTf, toTf, fromState, toState)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, action := range multiStateMvActions {
+		fromState, toState, err = action.MultiStateUpdate(ctx, fromTf, toTf, fromState, toState)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return fromState, toState, nil
+}
+
+// generateMvActions uses the states to determine the corresponding mv actions.
+// Unlike the xmv, it also lists the state of toDir to detect collisions.
+func (a *MultiStateRmvAction) generateMvActions(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) ([]*MultiStateMvAction, error) {
+	e, err := newRmvExpander(a.pattern, a.template)
+	if err != nil {
+		return nil, err
+	}
+
+	fromList, err := fromTf.StateList(ctx, fromState, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	toList, err := toTf.StateList(ctx, toState, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	stateMvActions, err := e.expand(fromList, toList, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// convert StateMvAction to MultiStateMvAction.
+	multiStateMvActions := []*MultiStateMvAction{}
+	for _, action := range stateMvActions {
+		multiStateMvActions = append(multiStateMvActions, NewMultiStateMvAction(action.source, action.destination))
+	}
+
+	return multiStateMvActions, nil
+}
+
+// String returns a string representation of the action.
+func (a *MultiStateRmvAction) String() string {
+	return FormatAction("rmv", a.pattern, a.template)
+}
@@ -0,0 +1,53 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMultiStateRmvActionGenerateMvActions(t *testing.T) {
+	cases := []struct {
+		desc     string
+		fromList []string
+		toList   []string
+		want     []*MultiStateMvAction
+		ok       bool
+	}{
+		{
+			desc:     "simple",
+			fromList: []string{`module.a["dev"].null_resource.foo`, `module.a["prd"].null_resource.foo`, "null_resource.bar"},
+			toList:   []string{"null_resource.baz"},
+			want: []*MultiStateMvAction{
+				NewMultiStateMvAction(`module.a["dev"].null_resource.foo`, "null_resource.foo_dev"),
+				NewMultiStateMvAction(`module.a["prd"].null_resource.foo`, "null_resource.foo_prd"),
+			},
+			ok: true,
+		},
+		{
+			desc:     "destination already exists in to",
+			fromList: []string{`module.a["dev"].null_resource.foo`},
+			toList:   []string{"null_resource.foo_dev"},
+			want:     nil,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			a := NewMultiStateRmvAction(`module\.a\["(?P<env>\w+)"\]\.null_resource\.(?P<name>\w+)`, "null_resource.${name}_${env}")
+			fromTf := &stateListStub{dir: "dir1", list: tc.fromList}
+			toTf := &stateListStub{dir: "dir2", list: tc.toList}
+			got, err := a.generateMvActions(context.Background(), fromTf, toTf, nil, nil)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
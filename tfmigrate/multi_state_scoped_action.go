@@ -0,0 +1,131 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// Scopes of MultiStateScopedAction.
+const (
+	// multiStateScopeFrom means that an action applies to the state in fromDir.
+	multiStateScopeFrom = "from"
+	// multiStateScopeTo means that an action applies to the state in toDir.
+	multiStateScopeTo = "to"
+	// multiStateScopeBoth means that an action applies to both states.
+	multiStateScopeBoth = "*"
+)
+
+// MultiStateScopedAction implements the MultiStateAction interface.
+// MultiStateScopedAction applies a single state action such as rm, xrm, import
+// and replace-provider to the state in fromDir, toDir, or both.
+type MultiStateScopedAction struct {
+	// scope is a scope of the action. Valid values are from, to and *.
+	scope string
+	// action is a single state action to be applied.
+	action StateAction
+}
+
+var _ MultiStateAction = (*MultiStateScopedAction)(nil)
+
+// NewMultiStateScopedAction returns a new MultiStateScopedAction instance.
+func NewMultiStateScopedAction(scope string, action StateAction) *MultiStateScopedAction {
+	return &MultiStateScopedAction{
+		scope:  scope,
+		action: action,
+	}
+}
+
+// newMultiStateScopedActionFromArgs builds a new MultiStateScopedAction from
+// a given scope, action type and arguments split from cmdStr.
+// cmdStr is only used for error messages.
+func newMultiStateScopedActionFromArgs(scope string, actionType string, args []string, cmdStr string) (*MultiStateScopedAction, error) {
+	switch scope {
+	case multiStateScopeFrom, multiStateScopeTo, multiStateScopeBoth:
+	default:
+		return nil, fmt.Errorf("unknown scope of multi state action: %s, valid scopes are from, to and *", cmdStr)
+	}
+
+	switch actionType {
+	case "rm", "xrm", "replace-provider":
+	case "import":
+		// The same resource should never be managed in both states.
+		if scope == multiStateScopeBoth {
+			return nil, fmt.Errorf("multi state import action cannot be applied to both states: %s", cmdStr)
+		}
+	default:
+		return nil, fmt.Errorf("unknown scoped multi state action type: %s, valid types are rm, xrm, import and replace-provider", cmdStr)
+	}
+
+	action, err := newStateActionFromArgs(actionType, args, cmdStr)
+	if err != nil {
+		return nil, err
+	}
+	return NewMultiStateScopedAction(scope, action), nil
+}
+
+// MultiStateUpdate updates given two states and returns new two states.
+// It applies the single state action to the states in the scope.
+func (a *MultiStateScopedAction) MultiStateUpdate(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) (*tfexec.State, *tfexec.State, error) {
+	var err error
+	if a.appliesToFrom() {
+		fromState, err = a.action.StateUpdate(ctx, fromTf, fromState)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if a.appliesToTo() {
+		toState, err = a.action.StateUpdate(ctx, toTf, toState)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return fromState, toState, nil
+}
+
+// expand expands an xrm action into rm actions matching the current states.
+// Other actions are returned as they are.
+func (a *MultiStateScopedAction) expand(ctx context.Context, fromTf tfexec.TerraformCLI, toTf tfexec.TerraformCLI, fromState *tfexec.State, toState *tfexec.State) ([]MultiStateAction, error) {
+	xrm, ok := a.action.(*StateXrmAction)
+	if !ok {
+		return []MultiStateAction{a}, nil
+	}
+
+	actions := []MultiStateAction{}
+	if a.appliesToFrom() {
+		rm, err := xrm.generateRmAction(ctx, fromTf, fromState)
+		if err != nil {
+			return nil, err
+		}
+		if rm != nil {
+			actions = append(actions, NewMultiStateScopedAction(multiStateScopeFrom, rm))
+		}
+	}
+	if a.appliesToTo() {
+		rm, err := xrm.generateRmAction(ctx, toTf, toState)
+		if err != nil {
+			return nil, err
+		}
+		if rm != nil {
+			actions = append(actions, NewMultiStateScopedAction(multiStateScopeTo, rm))
+		}
+	}
+	return actions, nil
+}
+
+// appliesToFrom returns true if the action applies to the state in fromDir.
+func (a *MultiStateScopedAction) appliesToFrom() bool {
+	return a.scope == multiStateScopeFrom || a.scope == multiStateScopeBoth
+}
+
+// appliesToTo returns true if the action applies to the state in toDir.
+func (a *MultiStateScopedAction) appliesToTo() bool {
+	return a.scope == multiStateScopeTo || a.scope == multiStateScopeBoth
+}
+
+// String returns a string representation of the action.
+// (e.g.) `from:rm aws_instance.foo`
+func (a *MultiStateScopedAction) String() string {
+	return a.scope + ":" + a.action.String()
+}
@@ -0,0 +1,154 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// stateRmRecorder is a TerraformCLI which records removed addresses for
+// testing. It overrides only StateRm and Dir.
+type stateRmRecorder struct {
+	tfexec.TerraformCLI
+	// dir is a working directory.
+	dir string
+	// removed is a list of removed addresses.
+	removed []string
+}
+
+// StateRm records given addresses and returns a given state as it is.
+func (tf *stateRmRecorder) StateRm(_ context.Context, state *tfexec.State, addresses []string, _ ...string) (*tfexec.State, error) {
+	tf.removed = append(tf.removed, addresses...)
+	return state, nil
+}
+
+// Dir returns a working directory.
+func (tf *stateRmRecorder) Dir() string {
+	return tf.dir
+}
+
+func TestMultiStateScopedActionMultiStateUpdate(t *testing.T) {
+	cases := []struct {
+		desc     string
+		cmdStr   string
+		wantFrom int
+		wantTo   int
+	}{
+		{
+			desc:     "from",
+			cmdStr:   "from:rm null_resource.foo",
+			wantFrom: 1,
+			wantTo:   0,
+		},
+		{
+			desc:     "to",
+			cmdStr:   "to:rm null_resource.foo",
+			wantFrom: 0,
+			wantTo:   1,
+		},
+		{
+			desc:     "both",
+			cmdStr:   "*:rm null_resource.foo",
+			wantFrom: 1,
+			wantTo:   1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			action, err := NewMultiStateActionFromString(tc.cmdStr)
+			if err != nil {
+				t.Fatalf("failed to parse action: %s", err)
+			}
+			if action.String() != tc.cmdStr {
+				t.Errorf("got: %s, want: %s", action.String(), tc.cmdStr)
+			}
+
+			fromTf := &stateRmRecorder{dir: "dir1"}
+			toTf := &stateRmRecorder{dir: "dir2"}
+			_, _, err = action.MultiStateUpdate(context.Background(), fromTf, toTf, tfexec.NewState([]byte{}), tfexec.NewState([]byte{}))
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if len(fromTf.removed) != tc.wantFrom {
+				t.Errorf("got %d removed in from, want: %d", len(fromTf.removed), tc.wantFrom)
+			}
+			if len(toTf.removed) != tc.wantTo {
+				t.Errorf("got %d removed in to, want: %d", len(toTf.removed), tc.wantTo)
+			}
+		})
+	}
+}
+
+func TestMultiStateScopedActionExpand(t *testing.T) {
+	fromTf := &stateListStub{dir: "dir1", list: []string{"null_resource.foo", "null_resource.bar", "time_static.foo"}}
+	toTf := &stateListStub{dir: "dir2", list: []string{"null_resource.baz"}}
+
+	cases := []struct {
+		desc   string
+		cmdStr string
+		want   []string
+		ok     bool
+	}{
+		{
+			desc:   "from:xrm",
+			cmdStr: "from:xrm null_resource.*",
+			want:   []string{"from:rm null_resource.foo null_resource.bar"},
+			ok:     true,
+		},
+		{
+			desc:   "*:xrm",
+			cmdStr: "*:xrm null_resource.*",
+			want:   []string{"from:rm null_resource.foo null_resource.bar", "to:rm null_resource.baz"},
+			ok:     true,
+		},
+		{
+			desc:   "*:xrm with --expect checked for each state",
+			cmdStr: "*:xrm null_resource.* --expect 2",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "to:xrm no matches",
+			cmdStr: "to:xrm time_static.*",
+			want:   []string{},
+			ok:     true,
+		},
+		{
+			desc:   "from:rm",
+			cmdStr: "from:rm null_resource.foo",
+			want:   []string{"from:rm null_resource.foo"},
+			ok:     true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			action, err := NewMultiStateActionFromString(tc.cmdStr)
+			if err != nil {
+				t.Fatalf("failed to parse action: %s", err)
+			}
+
+			expanded, err := expandMultiStateAction(context.Background(), fromTf, toTf, nil, nil, action)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %#v", expanded)
+				}
+				return
+			}
+
+			got := []string{}
+			for _, a := range expanded {
+				got = append(got, a.String())
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -70,3 +70,8 @@ func (a *MultiStateXmvAction) generateMvActions(ctx context.Context, fromTf tfex
 
 	return multiStateMvActions, nil
 }
+
+// String returns a string representation of the action.
+func (a *MultiStateXmvAction) String() string {
+	return FormatAction("xmv", a.source, a.destination)
+}
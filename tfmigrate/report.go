@@ -0,0 +1,134 @@
+package tfmigrate
+
+import (
+	"log"
+	"sort"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+const (
+	// PlanResultNoChanges means that terraform plan has no changes.
+	PlanResultNoChanges = "no-changes"
+	// PlanResultChanges means that terraform plan has changes.
+	PlanResultChanges = "changes"
+	// PlanResultError means that terraform plan failed.
+	PlanResultError = "error"
+	// PlanResultSkipped means that terraform plan was skipped by skip_plan.
+	PlanResultSkipped = "skipped"
+)
+
+// Report is a summary of the last plan or apply of a migration.
+// It is intended to be written as a machine-readable report.
+type Report struct {
+	// Actions is a list of actions which were actually run.
+	// Actions containing wildcards such as xmv are expanded.
+	Actions []string
+	// Imports is a list of results for each row of an import manifest.
+	// It is empty if the migration has no import manifest.
+	Imports []ImportResult
+	// States is a list of reports for each affected state.
+	States []StateReport
+}
+
+// StateReport is a summary of changes in a state.
+type StateReport struct {
+	// Dir is a working directory of the state.
+	Dir string
+	// Workspace is a workspace of the state.
+	Workspace string
+	// PlanResult is a result of terraform plan for the new state.
+	// It is empty if the plan has not been run.
+	PlanResult string
+	// Added is a list of resource addresses added to the state.
+	Added []string
+	// Removed is a list of resource addresses removed from the state.
+	Removed []string
+}
+
+// ReportProvider is an optional interface of Migrator which provides a
+// report about the last plan or apply.
+type ReportProvider interface {
+	// Report returns a summary of the last plan or apply.
+	// It can be called even if the migration failed, in which case it
+	// returns what is known at that point.
+	Report() *Report
+}
+
+// planResultOf returns a plan result from an error returned by the terraform
+// plan command with the -detailed-exitcode option.
+func planResultOf(err error) string {
+	if err == nil {
+		return PlanResultNoChanges
+	}
+	if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
+		return PlanResultChanges
+	}
+	return PlanResultError
+}
+
+// newStateReport returns a new StateReport from given states.
+// The report is only for information, so we don't want to fail the migration
+// even if it fails to parse states. In that case, it logs a warning and
+// leaves the lists of resources empty.
+func newStateReport(tf tfexec.TerraformCLI, workspace string, before *tfexec.State, after *tfexec.State, planResult string) StateReport {
+	r := StateReport{
+		Dir:        tf.Dir(),
+		Workspace:  workspace,
+		PlanResult: planResult,
+		Added:      []string{},
+		Removed:    []string{},
+	}
+
+	if before == nil || after == nil {
+		return r
+	}
+
+	beforeList, err := stateResources(before)
+	if err != nil {
+		log.Printf("[WARN] [migrator@%s] failed to list resources in a state for report: %s\n", tf.Dir(), err)
+		return r
+	}
+	afterList, err := stateResources(after)
+	if err != nil {
+		log.Printf("[WARN] [migrator@%s] failed to list resources in a state for report: %s\n", tf.Dir(), err)
+		return r
+	}
+
+	r.Added = subtractStrings(afterList, beforeList)
+	r.Removed = subtractStrings(beforeList, afterList)
+	return r
+}
+
+// stateResources returns a list of resource addresses in a given state.
+// If the state is empty, it is assumed to be a new state and returns an
+// empty list.
+func stateResources(state *tfexec.State) ([]string, error) {
+	if len(state.Bytes()) == 0 {
+		return []string{}, nil
+	}
+
+	s, err := tfstate.ParseState(state.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return s.List(nil)
+}
+
+// subtractStrings returns a sorted list of elements in a but not in b.
+func subtractStrings(a []string, b []string) []string {
+	m := make(map[string]bool)
+	for _, e := range b {
+		m[e] = true
+	}
+
+	diff := []string{}
+	for _, e := range a {
+		if !m[e] {
+			diff = append(diff, e)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,142 @@
+package tfmigrate
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// testExitError implements the tfexec.ExitError interface for testing.
+type testExitError int
+
+func (e testExitError) String() string { return e.Error() }
+func (e testExitError) Error() string  { return fmt.Sprintf("exit status %d", int(e)) }
+func (e testExitError) ExitCode() int  { return int(e) }
+
+func TestPlanResultOf(t *testing.T) {
+	cases := []struct {
+		desc string
+		err  error
+		want string
+	}{
+		{
+			desc: "no changes",
+			err:  nil,
+			want: PlanResultNoChanges,
+		},
+		{
+			desc: "changes",
+			err:  testExitError(2),
+			want: PlanResultChanges,
+		},
+		{
+			desc: "exit 1",
+			err:  testExitError(1),
+			want: PlanResultError,
+		},
+		{
+			desc: "other error",
+			err:  errors.New("failed"),
+			want: PlanResultError,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := planResultOf(tc.err)
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewStateReport(t *testing.T) {
+	before := `{
+  "version": 4,
+  "serial": 1,
+  "lineage": "foo",
+  "resources": [
+    {"mode": "managed", "type": "null_resource", "name": "foo", "provider": "provider[\"registry.terraform.io/hashicorp/null\"]", "instances": [{"attributes": {}}]},
+    {"mode": "managed", "type": "null_resource", "name": "bar", "provider": "provider[\"registry.terraform.io/hashicorp/null\"]", "instances": [{"attributes": {}}]}
+  ]
+}`
+	after := `{
+  "version": 4,
+  "serial": 2,
+  "lineage": "foo",
+  "resources": [
+    {"mode": "managed", "type": "null_resource", "name": "foo2", "provider": "provider[\"registry.terraform.io/hashicorp/null\"]", "instances": [{"attributes": {}}]},
+    {"mode": "managed", "type": "null_resource", "name": "bar", "provider": "provider[\"registry.terraform.io/hashicorp/null\"]", "instances": [{"attributes": {}}]}
+  ]
+}`
+
+	cases := []struct {
+		desc   string
+		before *tfexec.State
+		after  *tfexec.State
+		want   StateReport
+	}{
+		{
+			desc:   "simple",
+			before: tfexec.NewState([]byte(before)),
+			after:  tfexec.NewState([]byte(after)),
+			want: StateReport{
+				Dir:        "dir1",
+				Workspace:  "default",
+				PlanResult: PlanResultNoChanges,
+				Added:      []string{"null_resource.foo2"},
+				Removed:    []string{"null_resource.foo"},
+			},
+		},
+		{
+			desc:   "new state",
+			before: tfexec.NewState([]byte{}),
+			after:  tfexec.NewState([]byte(after)),
+			want: StateReport{
+				Dir:        "dir1",
+				Workspace:  "default",
+				PlanResult: PlanResultNoChanges,
+				Added:      []string{"null_resource.bar", "null_resource.foo2"},
+				Removed:    []string{},
+			},
+		},
+		{
+			desc:   "not computed",
+			before: tfexec.NewState([]byte(before)),
+			after:  nil,
+			want: StateReport{
+				Dir:        "dir1",
+				Workspace:  "default",
+				PlanResult: PlanResultNoChanges,
+				Added:      []string{},
+				Removed:    []string{},
+			},
+		},
+		{
+			desc:   "parse error",
+			before: tfexec.NewState([]byte("foo")),
+			after:  tfexec.NewState([]byte(after)),
+			want: StateReport{
+				Dir:        "dir1",
+				Workspace:  "default",
+				PlanResult: PlanResultNoChanges,
+				Added:      []string{},
+				Removed:    []string{},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			tf := tfexec.NewTerraformCLI(tfexec.NewExecutor("dir1", nil))
+			got := newStateReport(tf, "default", tc.before, tc.after, PlanResultNoChanges)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,345 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// rmvExpander is a helper object for implementing regex expansion for rmv
+// actions. It is shared between single and multi state rmv actions.
+type rmvExpander struct {
+	// re is a regex anchored to a whole address.
+	re *regexp.Regexp
+	// template is a parsed template of destination.
+	template []rmvExpr
+}
+
+// newRmvExpander returns a new rmvExpander instance.
+// It fails if the pattern is not a valid regex or the template is invalid.
+func newRmvExpander(pattern string, template string) (*rmvExpander, error) {
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid rmv pattern %s: %s", pattern, err)
+	}
+
+	exprs, err := parseRmvTemplate(template, re)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rmv template %s: %s", template, err)
+	}
+
+	return &rmvExpander{
+		re:       re,
+		template: exprs,
+	}, nil
+}
+
+// expand returns mv actions for addresses in a given stateList matching the
+// pattern. The destList is a list of addresses in the state which the
+// resources move to. If sameState is true, the destList is the same state as
+// the stateList, and a match whose destination is the same as its source is
+// skipped. All moves are validated for collisions before returning them, that
+// is, a destination must not be shared by multiple sources and must not exist
+// in the destList.
+func (e *rmvExpander) expand(stateList []string, destList []string, sameState bool) ([]*StateMvAction, error) {
+	exists := make(map[string]bool, len(destList))
+	for _, addr := range destList {
+		exists[addr] = true
+	}
+
+	actions := []*StateMvAction{}
+	sources := make(map[string]string)
+	for _, src := range stateList {
+		m := e.re.FindStringSubmatch(src)
+		if m == nil {
+			continue
+		}
+
+		dst := e.render(m)
+		if sameState && dst == src {
+			continue
+		}
+		if other, ok := sources[dst]; ok {
+			return nil, fmt.Errorf("rmv action moves multiple resources to the same destination %s: %s, %s", dst, other, src)
+		}
+		if exists[dst] {
+			return nil, fmt.Errorf("rmv action moves %s to %s, but it already exists", src, dst)
+		}
+		sources[dst] = src
+		actions = append(actions, NewStateMvAction(src, dst))
+	}
+
+	return actions, nil
+}
+
+// render returns a destination for a given submatch of the pattern.
+func (e *rmvExpander) render(groups []string) string {
+	var b strings.Builder
+	for _, expr := range e.template {
+		b.WriteString(expr.eval(groups))
+	}
+	return b.String()
+}
+
+// rmvExpr is an expression in a template of rmv destination.
+type rmvExpr interface {
+	// eval returns a value of the expression for a given submatch.
+	eval(groups []string) string
+}
+
+// rmvLiteral is a literal string in a template.
+type rmvLiteral string
+
+// eval returns the literal as it is.
+func (l rmvLiteral) eval(_ []string) string {
+	return string(l)
+}
+
+// rmvGroupRef is a reference to a capture group by index.
+type rmvGroupRef int
+
+// eval returns a matched string of the group.
+func (r rmvGroupRef) eval(groups []string) string {
+	return groups[r]
+}
+
+// rmvCall is a call of a transform function.
+type rmvCall struct {
+	// fn is a transform function.
+	fn func(args []string) string
+	// args is a list of arguments of the function.
+	args []rmvExpr
+}
+
+// eval returns a result of the function applied to evaluated arguments.
+func (c *rmvCall) eval(groups []string) string {
+	args := make([]string, 0, len(c.args))
+	for _, arg := range c.args {
+		args = append(args, arg.eval(groups))
+	}
+	return c.fn(args)
+}
+
+// rmvFunc is a transform function available in a template.
+type rmvFunc struct {
+	// arity is a number of arguments.
+	arity int
+	// fn is an implementation of the function.
+	fn func(args []string) string
+}
+
+// rmvFuncs is a set of transform functions available in a template.
+var rmvFuncs = map[string]rmvFunc{
+	"lower": {
+		arity: 1,
+		fn:    func(args []string) string { return strings.ToLower(args[0]) },
+	},
+	"upper": {
+		arity: 1,
+		fn:    func(args []string) string { return strings.ToUpper(args[0]) },
+	},
+	"replace": {
+		arity: 3,
+		fn:    func(args []string) string { return strings.ReplaceAll(args[0], args[1], args[2]) },
+	},
+}
+
+// rmvFuncNames returns a sorted list of names of transform functions.
+func rmvFuncNames() []string {
+	names := make([]string, 0, len(rmvFuncs))
+	for name := range rmvFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// parseRmvTemplate parses a template of rmv destination.
+// The syntax is as follows.
+// `$$` is a literal `$`.
+// `$name` or `${name}` is a reference to a named or numbered capture group.
+// `${fn(arg, ...)}` is a call of a transform function, where an argument is
+// a reference, a call or a double-quoted string.
+// All references are resolved against a given regex.
+func parseRmvTemplate(template string, re *regexp.Regexp) ([]rmvExpr, error) {
+	p := &rmvTemplateParser{src: template, re: re}
+	return p.parse()
+}
+
+// rmvTemplateParser is a parser of rmv templates.
+type rmvTemplateParser struct {
+	// src is a template to be parsed.
+	src string
+	// pos is a current position in src.
+	pos int
+	// re is a regex to resolve references.
+	re *regexp.Regexp
+}
+
+// parse parses the whole template.
+func (p *rmvTemplateParser) parse() ([]rmvExpr, error) {
+	exprs := []rmvExpr{}
+	var literal strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c != '$' {
+			literal.WriteByte(c)
+			p.pos++
+			continue
+		}
+
+		p.pos++
+		if p.pos < len(p.src) && p.src[p.pos] == '$' {
+			literal.WriteByte('$')
+			p.pos++
+			continue
+		}
+
+		var expr rmvExpr
+		var err error
+		if p.pos < len(p.src) && p.src[p.pos] == '{' {
+			p.pos++
+			expr, err = p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpaces()
+			if !p.consume('}') {
+				return nil, fmt.Errorf("expected } at %d", p.pos)
+			}
+		} else {
+			expr, err = p.parseRef(p.parseIdent())
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if literal.Len() > 0 {
+			exprs = append(exprs, rmvLiteral(literal.String()))
+			literal.Reset()
+		}
+		exprs = append(exprs, expr)
+	}
+
+	if literal.Len() > 0 {
+		exprs = append(exprs, rmvLiteral(literal.String()))
+	}
+	return exprs, nil
+}
+
+// parseExpr parses a reference, a call or a string literal.
+func (p *rmvTemplateParser) parseExpr() (rmvExpr, error) {
+	p.skipSpaces()
+	if p.pos < len(p.src) && p.src[p.pos] == '"' {
+		return p.parseString()
+	}
+
+	ident := p.parseIdent()
+	p.skipSpaces()
+	if !p.consume('(') {
+		return p.parseRef(ident)
+	}
+
+	f, ok := rmvFuncs[ident]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q, valid functions are %s", ident, strings.Join(rmvFuncNames(), ", "))
+	}
+
+	args := []rmvExpr{}
+	p.skipSpaces()
+	if !p.consume(')') {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			p.skipSpaces()
+			if p.consume(')') {
+				break
+			}
+			if !p.consume(',') {
+				return nil, fmt.Errorf("expected , or ) at %d", p.pos)
+			}
+		}
+	}
+
+	if len(args) != f.arity {
+		return nil, fmt.Errorf("function %s takes %d arguments, but got %d", ident, f.arity, len(args))
+	}
+	return &rmvCall{fn: f.fn, args: args}, nil
+}
+
+// parseString parses a double-quoted string literal.
+func (p *rmvTemplateParser) parseString() (rmvExpr, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			s, err := strconv.Unquote(p.src[start:p.pos])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s: %s", p.src[start:p.pos], err)
+			}
+			return rmvLiteral(s), nil
+		default:
+			p.pos++
+		}
+	}
+	return nil, fmt.Errorf("unterminated string at %d", start)
+}
+
+// parseIdent parses an identifier, that is, a name of function or reference.
+func (p *rmvTemplateParser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.src) && isRmvIdentChar(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// parseRef resolves a given name or number of capture group.
+func (p *rmvTemplateParser) parseRef(name string) (rmvExpr, error) {
+	if name == "" {
+		return nil, fmt.Errorf("expected a reference at %d", p.pos)
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		if n > p.re.NumSubexp() {
+			return nil, fmt.Errorf("reference $%s is out of range, the pattern has %d groups", name, p.re.NumSubexp())
+		}
+		return rmvGroupRef(n), nil
+	}
+
+	i := p.re.SubexpIndex(name)
+	if i < 0 {
+		return nil, fmt.Errorf("reference $%s is not a named group in the pattern", name)
+	}
+	return rmvGroupRef(i), nil
+}
+
+// skipSpaces skips white spaces.
+func (p *rmvTemplateParser) skipSpaces() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// consume advances the position if a current character is a given one.
+func (p *rmvTemplateParser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// isRmvIdentChar returns true if a given character can be used in an identifier.
+func isRmvIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
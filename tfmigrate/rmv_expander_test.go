@@ -0,0 +1,221 @@
+package tfmigrate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRmvExpander(t *testing.T) {
+	cases := []struct {
+		desc     string
+		pattern  string
+		template string
+		ok       bool
+	}{
+		{
+			desc:     "named groups",
+			pattern:  `aws_iam_role\.(?P<name>\w+)`,
+			template: `module.iam.aws_iam_role.${name}`,
+			ok:       true,
+		},
+		{
+			desc:     "numbered groups",
+			pattern:  `aws_iam_role\.(\w+)`,
+			template: `module.iam.aws_iam_role.$1`,
+			ok:       true,
+		},
+		{
+			desc:     "functions",
+			pattern:  `aws_iam_role\.(?P<name>\w+)`,
+			template: `module.iam.aws_iam_role["${ lower(replace(name, "_", "-")) }"]`,
+			ok:       true,
+		},
+		{
+			desc:     "escaped dollar",
+			pattern:  `null_resource\.(\w+)`,
+			template: `null_resource.$$${1}`,
+			ok:       true,
+		},
+		{
+			desc:     "invalid regex",
+			pattern:  `null_resource\.(`,
+			template: `null_resource.foo`,
+			ok:       false,
+		},
+		{
+			desc:     "unknown group name",
+			pattern:  `null_resource\.(?P<name>\w+)`,
+			template: `null_resource.${env}`,
+			ok:       false,
+		},
+		{
+			desc:     "group number out of range",
+			pattern:  `null_resource\.(\w+)`,
+			template: `null_resource.${2}`,
+			ok:       false,
+		},
+		{
+			desc:     "unknown function",
+			pattern:  `null_resource\.(?P<name>\w+)`,
+			template: `null_resource.${title(name)}`,
+			ok:       false,
+		},
+		{
+			desc:     "wrong number of arguments",
+			pattern:  `null_resource\.(?P<name>\w+)`,
+			template: `null_resource.${replace(name, "_")}`,
+			ok:       false,
+		},
+		{
+			desc:     "unterminated reference",
+			pattern:  `null_resource\.(?P<name>\w+)`,
+			template: `null_resource.${name`,
+			ok:       false,
+		},
+		{
+			desc:     "unterminated string",
+			pattern:  `null_resource\.(?P<name>\w+)`,
+			template: `null_resource.${replace(name, "_, "-")}`,
+			ok:       false,
+		},
+		{
+			desc:     "empty reference",
+			pattern:  `null_resource\.(?P<name>\w+)`,
+			template: `null_resource.$.foo`,
+			ok:       false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := newRmvExpander(tc.pattern, tc.template)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+		})
+	}
+}
+
+func TestRmvExpanderExpand(t *testing.T) {
+	cases := []struct {
+		desc      string
+		pattern   string
+		template  string
+		stateList []string
+		destList  []string
+		sameState bool
+		want      []*StateMvAction
+		ok        bool
+	}{
+		{
+			desc:      "reshape keys",
+			pattern:   `module\.a\["(?P<env>[^"]+)"\]\.aws_iam_role\.r\[(?P<i>\d+)\]`,
+			template:  `module.b["${upper(env)}"].aws_iam_role.r["role-${i}"]`,
+			stateList: []string{`module.a["x"].aws_iam_role.r[0]`, `module.a["y"].aws_iam_role.r[1]`, `module.a["x"].aws_iam_policy.p[0]`},
+			sameState: true,
+			want: []*StateMvAction{
+				NewStateMvAction(`module.a["x"].aws_iam_role.r[0]`, `module.b["X"].aws_iam_role.r["role-0"]`),
+				NewStateMvAction(`module.a["y"].aws_iam_role.r[1]`, `module.b["Y"].aws_iam_role.r["role-1"]`),
+			},
+			ok: true,
+		},
+		{
+			desc:      "anchored to whole addresses",
+			pattern:   `aws_iam_role\.(\w+)`,
+			template:  `aws_iam_role.${1}_new`,
+			stateList: []string{"aws_iam_role.foo", "module.a.aws_iam_role.foo", "aws_iam_role.foo[0]"},
+			sameState: true,
+			want: []*StateMvAction{
+				NewStateMvAction("aws_iam_role.foo", "aws_iam_role.foo_new"),
+			},
+			ok: true,
+		},
+		{
+			desc:      "replace",
+			pattern:   `null_resource\.(?P<name>[\w-]+)`,
+			template:  `null_resource.${replace(name, "-", "_")}`,
+			stateList: []string{"null_resource.foo-bar", "null_resource.baz"},
+			sameState: true,
+			want: []*StateMvAction{
+				NewStateMvAction("null_resource.foo-bar", "null_resource.foo_bar"),
+			},
+			ok: true,
+		},
+		{
+			desc:      "no matches",
+			pattern:   `null_resource\.(\w+)`,
+			template:  `null_resource.${1}_new`,
+			stateList: []string{"time_static.foo"},
+			sameState: true,
+			want:      []*StateMvAction{},
+			ok:        true,
+		},
+		{
+			desc:      "same destination",
+			pattern:   `null_resource\.(?P<name>\w+)`,
+			template:  `null_resource.${lower(name)}`,
+			stateList: []string{"null_resource.Foo", "null_resource.FOO"},
+			sameState: true,
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:      "destination already exists",
+			pattern:   `null_resource\.(?P<name>\w+)_old`,
+			template:  `null_resource.${name}`,
+			stateList: []string{"null_resource.foo_old", "null_resource.foo"},
+			sameState: true,
+			want:      nil,
+			ok:        false,
+		},
+		{
+			desc:      "same address across states",
+			pattern:   `null_resource\.(\w+)`,
+			template:  `null_resource.$1`,
+			stateList: []string{"null_resource.foo"},
+			destList:  []string{"null_resource.bar"},
+			sameState: false,
+			want: []*StateMvAction{
+				NewStateMvAction("null_resource.foo", "null_resource.foo"),
+			},
+			ok: true,
+		},
+		{
+			desc:      "destination already exists across states",
+			pattern:   `null_resource\.(\w+)`,
+			template:  `null_resource.$1`,
+			stateList: []string{"null_resource.foo"},
+			destList:  []string{"null_resource.foo"},
+			sameState: false,
+			want:      nil,
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			e, err := newRmvExpander(tc.pattern, tc.template)
+			if err != nil {
+				t.Fatalf("failed to new rmv expander: %s", err)
+			}
+
+			destList := tc.destList
+			if tc.sameState {
+				destList = tc.stateList
+			}
+			got, err := e.expand(tc.stateList, destList, tc.sameState)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// recoveryStateFile is a file name of a state written to a working directory
+// to recover from an incomplete apply manually.
+const recoveryStateFile = "tfmigrate_recovery.tfstate"
+
+// IncompleteApplyError is an error which indicates that an apply failed in
+// the middle of pushing states and left them inconsistent.
+// For example, resources moved across states may exist in both states.
+// It has to be resolved manually before applying other migrations.
+type IncompleteApplyError struct {
+	// Err is an original error.
+	Err error
+	// Recovery is an instruction to recover the states manually.
+	Recovery string
+}
+
+var _ error = (*IncompleteApplyError)(nil)
+
+// Error returns a string representation of the error.
+func (e *IncompleteApplyError) Error() string {
+	return fmt.Sprintf("apply is incomplete and states may be inconsistent: %s\n%s", e.Err, e.Recovery)
+}
+
+// Unwrap returns an original error.
+func (e *IncompleteApplyError) Unwrap() error {
+	return e.Err
+}
+
+// rollbackState pushes a given original state back to remote after a new
+// state has been pushed. Since Terraform refuses to push a state with an
+// older serial, the serial of the original state is bumped.
+// It cannot roll back an empty state, that is, the state didn't exist before
+// applying the migration.
+func rollbackState(ctx context.Context, tf tfexec.TerraformCLI, original *tfexec.State, pushed *tfexec.State) error {
+	if original == nil || len(original.Bytes()) == 0 {
+		return fmt.Errorf("the original state is empty and cannot be pushed")
+	}
+
+	restored, err := bumpSerial(original, pushed)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] [migrator@%s] push the original state back to remote\n", tf.Dir())
+	return tf.StatePush(ctx, restored)
+}
+
+// recoveryInstruction writes a given state to a file in a working directory
+// and returns an instruction to push it manually. If force is true, the
+// instruction includes the -force flag to push a state with an older serial.
+func recoveryInstruction(tf tfexec.TerraformCLI, workspace string, state *tfexec.State, force bool) string {
+	path := filepath.Join(tf.Dir(), recoveryStateFile)
+	if err := os.WriteFile(path, state.Bytes(), 0600); err != nil {
+		log.Printf("[ERROR] [migrator@%s] failed to write a recovery state: %s\n", tf.Dir(), err)
+		return fmt.Sprintf("To recover, push the state in %s (workspace %s) manually.", tf.Dir(), workspace)
+	}
+
+	pushOpt := ""
+	if force {
+		pushOpt = " -force"
+	}
+	return fmt.Sprintf("To recover, run the following command:\n  cd %s && terraform workspace select %s && terraform state push%s %s",
+		tf.Dir(), workspace, pushOpt, recoveryStateFile)
+}
+
+// stateUpdate is a new state to be pushed to a remote state of a working
+// directory.
+type stateUpdate struct {
+	// tf is an instance of TerraformCLI which executes terraform command in
+	// the working directory.
+	tf tfexec.TerraformCLI
+	// workspace is a workspace of the remote state.
+	workspace string
+	// before is a state before applying the migration.
+	before *tfexec.State
+	// after is a new state to be pushed.
+	after *tfexec.State
+}
+
+// pushStates pushes given new states to remote in order.
+// When moving resources across states, states to which resources move should
+// be ordered before states from which they move, so that resources are never
+// lost from all states. If it fails to push a state, it rolls back the states
+// already pushed.
+func pushStates(ctx context.Context, updates []*stateUpdate) error {
+	pushed := []*stateUpdate{}
+	for _, u := range updates {
+		log.Printf("[INFO] [migrator@%s] push the new state to remote\n", u.tf.Dir())
+		if err := u.tf.StatePush(ctx, u.after); err != nil {
+			cause := fmt.Errorf("failed to push the new state in %s: %s", u.tf.Dir(), err)
+			return rollbackStates(ctx, updates, pushed, cause)
+		}
+		pushed = append(pushed, u)
+	}
+	return nil
+}
+
+// rollbackStates rolls back given states which have been pushed and returns
+// an error wrapping a given cause. If it fails to roll back, it returns an
+// IncompleteApplyError with instructions to recover the states manually.
+func rollbackStates(ctx context.Context, updates []*stateUpdate, pushed []*stateUpdate, cause error) error {
+	if len(pushed) == 0 {
+		return cause
+	}
+
+	rolledBack := []*stateUpdate{}
+	failed := []*stateUpdate{}
+	errs := []string{}
+	for _, u := range pushed {
+		log.Printf("[ERROR] [migrator] %s, roll back the state in %s\n", cause, u.tf.Dir())
+		if err := rollbackState(ctx, u.tf, u.before, u.after); err != nil {
+			log.Printf("[ERROR] [migrator@%s] failed to roll back the state: %s\n", u.tf.Dir(), err)
+			failed = append(failed, u)
+			errs = append(errs, fmt.Sprintf("%s: %s", u.tf.Dir(), err))
+			continue
+		}
+		log.Printf("[INFO] [migrator@%s] rolled back the state\n", u.tf.Dir())
+		rolledBack = append(rolledBack, u)
+	}
+
+	if len(failed) == 0 {
+		dirs := []string{}
+		for _, u := range rolledBack {
+			dirs = append(dirs, u.tf.Dir())
+		}
+		return fmt.Errorf("%s, the states in %s have been rolled back", cause, strings.Join(dirs, ", "))
+	}
+
+	recovery := []string{}
+	if canRollbackManually(failed) {
+		// roll back the states which failed to roll back manually.
+		for _, u := range failed {
+			recovery = append(recovery, recoveryInstruction(u.tf, u.workspace, u.before, true))
+		}
+	} else {
+		// Some states didn't exist before the migration, so complete the
+		// migration by pushing all the new states manually instead.
+		for _, u := range updates {
+			if containsStateUpdate(failed, u) {
+				continue
+			}
+			// A rolled back state has a greater serial than the new state.
+			force := containsStateUpdate(rolledBack, u)
+			recovery = append(recovery, recoveryInstruction(u.tf, u.workspace, u.after, force))
+		}
+	}
+
+	return &IncompleteApplyError{
+		Err:      fmt.Errorf("%s, failed to roll back the states: %s", cause, strings.Join(errs, ", ")),
+		Recovery: strings.Join(recovery, "\n"),
+	}
+}
+
+// canRollbackManually returns true if all the states before the migration
+// of given updates are not empty.
+func canRollbackManually(updates []*stateUpdate) bool {
+	for _, u := range updates {
+		if u.before == nil || len(u.before.Bytes()) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// containsStateUpdate returns true if a given list contains a given update.
+func containsStateUpdate(updates []*stateUpdate, u *stateUpdate) bool {
+	for _, x := range updates {
+		if x == u {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package tfmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// pushRecorder is a TerraformCLI which records pushed states for testing.
+// It overrides only StatePush and Dir. Calling other methods panics.
+type pushRecorder struct {
+	tfexec.TerraformCLI
+	// dir is a working directory.
+	dir string
+	// errs is a sequence of errors returned by StatePush.
+	errs []error
+	// pushed is a list of pushed states.
+	pushed []*tfexec.State
+}
+
+// StatePush records a given state and returns an error if set.
+func (tf *pushRecorder) StatePush(_ context.Context, state *tfexec.State, _ ...string) error {
+	i := len(tf.pushed)
+	tf.pushed = append(tf.pushed, state)
+	if i < len(tf.errs) {
+		return tf.errs[i]
+	}
+	return nil
+}
+
+// Dir returns a working directory.
+func (tf *pushRecorder) Dir() string {
+	return tf.dir
+}
+
+func TestMultiStateMigratorPush(t *testing.T) {
+	fromBefore := `{"version": 4, "serial": 1, "lineage": "from", "resources": []}`
+	toBefore := `{"version": 4, "serial": 3, "lineage": "to", "resources": []}`
+	fromAfter := `{"version": 4, "serial": 2, "lineage": "from", "resources": []}`
+	toAfter := `{"version": 4, "serial": 4, "lineage": "to", "resources": []}`
+
+	cases := []struct {
+		desc       string
+		fromErrs   []error
+		toErrs     []error
+		toBefore   string
+		toPushed   int
+		incomplete bool
+		recovery   string
+		ok         bool
+	}{
+		{
+			desc:     "success",
+			toBefore: toBefore,
+			toPushed: 1,
+			ok:       true,
+		},
+		{
+			desc:     "failed to push toState",
+			toErrs:   []error{fmt.Errorf("failed to push")},
+			toBefore: toBefore,
+			toPushed: 1,
+			ok:       false,
+		},
+		{
+			desc:     "rolled back",
+			fromErrs: []error{fmt.Errorf("failed to push")},
+			toBefore: toBefore,
+			toPushed: 2,
+			ok:       false,
+		},
+		{
+			desc:       "failed to roll back",
+			fromErrs:   []error{fmt.Errorf("failed to push")},
+			toErrs:     []error{nil, fmt.Errorf("failed to roll back")},
+			toBefore:   toBefore,
+			toPushed:   2,
+			incomplete: true,
+			recovery:   "terraform state push -force " + recoveryStateFile,
+			ok:         false,
+		},
+		{
+			desc:       "cannot roll back an empty state",
+			fromErrs:   []error{fmt.Errorf("failed to push")},
+			toBefore:   "",
+			toPushed:   1,
+			incomplete: true,
+			recovery:   "terraform state push " + recoveryStateFile,
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fromTf := &pushRecorder{dir: t.TempDir(), errs: tc.fromErrs}
+			toTf := &pushRecorder{dir: t.TempDir(), errs: tc.toErrs}
+			m := &MultiStateMigrator{
+				from: &workDir{
+					tf:          fromTf,
+					workspace:   "default",
+					beforeState: tfexec.NewState([]byte(fromBefore)),
+					afterState:  tfexec.NewState([]byte(fromAfter)),
+				},
+				to: &workDir{
+					tf:          toTf,
+					workspace:   "default",
+					beforeState: tfexec.NewState([]byte(tc.toBefore)),
+					afterState:  tfexec.NewState([]byte(toAfter)),
+				},
+			}
+
+			err := m.push(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			if len(toTf.pushed) != tc.toPushed {
+				t.Fatalf("got %d pushes to toDir, want: %d", len(toTf.pushed), tc.toPushed)
+			}
+			if tc.toPushed == 2 {
+				// The rolled back state should have a serial greater than the pushed one.
+				identity, err := parseStateIdentity(toTf.pushed[1])
+				if err != nil {
+					t.Fatalf("failed to get serial: %s", err)
+				}
+				if identity.Serial != 5 {
+					t.Errorf("got serial: %d, want: 5", identity.Serial)
+				}
+			}
+
+			var ierr *IncompleteApplyError
+			if got := errors.As(err, &ierr); got != tc.incomplete {
+				t.Fatalf("got incomplete: %t, want: %t, err: %v", got, tc.incomplete, err)
+			}
+			if !tc.incomplete {
+				return
+			}
+			if !strings.Contains(ierr.Recovery, tc.recovery) {
+				t.Errorf("expected recovery to contain %q, but got: %s", tc.recovery, ierr.Recovery)
+			}
+
+			// The recovery state should be written to the working directory.
+			dir := toTf.dir
+			want := tc.toBefore
+			if len(tc.toBefore) == 0 {
+				dir = fromTf.dir
+				want = fromAfter
+			}
+			b, err := os.ReadFile(filepath.Join(dir, recoveryStateFile))
+			if err != nil {
+				t.Fatalf("failed to read a recovery state: %s", err)
+			}
+			if string(b) != want {
+				t.Errorf("got recovery state: %s, want: %s", string(b), want)
+			}
+		})
+	}
+}
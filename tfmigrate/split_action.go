@@ -0,0 +1,100 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SplitAction is an action of split migration.
+// It moves resources from a source dir to one of named destinations.
+type SplitAction struct {
+	// destination is a name of destination to move resources to.
+	destination string
+	// action is a multi state action which moves resources from the source
+	// dir to the destination.
+	action MultiStateAction
+}
+
+// newSplitAction returns a new SplitAction instance.
+func newSplitAction(destination string, action MultiStateAction) *SplitAction {
+	return &SplitAction{
+		destination: destination,
+		action:      action,
+	}
+}
+
+// NewSplitActionFromString is a factory method which returns a new
+// SplitAction from a given string.
+// cmdStr is a plain text for state operation.
+// Valid formats are the following.
+// "mv <source> <name>:<destination>"
+// "xmv <source> <name>:<destination>"
+func NewSplitActionFromString(cmdStr string) (*SplitAction, error) {
+	args, err := splitStateAction(cmdStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse action: %s, err: %s", cmdStr, err)
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("split action is empty: %s", cmdStr)
+	}
+	actionType := args[0]
+
+	// switch by action type and parse arguments and build an action.
+	switch actionType {
+	case "mv", "xmv":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("split %s action is invalid: %s", actionType, cmdStr)
+		}
+		src := args[1]
+		name, dst, err := parseSplitDestination(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("split %s action is invalid: %s, err: %s", actionType, cmdStr, err)
+		}
+		if actionType == "mv" {
+			return newSplitAction(name, NewMultiStateMvAction(src, dst)), nil
+		}
+		return newSplitAction(name, NewMultiStateXmvAction(src, dst)), nil
+
+	default:
+		return nil, fmt.Errorf("unknown split action type: %s", cmdStr)
+	}
+}
+
+// splitDestinationNameRe is a regular expression which matches a valid name
+// of destination.
+var splitDestinationNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// parseSplitDestination parses a destination prefixed with a name of
+// destination and returns the name and the address.
+// (e.g.) `network:aws_vpc.main` => `network`, `aws_vpc.main`
+func parseSplitDestination(s string) (string, string, error) {
+	i := strings.Index(s, ":")
+	if i == -1 {
+		return "", "", fmt.Errorf("destination must be prefixed with a name of destination such as <name>:<address>: %s", s)
+	}
+
+	name := s[:i]
+	addr := s[i+1:]
+	if !splitDestinationNameRe.MatchString(name) {
+		return "", "", fmt.Errorf("invalid name of destination: %s", s)
+	}
+	if len(addr) == 0 {
+		return "", "", fmt.Errorf("address of destination is empty: %s", s)
+	}
+	return name, addr, nil
+}
+
+// String returns a string representation of the action.
+// It can be parsed by NewSplitActionFromString.
+func (a *SplitAction) String() string {
+	switch action := a.action.(type) {
+	case *MultiStateMvAction:
+		return FormatAction("mv", action.source, a.destination+":"+action.destination)
+	case *MultiStateXmvAction:
+		return FormatAction("xmv", action.source, a.destination+":"+action.destination)
+	default:
+		return action.String()
+	}
+}
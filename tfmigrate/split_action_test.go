@@ -0,0 +1,81 @@
+package tfmigrate
+
+import (
+	"testing"
+)
+
+func TestNewSplitActionFromString(t *testing.T) {
+	cases := []struct {
+		desc   string
+		cmdStr string
+		want   string
+		ok     bool
+	}{
+		{
+			desc:   "mv action",
+			cmdStr: "mv aws_vpc.main network:aws_vpc.main",
+			want:   "mv aws_vpc.main network:aws_vpc.main",
+			ok:     true,
+		},
+		{
+			desc:   "xmv action",
+			cmdStr: "xmv aws_instance.* app:module.app.aws_instance.$1",
+			want:   "xmv aws_instance.* app:module.app.aws_instance.$1",
+			ok:     true,
+		},
+		{
+			desc:   "string key with colon",
+			cmdStr: `mv 'aws_instance.foo["a:b"]' 'app:aws_instance.foo["a:b"]'`,
+			want:   `mv 'aws_instance.foo["a:b"]' 'app:aws_instance.foo["a:b"]'`,
+			ok:     true,
+		},
+		{
+			desc:   "no destination name",
+			cmdStr: "mv aws_vpc.main aws_vpc.main",
+			ok:     false,
+		},
+		{
+			desc:   "invalid destination name",
+			cmdStr: `mv aws_vpc.main 'aws_vpc.foo["a"]:aws_vpc.main'`,
+			ok:     false,
+		},
+		{
+			desc:   "empty address",
+			cmdStr: "mv aws_vpc.main network:",
+			ok:     false,
+		},
+		{
+			desc:   "too many args",
+			cmdStr: "mv aws_vpc.main network:aws_vpc.main foo",
+			ok:     false,
+		},
+		{
+			desc:   "unknown type",
+			cmdStr: "rm aws_vpc.main",
+			ok:     false,
+		},
+		{
+			desc:   "empty",
+			cmdStr: "",
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := NewSplitActionFromString(tc.cmdStr)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok {
+				if err == nil {
+					t.Fatalf("expected to return an error, but no error, got: %#v", got)
+				}
+				return
+			}
+			if got.String() != tc.want {
+				t.Errorf("got: %s, want: %s", got.String(), tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,329 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// SplitMigratorConfig is a config for SplitMigrator.
+type SplitMigratorConfig struct {
+	// FromDir is a working directory where states of resources move from.
+	FromDir string `hcl:"from_dir"`
+	// FromWorkspace is a workspace within FromDir.
+	FromWorkspace string `hcl:"from_workspace,optional"`
+	// FromSkipPlan controls whether or not to run and analyze Terraform plan
+	// within the from_dir.
+	FromSkipPlan bool `hcl:"from_skip_plan,optional"`
+	// To is a list of named destinations where states of resources move to.
+	To []SplitDestinationConfig `hcl:"to,block"`
+	// Actions is a list of split action.
+	// Each action is a plain text for state operation.
+	// Valid formats are the following.
+	// "mv <source> <name>:<destination>"
+	// "xmv <source> <name>:<destination>"
+	Actions []string `hcl:"actions"`
+	// Force option controls behaviour in case of unexpected diff in plan.
+	// When set forces applying even if plan shows diff.
+	Force bool `hcl:"force,optional"`
+}
+
+// SplitDestinationConfig is a config for a named destination of split
+// migration.
+type SplitDestinationConfig struct {
+	// Name is a name of destination referenced in actions.
+	Name string `hcl:"name,label"`
+	// Dir is a working directory where states of resources move to.
+	Dir string `hcl:"dir"`
+	// Workspace is a workspace within Dir.
+	Workspace string `hcl:"workspace,optional"`
+	// SkipPlan controls whether or not to run and analyze Terraform plan
+	// within the dir.
+	SkipPlan bool `hcl:"skip_plan,optional"`
+}
+
+// SplitMigratorConfig implements a MigratorConfig.
+var _ MigratorConfig = (*SplitMigratorConfig)(nil)
+
+// NewMigrator returns a new instance of SplitMigrator.
+func (c *SplitMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
+	if len(c.To) == 0 {
+		return nil, fmt.Errorf("failed to NewMigrator with no destinations")
+	}
+	if len(c.Actions) == 0 {
+		return nil, fmt.Errorf("failed to NewMigrator with no actions")
+	}
+
+	// Since a working directory is switched to a local backend during plan,
+	// each directory can be used only once.
+	names := make(map[string]bool)
+	dirs := map[string]bool{filepath.Clean(c.FromDir): true}
+	for _, to := range c.To {
+		if !splitDestinationNameRe.MatchString(to.Name) {
+			return nil, fmt.Errorf("invalid name of destination: %s", to.Name)
+		}
+		if names[to.Name] {
+			return nil, fmt.Errorf("duplicate name of destination: %s", to.Name)
+		}
+		names[to.Name] = true
+		dir := filepath.Clean(to.Dir)
+		if dirs[dir] {
+			return nil, fmt.Errorf("dir is used more than once in split migration: %s", to.Dir)
+		}
+		dirs[dir] = true
+	}
+
+	// build actions from config.
+	actions := []*SplitAction{}
+	for _, cmdStr := range c.Actions {
+		action, err := NewSplitActionFromString(cmdStr)
+		if err != nil {
+			return nil, err
+		}
+		if !names[action.destination] {
+			return nil, fmt.Errorf("unknown destination in split action: %s", cmdStr)
+		}
+		actions = append(actions, action)
+	}
+
+	// use default workspace if not specified by user
+	if len(c.FromWorkspace) == 0 {
+		c.FromWorkspace = "default"
+	}
+	for i := range c.To {
+		if len(c.To[i].Workspace) == 0 {
+			c.To[i].Workspace = "default"
+		}
+	}
+
+	return NewSplitMigrator(c.FromDir, c.FromWorkspace, c.FromSkipPlan, c.To, actions, o, c.Force), nil
+}
+
+// splitDestination is a named destination of SplitMigrator.
+type splitDestination struct {
+	// name is a name of destination referenced in actions.
+	name string
+	// workDir is a working directory of the destination.
+	*workDir
+}
+
+// SplitMigrator implements the Migrator interface.
+// It moves resources from a source dir to multiple named destinations.
+type SplitMigrator struct {
+	// from is a working directory where states of resources move from.
+	from *workDir
+	// destinations is a list of destinations in the order of config.
+	destinations []*splitDestination
+	// actions is a list of split migration operations.
+	actions []*SplitAction
+	// o is an option for migrator.
+	// It is used for shared settings across Migrator instances.
+	o *MigratorOption
+	// force operation in case of unexpected diff
+	force bool
+	// expandedActions is a list of actions actually run, which is set by plan.
+	expandedActions []string
+	// metadata is metadata about the last applied migration.
+	metadata *ApplyMetadata
+}
+
+var _ Migrator = (*SplitMigrator)(nil)
+var _ ApplyMetadataProvider = (*SplitMigrator)(nil)
+var _ ReportProvider = (*SplitMigrator)(nil)
+
+// NewSplitMigrator returns a new SplitMigrator instance.
+func NewSplitMigrator(fromDir string, fromWorkspace string, fromSkipPlan bool, to []SplitDestinationConfig,
+	actions []*SplitAction, o *MigratorOption, force bool) *SplitMigrator {
+	destinations := make([]*splitDestination, 0, len(to))
+	for _, d := range to {
+		destinations = append(destinations, &splitDestination{
+			name:    d.Name,
+			workDir: newWorkDir(d.Dir, d.Workspace, d.SkipPlan, fmt.Sprintf("to %q", d.Name), o),
+		})
+	}
+
+	return &SplitMigrator{
+		from:         newWorkDir(fromDir, fromWorkspace, fromSkipPlan, "from_dir", o),
+		destinations: destinations,
+		actions:      actions,
+		o:            o,
+		force:        force,
+	}
+}
+
+// destination returns a destination of a given name.
+func (m *SplitMigrator) destination(name string) (*splitDestination, error) {
+	for _, d := range m.destinations {
+		if d.name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown destination: %s", name)
+}
+
+// plan computes new states by applying split migration operations to
+// temporary states. Each working directory is initialized only once.
+// It will fail if terraform plan detects any diffs with at least one new state.
+func (m *SplitMigrator) plan(ctx context.Context) (fromCurrentState *tfexec.State, err error) {
+	// setup fromDir.
+	fromCurrentState, fromSwitchBackToRemoteFunc, err := m.from.setup(ctx, m.o, false)
+	if err != nil {
+		return nil, err
+	}
+	// switch back it to remote on exit.
+	defer func() {
+		err = multierror.Append(err, fromSwitchBackToRemoteFunc())
+	}()
+
+	m.expandedActions = []string{}
+
+	// setup dirs of destinations.
+	toCurrentStates := make(map[string]*tfexec.State)
+	for _, d := range m.destinations {
+		var toCurrentState *tfexec.State
+		var switchBackToRemoteFunc func() error
+		toCurrentState, switchBackToRemoteFunc, err = d.setup(ctx, m.o, false)
+		if err != nil {
+			return nil, err
+		}
+		// switch back it to remote on exit.
+		defer func() {
+			err = multierror.Append(err, switchBackToRemoteFunc())
+		}()
+
+		toCurrentStates[d.name] = toCurrentState
+	}
+
+	// computes new states by applying state migration operations to temporary states.
+	log.Printf("[INFO] [migrator] compute new states (%s => %d destinations)\n", m.from.tf.Dir(), len(m.destinations))
+	for _, action := range m.actions {
+		d, err := m.destination(action.destination)
+		if err != nil {
+			return nil, err
+		}
+
+		// expand actions to record actions actually run.
+		expanded, err := expandMultiStateAction(ctx, m.from.tf, d.tf, fromCurrentState, toCurrentStates[d.name], action.action)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range expanded {
+			fromNewState, toNewState, err := a.MultiStateUpdate(ctx, m.from.tf, d.tf, fromCurrentState, toCurrentStates[d.name])
+			if err != nil {
+				return nil, err
+			}
+			fromCurrentState = tfexec.NewState(fromNewState.Bytes())
+			toCurrentStates[d.name] = tfexec.NewState(toNewState.Bytes())
+			m.expandedActions = append(m.expandedActions, newSplitAction(d.name, a).String())
+		}
+	}
+	m.from.afterState = fromCurrentState
+	for _, d := range m.destinations {
+		d.afterState = toCurrentStates[d.name]
+	}
+
+	if err := m.from.checkPlan(ctx, m.force, m.o.PlanOut, nil); err != nil {
+		return nil, err
+	}
+	for _, d := range m.destinations {
+		if err := d.checkPlan(ctx, m.force, m.o.PlanOut, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return fromCurrentState, nil
+}
+
+// Plan computes new states by applying split migration operations to temporary states.
+// It will fail if terraform plan detects any diffs with at least one new state.
+func (m *SplitMigrator) Plan(ctx context.Context) error {
+	log.Printf("[INFO] [migrator] start split migrator plan\n")
+	_, err := m.plan(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("[INFO] [migrator] split migrator plan success!\n")
+	return nil
+}
+
+// Apply computes new states and pushes them to remote states.
+// It will fail if terraform plan detects any diffs with at least one new state.
+// We are intended to this is used for state refactoring.
+// Any state migration operations should not break any real resources.
+func (m *SplitMigrator) Apply(ctx context.Context) error {
+	// Check if new states don't have any diffs compared to real resources
+	// before push new states to remote.
+	log.Printf("[INFO] [migrator] start split migrator plan phase for apply\n")
+	if _, err := m.plan(ctx); err != nil {
+		return err
+	}
+
+	// push the new states to remote.
+	log.Printf("[INFO] [migrator] start split migrator apply phase\n")
+	if err := ensureRemoteStateUnchanged(ctx, m.from.tf, m.from.beforeState); err != nil {
+		return err
+	}
+	for _, d := range m.destinations {
+		if err := ensureRemoteStateUnchanged(ctx, d.tf, d.beforeState); err != nil {
+			return err
+		}
+	}
+
+	snapshots := []StateSnapshot{m.from.snapshot()}
+	for _, d := range m.destinations {
+		snapshots = append(snapshots, d.snapshot())
+	}
+	if err := saveSnapshots(ctx, m.o, snapshots); err != nil {
+		return err
+	}
+
+	if err := m.push(ctx); err != nil {
+		return err
+	}
+
+	states := []StateApplyMetadata{m.from.applyMetadata()}
+	for _, d := range m.destinations {
+		states = append(states, d.applyMetadata())
+	}
+	m.metadata = newApplyMetadata(ctx, m.from.tf, m.expandedActions, states)
+
+	log.Printf("[INFO] [migrator] split migrator apply success!\n")
+	return nil
+}
+
+// push pushes new states to remote.
+// We push all states of destinations before the state of the source, because
+// when moving resources across states, write them to new states first and
+// then remove them from old one. If it fails to push a state, it rolls back
+// the states already pushed not to leave the resources in multiple states.
+func (m *SplitMigrator) push(ctx context.Context) error {
+	updates := []*stateUpdate{}
+	for _, d := range m.destinations {
+		updates = append(updates, d.stateUpdate())
+	}
+	updates = append(updates, m.from.stateUpdate())
+
+	return pushStates(ctx, updates)
+}
+
+// ApplyMetadata returns metadata about the last applied migration.
+// If the migration has not been applied successfully, it returns nil.
+func (m *SplitMigrator) ApplyMetadata() *ApplyMetadata {
+	return m.metadata
+}
+
+// Report returns a summary of the last plan or apply.
+func (m *SplitMigrator) Report() *Report {
+	states := []StateReport{m.from.report()}
+	for _, d := range m.destinations {
+		states = append(states, d.report())
+	}
+	return &Report{
+		Actions: m.expandedActions,
+		States:  states,
+	}
+}
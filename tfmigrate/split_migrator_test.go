@@ -0,0 +1,351 @@
+package tfmigrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestSplitMigratorConfigNewMigrator(t *testing.T) {
+	cases := []struct {
+		desc   string
+		config *SplitMigratorConfig
+		o      *MigratorOption
+		ok     bool
+	}{
+		{
+			desc: "valid",
+			config: &SplitMigratorConfig{
+				FromDir: "dir1",
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: "dir2"},
+					{Name: "app", Dir: "dir3", Workspace: "work1"},
+				},
+				Actions: []string{
+					"mv aws_vpc.main network:aws_vpc.main",
+					"xmv aws_instance.* app:aws_instance.$1",
+				},
+			},
+			o: &MigratorOption{
+				ExecPath: "direnv exec . terraform",
+			},
+			ok: true,
+		},
+		{
+			desc: "no destinations",
+			config: &SplitMigratorConfig{
+				FromDir: "dir1",
+				Actions: []string{
+					"mv aws_vpc.main network:aws_vpc.main",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "no actions",
+			config: &SplitMigratorConfig{
+				FromDir: "dir1",
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: "dir2"},
+				},
+				Actions: []string{},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "unknown destination",
+			config: &SplitMigratorConfig{
+				FromDir: "dir1",
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: "dir2"},
+				},
+				Actions: []string{
+					"mv aws_instance.foo app:aws_instance.foo",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "duplicate destination name",
+			config: &SplitMigratorConfig{
+				FromDir: "dir1",
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: "dir2"},
+					{Name: "network", Dir: "dir3"},
+				},
+				Actions: []string{
+					"mv aws_vpc.main network:aws_vpc.main",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "dir used more than once",
+			config: &SplitMigratorConfig{
+				FromDir: "dir1",
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: "dir2"},
+					{Name: "app", Dir: "./dir1"},
+				},
+				Actions: []string{
+					"mv aws_vpc.main network:aws_vpc.main",
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.config.NewMigrator(tc.o)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				_ = got.(*SplitMigrator)
+			}
+		})
+	}
+}
+
+func TestSplitMigratorPush(t *testing.T) {
+	fromAfter := `{"version": 4, "serial": 2, "lineage": "from", "resources": []}`
+	before := `{"version": 4, "serial": 3, "lineage": "to", "resources": []}`
+	after := `{"version": 4, "serial": 4, "lineage": "to", "resources": []}`
+
+	cases := []struct {
+		desc       string
+		fromErrs   []error
+		toErrs     [][]error
+		toBefore   []string
+		toPushed   []int
+		fromPushed int
+		incomplete bool
+		recovery   []string
+		// recoveryIn is an index of destination where a recovery state is written.
+		recoveryIn int
+		ok         bool
+	}{
+		{
+			desc:       "success",
+			toBefore:   []string{before, before},
+			toPushed:   []int{1, 1},
+			fromPushed: 1,
+			ok:         true,
+		},
+		{
+			desc:       "failed to push the first destination",
+			toErrs:     [][]error{{fmt.Errorf("failed to push")}, nil},
+			toBefore:   []string{before, before},
+			toPushed:   []int{1, 0},
+			fromPushed: 0,
+			ok:         false,
+		},
+		{
+			desc:       "failed to push the second destination and rolled back",
+			toErrs:     [][]error{nil, {fmt.Errorf("failed to push")}},
+			toBefore:   []string{before, before},
+			toPushed:   []int{2, 1},
+			fromPushed: 0,
+			ok:         false,
+		},
+		{
+			desc:       "failed to push the source and rolled back",
+			fromErrs:   []error{fmt.Errorf("failed to push")},
+			toBefore:   []string{before, before},
+			toPushed:   []int{2, 2},
+			fromPushed: 1,
+			ok:         false,
+		},
+		{
+			desc:       "failed to roll back",
+			fromErrs:   []error{fmt.Errorf("failed to push")},
+			toErrs:     [][]error{nil, {nil, fmt.Errorf("failed to roll back")}},
+			toBefore:   []string{before, before},
+			toPushed:   []int{2, 2},
+			fromPushed: 1,
+			incomplete: true,
+			recovery:   []string{"terraform state push -force " + recoveryStateFile},
+			recoveryIn: 1,
+			ok:         false,
+		},
+		{
+			desc:       "cannot roll back an empty state",
+			fromErrs:   []error{fmt.Errorf("failed to push")},
+			toBefore:   []string{before, ""},
+			toPushed:   []int{2, 1},
+			fromPushed: 1,
+			incomplete: true,
+			recovery: []string{
+				"terraform state push -force " + recoveryStateFile,
+				"terraform state push " + recoveryStateFile,
+			},
+			recoveryIn: 0,
+			ok:         false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fromTf := &pushRecorder{dir: t.TempDir(), errs: tc.fromErrs}
+			m := &SplitMigrator{
+				from: &workDir{
+					tf:         fromTf,
+					workspace:  "default",
+					afterState: tfexec.NewState([]byte(fromAfter)),
+				},
+			}
+			toTfs := []*pushRecorder{}
+			for i, name := range []string{"network", "app"} {
+				var errs []error
+				if i < len(tc.toErrs) {
+					errs = tc.toErrs[i]
+				}
+				tf := &pushRecorder{dir: t.TempDir(), errs: errs}
+				toTfs = append(toTfs, tf)
+				m.destinations = append(m.destinations, &splitDestination{
+					name: name,
+					workDir: &workDir{
+						tf:          tf,
+						workspace:   "default",
+						beforeState: tfexec.NewState([]byte(tc.toBefore[i])),
+						afterState:  tfexec.NewState([]byte(after)),
+					},
+				})
+			}
+
+			err := m.push(context.Background())
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+
+			for i, tf := range toTfs {
+				if len(tf.pushed) != tc.toPushed[i] {
+					t.Errorf("got %d pushes to destination %d, want: %d", len(tf.pushed), i, tc.toPushed[i])
+				}
+			}
+			if len(fromTf.pushed) != tc.fromPushed {
+				t.Errorf("got %d pushes to source, want: %d", len(fromTf.pushed), tc.fromPushed)
+			}
+
+			var ierr *IncompleteApplyError
+			if got := errors.As(err, &ierr); got != tc.incomplete {
+				t.Fatalf("got incomplete: %t, want: %t, err: %v", got, tc.incomplete, err)
+			}
+			if !tc.incomplete {
+				return
+			}
+			for _, r := range tc.recovery {
+				if !strings.Contains(ierr.Recovery, r) {
+					t.Errorf("expected recovery to contain %q, but got: %s", r, ierr.Recovery)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(toTfs[tc.recoveryIn].dir, recoveryStateFile)); err != nil {
+				t.Errorf("expected a recovery state to be written: %s", err)
+			}
+		})
+	}
+}
+
+func TestAccSplitMigratorApply(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+	ctx := context.Background()
+
+	// setup the initial files and states
+	fromBackend := tfexec.GetTestAccBackendS3Config(t.Name() + "/fromDir")
+	fromSource := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "null_resource" "baz" {}
+`
+	fromTf := tfexec.SetupTestAccWithApply(t, "default", fromBackend+fromSource)
+
+	to1Backend := tfexec.GetTestAccBackendS3Config(t.Name() + "/to1Dir")
+	to1Source := `
+resource "null_resource" "qux" {}
+`
+	to1Tf := tfexec.SetupTestAccWithApply(t, "default", to1Backend+to1Source)
+
+	to2Backend := tfexec.GetTestAccBackendS3Config(t.Name() + "/to2Dir")
+	to2Tf := tfexec.SetupTestAccWithApply(t, "default", to2Backend)
+
+	// update terraform resource files for migration
+	tfexec.UpdateTestAccSource(t, fromTf, fromBackend+`
+resource "null_resource" "baz" {}
+`)
+	tfexec.UpdateTestAccSource(t, to1Tf, to1Backend+`
+resource "null_resource" "foo" {}
+resource "null_resource" "qux" {}
+`)
+	tfexec.UpdateTestAccSource(t, to2Tf, to2Backend+`
+resource "null_resource" "bar2" {}
+`)
+
+	// perform state migration
+	actions := []*SplitAction{
+		newSplitAction("to1", NewMultiStateMvAction("null_resource.foo", "null_resource.foo")),
+		newSplitAction("to2", NewMultiStateMvAction("null_resource.bar", "null_resource.bar2")),
+	}
+	to := []SplitDestinationConfig{
+		{Name: "to1", Dir: to1Tf.Dir(), Workspace: "default"},
+		{Name: "to2", Dir: to2Tf.Dir(), Workspace: "default"},
+	}
+	o := &MigratorOption{}
+	m := NewSplitMigrator(fromTf.Dir(), "default", false, to, actions, o, false)
+	err := m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+
+	// verify state migration results
+	cases := []struct {
+		tf   tfexec.TerraformCLI
+		want []string
+	}{
+		{tf: fromTf, want: []string{"null_resource.baz"}},
+		{tf: to1Tf, want: []string{"null_resource.foo", "null_resource.qux"}},
+		{tf: to2Tf, want: []string{"null_resource.bar2"}},
+	}
+	for _, tc := range cases {
+		got, err := tc.tf.StateList(ctx, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to run terraform state list in %s: %s", tc.tf.Dir(), err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("got state: %v, want state: %v in %s", got, tc.want, tc.tf.Dir())
+		}
+
+		changed, err := tc.tf.PlanHasChange(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to run PlanHasChange in %s: %s", tc.tf.Dir(), err)
+		}
+		if changed {
+			t.Errorf("expect not to have changes in %s", tc.tf.Dir())
+		}
+	}
+}
@@ -3,6 +3,8 @@ package tfmigrate
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"strings"
 
 	"github.com/mattn/go-shellwords"
 	"github.com/minamijoyo/tfmigrate/tfexec"
@@ -12,6 +14,9 @@ import (
 type StateAction interface {
 	// StateUpdate updates a given state and returns a new state.
 	StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error)
+	// String returns a string representation of the action.
+	// It can be parsed by NewStateActionFromString.
+	String() string
 }
 
 // NewStateActionFromString is a factory method which returns a new StateAction
@@ -23,6 +28,8 @@ type StateAction interface {
 // "rm <addresses>...
 // "import <address> <id>"
 // "xmv <source> <destination>"
+// "xrm <pattern> [--expect N]"
+// "rmv <regex> <template>"
 func NewStateActionFromString(cmdStr string) (StateAction, error) {
 	args, err := splitStateAction(cmdStr)
 	if err != nil {
@@ -32,7 +39,15 @@ func NewStateActionFromString(cmdStr string) (StateAction, error) {
 	if len(args) == 0 {
 		return nil, fmt.Errorf("state action is empty: %s", cmdStr)
 	}
-	actionType := args[0]
+
+	return newStateActionFromArgs(args[0], args, cmdStr)
+}
+
+// newStateActionFromArgs builds a new StateAction of a given type from
+// arguments split from cmdStr. The first argument is ignored because it
+// may contain a scope of action in a multi state migration.
+// cmdStr is only used for error messages.
+func newStateActionFromArgs(actionType string, args []string, cmdStr string) (StateAction, error) {
 
 	// switch by action type and parse arguments and build an action.
 	var action StateAction
@@ -61,6 +76,14 @@ func NewStateActionFromString(cmdStr string) (StateAction, error) {
 		dst := args[2]
 		action = NewStateXmvAction(src, dst)
 
+	case "rmv":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("state rmv action is invalid: %s", cmdStr)
+		}
+		pattern := args[1]
+		template := args[2]
+		action = NewStateRmvAction(pattern, template)
+
 	case "rm":
 		if len(args) < 2 {
 			return nil, fmt.Errorf("state rm action is invalid: %s", cmdStr)
@@ -68,6 +91,13 @@ func NewStateActionFromString(cmdStr string) (StateAction, error) {
 		addrs := args[1:]
 		action = NewStateRmAction(addrs)
 
+	case "xrm":
+		xrm, err := newStateXrmActionFromArgs(args, cmdStr)
+		if err != nil {
+			return nil, err
+		}
+		action = xrm
+
 	case "import":
 		if len(args) != 3 {
 			return nil, fmt.Errorf("state import action is invalid: %s", cmdStr)
@@ -83,8 +113,81 @@ func NewStateActionFromString(cmdStr string) (StateAction, error) {
 	return action, nil
 }
 
+// expandStateAction expands a given action into actions to be actually run.
+// An xmv or rmv action is expanded into mv actions matching the current state.
+// An xrm action is expanded into a rm action, or nothing if no resources match.
+// Other actions are returned as they are.
+func expandStateAction(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State, action StateAction) ([]StateAction, error) {
+	switch a := action.(type) {
+	case *StateXmvAction:
+		mvs, err := a.generateMvActions(ctx, tf, state)
+		if err != nil {
+			return nil, err
+		}
+
+		actions := make([]StateAction, 0, len(mvs))
+		for _, mv := range mvs {
+			actions = append(actions, mv)
+		}
+		return actions, nil
+
+	case *StateRmvAction:
+		mvs, err := a.generateMvActions(ctx, tf, state)
+		if err != nil {
+			return nil, err
+		}
+
+		actions := make([]StateAction, 0, len(mvs))
+		for _, mv := range mvs {
+			actions = append(actions, mv)
+		}
+		return actions, nil
+
+	case *StateXrmAction:
+		rm, err := a.generateRmAction(ctx, tf, state)
+		if err != nil {
+			return nil, err
+		}
+		if rm == nil {
+			return []StateAction{}, nil
+		}
+		return []StateAction{rm}, nil
+
+	default:
+		return []StateAction{action}, nil
+	}
+}
+
 // splitStateAction splits a given string like a shell.
 func splitStateAction(cmdStr string) ([]string, error) {
 	// Note that we cannot simply split it by space because the address of resource can contain spaces.
 	return shellwords.Parse(cmdStr)
 }
+
+// safeActionArgRe is a pattern of an argument which doesn't need to be quoted.
+var safeActionArgRe = regexp.MustCompile(`^[a-zA-Z0-9_.\-\[\]/:@*{}$=,+]+$`)
+
+// FormatAction joins given arguments into a string of action.
+// Each argument is quoted if needed, so that the result can be split again by
+// splitStateAction. It is exported for generating migration files.
+func FormatAction(args ...string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, quoteActionArg(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteActionArg quotes a given argument like a shell if needed.
+func quoteActionArg(arg string) string {
+	if safeActionArgRe.MatchString(arg) {
+		return arg
+	}
+
+	if !strings.Contains(arg, "'") {
+		return "'" + arg + "'"
+	}
+
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(arg) + `"`
+}
@@ -115,6 +115,84 @@ func TestNewStateActionFromString(t *testing.T) {
 			},
 			ok: true,
 		},
+		{
+			desc:   "rmv action (valid)",
+			cmdStr: `rmv 'null_resource\.(?P<name>\w+)' 'null_resource.${upper(name)}'`,
+			want: &StateRmvAction{
+				pattern:  `null_resource\.(?P<name>\w+)`,
+				template: "null_resource.${upper(name)}",
+			},
+			ok: true,
+		},
+		{
+			desc:   "rmv action (1 arg)",
+			cmdStr: `rmv 'null_resource\.(\w+)'`,
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (valid)",
+			cmdStr: "xrm null_resource.*",
+			want: &StateXrmAction{
+				pattern: "null_resource.*",
+				expect:  -1,
+			},
+			ok: true,
+		},
+		{
+			desc:   "xrm action (--expect N)",
+			cmdStr: "xrm null_resource.* --expect 2",
+			want: &StateXrmAction{
+				pattern: "null_resource.*",
+				expect:  2,
+			},
+			ok: true,
+		},
+		{
+			desc:   "xrm action (--expect=N)",
+			cmdStr: "xrm --expect=0 null_resource.*",
+			want: &StateXrmAction{
+				pattern: "null_resource.*",
+				expect:  0,
+			},
+			ok: true,
+		},
+		{
+			desc:   "xrm action (no args)",
+			cmdStr: "xrm",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (2 patterns)",
+			cmdStr: "xrm null_resource.foo null_resource.bar",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (--expect without a value)",
+			cmdStr: "xrm null_resource.* --expect",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (negative --expect)",
+			cmdStr: "xrm null_resource.* --expect=-1",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (duplicated --expect)",
+			cmdStr: "xrm null_resource.* --expect 1 --expect 2",
+			want:   nil,
+			ok:     false,
+		},
+		{
+			desc:   "xrm action (unknown option)",
+			cmdStr: "xrm null_resource.* --foo",
+			want:   nil,
+			ok:     false,
+		},
 		{
 			desc:   "import action (valid)",
 			cmdStr: "import time_static.foo 2006-01-02T15:04:05Z",
@@ -232,3 +310,80 @@ func TestSplitStateAction(t *testing.T) {
 		})
 	}
 }
+
+func TestStateActionString(t *testing.T) {
+	cases := []struct {
+		desc   string
+		action StateAction
+		want   string
+	}{
+		{
+			desc:   "mv action",
+			action: NewStateMvAction("null_resource.foo", "null_resource.foo2"),
+			want:   "mv null_resource.foo null_resource.foo2",
+		},
+		{
+			desc:   "xmv action",
+			action: NewStateXmvAction("null_resource.*", "null_resource.${1}2"),
+			want:   "xmv null_resource.* null_resource.${1}2",
+		},
+		{
+			desc:   "rm action",
+			action: NewStateRmAction([]string{"null_resource.foo", "null_resource.bar"}),
+			want:   "rm null_resource.foo null_resource.bar",
+		},
+		{
+			desc:   "rmv action",
+			action: NewStateRmvAction(`module\.a\["(?P<env>\w+)"\]\.(.+)`, `module.b["${replace(env, "-", "_")}"].$2`),
+			want:   `rmv 'module\.a\["(?P<env>\w+)"\]\.(.+)' 'module.b["${replace(env, "-", "_")}"].$2'`,
+		},
+		{
+			desc:   "xrm action",
+			action: NewStateXrmAction("null_resource.*", -1),
+			want:   "xrm null_resource.*",
+		},
+		{
+			desc:   "xrm action with --expect",
+			action: NewStateXrmAction("module.foo.*", 3),
+			want:   "xrm module.foo.* --expect 3",
+		},
+		{
+			desc:   "import action",
+			action: NewStateImportAction("aws_security_group.foo", "sg-1234567890"),
+			want:   "import aws_security_group.foo sg-1234567890",
+		},
+		{
+			desc:   "replace-provider action",
+			action: NewStateReplaceProviderAction("registry.terraform.io/hashicorp/null", "registry.tfmigrate.io/hashicorp/null"),
+			want:   "replace-provider registry.terraform.io/hashicorp/null registry.tfmigrate.io/hashicorp/null",
+		},
+		{
+			desc:   "quoted address",
+			action: NewStateMvAction(`docker_container.nginx["foo"]`, `docker_container.nginx["This is an example"]`),
+			want:   `mv 'docker_container.nginx["foo"]' 'docker_container.nginx["This is an example"]'`,
+		},
+		{
+			desc:   "address with a single quote",
+			action: NewStateRmAction([]string{`null_resource.foo["it's"]`}),
+			want:   `rm "null_resource.foo[\"it's\"]"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := tc.action.String()
+			if got != tc.want {
+				t.Errorf("got: %s, want: %s", got, tc.want)
+			}
+
+			// It should be parsed again to the same action.
+			action, err := NewStateActionFromString(got)
+			if err != nil {
+				t.Fatalf("failed to parse action: %s", err)
+			}
+			if !reflect.DeepEqual(action, tc.action) {
+				t.Errorf("got: %#v, want: %#v", action, tc.action)
+			}
+		})
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/hashicorp/go-multierror"
 	"github.com/minamijoyo/tfmigrate/tfexec"
@@ -24,7 +23,18 @@ type StateMigratorConfig struct {
 	// We could define strict block schema for action, but intentionally use a
 	// schema-less string to allow us to easily copy terraform state command to
 	// action.
-	Actions []string `hcl:"actions"`
+	// It can be omitted if ImportFrom is set.
+	Actions []string `hcl:"actions,optional"`
+	// ImportFrom is a path of an import manifest in CSV or JSON format.
+	// A relative path is resolved from the directory of the migration file.
+	// Each row of the manifest has an address and id of a resource to be
+	// imported, and is imported after all actions.
+	// See loadImportManifest for details of the format.
+	ImportFrom string `hcl:"import_from,optional"`
+	// Parallelism is a number of concurrent imports for ImportFrom.
+	// Rows are split into batches which are imported into separate temporary
+	// states and merged. Default to 1, which imports rows sequentially.
+	Parallelism int `hcl:"parallelism,optional"`
 	// Force option controls behaviour in case of unexpected diff in plan.
 	// When set forces applying even if plan shows diff.
 	Force bool `hcl:"force,optional"`
@@ -32,6 +42,24 @@ type StateMigratorConfig struct {
 	SkipPlan bool `hcl:"to_skip_plan,optional"`
 	// Workspace is the state workspace which the migration works with.
 	Workspace string `hcl:"workspace,optional"`
+	// Engine is an implementation used for state operations.
+	// Valid values are `cli` (default) and `native`.
+	// The `native` engine handles mv and rm actions in-process without
+	// spawning the terraform command, and falls back to the terraform command
+	// for the other actions.
+	Engine string `hcl:"engine,optional"`
+	// AllowChanges is a list of rules which allow specific changes in
+	// terraform plan. If set, the migration fails only on changes which are
+	// not allowed by any of the rules, instead of any changes.
+	AllowChanges []AllowChangeRule `hcl:"allow_changes,block"`
+	// Preconditions is a list of assertions on the state before the migration.
+	// They are evaluated against the state pulled from remote before any
+	// actions, so that the migration fails fast when the state isn't what the
+	// author expected.
+	Preconditions []StateCondition `hcl:"precondition,block"`
+	// Postconditions is a list of assertions on the state after the migration.
+	// They are evaluated against the computed state before terraform plan.
+	Postconditions []StateCondition `hcl:"postcondition,block"`
 }
 
 // StateMigratorConfig implements a MigratorConfig.
@@ -45,10 +73,14 @@ func (c *StateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
 		dir = c.Dir
 	}
 
-	if len(c.Actions) == 0 {
+	if len(c.Actions) == 0 && len(c.ImportFrom) == 0 {
 		return nil, fmt.Errorf("failed to NewMigrator with no actions")
 	}
 
+	if c.Parallelism < 0 {
+		return nil, fmt.Errorf("failed to NewMigrator with negative parallelism: %d", c.Parallelism)
+	}
+
 	// build actions from config.
 	actions := []StateAction{}
 	for _, cmdStr := range c.Actions {
@@ -64,46 +96,84 @@ func (c *StateMigratorConfig) NewMigrator(o *MigratorOption) (Migrator, error) {
 		c.Workspace = "default"
 	}
 
-	return NewStateMigrator(dir, c.Workspace, actions, o, c.Force, c.SkipPlan), nil
+	for _, rule := range c.AllowChanges {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cond := range append(append([]StateCondition{}, c.Preconditions...), c.Postconditions...) {
+		if err := cond.Validate(false); err != nil {
+			return nil, err
+		}
+	}
+
+	m := NewStateMigrator(dir, c.Workspace, actions, o, c.Force, c.SkipPlan)
+	m.allowChanges = c.AllowChanges
+	m.preconditions = c.Preconditions
+	m.postconditions = c.Postconditions
+
+	if len(c.ImportFrom) > 0 {
+		imports, err := loadImportManifest(c.ImportFrom)
+		if err != nil {
+			return nil, err
+		}
+		m.imports = imports
+		m.parallelism = c.Parallelism
+	}
+
+	if err := validateEngine(c.Engine); err != nil {
+		return nil, err
+	}
+	if c.Engine == "native" {
+		m.tf = tfexec.NewNativeStateCLI(m.tf)
+	}
+
+	return m, nil
 }
 
 // StateMigrator implements the Migrator interface.
 type StateMigrator struct {
-	// tf is an instance of TerraformCLI.
-	tf tfexec.TerraformCLI
+	// workDir is a working directory of the state.
+	*workDir
 	// actions is a list of state migration operations.
 	actions []StateAction
 	// o is an option for migrator.
 	// It is used for shared settings across Migrator instances.
 	o *MigratorOption
-	// skipPlan controls whether or not to run and analyze Terraform plan.
-	skipPlan bool
 	// force operation in case of unexpected diff
 	force bool
-	// workspace is the state workspace which the migration works with.
-	workspace string
+	// allowChanges is a list of rules which allow specific changes in plan.
+	allowChanges []AllowChangeRule
+	// preconditions is a list of assertions on the state before actions.
+	preconditions []StateCondition
+	// postconditions is a list of assertions on the computed state.
+	postconditions []StateCondition
+	// imports is a list of import actions loaded from an import manifest,
+	// which are run after actions.
+	imports []*StateImportAction
+	// parallelism is a number of concurrent imports.
+	parallelism int
+	// importResults is a list of results for each import, which is set by plan.
+	importResults []ImportResult
+	// expandedActions is a list of actions actually run, which is set by plan.
+	expandedActions []string
+	// metadata is metadata about the last applied migration.
+	metadata *ApplyMetadata
 }
 
 var _ Migrator = (*StateMigrator)(nil)
+var _ ApplyMetadataProvider = (*StateMigrator)(nil)
+var _ ReportProvider = (*StateMigrator)(nil)
 
 // NewStateMigrator returns a new StateMigrator instance.
 func NewStateMigrator(dir string, workspace string, actions []StateAction,
 	o *MigratorOption, force bool, skipPlan bool) *StateMigrator {
-	e := tfexec.NewExecutor(dir, os.Environ())
-	tf := tfexec.NewTerraformCLI(e)
-	if o != nil && len(o.ExecPath) > 0 {
-		// While NewTerraformCLI reads the environment variable TFMIGRATE_EXEC_PATH
-		// at initialization, the MigratorOption takes precedence over it.
-		tf.SetExecPath(o.ExecPath)
-	}
-
 	return &StateMigrator{
-		tf:        tf,
-		actions:   actions,
-		o:         o,
-		force:     force,
-		skipPlan:  skipPlan,
-		workspace: workspace,
+		workDir: newWorkDir(dir, workspace, skipPlan, "dir", o),
+		actions: actions,
+		o:       o,
+		force:   force,
 	}
 }
 
@@ -125,7 +195,7 @@ func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, e
 	}
 
 	// setup work dir.
-	currentState, switchBackToRemoteFunc, err := setupWorkDir(ctx, m.tf, m.workspace, m.o.IsBackendTerraformCloud, m.o.BackendConfig, ignoreLegacyStateInitErr)
+	currentState, switchBackToRemoteFunc, err := m.setup(ctx, m.o, ignoreLegacyStateInitErr)
 	if err != nil {
 		return nil, err
 	}
@@ -135,44 +205,57 @@ func (m *StateMigrator) plan(ctx context.Context) (currentState *tfexec.State, e
 		err = multierror.Append(err, switchBackToRemoteFunc())
 	}()
 
+	m.expandedActions = []string{}
+	m.importResults = nil
+
+	if err := checkStateConditions(ctx, m.tf, currentState, m.preconditions, "precondition"); err != nil {
+		return nil, err
+	}
+
 	// computes a new state by applying state migration operations to a temporary state.
 	log.Printf("[INFO] [migrator@%s] compute a new state\n", m.tf.Dir())
 	var newState *tfexec.State
 	for _, action := range m.actions {
-		newState, err = action.StateUpdate(ctx, m.tf, currentState)
+		// expand actions to record actions actually run.
+		expanded, err := expandStateAction(ctx, m.tf, currentState, action)
 		if err != nil {
 			return nil, err
 		}
-		currentState = tfexec.NewState(newState.Bytes())
-	}
-
-	// build plan options
-	planOpts := []string{"-input=false", "-no-color", "-detailed-exitcode"}
-	if m.o.PlanOut != "" {
-		planOpts = append(planOpts, "-out="+m.o.PlanOut)
+		for _, a := range expanded {
+			newState, err = a.StateUpdate(ctx, m.tf, currentState)
+			if err != nil {
+				return nil, err
+			}
+			currentState = tfexec.NewState(newState.Bytes())
+			m.expandedActions = append(m.expandedActions, a.String())
+		}
 	}
 
-	if m.skipPlan {
-		log.Printf("[INFO] [migrator@%s] skipping check diffs\n", m.tf.Dir())
-	} else {
-		log.Printf("[INFO] [migrator@%s] check diffs\n", m.tf.Dir())
-		_, err = m.tf.Plan(ctx, currentState, planOpts...)
+	if len(m.imports) > 0 {
+		log.Printf("[INFO] [migrator@%s] import %d resources from manifest (parallelism = %d)\n", m.tf.Dir(), len(m.imports), max(m.parallelism, 1))
+		newState, results, err := bulkImport(ctx, m.tf, currentState, m.imports, m.parallelism)
+		m.importResults = results
 		if err != nil {
-			if exitErr, ok := err.(tfexec.ExitError); ok && exitErr.ExitCode() == 2 {
-				if !m.force {
-					log.Printf("[ERROR] [migrator@%s] unexpected diffs\n", m.tf.Dir())
-					return nil, fmt.Errorf("terraform plan command returns unexpected diffs: %s", err)
-				}
-				log.Printf("[INFO] [migrator@%s] unexpected diffs, ignoring as force option is true: %s", m.tf.Dir(), err)
-				// reset err to nil to intentionally ignore unexpected diffs.
-				err = nil
-			} else {
-				return nil, err
+			return nil, err
+		}
+		for i, r := range results {
+			if r.Result == ImportResultSuccess {
+				m.expandedActions = append(m.expandedActions, m.imports[i].String())
 			}
 		}
+		currentState = newState
+	}
+	m.afterState = currentState
+
+	if err := checkStateConditions(ctx, m.tf, currentState, m.postconditions, "postcondition"); err != nil {
+		return nil, err
 	}
 
-	return currentState, err
+	if err := m.checkPlan(ctx, m.force, m.o.PlanOut, m.allowChanges); err != nil {
+		return nil, err
+	}
+
+	return currentState, nil
 }
 
 // Plan computes a new state by applying state migration operations to a temporary state.
@@ -200,13 +283,41 @@ func (m *StateMigrator) Apply(ctx context.Context) error {
 		return err
 	}
 
-	// push the new state to remote.
 	log.Printf("[INFO] [migrator] start state migrator apply phase\n")
+	if err := ensureRemoteStateUnchanged(ctx, m.tf, m.beforeState); err != nil {
+		return err
+	}
+
+	snapshots := []StateSnapshot{m.snapshot()}
+	if err := saveSnapshots(ctx, m.o, snapshots); err != nil {
+		return err
+	}
+
+	// push the new state to remote.
 	log.Printf("[INFO] [migrator] push the new state to remote\n")
 	err = m.tf.StatePush(ctx, state)
 	if err != nil {
 		return err
 	}
+
+	states := []StateApplyMetadata{m.applyMetadata()}
+	m.metadata = newApplyMetadata(ctx, m.tf, m.expandedActions, states)
+
 	log.Printf("[INFO] [migrator] state migrator apply success!\n")
 	return nil
 }
+
+// ApplyMetadata returns metadata about the last applied migration.
+// If the migration has not been applied successfully, it returns nil.
+func (m *StateMigrator) ApplyMetadata() *ApplyMetadata {
+	return m.metadata
+}
+
+// Report returns a summary of the last plan or apply.
+func (m *StateMigrator) Report() *Report {
+	return &Report{
+		Actions: m.expandedActions,
+		Imports: m.importResults,
+		States:  []StateReport{m.report()},
+	}
+}
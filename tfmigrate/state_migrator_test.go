@@ -14,6 +14,11 @@ import (
 )
 
 func TestStateMigratorConfigNewMigrator(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "imports.csv")
+	if err := os.WriteFile(manifest, []byte("address,id\ntime_static.foo,2006-01-02T15:04:05Z\n"), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err)
+	}
+
 	cases := []struct {
 		desc   string
 		config *StateMigratorConfig
@@ -119,6 +124,126 @@ func TestStateMigratorConfigNewMigrator(t *testing.T) {
 			o:  nil,
 			ok: true,
 		},
+		{
+			desc: "with native engine",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+					"rm time_static.baz",
+					"import time_static.qux 2006-01-02T15:04:05Z",
+				},
+				Engine: "native",
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "with allow_changes",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				AllowChanges: []AllowChangeRule{
+					{
+						ResourceTypes: []string{"aws_*"},
+						Actions:       []string{"update"},
+						Attributes:    []string{"tags", "tags_all"},
+					},
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "invalid allow_changes",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				AllowChanges: []AllowChangeRule{
+					{
+						Actions: []string{"destroy"},
+					},
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with conditions",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Preconditions: []StateCondition{
+					{ResourceExists: []string{"null_resource.foo"}},
+				},
+				Postconditions: []StateCondition{
+					{ResourceAbsent: []string{"null_resource.foo"}},
+				},
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "condition with state",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Preconditions: []StateCondition{
+					{State: "from", ResourceExists: []string{"null_resource.foo"}},
+				},
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "with import_from",
+			config: &StateMigratorConfig{
+				Dir:         "dir1",
+				ImportFrom:  manifest,
+				Parallelism: 4,
+			},
+			o:  nil,
+			ok: true,
+		},
+		{
+			desc: "import_from not found",
+			config: &StateMigratorConfig{
+				Dir:        "dir1",
+				ImportFrom: manifest + ".not_found",
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "negative parallelism",
+			config: &StateMigratorConfig{
+				Dir:         "dir1",
+				ImportFrom:  manifest,
+				Parallelism: -1,
+			},
+			o:  nil,
+			ok: false,
+		},
+		{
+			desc: "unknown engine",
+			config: &StateMigratorConfig{
+				Dir: "dir1",
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Engine: "foo",
+			},
+			o:  nil,
+			ok: false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -34,3 +34,8 @@ func (a *StateReplaceProviderAction) StateUpdate(ctx context.Context, tf tfexec.
 	// The state replace-provider command doesn't provide a way to disable it, so we backup to /dev/null.
 	return tf.StateReplaceProvider(ctx, state, a.source, a.destination, "-backup=/dev/null", "-auto-approve")
 }
+
+// String returns a string representation of the action.
+func (a *StateReplaceProviderAction) String() string {
+	return FormatAction("replace-provider", a.source, a.destination)
+}
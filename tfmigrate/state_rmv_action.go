@@ -0,0 +1,67 @@
+package tfmigrate
+
+import (
+	"context"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// StateRmvAction implements the StateAction interface.
+// StateRmvAction is a regex version of StateXmvAction.
+// It allows you to move multiple resources matching a regex anchored to a
+// whole address, and to reshape their addresses with named capture groups
+// and transform functions in the destination template.
+type StateRmvAction struct {
+	// pattern is a regex of addresses of resources to be moved.
+	pattern string
+	// template is a template of new addresses which can contain references to
+	// capture groups and transform functions.
+	template string
+}
+
+var _ StateAction = (*StateRmvAction)(nil)
+
+// NewStateRmvAction returns a new StateRmvAction instance.
+func NewStateRmvAction(pattern string, template string) *StateRmvAction {
+	return &StateRmvAction{
+		pattern:  pattern,
+		template: template,
+	}
+}
+
+// StateUpdate updates a given state and returns a new state.
+// All moves are validated for collisions before any of them is executed.
+func (a *StateRmvAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	stateMvActions, err := a.generateMvActions(ctx, tf, state)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, action := range stateMvActions {
+		state, err = action.StateUpdate(ctx, tf, state)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return state, nil
+}
+
+// generateMvActions uses the state to determine the corresponding mv actions.
+func (a *StateRmvAction) generateMvActions(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) ([]*StateMvAction, error) {
+	e, err := newRmvExpander(a.pattern, a.template)
+	if err != nil {
+		return nil, err
+	}
+
+	stateList, err := tf.StateList(ctx, state, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.expand(stateList, stateList, true)
+}
+
+// String returns a string representation of the action.
+func (a *StateRmvAction) String() string {
+	return FormatAction("rmv", a.pattern, a.template)
+}
@@ -2,6 +2,8 @@ package tfmigrate
 
 import (
 	"context"
+	"fmt"
+	"regexp"
 
 	"github.com/minamijoyo/tfmigrate/tfexec"
 )
@@ -54,3 +56,18 @@ func (a *StateXmvAction) generateMvActions(ctx context.Context, tf tfexec.Terraf
 	e := newXmvExpander(a)
 	return e.expand(stateList)
 }
+
+// makeResourcePatternRegex returns a regex which matches a whole address with
+// a given pattern containing wildcards.
+func makeResourcePatternRegex(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile("^" + makeSourceMatchPattern(pattern) + "$")
+	if err != nil {
+		return nil, fmt.Errorf("could not make pattern out of %s due to %s", pattern, err)
+	}
+	return re, nil
+}
+
+// String returns a string representation of the action.
+func (a *StateXmvAction) String() string {
+	return FormatAction("xmv", a.source, a.destination)
+}
@@ -0,0 +1,140 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// StateXrmAction implements the StateAction interface.
+// StateXrmAction is an extended version of StateRmAction.
+// It allows you to remove multiple resources with wildcard matching.
+type StateXrmAction struct {
+	// pattern is an address of resources to be removed which can contain
+	// wildcards. It must match a whole address in the state, or the address
+	// without its instance key.
+	pattern string
+	// expect is an expected number of resources matched with the pattern.
+	// A negative value means that the number is not checked.
+	expect int
+}
+
+var _ StateAction = (*StateXrmAction)(nil)
+
+// NewStateXrmAction returns a new StateXrmAction instance.
+// If expect is not negative, the action fails unless exactly the expected
+// number of resources match the pattern.
+func NewStateXrmAction(pattern string, expect int) *StateXrmAction {
+	return &StateXrmAction{
+		pattern: pattern,
+		expect:  expect,
+	}
+}
+
+// newStateXrmActionFromArgs builds a new StateXrmAction from arguments split
+// from cmdStr. Valid formats are the following.
+// "xrm <pattern>"
+// "xrm <pattern> --expect N"
+// "xrm <pattern> --expect=N"
+func newStateXrmActionFromArgs(args []string, cmdStr string) (*StateXrmAction, error) {
+	pattern := ""
+	expect := -1
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		var value string
+		switch {
+		case arg == "--expect":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("state xrm action requires a value for --expect: %s", cmdStr)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, "--expect="):
+			value = strings.TrimPrefix(arg, "--expect=")
+		case strings.HasPrefix(arg, "-"):
+			return nil, fmt.Errorf("unknown option of state xrm action: %s", cmdStr)
+		default:
+			if pattern != "" {
+				return nil, fmt.Errorf("state xrm action is invalid: %s", cmdStr)
+			}
+			pattern = arg
+			continue
+		}
+
+		if expect >= 0 {
+			return nil, fmt.Errorf("state xrm action has duplicated --expect: %s", cmdStr)
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("state xrm action has an invalid --expect %q, it must be a non-negative integer: %s", value, cmdStr)
+		}
+		expect = n
+	}
+
+	if pattern == "" {
+		return nil, fmt.Errorf("state xrm action is invalid: %s", cmdStr)
+	}
+
+	return NewStateXrmAction(pattern, expect), nil
+}
+
+// StateUpdate updates a given state and returns a new state.
+// The pattern has wildcards which should be matched against the tf state.
+// All matched resources are removed by a single rm command.
+func (a *StateXrmAction) StateUpdate(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*tfexec.State, error) {
+	rm, err := a.generateRmAction(ctx, tf, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if rm == nil {
+		return state, nil
+	}
+	return rm.StateUpdate(ctx, tf, state)
+}
+
+// generateRmAction uses the state to determine the corresponding rm action.
+// It returns nil if no resources match the pattern.
+func (a *StateXrmAction) generateRmAction(ctx context.Context, tf tfexec.TerraformCLI, state *tfexec.State) (*StateRmAction, error) {
+	stateList, err := tf.StateList(ctx, state, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := a.expand(stateList)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addrs) == 0 {
+		log.Printf("[WARN] [migrator@%s] no resources match an xrm action: %s\n", tf.Dir(), a)
+		return nil, nil
+	}
+	return NewStateRmAction(addrs), nil
+}
+
+// expand returns a list of addresses matching the pattern.
+// It fails if the number of them differs from the expected number.
+func (a *StateXrmAction) expand(stateList []string) ([]string, error) {
+	addrs, err := matchConditionPattern(a.pattern, stateList)
+	if err != nil {
+		return nil, err
+	}
+
+	if a.expect >= 0 && len(addrs) != a.expect {
+		return nil, fmt.Errorf("xrm action expected %d resources to match, but got %d: %s, matched: %v", a.expect, len(addrs), a, addrs)
+	}
+	return addrs, nil
+}
+
+// String returns a string representation of the action.
+func (a *StateXrmAction) String() string {
+	if a.expect < 0 {
+		return FormatAction("xrm", a.pattern)
+	}
+	return FormatAction("xrm", a.pattern, "--expect", strconv.Itoa(a.expect))
+}
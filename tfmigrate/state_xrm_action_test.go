@@ -0,0 +1,150 @@
+package tfmigrate
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+func TestStateXrmActionExpand(t *testing.T) {
+	stateList := []string{
+		"null_resource.foo",
+		"null_resource.bar",
+		"time_static.foo",
+		"module.foo.null_resource.foo",
+		"module.foo.null_resource.bar",
+		"aws_instance.foo[0]",
+		"aws_instance.foo[1]",
+		"aws_instance.foobar",
+	}
+
+	cases := []struct {
+		desc   string
+		action *StateXrmAction
+		want   []string
+		ok     bool
+	}{
+		{
+			desc:   "wildcard",
+			action: NewStateXrmAction("null_resource.*", -1),
+			want:   []string{"null_resource.foo", "null_resource.bar"},
+			ok:     true,
+		},
+		{
+			desc:   "wildcard in module",
+			action: NewStateXrmAction("module.foo.*", -1),
+			want:   []string{"module.foo.null_resource.foo", "module.foo.null_resource.bar"},
+			ok:     true,
+		},
+		{
+			desc:   "multiple wildcards",
+			action: NewStateXrmAction("*.foo", -1),
+			want:   []string{"null_resource.foo", "time_static.foo", "module.foo.null_resource.foo", "aws_instance.foo[0]", "aws_instance.foo[1]"},
+			ok:     true,
+		},
+		{
+			desc:   "no wildcards",
+			action: NewStateXrmAction("time_static.foo", -1),
+			want:   []string{"time_static.foo"},
+			ok:     true,
+		},
+		{
+			desc:   "no wildcards with counted resource",
+			action: NewStateXrmAction("aws_instance.foo", 2),
+			want:   []string{"aws_instance.foo[0]", "aws_instance.foo[1]"},
+			ok:     true,
+		},
+		{
+			desc:   "instance key",
+			action: NewStateXrmAction("aws_instance.foo[1]", -1),
+			want:   []string{"aws_instance.foo[1]"},
+			ok:     true,
+		},
+		{
+			desc:   "no matches",
+			action: NewStateXrmAction("null_resource.baz*", -1),
+			want:   []string{},
+			ok:     true,
+		},
+		{
+			desc:   "expected number",
+			action: NewStateXrmAction("null_resource.*", 2),
+			want:   []string{"null_resource.foo", "null_resource.bar"},
+			ok:     true,
+		},
+		{
+			desc:   "expected zero",
+			action: NewStateXrmAction("null_resource.baz*", 0),
+			want:   []string{},
+			ok:     true,
+		},
+		{
+			desc:   "unexpected number",
+			action: NewStateXrmAction("null_resource.*", 1),
+			want:   nil,
+			ok:     false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.action.expand(stateList)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %#v, want: %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAccStateXrmAction(t *testing.T) {
+	tfexec.SkipUnlessAcceptanceTestEnabled(t)
+
+	backend := tfexec.GetTestAccBackendS3Config(t.Name())
+
+	source := `
+resource "null_resource" "foo" {}
+resource "null_resource" "bar" {}
+resource "time_static" "baz" {}
+`
+
+	workspace := "default"
+	tf := tfexec.SetupTestAccWithApply(t, workspace, backend+source)
+	ctx := context.Background()
+
+	updatedSource := `
+resource "time_static" "baz" {}
+`
+
+	tfexec.UpdateTestAccSource(t, tf, backend+updatedSource)
+
+	changed, err := tf.PlanHasChange(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to run PlanHasChange: %s", err)
+	}
+	if !changed {
+		t.Fatalf("expect to have changes")
+	}
+
+	actions := []StateAction{
+		NewStateXrmAction("null_resource.*", 2),
+	}
+
+	m := NewStateMigrator(tf.Dir(), workspace, actions, &MigratorOption{}, false, false)
+	err = m.Plan(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator plan: %s", err)
+	}
+
+	err = m.Apply(ctx)
+	if err != nil {
+		t.Fatalf("failed to run migrator apply: %s", err)
+	}
+}
@@ -0,0 +1,274 @@
+package tfmigrate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfexec"
+)
+
+// Confidence levels of a suggested move.
+const (
+	// ConfidenceHigh means that an identifying attribute such as id, arn or
+	// name has the same value.
+	ConfidenceHigh = "high"
+	// ConfidenceMedium means that at least half of comparable attributes have
+	// the same values.
+	ConfidenceMedium = "medium"
+	// ConfidenceLow means that less than half of comparable attributes have
+	// the same values, or it is the only pair of deleted and created resources
+	// of the type without any matches.
+	ConfidenceLow = "low"
+)
+
+// identityAttributes is a list of attribute names which identify a real
+// resource in most providers.
+var identityAttributes = []string{"id", "arn", "name"}
+
+// MoveSuggestion is a candidate of mv action which pairs a resource planned to
+// be deleted with one planned to be created.
+type MoveSuggestion struct {
+	// From is an address of the resource planned to be deleted.
+	From string
+	// To is an address of the resource planned to be created.
+	To string
+	// Confidence is a level of confidence of the pair.
+	// It is one of ConfidenceHigh, ConfidenceMedium and ConfidenceLow.
+	Confidence string
+	// MatchedAttributes is a sorted list of attribute names which have the
+	// same values.
+	MatchedAttributes []string
+}
+
+// Action returns a mv action of the suggestion.
+func (s *MoveSuggestion) Action() string {
+	return FormatAction("mv", s.From, s.To)
+}
+
+// Annotation returns a human readable reason of the suggestion.
+// (e.g.) `confidence: high (matched: arn, bucket)`
+func (s *MoveSuggestion) Annotation() string {
+	if len(s.MatchedAttributes) == 0 {
+		return fmt.Sprintf("confidence: %s (the only pair of the type)", s.Confidence)
+	}
+	return fmt.Sprintf("confidence: %s (matched: %s)", s.Confidence, strings.Join(s.MatchedAttributes, ", "))
+}
+
+// SuggestStateMoves runs terraform plan for a remote state in a given
+// directory and returns suggestions which pair resources planned to be
+// deleted with ones planned to be created.
+func SuggestStateMoves(ctx context.Context, dir string, workspace string, o *MigratorOption) ([]*MoveSuggestion, error) {
+	changes, err := planRemoteChanges(ctx, dir, workspace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return pairMoves(changes, changes), nil
+}
+
+// SuggestMultiStateMoves runs terraform plan for remote states in given
+// directories and returns suggestions which pair resources planned to be
+// deleted in fromDir with ones planned to be created in toDir.
+func SuggestMultiStateMoves(ctx context.Context, fromDir string, fromWorkspace string, toDir string, toWorkspace string, o *MigratorOption) ([]*MoveSuggestion, error) {
+	fromChanges, err := planRemoteChanges(ctx, fromDir, fromWorkspace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	toChanges, err := planRemoteChanges(ctx, toDir, toWorkspace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	return pairMoves(fromChanges, toChanges), nil
+}
+
+// planRemoteChanges runs terraform plan for a remote state in a given
+// directory and returns a list of resource changes.
+func planRemoteChanges(ctx context.Context, dir string, workspace string, o *MigratorOption) ([]*planChange, error) {
+	tf, err := newRemoteTerraformCLI(ctx, dir, workspace, o)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("[INFO] [migrator@%s] compute a plan\n", tf.Dir())
+	plan, err := tf.Plan(ctx, nil, "-input=false", "-no-color")
+	if err != nil {
+		return nil, err
+	}
+
+	return showPlanChanges(ctx, tf, plan)
+}
+
+// showPlanChanges shows a given plan as json and returns a list of resource
+// changes.
+func showPlanChanges(ctx context.Context, tf tfexec.TerraformCLI, plan *tfexec.Plan) ([]*planChange, error) {
+	planJSON, err := tf.Show(ctx, plan, "-json", "-no-color")
+	if err != nil {
+		return nil, err
+	}
+
+	return parsePlanChanges([]byte(planJSON))
+}
+
+// pairCandidate is a scored pair of deleted and created resources.
+type pairCandidate struct {
+	from    int
+	to      int
+	score   float64
+	matched []string
+}
+
+// pairMoves pairs resources planned to be deleted in a given deleted changes
+// with ones planned to be created in a given created changes, and returns
+// suggestions in the order of deleted changes.
+//
+// Only resources of the same type are paired. Pairs are chosen greedily in
+// descending order of attribute similarity so that each resource is used at
+// most once. A pair without any matched attributes is suggested with low
+// confidence only if it is the only pair of the type.
+func pairMoves(deletedChanges []*planChange, createdChanges []*planChange) []*MoveSuggestion {
+	deleted := filterChanges(deletedChanges, "delete")
+	created := filterChanges(createdChanges, "create")
+
+	candidates := []pairCandidate{}
+	deletedByType := make(map[string]int)
+	createdByType := make(map[string]int)
+	for _, d := range deleted {
+		deletedByType[d.resourceType]++
+	}
+	for _, c := range created {
+		createdByType[c.resourceType]++
+	}
+	for i, d := range deleted {
+		for j, c := range created {
+			if d.resourceType != c.resourceType {
+				continue
+			}
+			score, matched := compareValues(d.values, c.values)
+			if len(matched) == 0 && (deletedByType[d.resourceType] != 1 || createdByType[c.resourceType] != 1) {
+				continue
+			}
+			candidates = append(candidates, pairCandidate{from: i, to: j, score: score, matched: matched})
+		}
+	}
+
+	// Prefer a pair with an identity attribute, then a higher score.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ci, cj := candidates[i], candidates[j]
+		ii, ij := hasIdentityAttribute(ci.matched), hasIdentityAttribute(cj.matched)
+		if ii != ij {
+			return ii
+		}
+		return ci.score > cj.score
+	})
+
+	usedFrom := make(map[int]bool)
+	usedTo := make(map[int]bool)
+	chosen := make(map[int]*MoveSuggestion)
+	for _, p := range candidates {
+		if usedFrom[p.from] || usedTo[p.to] {
+			continue
+		}
+		usedFrom[p.from] = true
+		usedTo[p.to] = true
+		chosen[p.from] = &MoveSuggestion{
+			From:              deleted[p.from].address,
+			To:                created[p.to].address,
+			Confidence:        confidenceOf(p),
+			MatchedAttributes: p.matched,
+		}
+	}
+
+	suggestions := []*MoveSuggestion{}
+	for i, d := range deleted {
+		s, ok := chosen[i]
+		if !ok {
+			if createdByType[d.resourceType] > 0 {
+				log.Printf("[WARN] [migrator] no candidate to move found for %s\n", d.address)
+			}
+			continue
+		}
+		suggestions = append(suggestions, s)
+	}
+
+	return suggestions
+}
+
+// filterChanges returns changes of a given action.
+func filterChanges(changes []*planChange, action string) []*planChange {
+	filtered := []*planChange{}
+	for _, c := range changes {
+		if c.action == action {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// compareValues compares attribute values known in both before and after,
+// and returns a ratio of matched attributes and a sorted list of their names.
+// Null and empty values are ignored because they don't identify anything.
+func compareValues(before, after map[string]interface{}) (float64, []string) {
+	compared := 0
+	matched := []string{}
+	for k, v := range before {
+		w, ok := after[k]
+		if !ok || isEmptyValue(v) || isEmptyValue(w) {
+			continue
+		}
+		compared++
+		if reflect.DeepEqual(v, w) {
+			matched = append(matched, k)
+		}
+	}
+	sort.Strings(matched)
+
+	if compared == 0 {
+		return 0, matched
+	}
+	return float64(len(matched)) / float64(compared), matched
+}
+
+// isEmptyValue returns true if a given attribute value is null or empty.
+func isEmptyValue(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	case map[string]interface{}:
+		return len(t) == 0
+	default:
+		return false
+	}
+}
+
+// hasIdentityAttribute returns true if a given list of attribute names
+// contains any of identity attributes.
+func hasIdentityAttribute(attrs []string) bool {
+	for _, a := range identityAttributes {
+		if containsString(attrs, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// confidenceOf returns a confidence level of a given pair.
+func confidenceOf(p pairCandidate) string {
+	switch {
+	case hasIdentityAttribute(p.matched):
+		return ConfidenceHigh
+	case len(p.matched) > 0 && p.score >= 0.5:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
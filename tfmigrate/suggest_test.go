@@ -0,0 +1,144 @@
+package tfmigrate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPairMoves(t *testing.T) {
+	cases := []struct {
+		desc    string
+		deleted []*planChange
+		created []*planChange
+		want    []*MoveSuggestion
+	}{
+		{
+			desc: "the only pair of the type",
+			deleted: []*planChange{
+				{address: "aws_security_group.foo", resourceType: "aws_security_group", action: "delete", values: map[string]interface{}{"description": "foo"}},
+				{address: "aws_instance.bar", resourceType: "aws_instance", action: "update"},
+			},
+			created: []*planChange{
+				{address: "aws_security_group.foo2", resourceType: "aws_security_group", action: "create", values: map[string]interface{}{"description": "bar"}},
+			},
+			want: []*MoveSuggestion{
+				{From: "aws_security_group.foo", To: "aws_security_group.foo2", Confidence: ConfidenceLow, MatchedAttributes: []string{}},
+			},
+		},
+		{
+			desc: "match by identity attribute",
+			deleted: []*planChange{
+				{address: "aws_s3_bucket.foo", resourceType: "aws_s3_bucket", action: "delete", values: map[string]interface{}{"bucket": "foo", "arn": "arn:aws:s3:::foo", "force_destroy": false}},
+				{address: "aws_s3_bucket.bar", resourceType: "aws_s3_bucket", action: "delete", values: map[string]interface{}{"bucket": "bar", "arn": "arn:aws:s3:::bar", "force_destroy": false}},
+			},
+			created: []*planChange{
+				{address: "module.s3.aws_s3_bucket.this", resourceType: "aws_s3_bucket", action: "create", values: map[string]interface{}{"bucket": "bar", "arn": "arn:aws:s3:::bar", "force_destroy": true}},
+				{address: "module.s3.aws_s3_bucket.that", resourceType: "aws_s3_bucket", action: "create", values: map[string]interface{}{"bucket": "foo", "arn": nil, "force_destroy": false}},
+			},
+			want: []*MoveSuggestion{
+				{From: "aws_s3_bucket.foo", To: "module.s3.aws_s3_bucket.that", Confidence: ConfidenceMedium, MatchedAttributes: []string{"bucket", "force_destroy"}},
+				{From: "aws_s3_bucket.bar", To: "module.s3.aws_s3_bucket.this", Confidence: ConfidenceHigh, MatchedAttributes: []string{"arn", "bucket"}},
+			},
+		},
+		{
+			desc: "ambiguous without matched attributes",
+			deleted: []*planChange{
+				{address: "aws_security_group.foo", resourceType: "aws_security_group", action: "delete"},
+				{address: "aws_security_group.bar", resourceType: "aws_security_group", action: "delete"},
+			},
+			created: []*planChange{
+				{address: "aws_security_group.foo2", resourceType: "aws_security_group", action: "create"},
+			},
+			want: []*MoveSuggestion{},
+		},
+		{
+			desc: "different types",
+			deleted: []*planChange{
+				{address: "aws_security_group.foo", resourceType: "aws_security_group", action: "delete", values: map[string]interface{}{"name": "foo"}},
+			},
+			created: []*planChange{
+				{address: "aws_instance.foo", resourceType: "aws_instance", action: "create", values: map[string]interface{}{"name": "foo"}},
+			},
+			want: []*MoveSuggestion{},
+		},
+		{
+			desc: "string key",
+			deleted: []*planChange{
+				{address: `aws_security_group.foo["a"]`, resourceType: "aws_security_group", action: "delete", values: map[string]interface{}{"name": "a"}},
+			},
+			created: []*planChange{
+				{address: `module.foo.aws_security_group.foo["a"]`, resourceType: "aws_security_group", action: "create", values: map[string]interface{}{"name": "a"}},
+			},
+			want: []*MoveSuggestion{
+				{From: `aws_security_group.foo["a"]`, To: `module.foo.aws_security_group.foo["a"]`, Confidence: ConfidenceHigh, MatchedAttributes: []string{"name"}},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := pairMoves(tc.deleted, tc.created)
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %#v, want: %#v, diff: %s", got, tc.want, diff)
+			}
+		})
+	}
+}
+
+func TestMoveSuggestionAction(t *testing.T) {
+	s := &MoveSuggestion{
+		From:              `aws_security_group.foo["a"]`,
+		To:                "aws_security_group.bar",
+		Confidence:        ConfidenceHigh,
+		MatchedAttributes: []string{"arn", "name"},
+	}
+
+	wantAction := `mv 'aws_security_group.foo["a"]' aws_security_group.bar`
+	if got := s.Action(); got != wantAction {
+		t.Errorf("got: %s, want: %s", got, wantAction)
+	}
+
+	wantAnnotation := "confidence: high (matched: arn, name)"
+	if got := s.Annotation(); got != wantAnnotation {
+		t.Errorf("got: %s, want: %s", got, wantAnnotation)
+	}
+}
+
+func TestParsePlanChangesValues(t *testing.T) {
+	planJSON := `{
+  "resource_changes": [
+    {
+      "address": "aws_s3_bucket.foo",
+      "type": "aws_s3_bucket",
+      "change": {
+        "actions": ["delete"],
+        "before": {"bucket": "foo"},
+        "after": null
+      }
+    },
+    {
+      "address": "aws_s3_bucket.bar",
+      "type": "aws_s3_bucket",
+      "change": {
+        "actions": ["create"],
+        "before": null,
+        "after": {"bucket": "foo"},
+        "after_unknown": {"arn": true}
+      }
+    }
+  ]
+}`
+	changes, err := parsePlanChanges([]byte(planJSON))
+	if err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	got := pairMoves(changes, changes)
+	want := []*MoveSuggestion{
+		{From: "aws_s3_bucket.foo", To: "aws_s3_bucket.bar", Confidence: ConfidenceMedium, MatchedAttributes: []string{"bucket"}},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("got: %#v, want: %#v, diff: %s", got, want, diff)
+	}
+}
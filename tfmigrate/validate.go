@@ -0,0 +1,368 @@
+package tfmigrate
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/minamijoyo/tfmigrate/tfstate"
+)
+
+// ValidationError is an error found by static validation of a migration.
+type ValidationError struct {
+	// Attribute is a name of the attribute which has the error.
+	// It is empty if the error is not specific to an attribute.
+	Attribute string
+	// Index is an index of the element in a list attribute such as actions.
+	// It is -1 if the error is for the attribute itself.
+	Index int
+	// Err is an underlying error.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Validator is an optional interface of MigratorConfig which validates a
+// migration statically without running the terraform command.
+// It is intended to be used as a fast lint which doesn't need credentials.
+type Validator interface {
+	// Validate returns a list of errors found in the migration.
+	// It returns an empty list if no errors are found.
+	Validate() []*ValidationError
+}
+
+var _ Validator = (*StateMigratorConfig)(nil)
+var _ Validator = (*MultiStateMigratorConfig)(nil)
+var _ Validator = (*SplitMigratorConfig)(nil)
+var _ Validator = (*MergeStateMigratorConfig)(nil)
+
+// Validate returns a list of errors found in the migration.
+func (c *StateMigratorConfig) Validate() []*ValidationError {
+	errs := []*ValidationError{}
+	if err := validateDir(c.Dir); err != nil {
+		errs = append(errs, &ValidationError{Attribute: "dir", Index: -1, Err: err})
+	}
+	if err := validateEngine(c.Engine); err != nil {
+		errs = append(errs, &ValidationError{Attribute: "engine", Index: -1, Err: err})
+	}
+	for _, rule := range c.AllowChanges {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "allow_changes", Index: -1, Err: err})
+		}
+	}
+	if len(c.Actions) == 0 && len(c.ImportFrom) == 0 {
+		errs = append(errs, &ValidationError{Attribute: "actions", Index: -1, Err: fmt.Errorf("no actions")})
+	}
+	if len(c.ImportFrom) > 0 {
+		if _, err := loadImportManifest(c.ImportFrom); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "import_from", Index: -1, Err: err})
+		}
+	}
+	if c.Parallelism < 0 {
+		errs = append(errs, &ValidationError{Attribute: "parallelism", Index: -1, Err: fmt.Errorf("parallelism must not be negative: %d", c.Parallelism)})
+	}
+	errs = append(errs, validateConditions("precondition", c.Preconditions, false)...)
+	errs = append(errs, validateConditions("postcondition", c.Postconditions, false)...)
+
+	tracker := newMoveTracker()
+	for i, cmdStr := range c.Actions {
+		action, err := NewStateActionFromString(cmdStr)
+		if err != nil {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: err})
+			continue
+		}
+		if err := validateStateAction(action, tracker); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: err})
+		}
+	}
+
+	return errs
+}
+
+// validateStateAction checks addresses in a given state action.
+func validateStateAction(action StateAction, tracker *moveTracker) error {
+	switch a := action.(type) {
+	case *StateMvAction:
+		if err := validateAddresses(a.source, a.destination); err != nil {
+			return err
+		}
+		return tracker.move(a.source, a.destination)
+	case *StateXmvAction:
+		return validateXmv(a.source, a.destination, tracker)
+	case *StateRmAction:
+		return validateAddresses(a.addresses...)
+	case *StateRmvAction:
+		_, err := newRmvExpander(a.pattern, a.template)
+		return err
+	case *StateXrmAction:
+		if !strings.Contains(a.pattern, wildcardChar) {
+			return validateAddresses(a.pattern)
+		}
+		_, err := makeResourcePatternRegex(a.pattern)
+		return err
+	case *StateImportAction:
+		return validateAddresses(a.address)
+	default:
+		return nil
+	}
+}
+
+// Validate returns a list of errors found in the migration.
+func (c *MultiStateMigratorConfig) Validate() []*ValidationError {
+	errs := []*ValidationError{}
+	if err := validateDir(c.FromDir); err != nil {
+		errs = append(errs, &ValidationError{Attribute: "from_dir", Index: -1, Err: err})
+	}
+	if err := validateDir(c.ToDir); err != nil {
+		errs = append(errs, &ValidationError{Attribute: "to_dir", Index: -1, Err: err})
+	}
+	if len(c.Actions) == 0 {
+		errs = append(errs, &ValidationError{Attribute: "actions", Index: -1, Err: fmt.Errorf("no actions")})
+	}
+	errs = append(errs, validateConditions("precondition", c.Preconditions, true)...)
+	errs = append(errs, validateConditions("postcondition", c.Postconditions, true)...)
+
+	tracker := newMoveTracker()
+	for i, cmdStr := range c.Actions {
+		action, err := NewMultiStateActionFromString(cmdStr)
+		if err != nil {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: err})
+			continue
+		}
+		if err := validateMultiStateAction(action, tracker); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: err})
+		}
+	}
+
+	return errs
+}
+
+// validateMultiStateAction checks addresses in a given multi state action.
+// Since a source and destination belong to different states, we only track
+// sources to detect a resource moved twice.
+func validateMultiStateAction(action MultiStateAction, tracker *moveTracker) error {
+	switch a := action.(type) {
+	case *MultiStateMvAction:
+		if err := validateAddresses(a.source, a.destination); err != nil {
+			return err
+		}
+		return tracker.moveOut(a.source)
+	case *MultiStateXmvAction:
+		return validateXmv(a.source, a.destination, nil)
+	case *MultiStateRmvAction:
+		_, err := newRmvExpander(a.pattern, a.template)
+		return err
+	case *MultiStateScopedAction:
+		return validateStateAction(a.action, newMoveTracker())
+	default:
+		return nil
+	}
+}
+
+// Validate returns a list of errors found in the migration.
+func (c *SplitMigratorConfig) Validate() []*ValidationError {
+	errs := []*ValidationError{}
+	if err := validateDir(c.FromDir); err != nil {
+		errs = append(errs, &ValidationError{Attribute: "from_dir", Index: -1, Err: err})
+	}
+	if len(c.To) == 0 {
+		errs = append(errs, &ValidationError{Attribute: "to", Index: -1, Err: fmt.Errorf("no destinations")})
+	}
+	names := make(map[string]bool)
+	for i, to := range c.To {
+		if !splitDestinationNameRe.MatchString(to.Name) {
+			errs = append(errs, &ValidationError{Attribute: "to", Index: i, Err: fmt.Errorf("invalid name of destination: %s", to.Name)})
+		}
+		if names[to.Name] {
+			errs = append(errs, &ValidationError{Attribute: "to", Index: i, Err: fmt.Errorf("duplicate name of destination: %s", to.Name)})
+		}
+		names[to.Name] = true
+		if err := validateDir(to.Dir); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "to", Index: i, Err: err})
+		}
+	}
+	if len(c.Actions) == 0 {
+		errs = append(errs, &ValidationError{Attribute: "actions", Index: -1, Err: fmt.Errorf("no actions")})
+	}
+
+	tracker := newMoveTracker()
+	for i, cmdStr := range c.Actions {
+		action, err := NewSplitActionFromString(cmdStr)
+		if err != nil {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: err})
+			continue
+		}
+		if !names[action.destination] {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: fmt.Errorf("unknown destination: %s", action.destination)})
+			continue
+		}
+		if err := validateMultiStateAction(action.action, tracker); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "actions", Index: i, Err: err})
+		}
+	}
+
+	return errs
+}
+
+// Validate returns a list of errors found in the migration.
+func (c *MergeStateMigratorConfig) Validate() []*ValidationError {
+	errs := []*ValidationError{}
+	if err := validateDir(c.ToDir); err != nil {
+		errs = append(errs, &ValidationError{Attribute: "to_dir", Index: -1, Err: err})
+	}
+	if len(c.From) == 0 {
+		errs = append(errs, &ValidationError{Attribute: "from", Index: -1, Err: fmt.Errorf("no sources")})
+	}
+	names := make(map[string]bool)
+	for i, from := range c.From {
+		if err := validateMergeSource(from); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "from", Index: i, Err: err})
+		}
+		if names[from.Name] {
+			errs = append(errs, &ValidationError{Attribute: "from", Index: i, Err: fmt.Errorf("duplicate name of source: %s", from.Name)})
+		}
+		names[from.Name] = true
+		if err := validateDir(from.Dir); err != nil {
+			errs = append(errs, &ValidationError{Attribute: "from", Index: i, Err: err})
+		}
+	}
+
+	return errs
+}
+
+// validateConditions checks given precondition or postcondition blocks.
+// The attribute is a name of the block.
+func validateConditions(attribute string, conditions []StateCondition, multi bool) []*ValidationError {
+	errs := []*ValidationError{}
+	for i, cond := range conditions {
+		if err := cond.Validate(multi); err != nil {
+			errs = append(errs, &ValidationError{Attribute: attribute, Index: i, Err: err})
+		}
+	}
+	return errs
+}
+
+// validateDir checks if a given working directory exists.
+// An empty dir means the current directory.
+func validateDir(dir string) error {
+	if len(dir) == 0 {
+		return nil
+	}
+
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("dir does not exist: %s", dir)
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("dir is not a directory: %s", dir)
+	}
+	return nil
+}
+
+// validateEngine checks if a given engine is valid.
+func validateEngine(engine string) error {
+	switch engine {
+	case "", "cli", "native":
+		return nil
+	default:
+		return fmt.Errorf("unknown engine: %s", engine)
+	}
+}
+
+// validateAddresses checks if given addresses are well-formed.
+func validateAddresses(addresses ...string) error {
+	for _, addr := range addresses {
+		if _, err := tfstate.ParseAddress(addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xmvReferenceRe is a pattern of a reference to a matched wildcard in the
+// destination of xmv, following the syntax of regexp.Expand.
+// (e.g.) `$1`, `${1}`, `$$`
+var xmvReferenceRe = regexp.MustCompile(`\$(\$|\{[^}]*\}|[a-zA-Z0-9_]+)`)
+
+// validateXmv checks if references in a destination of xmv match wildcards
+// in a source. If a tracker is given and the source has no wildcards, it is
+// tracked as a mv action.
+func validateXmv(source string, destination string, tracker *moveTracker) error {
+	wildcards := strings.Count(source, wildcardChar)
+	if wildcards == 0 {
+		if err := validateAddresses(source, destination); err != nil {
+			return err
+		}
+		if tracker != nil {
+			return tracker.move(source, destination)
+		}
+		return nil
+	}
+
+	for _, m := range xmvReferenceRe.FindAllStringSubmatch(destination, -1) {
+		ref := m[1]
+		if ref == "$" {
+			continue
+		}
+		braced := strings.HasPrefix(ref, "{")
+		name := strings.TrimSuffix(strings.TrimPrefix(ref, "{"), "}")
+		n, err := strconv.Atoi(name)
+		if err != nil {
+			if !braced && len(name) > 0 && '0' <= name[0] && name[0] <= '9' {
+				return fmt.Errorf("ambiguous reference $%s in xmv destination: %s, use ${N} to separate it from the following characters", name, destination)
+			}
+			return fmt.Errorf("invalid reference $%s in xmv destination: %s", ref, destination)
+		}
+		if n < 1 || n > wildcards {
+			return fmt.Errorf("reference $%s in xmv destination is out of range: %s, the source has %d wildcards", ref, destination, wildcards)
+		}
+	}
+
+	return nil
+}
+
+// moveTracker tracks addresses moved by actions in a migration to detect a
+// resource moved twice.
+type moveTracker struct {
+	// movedOut is a set of addresses which have been moved away.
+	movedOut map[string]bool
+	// movedIn is a set of addresses which have been moved to.
+	movedIn map[string]bool
+}
+
+// newMoveTracker returns a new moveTracker instance.
+func newMoveTracker() *moveTracker {
+	return &moveTracker{
+		movedOut: make(map[string]bool),
+		movedIn:  make(map[string]bool),
+	}
+}
+
+// move tracks a mv action in the same state.
+// Note that a chain of moves such as `mv a b` and `mv b c` is valid.
+func (t *moveTracker) move(source string, destination string) error {
+	if err := t.moveOut(source); err != nil {
+		return err
+	}
+	if t.movedIn[destination] {
+		return fmt.Errorf("resource is moved to the same destination twice: %s", destination)
+	}
+	t.movedIn[destination] = true
+	delete(t.movedOut, destination)
+	return nil
+}
+
+// moveOut tracks a source address moved away.
+func (t *moveTracker) moveOut(source string) error {
+	if t.movedOut[source] {
+		return fmt.Errorf("resource is moved twice: %s", source)
+	}
+	t.movedOut[source] = true
+	delete(t.movedIn, source)
+	return nil
+}
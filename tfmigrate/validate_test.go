@@ -0,0 +1,338 @@
+package tfmigrate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestStateMigratorConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "imports.json")
+	if err := os.WriteFile(manifest, []byte(`[{"address": "time_static.foo", "id": "foo"}]`), 0644); err != nil {
+		t.Fatalf("failed to write manifest: %s", err)
+	}
+	cases := []struct {
+		desc   string
+		config *StateMigratorConfig
+		// want is a list of pairs of attribute and index of errors.
+		want [][2]interface{}
+	}{
+		{
+			desc: "valid",
+			config: &StateMigratorConfig{
+				Dir: dir,
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+					"mv null_resource.foo2 null_resource.foo3",
+					`mv 'module.foo["a"]' module.bar`,
+					"xmv null_resource.* null_resource.${1}_new",
+					"rm time_static.baz data.null_data_source.d",
+					"xrm module.baz.* --expect 2",
+					`rmv 'time_static\.(?P<name>\w+)' 'time_static.${lower(name)}'`,
+					"import time_static.qux 2006-01-02T15:04:05Z",
+					"replace-provider registry.terraform.io/-/null registry.terraform.io/hashicorp/null",
+				},
+				Engine: "native",
+			},
+			want: [][2]interface{}{},
+		},
+		{
+			desc: "missing dir and no actions",
+			config: &StateMigratorConfig{
+				Dir:     dir + "/not_found",
+				Actions: []string{},
+			},
+			want: [][2]interface{}{{"dir", -1}, {"actions", -1}},
+		},
+		{
+			desc: "invalid actions",
+			config: &StateMigratorConfig{
+				Actions: []string{
+					"foo null_resource.foo",
+					"mv null_resource.foo",
+					"mv null_resource.foo null_resource.[",
+					"rm foo",
+					"xmv null_resource.* null_resource.$2",
+					"xmv null_resource.* null_resource.$1_new",
+					"xrm foo",
+					"xrm null_resource.* --expect many",
+					`rmv 'null_resource\.(\w+)' 'null_resource.${name}'`,
+				},
+			},
+			want: [][2]interface{}{{"actions", 0}, {"actions", 1}, {"actions", 2}, {"actions", 3}, {"actions", 4}, {"actions", 5}, {"actions", 6}, {"actions", 7}, {"actions", 8}},
+		},
+		{
+			desc: "import_from without actions",
+			config: &StateMigratorConfig{
+				Dir:         dir,
+				ImportFrom:  manifest,
+				Parallelism: 2,
+			},
+			want: [][2]interface{}{},
+		},
+		{
+			desc: "invalid import_from and parallelism",
+			config: &StateMigratorConfig{
+				Dir:         dir,
+				ImportFrom:  dir + "/not_found.csv",
+				Parallelism: -1,
+			},
+			want: [][2]interface{}{{"import_from", -1}, {"parallelism", -1}},
+		},
+		{
+			desc: "moved twice",
+			config: &StateMigratorConfig{
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+					"mv null_resource.foo null_resource.foo3",
+					"mv null_resource.bar null_resource.foo2",
+				},
+			},
+			want: [][2]interface{}{{"actions", 1}, {"actions", 2}},
+		},
+		{
+			desc: "unknown engine and invalid allow_changes",
+			config: &StateMigratorConfig{
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Engine: "foo",
+				AllowChanges: []AllowChangeRule{
+					{Actions: []string{"destroy"}},
+				},
+			},
+			want: [][2]interface{}{{"engine", -1}, {"allow_changes", -1}},
+		},
+		{
+			desc: "invalid conditions",
+			config: &StateMigratorConfig{
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Preconditions: []StateCondition{
+					{ResourceExists: []string{"null_resource.foo"}},
+					{},
+				},
+				Postconditions: []StateCondition{
+					{State: "to", ResourceExists: []string{"null_resource.foo2"}},
+				},
+			},
+			want: [][2]interface{}{{"precondition", 1}, {"postcondition", 0}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := [][2]interface{}{}
+			for _, e := range tc.config.Validate() {
+				got = append(got, [2]interface{}{e.Attribute, e.Index})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMultiStateMigratorConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+	file, err := os.CreateTemp(dir, "file")
+	if err != nil {
+		t.Fatalf("failed to create a file: %s", err)
+	}
+	file.Close()
+
+	cases := []struct {
+		desc   string
+		config *MultiStateMigratorConfig
+		want   [][2]interface{}
+	}{
+		{
+			desc: "valid",
+			config: &MultiStateMigratorConfig{
+				FromDir: dir,
+				ToDir:   dir,
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo",
+					"mv null_resource.bar null_resource.foo2",
+					"xmv null_resource.* null_resource.$1",
+					"from:rm null_resource.baz",
+					"to:import null_resource.qux qux",
+				},
+			},
+			want: [][2]interface{}{},
+		},
+		{
+			desc: "invalid scoped actions",
+			config: &MultiStateMigratorConfig{
+				FromDir: dir,
+				ToDir:   dir,
+				Actions: []string{
+					"from:rm null_resource.",
+					"to:import null_resource.foo[ foo",
+				},
+			},
+			want: [][2]interface{}{{"actions", 0}, {"actions", 1}},
+		},
+		{
+			desc: "invalid conditions",
+			config: &MultiStateMigratorConfig{
+				FromDir: dir,
+				ToDir:   dir,
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo2",
+				},
+				Preconditions: []StateCondition{
+					{State: "from", ResourceExists: []string{"null_resource.foo"}},
+					{ResourceExists: []string{"null_resource.foo"}},
+				},
+				Postconditions: []StateCondition{
+					{State: "to", ResourceCount: []ResourceCountCondition{{Pattern: "null_resource.*", Equals: -1}}},
+				},
+			},
+			want: [][2]interface{}{{"precondition", 1}, {"postcondition", 0}},
+		},
+		{
+			desc: "invalid",
+			config: &MultiStateMigratorConfig{
+				FromDir: dir + "/not_found",
+				ToDir:   file.Name(),
+				Actions: []string{
+					"mv null_resource.foo null_resource.foo",
+					"mv null_resource.foo null_resource.foo2",
+					"rm null_resource.bar",
+				},
+			},
+			want: [][2]interface{}{{"from_dir", -1}, {"to_dir", -1}, {"actions", 1}, {"actions", 2}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := [][2]interface{}{}
+			for _, e := range tc.config.Validate() {
+				got = append(got, [2]interface{}{e.Attribute, e.Index})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSplitMigratorConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		desc   string
+		config *SplitMigratorConfig
+		want   [][2]interface{}
+	}{
+		{
+			desc: "valid",
+			config: &SplitMigratorConfig{
+				FromDir: dir,
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: dir},
+					{Name: "app", Dir: dir},
+				},
+				Actions: []string{
+					"mv aws_vpc.main network:aws_vpc.main",
+					"xmv aws_instance.* app:aws_instance.$1",
+				},
+			},
+			want: [][2]interface{}{},
+		},
+		{
+			desc: "invalid",
+			config: &SplitMigratorConfig{
+				FromDir: dir,
+				To: []SplitDestinationConfig{
+					{Name: "network", Dir: dir},
+					{Name: "network", Dir: dir + "/not_found"},
+				},
+				Actions: []string{
+					"mv aws_vpc.main network:aws_vpc.main",
+					"mv aws_vpc.main network:aws_vpc.main2",
+					"mv aws_instance.foo app:aws_instance.foo",
+					"mv aws_instance.bar aws_instance.bar",
+				},
+			},
+			want: [][2]interface{}{{"to", 1}, {"to", 1}, {"actions", 1}, {"actions", 2}, {"actions", 3}},
+		},
+		{
+			desc: "no destinations",
+			config: &SplitMigratorConfig{
+				FromDir: dir,
+				Actions: []string{},
+			},
+			want: [][2]interface{}{{"to", -1}, {"actions", -1}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := [][2]interface{}{}
+			for _, e := range tc.config.Validate() {
+				got = append(got, [2]interface{}{e.Attribute, e.Index})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMergeStateMigratorConfigValidate(t *testing.T) {
+	dir := t.TempDir()
+
+	cases := []struct {
+		desc   string
+		config *MergeStateMigratorConfig
+		want   [][2]interface{}
+	}{
+		{
+			desc: "valid",
+			config: &MergeStateMigratorConfig{
+				From: []MergeSourceConfig{
+					{Name: "dev", Dir: dir, Module: "module.dev"},
+					{Name: "prod", Dir: dir, Resources: []string{"aws_s3_bucket.*"}},
+				},
+				ToDir: dir,
+			},
+			want: [][2]interface{}{},
+		},
+		{
+			desc: "invalid",
+			config: &MergeStateMigratorConfig{
+				From: []MergeSourceConfig{
+					{Name: "dev", Dir: dir, Module: "aws_s3_bucket.foo"},
+					{Name: "dev", Dir: dir + "/not_found"},
+				},
+				ToDir: dir + "/not_found",
+			},
+			want: [][2]interface{}{{"to_dir", -1}, {"from", 0}, {"from", 1}, {"from", 1}},
+		},
+		{
+			desc: "no sources",
+			config: &MergeStateMigratorConfig{
+				ToDir: dir,
+			},
+			want: [][2]interface{}{{"from", -1}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := [][2]interface{}{}
+			for _, e := range tc.config.Validate() {
+				got = append(got, [2]interface{}{e.Attribute, e.Index})
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v, want: %v", got, tc.want)
+			}
+		})
+	}
+}
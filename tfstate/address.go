@@ -0,0 +1,324 @@
+package tfstate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keyType is a type of an instance key.
+type keyType int
+
+const (
+	// noKey means that an instance has no key.
+	noKey keyType = iota
+	// intKey means that an instance is indexed by count.
+	intKey
+	// stringKey means that an instance is indexed by for_each.
+	stringKey
+)
+
+// Key represents an instance key of a module or resource.
+// The zero value means no key.
+type Key struct {
+	// typ is a type of key.
+	typ keyType
+	// intValue is a value of key for count.
+	intValue int
+	// stringValue is a value of key for for_each.
+	stringValue string
+}
+
+// NoKey returns a Key which represents no key.
+func NoKey() Key {
+	return Key{typ: noKey}
+}
+
+// IntKey returns a Key for count.
+func IntKey(i int) Key {
+	return Key{typ: intKey, intValue: i}
+}
+
+// StringKey returns a Key for for_each.
+func StringKey(s string) Key {
+	return Key{typ: stringKey, stringValue: s}
+}
+
+// String returns a string representation of the key in address format.
+// (e.g.) "", "[0]", `["foo"]`
+func (k Key) String() string {
+	switch k.typ {
+	case intKey:
+		return fmt.Sprintf("[%d]", k.intValue)
+	case stringKey:
+		return fmt.Sprintf("[%s]", strconv.Quote(k.stringValue))
+	default:
+		return ""
+	}
+}
+
+// eachMode returns a value of the each attribute of a resource in tfstate
+// corresponding to the key type.
+func (k Key) eachMode() string {
+	switch k.typ {
+	case intKey:
+		return "list"
+	case stringKey:
+		return "map"
+	default:
+		return ""
+	}
+}
+
+// ModuleStep represents a step of module path.
+type ModuleStep struct {
+	// Name is a module name.
+	Name string
+	// Key is an instance key of module.
+	Key Key
+}
+
+// Address represents an address of module, resource or resource instance.
+type Address struct {
+	// Module is a module path. It is empty for the root module.
+	Module []ModuleStep
+	// Mode is a resource mode, either managed or data.
+	// It is empty for a module address.
+	Mode string
+	// Type is a resource type.
+	Type string
+	// Name is a resource name.
+	Name string
+	// Key is an instance key of resource.
+	Key Key
+}
+
+// IsModule returns true if the address points to a module.
+func (a *Address) IsModule() bool {
+	return len(a.Mode) == 0
+}
+
+// IsInstance returns true if the address points to a specific resource instance.
+func (a *Address) IsInstance() bool {
+	return !a.IsModule() && a.Key.typ != noKey
+}
+
+// ModulePath returns a string representation of the module path.
+// It is the same format as the module attribute of resource in tfstate.
+// (e.g.) `module.foo["a"].module.bar`
+func (a *Address) ModulePath() string {
+	return formatModulePath(a.Module)
+}
+
+// ResourcePath returns a string representation of the address without
+// instance key.
+func (a *Address) ResourcePath() string {
+	if a.IsModule() {
+		return a.ModulePath()
+	}
+
+	parts := []string{}
+	if len(a.Module) > 0 {
+		parts = append(parts, a.ModulePath())
+	}
+	if a.Mode == "data" {
+		parts = append(parts, "data")
+	}
+	parts = append(parts, a.Type, a.Name)
+	return strings.Join(parts, ".")
+}
+
+// String returns a string representation of the address.
+func (a *Address) String() string {
+	if a.IsModule() {
+		return a.ModulePath()
+	}
+
+	return a.ResourcePath() + a.Key.String()
+}
+
+// formatModulePath returns a string representation of a given module path.
+func formatModulePath(module []ModuleStep) string {
+	parts := make([]string, 0, len(module))
+	for _, m := range module {
+		parts = append(parts, "module."+m.Name+m.Key.String())
+	}
+	return strings.Join(parts, ".")
+}
+
+// addressStep is an intermediate representation of a dot separated part of
+// address.
+type addressStep struct {
+	name string
+	key  Key
+}
+
+// ParseAddress parses a given string and returns an Address.
+// (e.g.) `aws_instance.foo`, `module.foo[0].aws_instance.bar["baz"]`,
+// `data.aws_ami.foo`, `module.foo`
+func ParseAddress(s string) (*Address, error) {
+	steps, err := splitAddress(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse address: %s, err: %s", s, err)
+	}
+
+	a := &Address{}
+	i := 0
+	for i < len(steps) && steps[i].name == "module" {
+		if steps[i].key.typ != noKey || i+1 >= len(steps) {
+			return nil, fmt.Errorf("invalid module address: %s", s)
+		}
+		a.Module = append(a.Module, ModuleStep{Name: steps[i+1].name, Key: steps[i+1].key})
+		i += 2
+	}
+
+	rest := steps[i:]
+	if len(rest) == 0 {
+		if len(a.Module) == 0 {
+			return nil, fmt.Errorf("address is empty")
+		}
+		return a, nil
+	}
+
+	a.Mode = "managed"
+	if rest[0].name == "data" && rest[0].key.typ == noKey {
+		a.Mode = "data"
+		rest = rest[1:]
+	}
+
+	if len(rest) != 2 || rest[0].key.typ != noKey {
+		return nil, fmt.Errorf("invalid resource address: %s", s)
+	}
+	a.Type = rest[0].name
+	a.Name = rest[1].name
+	a.Key = rest[1].key
+
+	return a, nil
+}
+
+// splitAddress splits a given address into dot separated steps.
+// Note that we cannot simply split it by dot because a string key can contain dots.
+func splitAddress(s string) ([]addressStep, error) {
+	steps := []addressStep{}
+	i := 0
+	for {
+		// read a name
+		start := i
+		for i < len(s) && isNameChar(s[i]) {
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("expected a name at %d", i)
+		}
+		step := addressStep{name: s[start:i]}
+
+		// read an optional key
+		if i < len(s) && s[i] == '[' {
+			key, n, err := parseKey(s[i:])
+			if err != nil {
+				return nil, err
+			}
+			step.key = key
+			i += n
+		}
+		steps = append(steps, step)
+
+		if i == len(s) {
+			return steps, nil
+		}
+		if s[i] != '.' {
+			return nil, fmt.Errorf("unexpected character %q at %d", s[i], i)
+		}
+		i++
+	}
+}
+
+// isNameChar returns true if a given character can be used in a name.
+func isNameChar(c byte) bool {
+	return c == '_' || c == '-' ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// parseKey parses a key in brackets at the beginning of a given string.
+// It returns the key and the number of bytes consumed.
+func parseKey(s string) (Key, int, error) {
+	if len(s) > 1 && s[1] == '"' {
+		// string key
+		i := 2
+		for i < len(s) && s[i] != '"' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i+1 >= len(s) || s[i+1] != ']' {
+			return Key{}, 0, fmt.Errorf("unterminated string key: %s", s)
+		}
+		v, err := strconv.Unquote(s[1 : i+1])
+		if err != nil {
+			return Key{}, 0, fmt.Errorf("invalid string key: %s", s[:i+2])
+		}
+		return StringKey(v), i + 2, nil
+	}
+
+	// int key
+	end := strings.IndexByte(s, ']')
+	if end == -1 {
+		return Key{}, 0, fmt.Errorf("unterminated key: %s", s)
+	}
+	v, err := strconv.Atoi(s[1:end])
+	if err != nil {
+		return Key{}, 0, fmt.Errorf("invalid index key: %s", s[:end+1])
+	}
+	return IntKey(v), end + 1, nil
+}
+
+// parseModulePath parses a module path in tfstate.
+func parseModulePath(s string) ([]ModuleStep, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	a, err := ParseAddress(s)
+	if err != nil {
+		return nil, err
+	}
+	if !a.IsModule() {
+		return nil, fmt.Errorf("invalid module path: %s", s)
+	}
+	return a.Module, nil
+}
+
+// hasModulePrefix returns true if a given module path is equal to or nested
+// under a given prefix.
+func hasModulePrefix(module []ModuleStep, prefix []ModuleStep) bool {
+	if len(module) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if module[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// matchModuleFilter returns true if a given module path is equal to or nested
+// under a given filter. Unlike hasModulePrefix, a step of the filter without
+// an instance key matches any instances of the module, as terraform does for
+// the state list and state rm commands.
+// (e.g.) `module.a` matches `module.a[0]` and `module.a["x"].module.b`
+func matchModuleFilter(module []ModuleStep, filter []ModuleStep) bool {
+	if len(module) < len(filter) {
+		return false
+	}
+	for i, f := range filter {
+		if module[i].Name != f.Name {
+			return false
+		}
+		if f.Key.typ != noKey && module[i].Key != f.Key {
+			return false
+		}
+	}
+	return true
+}
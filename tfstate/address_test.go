@@ -0,0 +1,186 @@
+package tfstate
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseAddress(t *testing.T) {
+	cases := []struct {
+		desc string
+		s    string
+		want *Address
+		ok   bool
+	}{
+		{
+			desc: "resource",
+			s:    "aws_instance.foo",
+			want: &Address{Mode: "managed", Type: "aws_instance", Name: "foo"},
+			ok:   true,
+		},
+		{
+			desc: "data resource",
+			s:    "data.aws_ami.foo",
+			want: &Address{Mode: "data", Type: "aws_ami", Name: "foo"},
+			ok:   true,
+		},
+		{
+			desc: "count instance",
+			s:    "aws_instance.foo[0]",
+			want: &Address{Mode: "managed", Type: "aws_instance", Name: "foo", Key: IntKey(0)},
+			ok:   true,
+		},
+		{
+			desc: "for_each instance with dots",
+			s:    `aws_instance.foo["a.b[c]"]`,
+			want: &Address{Mode: "managed", Type: "aws_instance", Name: "foo", Key: StringKey("a.b[c]")},
+			ok:   true,
+		},
+		{
+			desc: "resource in nested modules",
+			s:    `module.foo["a"].module.bar[1].aws_instance.baz`,
+			want: &Address{
+				Module: []ModuleStep{
+					{Name: "foo", Key: StringKey("a")},
+					{Name: "bar", Key: IntKey(1)},
+				},
+				Mode: "managed",
+				Type: "aws_instance",
+				Name: "baz",
+			},
+			ok: true,
+		},
+		{
+			desc: "module",
+			s:    "module.foo",
+			want: &Address{Module: []ModuleStep{{Name: "foo"}}},
+			ok:   true,
+		},
+		{
+			desc: "empty",
+			s:    "",
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "type only",
+			s:    "aws_instance",
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "too many parts",
+			s:    "aws_instance.foo.bar",
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "key on type",
+			s:    "aws_instance[0].foo",
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "module without name",
+			s:    "module",
+			want: nil,
+			ok:   false,
+		},
+		{
+			desc: "unterminated key",
+			s:    `aws_instance.foo["a]`,
+			want: nil,
+			ok:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParseAddress(tc.s)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatalf("expected to return an error, but no error, got: %#v", got)
+			}
+			if tc.ok {
+				if diff := cmp.Diff(got, tc.want, cmp.AllowUnexported(Key{})); diff != "" {
+					t.Errorf("got: %#v, want: %#v, diff: %s", got, tc.want, diff)
+				}
+				if got.String() != tc.s {
+					t.Errorf("got string: %s, want: %s", got.String(), tc.s)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchModuleFilter(t *testing.T) {
+	cases := []struct {
+		desc   string
+		module string
+		filter string
+		want   bool
+	}{
+		{
+			desc:   "same",
+			module: "module.a",
+			filter: "module.a",
+			want:   true,
+		},
+		{
+			desc:   "filter without key matches count instance",
+			module: "module.a[0]",
+			filter: "module.a",
+			want:   true,
+		},
+		{
+			desc:   "filter without key matches for_each instance",
+			module: `module.a["x"].module.b`,
+			filter: "module.a",
+			want:   true,
+		},
+		{
+			desc:   "different key",
+			module: "module.a[0]",
+			filter: "module.a[1]",
+			want:   false,
+		},
+		{
+			desc:   "filter with key doesn't match no key",
+			module: "module.a",
+			filter: "module.a[0]",
+			want:   false,
+		},
+		{
+			desc:   "different name",
+			module: "module.ab",
+			filter: "module.a",
+			want:   false,
+		},
+		{
+			desc:   "filter is longer",
+			module: "module.a[0]",
+			filter: "module.a.module.b",
+			want:   false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			module, err := parseModulePath(tc.module)
+			if err != nil {
+				t.Fatalf("failed to parse module: %s", err)
+			}
+			filter, err := parseModulePath(tc.filter)
+			if err != nil {
+				t.Fatalf("failed to parse filter: %s", err)
+			}
+			got := matchModuleFilter(module, filter)
+			if got != tc.want {
+				t.Errorf("got: %t, want: %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,682 @@
+package tfstate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// supportedStateVersion is a version of tfstate format which we can handle.
+// It has been used since Terraform v0.12.
+const supportedStateVersion = 4
+
+// topLevelKeys is a list of top-level keys of tfstate in the order of
+// Terraform writes them. Unknown keys are written after them in
+// alphabetical order.
+var topLevelKeys = []string{
+	"version",
+	"terraform_version",
+	"serial",
+	"lineage",
+	"outputs",
+	"resources",
+	"check_results",
+}
+
+// State is an in-memory representation of tfstate v4.
+// We parse only what we need to list, move and remove resources, and keep
+// the other parts as they are.
+type State struct {
+	// fields is a set of top-level fields except for resources.
+	fields map[string]json.RawMessage
+	// resources is a list of resources.
+	resources []*resource
+}
+
+// resource represents a resource in tfstate.
+type resource struct {
+	// Module is a module path of resource. It is empty for the root module.
+	Module string `json:"module,omitempty"`
+	// Mode is a resource mode, either managed or data.
+	Mode string `json:"mode"`
+	// Type is a resource type.
+	Type string `json:"type"`
+	// Name is a resource name.
+	Name string `json:"name"`
+	// Each is a type of repetition, either list (count) or map (for_each).
+	// It is omitted for a resource without repetition.
+	Each string `json:"each,omitempty"`
+	// Provider is a provider configuration address.
+	Provider string `json:"provider"`
+	// Instances is a list of resource instances.
+	// We don't parse them except for the index key.
+	Instances []json.RawMessage `json:"instances"`
+}
+
+// ParseState parses a given tfstate and returns a State.
+// Only tfstate v4 is supported.
+func ParseState(b []byte) (*State, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse tfstate: %s", err)
+	}
+
+	var version int
+	if err := json.Unmarshal(fields["version"], &version); err != nil {
+		return nil, fmt.Errorf("failed to parse tfstate version: %s", err)
+	}
+	if version != supportedStateVersion {
+		return nil, fmt.Errorf("unsupported tfstate version: %d", version)
+	}
+
+	resources := []*resource{}
+	if raw, ok := fields["resources"]; ok {
+		if err := json.Unmarshal(raw, &resources); err != nil {
+			return nil, fmt.Errorf("failed to parse resources in tfstate: %s", err)
+		}
+	}
+	delete(fields, "resources")
+
+	return &State{
+		fields:    fields,
+		resources: resources,
+	}, nil
+}
+
+// Bytes encodes the state to bytes in the same format as Terraform writes.
+func (s *State) Bytes() ([]byte, error) {
+	resources, err := json.Marshal(s.resources)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+	fields["resources"] = resources
+
+	keys := []string{}
+	known := make(map[string]bool)
+	for _, k := range topLevelKeys {
+		known[k] = true
+		if _, ok := fields[k]; ok {
+			keys = append(keys, k)
+		}
+	}
+	unknown := []string{}
+	for k := range fields {
+		if !known[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	keys = append(keys, unknown...)
+
+	// build a JSON object in the order of keys.
+	var buf bytes.Buffer
+	buf.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(strconv.Quote(k))
+		buf.WriteString(":")
+		buf.Write(fields[k])
+	}
+	buf.WriteString("}")
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteString("\n")
+	return out.Bytes(), nil
+}
+
+// List returns a list of addresses of resource instances in the state.
+// If addresses are given, it returns only instances matching any of them.
+func (s *State) List(addresses []string) ([]string, error) {
+	filters := make([]*Address, 0, len(addresses))
+	for _, addr := range addresses {
+		a, err := ParseAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, a)
+	}
+
+	list := []string{}
+	for _, r := range s.resources {
+		module, err := parseModulePath(r.Module)
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[Key]bool)
+		for _, raw := range r.Instances {
+			key, err := instanceKey(raw)
+			if err != nil {
+				return nil, err
+			}
+			// skip deposed objects which have the same key.
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			a := &Address{
+				Module: module,
+				Mode:   r.Mode,
+				Type:   r.Type,
+				Name:   r.Name,
+				Key:    key,
+			}
+			if len(filters) == 0 || matchAny(a, filters) {
+				list = append(list, a.String())
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// matchAny returns true if a given instance address matches any of filters.
+func matchAny(instance *Address, filters []*Address) bool {
+	for _, f := range filters {
+		if f.IsModule() {
+			if matchModuleFilter(instance.Module, f.Module) {
+				return true
+			}
+			continue
+		}
+		if f.ResourcePath() != instance.ResourcePath() {
+			continue
+		}
+		if !f.IsInstance() || f.Key == instance.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// Move moves a module, resource or resource instance from source to
+// destination address. It behaves like the terraform state mv command.
+// It increments the serial of the state.
+func (s *State) Move(source string, destination string) error {
+	src, err := ParseAddress(source)
+	if err != nil {
+		return err
+	}
+	dst, err := ParseAddress(destination)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case src.IsModule() && dst.IsModule():
+		err = s.moveModule(src, dst)
+	case src.IsModule() || dst.IsModule():
+		err = fmt.Errorf("cannot move between a module and a resource: %s, %s", source, destination)
+	case src.Mode != dst.Mode || src.Type != dst.Type:
+		err = fmt.Errorf("resource types don't match: %s, %s", source, destination)
+	case !src.IsInstance() && !dst.IsInstance():
+		err = s.moveResource(src, dst)
+	default:
+		err = s.moveInstance(src, dst)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.incrementSerial()
+}
+
+// moveModule moves all resources in a module to another module.
+// If a step of the source module has no instance key, it matches all
+// instances of the module, so that a module with count or for_each can be
+// moved at once. In this case, if the destination module has no instance key
+// either, each instance keeps its key.
+// (e.g.) `module.a` => `module.b` moves `module.a[0]` to `module.b[0]`
+func (s *State) moveModule(src *Address, dst *Address) error {
+	type target struct {
+		r      *resource
+		module []ModuleStep
+		prefix []ModuleStep
+	}
+	targets := []target{}
+	// dsts maps a new module prefix to an original module prefix to detect
+	// that multiple instances are moved to the same destination.
+	dsts := map[string]string{}
+	for _, r := range s.resources {
+		module, err := parseModulePath(r.Module)
+		if err != nil {
+			return err
+		}
+		if !matchModuleFilter(module, src.Module) {
+			continue
+		}
+		prefix := movedModulePrefix(module[:len(src.Module)], src.Module, dst.Module)
+		srcPrefix := formatModulePath(module[:len(src.Module)])
+		dstPrefix := formatModulePath(prefix)
+		if other, ok := dsts[dstPrefix]; ok && other != srcPrefix {
+			return fmt.Errorf("cannot move multiple module instances to the same destination: %s, %s => %s", other, srcPrefix, dstPrefix)
+		}
+		dsts[dstPrefix] = srcPrefix
+		targets = append(targets, target{r: r, module: module, prefix: prefix})
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("no matching objects found: %s", src)
+	}
+
+	for _, r := range s.resources {
+		module, err := parseModulePath(r.Module)
+		if err != nil {
+			return err
+		}
+		if matchModuleFilter(module, src.Module) {
+			continue
+		}
+		for _, t := range targets {
+			if hasModulePrefix(module, t.prefix) {
+				return fmt.Errorf("destination module already exists: %s", formatModulePath(t.prefix))
+			}
+		}
+	}
+
+	for _, t := range targets {
+		newModule := append(append([]ModuleStep{}, t.prefix...), t.module[len(src.Module):]...)
+		t.r.Module = formatModulePath(newModule)
+	}
+
+	return nil
+}
+
+// movedModulePrefix returns a new module prefix for a given matched module
+// prefix when moving src to dst. If both the last steps of src and dst have
+// no instance key, the key of the matched module instance is kept.
+func movedModulePrefix(matched []ModuleStep, src []ModuleStep, dst []ModuleStep) []ModuleStep {
+	prefix := append([]ModuleStep{}, dst...)
+	last := len(src) - 1
+	if src[last].Key.typ == noKey && prefix[len(prefix)-1].Key.typ == noKey {
+		prefix[len(prefix)-1].Key = matched[last].Key
+	}
+	return prefix
+}
+
+// moveResource moves a resource with all its instances.
+func (s *State) moveResource(src *Address, dst *Address) error {
+	r := s.findResource(src)
+	if r == nil {
+		return fmt.Errorf("no matching objects found: %s", src)
+	}
+	if s.findResource(dst) != nil {
+		return fmt.Errorf("destination resource already exists: %s", dst)
+	}
+
+	r.Module = dst.ModulePath()
+	r.Name = dst.Name
+	return nil
+}
+
+// moveInstance moves a resource instance.
+// If the source resource has no instances after moving, it is removed.
+// If the destination resource doesn't exist, it is created.
+func (s *State) moveInstance(src *Address, dst *Address) error {
+	srcResource := s.findResource(src)
+	if srcResource == nil {
+		return fmt.Errorf("no matching objects found: %s", src)
+	}
+
+	moved := []json.RawMessage{}
+	remains := []json.RawMessage{}
+	for _, raw := range srcResource.Instances {
+		key, err := instanceKey(raw)
+		if err != nil {
+			return err
+		}
+		if key == src.Key {
+			moved = append(moved, raw)
+		} else {
+			remains = append(remains, raw)
+		}
+	}
+	if len(moved) == 0 {
+		return fmt.Errorf("no matching objects found: %s", src)
+	}
+
+	dstResource := s.findResource(dst)
+	if dstResource != nil {
+		for _, raw := range dstResource.Instances {
+			key, err := instanceKey(raw)
+			if err != nil {
+				return err
+			}
+			if key == dst.Key && !(dstResource == srcResource && key == src.Key) {
+				return fmt.Errorf("destination instance already exists: %s", dst)
+			}
+		}
+	}
+
+	// rewrite the index key of moved instances.
+	for i, raw := range moved {
+		updated, err := setInstanceKey(raw, dst.Key)
+		if err != nil {
+			return err
+		}
+		moved[i] = updated
+	}
+
+	created := false
+	if dstResource == nil {
+		dstResource = &resource{
+			Module:    dst.ModulePath(),
+			Mode:      dst.Mode,
+			Type:      dst.Type,
+			Name:      dst.Name,
+			Provider:  srcResource.Provider,
+			Instances: []json.RawMessage{},
+		}
+		created = true
+	}
+
+	// check the types of keys before changing the state.
+	instances := []json.RawMessage{}
+	if dstResource == srcResource {
+		instances = append(instances, remains...)
+	} else {
+		instances = append(instances, dstResource.Instances...)
+	}
+	instances = append(instances, moved...)
+	each, err := eachOf(instances)
+	if err != nil {
+		return fmt.Errorf("failed to move %s to %s: %s", src, dst, err)
+	}
+
+	srcResource.Instances = remains
+	if created {
+		s.resources = append(s.resources, dstResource)
+	}
+	dstResource.Instances = append(dstResource.Instances, moved...)
+	dstResource.Each = each
+	if len(srcResource.Instances) == 0 {
+		s.removeResource(srcResource)
+	}
+
+	return nil
+}
+
+// Remove removes modules, resources or resource instances at given addresses.
+// It behaves like the terraform state rm command.
+// It increments the serial of the state.
+func (s *State) Remove(addresses []string) error {
+	for _, addr := range addresses {
+		a, err := ParseAddress(addr)
+		if err != nil {
+			return err
+		}
+		if err := s.remove(a); err != nil {
+			return err
+		}
+	}
+
+	return s.incrementSerial()
+}
+
+// remove removes a module, resource or resource instance at a given address.
+func (s *State) remove(a *Address) error {
+	if a.IsModule() {
+		remains := []*resource{}
+		for _, r := range s.resources {
+			module, err := parseModulePath(r.Module)
+			if err != nil {
+				return err
+			}
+			if !matchModuleFilter(module, a.Module) {
+				remains = append(remains, r)
+			}
+		}
+		if len(remains) == len(s.resources) {
+			return fmt.Errorf("no matching objects found: %s", a)
+		}
+		s.resources = remains
+		return nil
+	}
+
+	r := s.findResource(a)
+	if r == nil {
+		return fmt.Errorf("no matching objects found: %s", a)
+	}
+
+	if !a.IsInstance() {
+		s.removeResource(r)
+		return nil
+	}
+
+	remains := []json.RawMessage{}
+	for _, raw := range r.Instances {
+		key, err := instanceKey(raw)
+		if err != nil {
+			return err
+		}
+		if key != a.Key {
+			remains = append(remains, raw)
+		}
+	}
+	if len(remains) == len(r.Instances) {
+		return fmt.Errorf("no matching objects found: %s", a)
+	}
+	r.Instances = remains
+	if len(r.Instances) == 0 {
+		s.removeResource(r)
+	}
+
+	return nil
+}
+
+// WithoutResources returns a copy of the state which has no resources.
+// The other top-level fields such as lineage and serial are kept as they are.
+func (s *State) WithoutResources() *State {
+	fields := make(map[string]json.RawMessage)
+	for k, v := range s.fields {
+		fields[k] = v
+	}
+
+	return &State{
+		fields:    fields,
+		resources: []*resource{},
+	}
+}
+
+// Merge adds all resource instances in a given state to the state.
+// It fails if any of them already exists in the state, or a resource has a
+// different provider from the one in the state.
+// It increments the serial of the state.
+func (s *State) Merge(other *State) error {
+	for _, r := range other.resources {
+		var dst *resource
+		for _, e := range s.resources {
+			if e.Module == r.Module && e.Mode == r.Mode && e.Type == r.Type && e.Name == r.Name {
+				dst = e
+				break
+			}
+		}
+
+		if dst == nil {
+			instances := append([]json.RawMessage{}, r.Instances...)
+			copied := *r
+			copied.Instances = instances
+			s.resources = append(s.resources, &copied)
+			continue
+		}
+
+		if dst.Provider != r.Provider {
+			return fmt.Errorf("failed to merge resource with a different provider: %s, %s", dst.Provider, r.Provider)
+		}
+
+		existing := make(map[Key]bool)
+		for _, raw := range dst.Instances {
+			key, err := instanceKey(raw)
+			if err != nil {
+				return err
+			}
+			existing[key] = true
+		}
+		for _, raw := range r.Instances {
+			key, err := instanceKey(raw)
+			if err != nil {
+				return err
+			}
+			if existing[key] {
+				module, err := parseModulePath(r.Module)
+				if err != nil {
+					return err
+				}
+				a := &Address{Module: module, Mode: r.Mode, Type: r.Type, Name: r.Name, Key: key}
+				return fmt.Errorf("failed to merge resource instance which already exists: %s", a)
+			}
+		}
+
+		instances := append(append([]json.RawMessage{}, dst.Instances...), r.Instances...)
+		each, err := eachOf(instances)
+		if err != nil {
+			return fmt.Errorf("failed to merge resource %s.%s: %s", r.Type, r.Name, err)
+		}
+		dst.Instances = instances
+		dst.Each = each
+	}
+
+	return s.incrementSerial()
+}
+
+// findResource returns a resource at a given address ignoring its instance
+// key. If not found, it returns nil.
+func (s *State) findResource(a *Address) *resource {
+	modulePath := a.ModulePath()
+	for _, r := range s.resources {
+		if r.Module == modulePath && r.Mode == a.Mode && r.Type == a.Type && r.Name == a.Name {
+			return r
+		}
+	}
+	return nil
+}
+
+// removeResource removes a given resource from the state.
+func (s *State) removeResource(target *resource) {
+	remains := make([]*resource, 0, len(s.resources))
+	for _, r := range s.resources {
+		if r != target {
+			remains = append(remains, r)
+		}
+	}
+	s.resources = remains
+}
+
+// incrementSerial increments the serial of the state as Terraform does on
+// every state change.
+func (s *State) incrementSerial() error {
+	serial, err := s.Serial()
+	if err != nil {
+		return err
+	}
+
+	s.SetSerial(serial + 1)
+	return nil
+}
+
+// Serial returns the serial of the state.
+func (s *State) Serial() (uint64, error) {
+	var serial uint64
+	if raw, ok := s.fields["serial"]; ok {
+		if err := json.Unmarshal(raw, &serial); err != nil {
+			return 0, fmt.Errorf("failed to parse serial of tfstate: %s", err)
+		}
+	}
+	return serial, nil
+}
+
+// SetSerial sets the serial of the state.
+func (s *State) SetSerial(serial uint64) {
+	s.fields["serial"] = json.RawMessage(strconv.FormatUint(serial, 10))
+}
+
+// eachOf returns a value of the each attribute of a resource depending on
+// the index keys of given instances.
+// It returns an error if they have mixed types of keys.
+func eachOf(instances []json.RawMessage) (string, error) {
+	each := ""
+	for i, raw := range instances {
+		key, err := instanceKey(raw)
+		if err != nil {
+			return "", err
+		}
+		if i > 0 && key.eachMode() != each {
+			return "", fmt.Errorf("instances have mixed types of keys")
+		}
+		each = key.eachMode()
+	}
+
+	return each, nil
+}
+
+// instanceKey returns an index key of a given resource instance.
+func instanceKey(raw json.RawMessage) (Key, error) {
+	var instance struct {
+		IndexKey json.RawMessage `json:"index_key"`
+	}
+	if err := json.Unmarshal(raw, &instance); err != nil {
+		return Key{}, fmt.Errorf("failed to parse resource instance: %s", err)
+	}
+
+	if len(instance.IndexKey) == 0 {
+		return NoKey(), nil
+	}
+
+	v := strings.TrimSpace(string(instance.IndexKey))
+	if strings.HasPrefix(v, `"`) {
+		var s string
+		if err := json.Unmarshal(instance.IndexKey, &s); err != nil {
+			return Key{}, fmt.Errorf("failed to parse index key: %s", err)
+		}
+		return StringKey(s), nil
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return Key{}, fmt.Errorf("failed to parse index key: %s", err)
+	}
+	return IntKey(i), nil
+}
+
+// setInstanceKey returns a resource instance with a given index key.
+func setInstanceKey(raw json.RawMessage, key Key) (json.RawMessage, error) {
+	current, err := instanceKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	if current == key {
+		return raw, nil
+	}
+
+	instance := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &instance); err != nil {
+		return nil, fmt.Errorf("failed to parse resource instance: %s", err)
+	}
+
+	switch key.typ {
+	case intKey:
+		instance["index_key"] = json.RawMessage(strconv.Itoa(key.intValue))
+	case stringKey:
+		b, err := json.Marshal(key.stringValue)
+		if err != nil {
+			return nil, err
+		}
+		instance["index_key"] = b
+	default:
+		delete(instance, "index_key")
+	}
+
+	return json.Marshal(instance)
+}
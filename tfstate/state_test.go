@@ -0,0 +1,841 @@
+package tfstate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// testState is a tfstate for testing.
+const testState = `{
+  "version": 4,
+  "terraform_version": "1.9.0",
+  "serial": 3,
+  "lineage": "de2ea2c2-e0e2-4c8e-9a5f-6b0e7f8c0e2a",
+  "outputs": {},
+  "resources": [
+    {
+      "mode": "data",
+      "type": "null_data_source",
+      "name": "d",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {}
+        }
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "bar",
+      "each": "list",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "index_key": 0,
+          "schema_version": 0,
+          "attributes": {
+            "id": "bar0"
+          }
+        },
+        {
+          "index_key": 1,
+          "schema_version": 0,
+          "attributes": {
+            "id": "bar1"
+          }
+        }
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "baz",
+      "each": "map",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "index_key": "a",
+          "schema_version": 0,
+          "attributes": {
+            "id": "baza"
+          }
+        },
+        {
+          "index_key": "b",
+          "schema_version": 0,
+          "attributes": {
+            "id": "bazb"
+          }
+        }
+      ]
+    },
+    {
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "foo"
+          }
+        }
+      ]
+    },
+    {
+      "module": "module.m[\"x\"]",
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "qux",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {
+            "id": "qux"
+          }
+        }
+      ]
+    }
+  ],
+  "check_results": null
+}
+`
+
+// testModuleState is a tfstate with count and for_each modules for testing.
+const testModuleState = `{
+  "version": 4,
+  "terraform_version": "1.9.0",
+  "serial": 1,
+  "lineage": "0c1f6a2e-6a3c-4f6e-9d3a-5d2b9d7c2f10",
+  "outputs": {},
+  "resources": [
+    {
+      "module": "module.a[0]",
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {}
+        }
+      ]
+    },
+    {
+      "module": "module.a[1]",
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {}
+        }
+      ]
+    },
+    {
+      "module": "module.b[\"x\"]",
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {}
+        }
+      ]
+    },
+    {
+      "module": "module.b[\"x\"].module.c[0]",
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "bar",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {}
+        }
+      ]
+    },
+    {
+      "module": "module.ab",
+      "mode": "managed",
+      "type": "null_resource",
+      "name": "foo",
+      "provider": "provider[\"registry.terraform.io/hashicorp/null\"]",
+      "instances": [
+        {
+          "schema_version": 0,
+          "attributes": {}
+        }
+      ]
+    }
+  ],
+  "check_results": null
+}
+`
+
+func TestStateBytes(t *testing.T) {
+	s, err := ParseState([]byte(testState))
+	if err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+
+	got, err := s.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode state: %s", err)
+	}
+	if string(got) != testState {
+		t.Errorf("got: %s, want: %s", string(got), testState)
+	}
+}
+
+func TestParseStateUnsupportedVersion(t *testing.T) {
+	_, err := ParseState([]byte(`{"version": 3, "serial": 1, "modules": []}`))
+	if err == nil {
+		t.Fatal("expected to return an error, but no error")
+	}
+}
+
+func TestStateSerial(t *testing.T) {
+	s, err := ParseState([]byte(testState))
+	if err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+
+	got, err := s.Serial()
+	if err != nil {
+		t.Fatalf("failed to get serial: %s", err)
+	}
+	if got != 3 {
+		t.Errorf("got: %d, want: 3", got)
+	}
+
+	s.SetSerial(10)
+	got, err = s.Serial()
+	if err != nil {
+		t.Fatalf("failed to get serial: %s", err)
+	}
+	if got != 10 {
+		t.Errorf("got: %d, want: 10", got)
+	}
+}
+
+func TestStateList(t *testing.T) {
+	cases := []struct {
+		desc      string
+		addresses []string
+		want      []string
+	}{
+		{
+			desc:      "all",
+			addresses: nil,
+			want: []string{
+				"data.null_data_source.d",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				"null_resource.foo",
+				`module.m["x"].null_resource.qux`,
+			},
+		},
+		{
+			desc:      "filter",
+			addresses: []string{"null_resource.bar", `null_resource.baz["b"]`, `module.m["x"]`},
+			want: []string{
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["b"]`,
+				`module.m["x"].null_resource.qux`,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseState([]byte(testState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+			got, err := s.List(tc.addresses)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %v, want: %v, diff: %s", got, tc.want, diff)
+			}
+		})
+	}
+}
+
+func TestStateListModuleInstances(t *testing.T) {
+	cases := []struct {
+		desc      string
+		addresses []string
+		want      []string
+	}{
+		{
+			desc:      "count module",
+			addresses: []string{"module.a"},
+			want: []string{
+				"module.a[0].null_resource.foo",
+				"module.a[1].null_resource.foo",
+			},
+		},
+		{
+			desc:      "count module instance",
+			addresses: []string{"module.a[1]"},
+			want: []string{
+				"module.a[1].null_resource.foo",
+			},
+		},
+		{
+			desc:      "for_each module",
+			addresses: []string{"module.b"},
+			want: []string{
+				`module.b["x"].null_resource.foo`,
+				`module.b["x"].module.c[0].null_resource.bar`,
+			},
+		},
+		{
+			desc:      "nested module without keys",
+			addresses: []string{"module.b.module.c"},
+			want: []string{
+				`module.b["x"].module.c[0].null_resource.bar`,
+			},
+		},
+		{
+			desc:      "resource in count module",
+			addresses: []string{"module.a.null_resource.foo"},
+			want:      []string{},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseState([]byte(testModuleState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+			got, err := s.List(tc.addresses)
+			if err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if diff := cmp.Diff(got, tc.want); diff != "" {
+				t.Errorf("got: %v, want: %v, diff: %s", got, tc.want, diff)
+			}
+		})
+	}
+}
+
+func TestStateMove(t *testing.T) {
+	cases := []struct {
+		desc        string
+		source      string
+		destination string
+		want        []string
+		ok          bool
+	}{
+		{
+			desc:        "rename resource",
+			source:      "null_resource.foo",
+			destination: "null_resource.foo2",
+			want: []string{
+				"data.null_data_source.d",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				"null_resource.foo2",
+				`module.m["x"].null_resource.qux`,
+			},
+			ok: true,
+		},
+		{
+			desc:        "move resource into module",
+			source:      "null_resource.bar",
+			destination: "module.n.null_resource.bar",
+			want: []string{
+				"data.null_data_source.d",
+				"module.n.null_resource.bar[0]",
+				"module.n.null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				"null_resource.foo",
+				`module.m["x"].null_resource.qux`,
+			},
+			ok: true,
+		},
+		{
+			desc:        "move module",
+			source:      `module.m["x"]`,
+			destination: "module.n.module.m",
+			want: []string{
+				"data.null_data_source.d",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				"null_resource.foo",
+				"module.n.module.m.null_resource.qux",
+			},
+			ok: true,
+		},
+		{
+			desc:        "count index to for_each key",
+			source:      "null_resource.bar[1]",
+			destination: `null_resource.baz["c"]`,
+			want: []string{
+				"data.null_data_source.d",
+				"null_resource.bar[0]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				`null_resource.baz["c"]`,
+				"null_resource.foo",
+				`module.m["x"].null_resource.qux`,
+			},
+			ok: true,
+		},
+		{
+			desc:        "no key to count index of a new resource",
+			source:      "null_resource.foo",
+			destination: "null_resource.qux[0]",
+			want: []string{
+				"data.null_data_source.d",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				`module.m["x"].null_resource.qux`,
+				"null_resource.qux[0]",
+			},
+			ok: true,
+		},
+		{
+			desc:        "mixed keys",
+			source:      "null_resource.foo",
+			destination: "null_resource.baz[2]",
+			ok:          false,
+		},
+		{
+			desc:        "source not found",
+			source:      "null_resource.not_found",
+			destination: "null_resource.foo2",
+			ok:          false,
+		},
+		{
+			desc:        "destination already exists",
+			source:      "null_resource.bar[0]",
+			destination: "null_resource.bar[1]",
+			ok:          false,
+		},
+		{
+			desc:        "resource types don't match",
+			source:      "null_resource.foo",
+			destination: "time_static.foo",
+			ok:          false,
+		},
+		{
+			desc:        "module to resource",
+			source:      `module.m["x"]`,
+			destination: "null_resource.m",
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseState([]byte(testState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+
+			err = s.Move(tc.source, tc.destination)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if !tc.ok {
+				return
+			}
+
+			assertState(t, s, tc.want, 4)
+		})
+	}
+}
+
+func TestStateMoveModuleInstances(t *testing.T) {
+	cases := []struct {
+		desc        string
+		source      string
+		destination string
+		want        []string
+		ok          bool
+	}{
+		{
+			desc:        "count module",
+			source:      "module.a",
+			destination: "module.z",
+			want: []string{
+				"module.z[0].null_resource.foo",
+				"module.z[1].null_resource.foo",
+				`module.b["x"].null_resource.foo`,
+				`module.b["x"].module.c[0].null_resource.bar`,
+				"module.ab.null_resource.foo",
+			},
+			ok: true,
+		},
+		{
+			desc:        "for_each module with nested module",
+			source:      "module.b",
+			destination: "module.y.module.z",
+			want: []string{
+				"module.a[0].null_resource.foo",
+				"module.a[1].null_resource.foo",
+				`module.y.module.z["x"].null_resource.foo`,
+				`module.y.module.z["x"].module.c[0].null_resource.bar`,
+				"module.ab.null_resource.foo",
+			},
+			ok: true,
+		},
+		{
+			desc:        "count module instance",
+			source:      "module.a[1]",
+			destination: "module.z",
+			want: []string{
+				"module.a[0].null_resource.foo",
+				"module.z.null_resource.foo",
+				`module.b["x"].null_resource.foo`,
+				`module.b["x"].module.c[0].null_resource.bar`,
+				"module.ab.null_resource.foo",
+			},
+			ok: true,
+		},
+		{
+			desc:        "multiple instances to a single instance",
+			source:      "module.a",
+			destination: "module.z[0]",
+			ok:          false,
+		},
+		{
+			desc:        "destination has other instances",
+			source:      "module.a",
+			destination: "module.b",
+			ok:          true,
+			want: []string{
+				"module.b[0].null_resource.foo",
+				"module.b[1].null_resource.foo",
+				`module.b["x"].null_resource.foo`,
+				`module.b["x"].module.c[0].null_resource.bar`,
+				"module.ab.null_resource.foo",
+			},
+		},
+		{
+			desc:        "destination instance conflicts",
+			source:      "module.b.module.c",
+			destination: "module.a",
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseState([]byte(testModuleState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+
+			err = s.Move(tc.source, tc.destination)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if !tc.ok {
+				return
+			}
+
+			assertState(t, s, tc.want, 2)
+		})
+	}
+}
+
+func TestStateMoveInstanceKey(t *testing.T) {
+	s, err := ParseState([]byte(testState))
+	if err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+
+	if err := s.Move("null_resource.bar[1]", "null_resource.foo2"); err != nil {
+		t.Fatalf("unexpected err: %s", err)
+	}
+
+	r := s.findResource(&Address{Mode: "managed", Type: "null_resource", Name: "foo2"})
+	if r == nil {
+		t.Fatal("expected to have a moved resource, but not found")
+	}
+	if r.Each != "" {
+		t.Errorf("got each: %s, want empty", r.Each)
+	}
+	var instance map[string]interface{}
+	if err := json.Unmarshal(r.Instances[0], &instance); err != nil {
+		t.Fatalf("failed to parse instance: %s", err)
+	}
+	if _, ok := instance["index_key"]; ok {
+		t.Errorf("expected index_key to be removed, but got: %v", instance["index_key"])
+	}
+	if got := instance["attributes"].(map[string]interface{})["id"]; got != "bar1" {
+		t.Errorf("got id: %v, want: bar1", got)
+	}
+}
+
+func TestStateRemove(t *testing.T) {
+	cases := []struct {
+		desc      string
+		addresses []string
+		want      []string
+		ok        bool
+	}{
+		{
+			desc:      "resource and instance",
+			addresses: []string{"null_resource.foo", `null_resource.baz["a"]`},
+			want: []string{
+				"data.null_data_source.d",
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["b"]`,
+				`module.m["x"].null_resource.qux`,
+			},
+			ok: true,
+		},
+		{
+			desc:      "module and data",
+			addresses: []string{`module.m["x"]`, "data.null_data_source.d"},
+			want: []string{
+				"null_resource.bar[0]",
+				"null_resource.bar[1]",
+				`null_resource.baz["a"]`,
+				`null_resource.baz["b"]`,
+				"null_resource.foo",
+			},
+			ok: true,
+		},
+		{
+			desc:      "not found",
+			addresses: []string{"null_resource.bar[2]"},
+			ok:        false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseState([]byte(testState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+
+			err = s.Remove(tc.addresses)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if !tc.ok {
+				return
+			}
+
+			assertState(t, s, tc.want, 4)
+		})
+	}
+}
+
+func TestStateMerge(t *testing.T) {
+	all := []string{
+		"data.null_data_source.d",
+		"null_resource.bar[0]",
+		"null_resource.bar[1]",
+		`null_resource.baz["a"]`,
+		`null_resource.baz["b"]`,
+		"null_resource.foo",
+		`module.m["x"].null_resource.qux`,
+	}
+
+	cases := []struct {
+		desc        string
+		baseRemove  []string
+		otherRemove []string
+		want        []string
+		wantSerial  int
+		ok          bool
+	}{
+		{
+			desc:        "into a state without resources",
+			baseRemove:  nil,
+			otherRemove: []string{},
+			want:        all,
+			wantSerial:  5,
+			ok:          true,
+		},
+		{
+			desc:        "instances of the same resource",
+			baseRemove:  []string{"null_resource.bar[1]"},
+			otherRemove: []string{"data.null_data_source.d", "null_resource.bar[0]", "null_resource.baz", "null_resource.foo", `module.m["x"]`},
+			want:        all,
+			wantSerial:  5,
+			ok:          true,
+		},
+		{
+			desc:        "already exists",
+			baseRemove:  []string{"null_resource.foo"},
+			otherRemove: []string{"data.null_data_source.d", "null_resource.foo", `module.m["x"]`},
+			ok:          false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			base, err := ParseState([]byte(testState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+			if tc.baseRemove == nil {
+				base = base.WithoutResources()
+				if err := base.incrementSerial(); err != nil {
+					t.Fatalf("failed to increment serial: %s", err)
+				}
+			} else if err := base.Remove(tc.baseRemove); err != nil {
+				t.Fatalf("failed to remove resources from base: %s", err)
+			}
+
+			other, err := ParseState([]byte(testState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+			if len(tc.otherRemove) > 0 {
+				if err := other.Remove(tc.otherRemove); err != nil {
+					t.Fatalf("failed to remove resources from other: %s", err)
+				}
+			}
+
+			err = base.Merge(other)
+			if tc.ok && err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+			if !tc.ok && err == nil {
+				t.Fatal("expected to return an error, but no error")
+			}
+			if !tc.ok {
+				return
+			}
+
+			assertState(t, base, tc.want, tc.wantSerial)
+		})
+	}
+}
+
+func TestStateWithoutResources(t *testing.T) {
+	s, err := ParseState([]byte(testState))
+	if err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+
+	assertState(t, s.WithoutResources(), []string{}, 3)
+
+	// the original state should not be changed.
+	assertState(t, s, []string{
+		"data.null_data_source.d",
+		"null_resource.bar[0]",
+		"null_resource.bar[1]",
+		`null_resource.baz["a"]`,
+		`null_resource.baz["b"]`,
+		"null_resource.foo",
+		`module.m["x"].null_resource.qux`,
+	}, 3)
+}
+
+// assertState is a test helper which checks a list of addresses and a serial
+// after encoding and parsing a given state again.
+func TestStateRemoveModuleInstances(t *testing.T) {
+	cases := []struct {
+		desc      string
+		addresses []string
+		want      []string
+	}{
+		{
+			desc:      "count module",
+			addresses: []string{"module.a"},
+			want: []string{
+				`module.b["x"].null_resource.foo`,
+				`module.b["x"].module.c[0].null_resource.bar`,
+				"module.ab.null_resource.foo",
+			},
+		},
+		{
+			desc:      "for_each module",
+			addresses: []string{"module.b"},
+			want: []string{
+				"module.a[0].null_resource.foo",
+				"module.a[1].null_resource.foo",
+				"module.ab.null_resource.foo",
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := ParseState([]byte(testModuleState))
+			if err != nil {
+				t.Fatalf("failed to parse state: %s", err)
+			}
+
+			if err := s.Remove(tc.addresses); err != nil {
+				t.Fatalf("unexpected err: %s", err)
+			}
+
+			assertState(t, s, tc.want, 2)
+		})
+	}
+}
+
+func assertState(t *testing.T, s *State, want []string, wantSerial int) {
+	t.Helper()
+	b, err := s.Bytes()
+	if err != nil {
+		t.Fatalf("failed to encode state: %s", err)
+	}
+	s, err = ParseState(b)
+	if err != nil {
+		t.Fatalf("failed to parse state: %s", err)
+	}
+
+	got, err := s.List(nil)
+	if err != nil {
+		t.Fatalf("failed to list state: %s", err)
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("got: %v, want: %v, diff: %s", got, want, diff)
+	}
+
+	var serial int
+	if err := json.Unmarshal(s.fields["serial"], &serial); err != nil {
+		t.Fatalf("failed to parse serial: %s", err)
+	}
+	if serial != wantSerial {
+		t.Errorf("got serial: %d, want: %d", serial, wantSerial)
+	}
+}